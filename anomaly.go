@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/database"
+	"dns-sync/internal/models"
+)
+
+// anomalyWindow 计算平均删除数时参考的最近同步次数
+const anomalyWindow = 7
+
+// detectDeleteAnomalies 对比每个域名本次的删除数与最近anomalyWindow次同步的平均值，超出delete_anomaly_factor倍时标记为异常
+// 未配置delete_anomaly_factor或dry-run模式下跳过；失败和跳过的域名不参与也不保存
+// 读写sync_metrics失败只记录警告，不影响同步结果
+func detectDeleteAnomalies(ctx context.Context, cfg *config.Config, store database.Store, stats []*SyncStats) {
+	if cfg.Sync.DeleteAnomalyFactor == 0 || cfg.Sync.DryRun {
+		return
+	}
+
+	domainIDs := make(map[string]string, len(cfg.Domains))
+	for _, domainMapping := range cfg.Domains {
+		domainIDs[models.NormalizeDomain(domainMapping.Domain)] = domainMapping.DomainID
+	}
+
+	for _, stat := range stats {
+		if stat.Error != "" || stat.Skipped {
+			continue
+		}
+		domainID := domainIDs[models.NormalizeDomain(stat.Domain)]
+
+		// 先读取历史再保存本次结果，平均值不包含本次
+		deletes, err := store.GetRecentDeletes(ctx, domainID, anomalyWindow)
+		if err != nil {
+			slog.Warn("Failed to load sync metrics", "domain", stat.Domain, "error", err)
+		} else if len(deletes) > 0 {
+			total := 0
+			for _, deleted := range deletes {
+				total += deleted
+			}
+			average := float64(total) / float64(len(deletes))
+
+			if stat.Deleted >= cfg.Sync.DeleteAnomalyMin && float64(stat.Deleted) > average*cfg.Sync.DeleteAnomalyFactor {
+				stat.DeleteAnomaly = true
+				stat.DeleteAverage = average
+				slog.Warn("Deletion anomaly detected", "domain", stat.Domain, "deleted", stat.Deleted,
+					"average", average, "runs", len(deletes), "factor", cfg.Sync.DeleteAnomalyFactor)
+			}
+		}
+
+		if err := store.SaveSyncMetrics(ctx, domainID, stat.Added, stat.Updated, stat.Deleted); err != nil {
+			slog.Warn("Failed to save sync metrics", "domain", stat.Domain, "error", err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"dns-sync/internal/config"
+)
+
+// TestDetectDeleteAnomalies 本次删除数超过最近anomalyWindow次平均值的delete_anomaly_factor倍且不少于delete_anomaly_min时标记异常，
+// 平均值不包含本次；每次检查后保存本次的删除数，失败的域名不参与
+func TestDetectDeleteAnomalies(t *testing.T) {
+	tests := []struct {
+		name        string
+		history     []int
+		deleted     int
+		failed      bool
+		factor      float64
+		dryRun      bool
+		wantAnomaly bool
+		wantAverage float64
+		wantSaved   bool
+	}{
+		{name: "far above average", history: []int{2, 3, 1, 2}, deleted: 20, factor: 3, wantAnomaly: true,
+			wantAverage: 2, wantSaved: true},
+		{name: "within factor", history: []int{2, 3, 1, 2}, deleted: 6, factor: 3, wantSaved: true},
+		{name: "equal to factor", history: []int{2, 2}, deleted: 6, factor: 3, wantSaved: true},
+		{name: "below minimum", history: []int{0, 0, 0}, deleted: 4, factor: 3, wantSaved: true},
+		{name: "at minimum", history: []int{0, 0, 0}, deleted: 5, factor: 3, wantAnomaly: true, wantSaved: true},
+		// 只参考最近7次，更早的大批删除不拉高平均值
+		{name: "window of seven runs", history: []int{500, 500, 1, 1, 1, 1, 1, 1, 1}, deleted: 10, factor: 3,
+			wantAnomaly: true, wantAverage: 1, wantSaved: true},
+		{name: "no history", deleted: 50, factor: 3, wantSaved: true},
+		{name: "failed domain", history: []int{1, 1}, deleted: 50, failed: true, factor: 3},
+		{name: "disabled", history: []int{1, 1}, deleted: 50},
+		{name: "dry run", history: []int{1, 1}, deleted: 50, factor: 3, dryRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domainMapping := testDomain()
+			cfg := &config.Config{Sync: testSyncConfig(), Domains: []config.DomainMapping{domainMapping}}
+			cfg.Sync.DeleteAnomalyFactor, cfg.Sync.DeleteAnomalyMin, cfg.Sync.DryRun = tt.factor, 5, tt.dryRun
+
+			store := newMemStore()
+			store.metrics = map[string][]int{domainMapping.DomainID: append([]int(nil), tt.history...)}
+			stat := &SyncStats{Domain: domainMapping.Domain, Deleted: tt.deleted}
+			if tt.failed {
+				stat.Error = "failed to get DNS records: request failed"
+			}
+
+			detectDeleteAnomalies(context.Background(), cfg, store, []*SyncStats{stat})
+			if stat.DeleteAnomaly != tt.wantAnomaly {
+				t.Errorf("DeleteAnomaly = %v, want %v", stat.DeleteAnomaly, tt.wantAnomaly)
+			}
+			if tt.wantAverage != 0 && stat.DeleteAverage != tt.wantAverage {
+				t.Errorf("DeleteAverage = %v, want %v", stat.DeleteAverage, tt.wantAverage)
+			}
+			history := store.metrics[domainMapping.DomainID]
+			if saved := len(history) == len(tt.history)+1; saved != tt.wantSaved {
+				t.Fatalf("history = %v, want saved = %v", history, tt.wantSaved)
+			}
+			if tt.wantSaved && history[len(history)-1] != tt.deleted {
+				t.Errorf("saved deletes = %d, want %d", history[len(history)-1], tt.deleted)
+			}
+
+			result := buildSyncReport([]*SyncStats{stat}, time.Now(), time.Now(), false).Domains[0]
+			if result.DeleteAnomaly != tt.wantAnomaly || result.DeleteAverage != stat.DeleteAverage {
+				t.Errorf("report domain = %+v, want delete_anomaly %v", result, tt.wantAnomaly)
+			}
+		})
+	}
+}
+
+// TestDetectDeleteAnomaliesAcrossRuns 连续多次同步的删除数保存为历史，一次异常的大批删除在之后的同步中参与平均
+func TestDetectDeleteAnomaliesAcrossRuns(t *testing.T) {
+	domainMapping := testDomain()
+	cfg := &config.Config{Sync: testSyncConfig(), Domains: []config.DomainMapping{domainMapping}}
+	cfg.Sync.DeleteAnomalyFactor, cfg.Sync.DeleteAnomalyMin = 3, 5
+	store := newMemStore()
+
+	runs := []struct {
+		deleted     int
+		wantAnomaly bool
+	}{
+		{deleted: 2}, {deleted: 1}, {deleted: 3}, {deleted: 2},
+		{deleted: 40, wantAnomaly: true},
+		// 平均值(2+1+3+2+40)/5=9.6，28没有超过3倍
+		{deleted: 28},
+	}
+	for i, run := range runs {
+		stat := &SyncStats{Domain: domainMapping.Domain, Deleted: run.deleted}
+		detectDeleteAnomalies(context.Background(), cfg, store, []*SyncStats{stat})
+		if stat.DeleteAnomaly != run.wantAnomaly {
+			t.Errorf("run %d: DeleteAnomaly = %v, want %v (average %v)", i, stat.DeleteAnomaly, run.wantAnomaly,
+				stat.DeleteAverage)
+		}
+	}
+	if got := fmt.Sprint(store.metrics[domainMapping.DomainID]); got != "[2 1 3 2 40 28]" {
+		t.Errorf("history = %s, want [2 1 3 2 40 28]", got)
+	}
+}
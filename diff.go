@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/database"
+	"dns-sync/internal/logger"
+	"dns-sync/internal/models"
+	"dns-sync/internal/provider"
+)
+
+// ANSI颜色，仅在标准输出为终端时使用
+const (
+	colorReset  = "\033[0m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorRed    = "\033[31m"
+	colorBold   = "\033[1m"
+)
+
+// runDiff 计算每个域名的变更并按域名分组输出，不写入数据库，返回失败的域名数
+// diff只比较拉取方向，即服务商记录相对本地记录的变更
+func runDiff(ctx context.Context, cfg *config.Config, providers map[string]provider.DNSProvider,
+	store database.Store, syncTimeout time.Duration) int {
+
+	if syncTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, syncTimeout)
+		defer cancel()
+	}
+
+	domains := enabledDomains(cfg.Domains)
+
+	// diff始终对比全部记录，不使用增量水位
+	syncCfg := cfg.Sync
+	syncCfg.Since = false
+
+	color := isTerminal(os.Stdout)
+	totalAdded, totalChanged, totalRemoved, failures := 0, 0, 0, 0
+
+	for _, domainMapping := range domains {
+		stats := &SyncStats{Domain: domainMapping.Domain}
+		changes, _, err := computeSyncChanges(ctx, providers[domainMapping.ProviderKey()], store, domainMapping,
+			syncCfg, stats)
+		if err != nil {
+			failures++
+			slog.Error("Error computing diff", "domain", domainMapping.Domain, "error", err)
+			fmt.Fprintf(os.Stdout, "%s: error: %s\n\n", domainMapping.Domain, logger.Redact(err.Error()))
+			continue
+		}
+
+		fmt.Fprint(os.Stdout, formatDomainDiff(domainMapping.Domain, changes, color))
+		totalAdded += len(changes.Inserts)
+		totalChanged += len(changes.Updates)
+		totalRemoved += len(changes.Deletes)
+	}
+
+	fmt.Fprintf(os.Stdout, "%d domains, %d added, %d changed, %d removed",
+		len(domains), totalAdded, totalChanged, totalRemoved)
+	if failures > 0 {
+		fmt.Fprintf(os.Stdout, ", %d failed", failures)
+	}
+	fmt.Fprintln(os.Stdout)
+
+	return failures
+}
+
+// formatDomainDiff 将单个域名的变更格式化为类似git diff的文本
+// 新增以+开头，更新以~开头并列出变化的字段，删除以-开头；每组按子域名排序，末尾为空行
+func formatDomainDiff(domain string, changes *database.SyncChanges, color bool) string {
+	var b strings.Builder
+
+	header := fmt.Sprintf("%s (+%d ~%d -%d)", domain,
+		len(changes.Inserts), len(changes.Updates), len(changes.Deletes))
+	b.WriteString(colorize(header, colorBold, color) + "\n")
+
+	var added []string
+	for _, record := range changes.Inserts {
+		added = append(added, fmt.Sprintf("+ %s %s %s", record.SubDomain, record.Type, recordValue(record)))
+	}
+	sort.Strings(added)
+	for _, line := range added {
+		b.WriteString(colorize(line, colorGreen, color) + "\n")
+	}
+
+	var changed []string
+	for _, update := range changes.Updates {
+		changed = append(changed, fmt.Sprintf("~ %s %s (%s)", update.AliyunRecord.FullDomain(),
+			update.AliyunRecord.Type, describeUpdate(update)))
+	}
+	sort.Strings(changed)
+	for _, line := range changed {
+		b.WriteString(colorize(line, colorYellow, color) + "\n")
+	}
+
+	var removed []string
+	for _, record := range changes.Deletes {
+		removed = append(removed, fmt.Sprintf("- %s %s %s", record.SubDomain, record.Type, recordValue(record)))
+	}
+	sort.Strings(removed)
+	for _, line := range removed {
+		b.WriteString(colorize(line, colorRed, color) + "\n")
+	}
+
+	b.WriteString("\n")
+	return b.String()
+}
+
+// describeUpdate 列出更新前后发生变化的字段，旧值 → 新值
+func describeUpdate(update database.RecordUpdate) string {
+	local := update.LocalRecord
+	remote := update.AliyunRecord.ConvertToAssetSubDomain(local.DomainID, local.ProjectID, local.Source)
+
+	var parts []string
+	if update.Restore {
+		parts = append(parts, "restored")
+	}
+	if update.Relink {
+		parts = append(parts, fmt.Sprintf("record id %s → %s", recordID(local), update.AliyunRecord.RecordId))
+	}
+	if local.SubDomain != remote.SubDomain {
+		parts = append(parts, fmt.Sprintf("name %s → %s", local.SubDomain, remote.SubDomain))
+	}
+	if local.Type != remote.Type {
+		parts = append(parts, fmt.Sprintf("type %s → %s", local.Type, remote.Type))
+	}
+	if recordValue(local) != recordValue(remote) {
+		parts = append(parts, fmt.Sprintf("%s → %s", recordValue(local), recordValue(remote)))
+	}
+	if local.TTL != remote.TTL {
+		parts = append(parts, fmt.Sprintf("ttl %d → %d", local.TTL, remote.TTL))
+	}
+	if local.Weight != remote.Weight {
+		parts = append(parts, fmt.Sprintf("weight %d → %d", local.Weight, remote.Weight))
+	}
+	if local.Priority != remote.Priority {
+		parts = append(parts, fmt.Sprintf("priority %d → %d", local.Priority, remote.Priority))
+	}
+	if local.Line != remote.Line {
+		parts = append(parts, fmt.Sprintf("line %s → %s", local.Line, remote.Line))
+	}
+	if local.LineName != remote.LineName {
+		parts = append(parts, fmt.Sprintf("line name %q → %q", local.LineName, remote.LineName))
+	}
+	if local.Status != "" && local.Status != remote.Status {
+		parts = append(parts, fmt.Sprintf("status %s → %s", local.Status, remote.Status))
+	}
+	if local.Remark != remote.Remark {
+		parts = append(parts, fmt.Sprintf("remark %q → %q", local.Remark, remote.Remark))
+	}
+
+	// 旧数据没有content_hash时，字段可能完全相同
+	if len(parts) == 0 {
+		parts = append(parts, "metadata")
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// recordValue 返回记录值，空值显示为-
+func recordValue(record *models.AssetSubDomain) string {
+	if record.DNSRecord == nil || *record.DNSRecord == "" {
+		return "-"
+	}
+	return *record.DNSRecord
+}
+
+// recordID 返回本地记录关联的RecordId，空值显示为-
+func recordID(record *models.AssetSubDomain) string {
+	if record.AliyunRecordID == nil {
+		return "-"
+	}
+	return *record.AliyunRecordID
+}
+
+// colorize 按需为文本加上ANSI颜色
+func colorize(text, color string, enabled bool) string {
+	if !enabled {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// isTerminal 判断文件是否为终端，输出被重定向到文件或管道时不使用颜色
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	"dns-sync/internal/database"
+	"dns-sync/internal/models"
+)
+
+// TestFormatDomainDiff 新增、更新、删除分组输出并按子域名排序，更新列出变化的字段，开启颜色时每行带ANSI颜色
+func TestFormatDomainDiff(t *testing.T) {
+	domainMapping := testDomain()
+
+	// labelled 与同步时一样先补全线路名称
+	labelled := func(record *models.DNSRecord) *models.DNSRecord {
+		labelLine(record, nil)
+		return record
+	}
+
+	changedValue := labelled(testRecord("1001", "changed", "A", "2.2.2.2"))
+	changedTTL := labelled(testRecord("1002", "api", "CNAME", "lb.example.net"))
+	changedTTL.TTL = 60
+	restored := labelled(testRecord("1003", "old", "A", "10.0.0.3"))
+	relinked := labelled(testRecord("2004", "moved", "A", "10.0.0.4"))
+
+	changes := &database.SyncChanges{
+		Inserts: []*models.AssetSubDomain{
+			testRow(domainMapping, testRecord("1000", "zeta", "A", "1.2.3.4")),
+			testRow(domainMapping, testRecord("1005", "added", "TXT", "")),
+		},
+		Updates: []database.RecordUpdate{
+			{LocalRecord: testRow(domainMapping, testRecord("1001", "changed", "A", "1.1.1.1")), AliyunRecord: changedValue},
+			{LocalRecord: testRow(domainMapping, testRecord("1002", "api", "CNAME", "lb.example.net")),
+				AliyunRecord: changedTTL},
+			{LocalRecord: testRow(domainMapping, testRecord("1003", "old", "A", "10.0.0.3")), AliyunRecord: restored,
+				Restore: true},
+			{LocalRecord: testRow(domainMapping, testRecord("1004", "moved", "A", "10.0.0.4")), AliyunRecord: relinked,
+				Relink: true},
+		},
+		Deletes: []*models.AssetSubDomain{
+			testRow(domainMapping, testRecord("1006", "removed", "A", "10.0.0.6")),
+		},
+	}
+
+	tests := []struct {
+		name  string
+		color bool
+		want  string
+	}{
+		{
+			name: "plain",
+			want: "example.com (+2 ~4 -1)\n" +
+				"+ added.example.com TXT -\n" +
+				"+ zeta.example.com A 1.2.3.4\n" +
+				"~ api.example.com CNAME (ttl 600 → 60)\n" +
+				"~ changed.example.com A (1.1.1.1 → 2.2.2.2)\n" +
+				"~ moved.example.com A (record id 1004 → 2004)\n" +
+				"~ old.example.com A (restored)\n" +
+				"- removed.example.com A 10.0.0.6\n" +
+				"\n",
+		},
+		{
+			name:  "color",
+			color: true,
+			want: colorBold + "example.com (+2 ~4 -1)" + colorReset + "\n" +
+				colorGreen + "+ added.example.com TXT -" + colorReset + "\n" +
+				colorGreen + "+ zeta.example.com A 1.2.3.4" + colorReset + "\n" +
+				colorYellow + "~ api.example.com CNAME (ttl 600 → 60)" + colorReset + "\n" +
+				colorYellow + "~ changed.example.com A (1.1.1.1 → 2.2.2.2)" + colorReset + "\n" +
+				colorYellow + "~ moved.example.com A (record id 1004 → 2004)" + colorReset + "\n" +
+				colorYellow + "~ old.example.com A (restored)" + colorReset + "\n" +
+				colorRed + "- removed.example.com A 10.0.0.6" + colorReset + "\n" +
+				"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatDomainDiff("example.com", changes, tt.color); got != tt.want {
+				t.Errorf("formatDomainDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFormatDomainDiffEmpty 没有变更的域名只输出计数为0的标题
+func TestFormatDomainDiffEmpty(t *testing.T) {
+	want := "example.com (+0 ~0 -0)\n\n"
+	if got := formatDomainDiff("example.com", &database.SyncChanges{}, false); got != want {
+		t.Errorf("formatDomainDiff() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+)
+
+// 进程退出码，便于按失败类型配置告警
+const (
+	// exitOK 全部域名同步成功
+	exitOK = 0
+	// exitSetupError 配置错误，或服务商、数据库连接失败，未开始同步
+	exitSetupError = 2
+	// exitDomainFailed 至少一个域名同步失败
+	exitDomainFailed = 3
+	// exitDeleteThreshold 失败的域名都是因为删除数量超出阈值而放弃同步
+	exitDeleteThreshold = 4
+	// exitDrift verify发现的差异数超过--max-drift
+	exitDrift = 5
+	// exitDegraded 没有域名整体失败，但有域名部分记录写入失败
+	exitDegraded = 6
+	// exitPostSyncFailed 同步本身成功，但post_sync_sql执行失败且配置了post_sync_sql_fatal
+	exitPostSyncFailed = 7
+	// exitLocked 开启了run_lock且另一个实例正在运行，未开始同步
+	exitLocked = 8
+)
+
+// errDeleteThreshold 删除数量超出max_delete_count或max_delete_percent
+var errDeleteThreshold = errors.New("delete threshold exceeded")
+
+// syncExitCode 根据各域名的同步结果确定退出码
+// 只要有一个域名因其它原因失败即返回exitDomainFailed，全部失败都是阈值保护时返回exitDeleteThreshold，
+// 没有域名失败但有域名部分记录写入失败时返回exitDegraded
+func syncExitCode(stats []*SyncStats) int {
+	code := exitOK
+	for _, stat := range stats {
+		if stat.Degraded() && code == exitOK {
+			code = exitDegraded
+		}
+		if stat.Error == "" {
+			continue
+		}
+		if !stat.ThresholdExceeded {
+			return exitDomainFailed
+		}
+		code = exitDeleteThreshold
+	}
+	return code
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/models"
+	"dns-sync/internal/provider"
+)
+
+// exportHeader 导出CSV的表头
+var exportHeader = []string{"domain", "rr", "type", "value", "ttl", "line", "status", "record_id"}
+
+// runExport 拉取每个域名在服务商上的全部记录并导出为CSV，不访问数据库
+// path为空或为-时写入标准输出；有域名拉取失败时返回exitDomainFailed，其它域名照常导出
+func runExport(ctx context.Context, cfg *config.Config, providers map[string]provider.DNSProvider,
+	syncTimeout time.Duration, path string) int {
+
+	if syncTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, syncTimeout)
+		defer cancel()
+	}
+
+	out := io.Writer(os.Stdout)
+	if path != "" && path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fatal("Failed to create export file", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	domains := enabledDomains(cfg.Domains)
+
+	writer := csv.NewWriter(out)
+	if err := writer.Write(exportHeader); err != nil {
+		return fatal("Failed to write export", err)
+	}
+
+	failures, total := 0, 0
+	for _, domainMapping := range domains {
+		records, _, err := fetchDomainRecords(ctx, providers[domainMapping.ProviderKey()], domainMapping)
+		if err != nil {
+			failures++
+			slog.Error("Failed to export domain", "domain", domainMapping.Domain, "error", err)
+			continue
+		}
+
+		if err := writeExportRecords(writer, domainMapping.Domain, records); err != nil {
+			return fatal("Failed to write export", err)
+		}
+		total += len(records)
+		slog.Info("Exported domain records", "domain", domainMapping.Domain, "count", len(records))
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fatal("Failed to write export", err)
+	}
+
+	slog.Info("Export completed", "domains", len(domains), "records", total, "failed", failures)
+	if failures > 0 {
+		return exitDomainFailed
+	}
+	return exitOK
+}
+
+// writeExportRecords 按主机记录、类型和记录值排序后写入CSV，csv.Writer会为包含逗号、引号的值加引号
+// value与写入数据库的dns_record相同，MX记录为"优先级 值"
+func writeExportRecords(writer *csv.Writer, domain string, records []*models.DNSRecord) error {
+	sorted := make([]*models.DNSRecord, len(records))
+	copy(sorted, records)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].HostRecord() != sorted[j].HostRecord() {
+			return sorted[i].HostRecord() < sorted[j].HostRecord()
+		}
+		if sorted[i].Type != sorted[j].Type {
+			return sorted[i].Type < sorted[j].Type
+		}
+		return sorted[i].RecordValue() < sorted[j].RecordValue()
+	})
+
+	for _, record := range sorted {
+		row := []string{
+			domain,
+			record.HostRecord(),
+			record.Type,
+			record.RecordValue(),
+			strconv.Itoa(int(record.TTL)),
+			record.Line,
+			record.Status,
+			record.RecordId,
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write record %s: %w", record.RecordId, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/models"
+	"dns-sync/internal/provider"
+)
+
+// TestRunExport 导出的CSV按主机记录、类型和记录值排序，包含逗号和引号的值加引号；拉取失败的域名跳过并以exitDomainFailed退出
+func TestRunExport(t *testing.T) {
+	spf := testRecord("1003", "@", "TXT", `v=spf1 include:"mail.example.com", -all`)
+	mx := testRecord("1002", "@", "MX", "mail.example.com")
+	mx.Priority = 10
+	disabled := testRecord("1004", "old", "A", "10.0.0.9")
+	disabled.Status, disabled.Line = "DISABLE", "telecom"
+	zone := []*models.DNSRecord{
+		testRecord("1001", "www", "A", "10.0.0.2"),
+		testRecord("1000", "www", "A", "10.0.0.1"),
+		spf, mx, disabled,
+		testRecord("1005", "*", "CNAME", "lb.example.net"),
+	}
+
+	tests := []struct {
+		name     string
+		failNet  bool
+		want     string
+		wantCode int
+	}{
+		{
+			name: "fixture zone",
+			want: "domain,rr,type,value,ttl,line,status,record_id\n" +
+				"example.com,*,CNAME,lb.example.net,600,default,ENABLE,1005\n" +
+				"example.com,@,MX,10 mail.example.com,600,default,ENABLE,1002\n" +
+				`example.com,@,TXT,"v=spf1 include:""mail.example.com"", -all",600,default,ENABLE,1003` + "\n" +
+				"example.com,old,A,10.0.0.9,600,telecom,DISABLE,1004\n" +
+				"example.com,www,A,10.0.0.1,600,default,ENABLE,1000\n" +
+				"example.com,www,A,10.0.0.2,600,default,ENABLE,1001\n" +
+				"example.net,www,A,10.1.0.1,600,default,ENABLE,2000\n",
+			wantCode: exitOK,
+		},
+		{
+			name:    "failed domain skipped",
+			failNet: true,
+			want: "domain,rr,type,value,ttl,line,status,record_id\n" +
+				"example.com,*,CNAME,lb.example.net,600,default,ENABLE,1005\n" +
+				"example.com,@,MX,10 mail.example.com,600,default,ENABLE,1002\n" +
+				`example.com,@,TXT,"v=spf1 include:""mail.example.com"", -all",600,default,ENABLE,1003` + "\n" +
+				"example.com,old,A,10.0.0.9,600,telecom,DISABLE,1004\n" +
+				"example.com,www,A,10.0.0.1,600,default,ENABLE,1000\n" +
+				"example.com,www,A,10.0.0.2,600,default,ENABLE,1001\n",
+			wantCode: exitDomainFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			com := testDomain()
+			com.Account = "com"
+			net := testDomain()
+			net.Domain, net.DomainID, net.Account = "example.net", "domain-2", "net"
+
+			netRecord := testRecord("2000", "www", "A", "10.1.0.1")
+			netRecord.DomainName = "example.net"
+			netProvider := &fakeProvider{records: []*models.DNSRecord{netRecord}}
+			if tt.failNet {
+				netProvider.err = errors.New("request failed")
+			}
+			providers := map[string]provider.DNSProvider{
+				com.ProviderKey(): &fakeProvider{records: zone},
+				net.ProviderKey(): netProvider,
+			}
+			cfg := &config.Config{Domains: []config.DomainMapping{com, net}}
+
+			path := filepath.Join(t.TempDir(), "records.csv")
+			if got := runExport(context.Background(), cfg, providers, 0, path); got != tt.wantCode {
+				t.Errorf("runExport() = %d, want %d", got, tt.wantCode)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("exported CSV =\n%s\nwant\n%s", data, tt.want)
+			}
+		})
+	}
+}
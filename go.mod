@@ -3,6 +3,11 @@ module dns-sync
 go 1.23.3
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
 	github.com/go-sql-driver/mysql v1.7.1
+	github.com/lib/pq v1.10.9
+	golang.org/x/net v0.33.0
 	gopkg.in/yaml.v2 v2.4.0
 )
+
+require golang.org/x/text v0.21.0 // indirect
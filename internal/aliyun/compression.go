@@ -0,0 +1,43 @@
+package aliyun
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// countingReader 统计从底层读取的字节数，用于记录实际传输的响应大小
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// readResponseBody 读取响应体，Content-Encoding为gzip时解压
+// 返回解压后的内容和线上传输的字节数
+func readResponseBody(resp *http.Response) ([]byte, int64, error) {
+	wire := &countingReader{r: resp.Body}
+
+	var reader io.Reader = wire
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(wire)
+		if err != nil {
+			return nil, wire.n, fmt.Errorf("invalid gzip response: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, wire.n, err
+	}
+	return body, wire.n, nil
+}
@@ -0,0 +1,161 @@
+package aliyun
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"dns-sync/internal/config"
+)
+
+// recordsPage 生成n条A记录的DescribeDomainRecords响应
+func recordsPage(t *testing.T, n int) []byte {
+	t.Helper()
+	page := make([]map[string]any, 0, n)
+	for i := 0; i < n; i++ {
+		page = append(page, map[string]any{
+			"DomainName": "example.com",
+			"RecordId":   strconv.Itoa(1000 + i),
+			"RR":         fmt.Sprintf("host%d", i),
+			"Type":       "A",
+			"Value":      fmt.Sprintf("10.0.%d.%d", i/256, i%256),
+			"Line":       "default",
+			"TTL":        600,
+			"Status":     "ENABLE",
+		})
+	}
+	body, err := json.Marshal(map[string]any{
+		"TotalCount":    n,
+		"PageNumber":    1,
+		"PageSize":      500,
+		"RequestId":     "test",
+		"DomainRecords": map[string]any{"Record": page},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// TestGetDomainRecordsGzip 请求带Accept-Encoding: gzip，服务端返回gzip压缩的响应时解压后解析；关闭压缩时不请求gzip
+func TestGetDomainRecordsGzip(t *testing.T) {
+	tests := []struct {
+		name        string
+		disable     bool
+		invalidGzip bool
+		wantGzip    bool
+		wantErr     string
+	}{
+		{name: "gzip response", wantGzip: true},
+		{name: "compression disabled", disable: true},
+		{name: "invalid gzip body", invalidGzip: true, wantGzip: true, wantErr: "invalid gzip response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := recordsPage(t, 300)
+			var gzipRequests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if r.Header.Get("Accept-Encoding") != "gzip" {
+					w.Write(body)
+					return
+				}
+				gzipRequests.Add(1)
+				w.Header().Set("Content-Encoding", "gzip")
+				if tt.invalidGzip {
+					w.Write(body)
+					return
+				}
+				gz := gzip.NewWriter(w)
+				gz.Write(body)
+				gz.Close()
+			}))
+			defer server.Close()
+
+			client, err := NewDNSClient(&config.AliyunConfig{
+				AccessKeyID:        "test-id",
+				AccessKeySecret:    "test-secret",
+				QPS:                1000,
+				PageSize:           500,
+				DisableCompression: tt.disable,
+			})
+			if err != nil {
+				t.Fatalf("NewDNSClient() error = %v", err)
+			}
+			client.endpoint = server.URL
+
+			records, err := client.GetDomainRecords(context.Background(), "example.com")
+			if got := gzipRequests.Load() > 0; got != tt.wantGzip {
+				t.Errorf("requested gzip = %v, want %v", got, tt.wantGzip)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetDomainRecords() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetDomainRecords() error = %v", err)
+			}
+			if len(records) != 300 {
+				t.Fatalf("got %d records, want 300", len(records))
+			}
+			if last := records[299]; last.RecordId != "1299" || last.Value != "10.0.1.43" {
+				t.Errorf("last record = %+v", last)
+			}
+		})
+	}
+}
+
+// TestReadResponseBody 返回解压后的内容和线上传输的字节数，gzip响应的传输字节数小于解压后的大小
+func TestReadResponseBody(t *testing.T) {
+	plain := bytes.Repeat([]byte(`{"RR":"www","Type":"A","Value":"10.0.0.1"},`), 100)
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(plain)
+	gz.Close()
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantWire int64
+	}{
+		{name: "plain", body: plain, wantWire: int64(len(plain))},
+		{name: "gzip", encoding: "gzip", body: compressed.Bytes(), wantWire: int64(compressed.Len())},
+		{name: "upper case encoding", encoding: "GZIP", body: compressed.Bytes(), wantWire: int64(compressed.Len())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(tt.body))}
+			if tt.encoding != "" {
+				resp.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			body, wire, err := readResponseBody(resp)
+			if err != nil {
+				t.Fatalf("readResponseBody() error = %v", err)
+			}
+			if !bytes.Equal(body, plain) {
+				t.Errorf("body = %d bytes, want %d bytes of the original", len(body), len(plain))
+			}
+			if wire != tt.wantWire {
+				t.Errorf("wire bytes = %d, want %d", wire, tt.wantWire)
+			}
+		})
+	}
+	if compressed.Len() >= len(plain) {
+		t.Errorf("compressed size %d not smaller than %d", compressed.Len(), len(plain))
+	}
+}
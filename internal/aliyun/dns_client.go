@@ -1,13 +1,14 @@
 package aliyun
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"sort"
@@ -17,16 +18,36 @@ import (
 
 	"dns-sync/internal/config"
 	"dns-sync/internal/models"
+	"dns-sync/internal/provider"
+	"dns-sync/internal/tracing"
 )
 
+// maxPages 单个域名最多拉取的页数，防止分页死循环
+const maxPages = 1000
+
 // DNSClient 阿里云DNS客户端
 type DNSClient struct {
-	accessKeyID     string
-	accessKeySecret string
-	region          string
-	endpoint        string
+	accessKeyID      string
+	accessKeySecret  string
+	securityToken    string
+	region           string
+	endpoint         string
+	httpClient       *http.Client
+	// signatureVersion 签名版本：v1（HMAC-SHA1）或v3（ACS3-HMAC-SHA256）
+	signatureVersion string
+	// limiter 所有请求共享的限流器
+	limiter *rateLimiter
+	// compression 是否请求gzip压缩的响应
+	compression bool
+	// orderBy 拉取记录时的OrderBy参数，为空时不传，使用接口的默认排序
+	orderBy string
+	// pageSize 拉取记录时每页的记录数
+	pageSize int64
 }
 
+// errTotalCountChanged 分页过程中TotalCount发生变化，说明域名下的记录正在被修改，已获取的结果可能遗漏或重复
+var errTotalCountChanged = errors.New("record count changed during pagination")
+
 // DomainRecordsResponse API响应结构
 type DomainRecordsResponse struct {
 	TotalCount    int64 `json:"TotalCount"`
@@ -46,18 +67,34 @@ type DomainRecordsResponse struct {
 			Status          string `json:"Status"`
 			Locked          bool   `json:"Locked"`
 			Weight          *int32 `json:"Weight,omitempty"`
+			// LbaStatus 是否开启加权轮询，部分接口版本不返回
+			LbaStatus       *bool  `json:"LbaStatus,omitempty"`
+			// Remark 记录备注，未设置备注时不返回
+			Remark          string `json:"Remark,omitempty"`
 			CreateTimestamp *int64 `json:"CreateTimestamp,omitempty"`
 			UpdateTimestamp *int64 `json:"UpdateTimestamp,omitempty"`
 		} `json:"Record"`
 	} `json:"DomainRecords"`
 }
 
-// DomainsResponse API响应结构用于测试连接
+// RecordResponse 新增、修改、删除记录的API响应结构
+type RecordResponse struct {
+	RequestId string `json:"RequestId"`
+	RecordId  string `json:"RecordId"`
+}
+
+// DomainsResponse API响应结构用于测试连接和检查域名
 type DomainsResponse struct {
 	TotalCount int64 `json:"TotalCount"`
 	PageNumber int64 `json:"PageNumber"`
 	PageSize   int64 `json:"PageSize"`
 	RequestId  string `json:"RequestId"`
+	Domains    struct {
+		Domain []struct {
+			DomainName string `json:"DomainName"`
+			PunyCode   string `json:"PunyCode"`
+		} `json:"Domain"`
+	} `json:"Domains"`
 }
 
 // NewDNSClient 创建DNS客户端
@@ -66,19 +103,70 @@ func NewDNSClient(cfg *config.AliyunConfig) (*DNSClient, error) {
 		return nil, fmt.Errorf("access key id and secret are required")
 	}
 
-	endpoint := "https://alidns.cn-hangzhou.aliyuncs.com"
-	if cfg.Region != "" && cfg.Region != "cn-hangzhou" {
-		endpoint = fmt.Sprintf("https://alidns.%s.aliyuncs.com", cfg.Region)
+	// Region在加载配置时已默认为cn-hangzhou
+	region := cfg.Region
+	if region == "" {
+		region = "cn-hangzhou"
 	}
+	endpoint := fmt.Sprintf("https://alidns.%s.aliyuncs.com", region)
+
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// QPS在加载配置时已默认为10
+	qps := cfg.QPS
+	if qps <= 0 {
+		qps = 10
+	}
+	// 每页记录数在加载配置时已默认为100并限制在接口上限内
+	pageSize := cfg.PageSize
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+	pageSize = min(pageSize, config.MaxAliyunPageSize)
 
 	return &DNSClient{
-		accessKeyID:     cfg.AccessKeyID,
-		accessKeySecret: cfg.AccessKeySecret,
-		region:          cfg.Region,
-		endpoint:        endpoint,
+		accessKeyID:      cfg.AccessKeyID,
+		accessKeySecret:  cfg.AccessKeySecret,
+		securityToken:    cfg.SecurityToken,
+		region:           region,
+		endpoint:         endpoint,
+		httpClient:       httpClient,
+		signatureVersion: cfg.SignatureVersion,
+		limiter:          newRateLimiter(qps),
+		compression:      !cfg.DisableCompression,
+		orderBy:          cfg.OrderBy,
+		pageSize:         pageSize,
 	}, nil
 }
 
+// newHTTPClient 创建复用连接的HTTP客户端，分页拉取时无需重复建立连接
+// 响应的gzip解压由makeRequest处理，以便统计实际传输的字节数，这里关闭Transport的自动解压
+func newHTTPClient(cfg *config.AliyunConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableCompression = true
+	// 同一账号的域名并发同步时共享同一个host，默认每个host只保留2个空闲连接
+	transport.MaxIdleConnsPerHost = 16
+	transport.IdleConnTimeout = 90 * time.Second
+	transport.Proxy = http.ProxyFromEnvironment
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
 // signRequest 对请求进行签名
 func (c *DNSClient) signRequest(params map[string]string) string {
 	// 添加公共参数
@@ -89,8 +177,17 @@ func (c *DNSClient) signRequest(params map[string]string) string {
 	params["SignatureVersion"] = "1.0"
 	params["SignatureNonce"] = strconv.FormatInt(time.Now().UnixNano(), 10)
 	params["Format"] = "JSON"
-	params["Version"] = "2015-01-09"
+	params["Version"] = apiVersion
+	// 使用STS临时凭证时需要携带SecurityToken并参与签名
+	if c.securityToken != "" {
+		params["SecurityToken"] = c.securityToken
+	}
 
+	return signV1(c.accessKeySecret, params)
+}
+
+// signV1 按V1规则计算已包含公共参数的请求签名
+func signV1(accessKeySecret string, params map[string]string) string {
 	// 排序参数
 	var keys []string
 	for k := range params {
@@ -109,15 +206,15 @@ func (c *DNSClient) signRequest(params map[string]string) string {
 	stringToSign := "GET&%2F&" + url.QueryEscape(queryString)
 
 	// 计算签名
-	mac := hmac.New(sha1.New, []byte(c.accessKeySecret+"&"))
+	mac := hmac.New(sha1.New, []byte(accessKeySecret+"&"))
 	mac.Write([]byte(stringToSign))
 	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
 
 	return signature
 }
 
-// makeRequest 发送HTTP请求
-func (c *DNSClient) makeRequest(params map[string]string) ([]byte, error) {
+// newRequestV1 构建使用V1签名的请求，签名和公共参数都放在查询字符串里
+func (c *DNSClient) newRequestV1(ctx context.Context, params map[string]string) (*http.Request, error) {
 	signature := c.signRequest(params)
 	params["Signature"] = signature
 
@@ -133,54 +230,159 @@ func (c *DNSClient) makeRequest(params map[string]string) ([]byte, error) {
 	}
 	u.RawQuery = query.Encode()
 
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	return req, nil
+}
+
+// makeRequest 按配置的签名版本发送HTTP请求
+func (c *DNSClient) makeRequest(ctx context.Context, params map[string]string) ([]byte, error) {
+	body, _, err := c.doRequest(ctx, params)
+	return body, err
+}
+
+// doRequest 发送请求并返回解压后的响应体和线上传输的字节数，开启追踪时每次请求一个span
+func (c *DNSClient) doRequest(ctx context.Context, params map[string]string) ([]byte, int64, error) {
+	ctx, span := tracing.StartClient(ctx, "aliyun.request", "action", params["Action"], "domain", params["DomainName"])
+	if page := params["PageNumber"]; page != "" {
+		span.SetAttributes("page", page)
+	}
+	body, wireBytes, err := c.sendRequest(ctx, params)
+	span.SetAttributes("wire_bytes", wireBytes, "bytes", len(body))
+	span.RecordError(err)
+	span.End()
+	return body, wireBytes, err
+}
+
+// sendRequest 等待限流令牌后签名并发送请求
+func (c *DNSClient) sendRequest(ctx context.Context, params map[string]string) ([]byte, int64, error) {
+	// 等待限流令牌，超出QPS配额时阻塞而不是报错；在签名前等待，避免签名时间戳过期
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, 0, fmt.Errorf("rate limit wait cancelled: %w", err)
+	}
+
+	var req *http.Request
+	var err error
+	if c.signatureVersion == "v3" {
+		req, err = c.newRequestV3(ctx, params)
+	} else {
+		req, err = c.newRequestV1(ctx, params)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.compression {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+
 	// 发送请求
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Get(u.String())
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		// v1签名的参数都在URL中，包括Signature和SecurityToken，错误信息中只保留不带参数的地址
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = c.endpoint
+		}
+		return nil, 0, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, wireBytes, err := readResponseBody(resp)
 	if err != nil {
-		return nil, fmt.Errorf("read response failed: %w", err)
+		return nil, wireBytes, fmt.Errorf("read response failed: %w", err)
 	}
+	slog.Debug("Aliyun API response", "action", params["Action"], "status", resp.StatusCode,
+		"wire_bytes", wireBytes, "bytes", len(body))
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		// 错误响应通常为带Code的JSON，解析为APIError便于调用方区分错误类型
+		if apiErr := parseAPIError(resp.StatusCode, body); apiErr != nil {
+			return nil, wireBytes, apiErr
+		}
+		return nil, wireBytes, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
-	return body, nil
+	return body, wireBytes, nil
 }
 
 // GetDomainRecords 获取域名的DNS记录
-func (c *DNSClient) GetDomainRecords(domain string) ([]*models.DNSRecord, error) {
-	log.Printf("Getting DNS records for domain: %s", domain)
+// 分页过程中TotalCount变化时重新拉取一次完整列表，仍然变化时返回错误，避免用不一致的结果删除本地记录
+func (c *DNSClient) GetDomainRecords(ctx context.Context, domain string) ([]*models.DNSRecord, error) {
+	slog.Debug("Getting DNS records", "provider", "aliyun", "domain", domain)
+
+	records, err := c.listDomainRecords(ctx, domain)
+	if errors.Is(err, errTotalCountChanged) {
+		slog.Warn("Records changed while paging, fetching the domain again", "provider", "aliyun",
+			"domain", domain, "error", err)
+		records, err = c.listDomainRecords(ctx, domain)
+	}
+	return records, err
+}
 
+// listDomainRecords 分页拉取域名的全部记录，TotalCount与第一页不一致时返回errTotalCountChanged
+func (c *DNSClient) listDomainRecords(ctx context.Context, domain string) ([]*models.DNSRecord, error) {
 	var allRecords []*models.DNSRecord
 	pageNumber := int64(1)
-	pageSize := int64(100)
+	pageSize := c.pageSize
+	pagesFetched := 0
+	seen := make(map[string]bool)
+	var wireBytes, bodyBytes int64
+	totalCount := int64(-1)
 
 	for {
+		// 防止TotalCount异常导致死循环
+		if pagesFetched >= maxPages {
+			return nil, fmt.Errorf("pagination for %s exceeded %d pages, aborting to avoid infinite loop", domain, maxPages)
+		}
+
 		params := map[string]string{
 			"Action":     "DescribeDomainRecords",
 			"DomainName": domain,
 			"PageNumber": strconv.FormatInt(pageNumber, 10),
 			"PageSize":   strconv.FormatInt(pageSize, 10),
 		}
+		if c.orderBy != "" {
+			params["OrderBy"] = c.orderBy
+		}
 
-		body, err := c.makeRequest(params)
+		body, n, err := c.doRequest(ctx, params)
 		if err != nil {
 			return nil, fmt.Errorf("failed to describe domain records for %s: %w", domain, err)
 		}
+		pagesFetched++
+		wireBytes += n
+		bodyBytes += int64(len(body))
 
 		var response DomainRecordsResponse
 		if err := json.Unmarshal(body, &response); err != nil {
 			return nil, fmt.Errorf("failed to parse response: %w", err)
 		}
 
+		// 按页码分页时，记录增删会使后续页整体偏移，导致漏掉或重复获取记录
+		if totalCount < 0 {
+			totalCount = response.TotalCount
+		} else if response.TotalCount != totalCount {
+			return nil, fmt.Errorf("%w for %s: %d on page 1, %d on page %d",
+				errTotalCountChanged, domain, totalCount, response.TotalCount, pageNumber)
+		}
+
+		// 空页面表示已经没有更多记录，不再依赖可能过期的TotalCount
+		if len(response.DomainRecords.Record) == 0 {
+			break
+		}
+
 		// 转换记录格式
+		newInPage := 0
 		for _, record := range response.DomainRecords.Record {
+			if seen[record.RecordId] {
+				continue
+			}
+			seen[record.RecordId] = true
+			newInPage++
+
 			dnsRecord := &models.DNSRecord{
 				DomainName: record.DomainName,
 				RR:         record.RR,
@@ -190,15 +392,25 @@ func (c *DNSClient) GetDomainRecords(domain string) ([]*models.DNSRecord, error)
 				Line:       record.Line,
 				Status:     record.Status,
 				Locked:     record.Locked,
+				Remark:     record.Remark,
 			}
 
 			// 处理可能为nil的字段
 			if record.TTL != nil {
 				dnsRecord.TTL = *record.TTL
 			}
+			if record.Priority != nil {
+				dnsRecord.Priority = *record.Priority
+			}
 			if record.Weight != nil {
 				dnsRecord.Weight = *record.Weight
 			}
+			// 未返回LbaStatus时按是否返回Weight判断，未开启加权轮询的记录不返回Weight
+			if record.LbaStatus != nil {
+				dnsRecord.LbaStatus = *record.LbaStatus
+			} else {
+				dnsRecord.LbaStatus = record.Weight != nil
+			}
 			if record.CreateTimestamp != nil {
 				dnsRecord.CreateTimestamp = *record.CreateTimestamp
 			}
@@ -209,24 +421,105 @@ func (c *DNSClient) GetDomainRecords(domain string) ([]*models.DNSRecord, error)
 			allRecords = append(allRecords, dnsRecord)
 		}
 
-		// 检查是否还有更多页面
-		if int64(len(allRecords)) >= response.TotalCount {
+		// 整页都是已获取过的记录，说明接口在重复返回同一页
+		if newInPage == 0 {
+			return nil, fmt.Errorf("page %d for %s returned only duplicate records, aborting to avoid infinite loop", pageNumber, domain)
+		}
+
+		var stop bool
+		if allRecords, stop, err = provider.ApplyRecordLimit(ctx, domain, allRecords); err != nil || stop {
+			if err != nil {
+				return nil, err
+			}
 			break
 		}
 
-		pageNumber++
-		if pageNumber > (response.TotalCount/pageSize)+1 {
+		// 最后一页不满pageSize，说明已经取完
+		if int64(len(response.DomainRecords.Record)) < pageSize {
 			break
 		}
+
+		pageNumber++
 	}
 
-	log.Printf("Retrieved %d DNS records for domain: %s", len(allRecords), domain)
+	slog.Info("Retrieved DNS records", "provider", "aliyun", "domain", domain,
+		"count", len(allRecords), "pages", pagesFetched)
+	slog.Debug("DNS records transfer size", "provider", "aliyun", "domain", domain,
+		"wire_bytes", wireBytes, "bytes", bodyBytes)
 	return allRecords, nil
 }
 
+// recordParams 构建新增、修改记录的公共参数
+func recordParams(record *models.DNSRecord) map[string]string {
+	params := map[string]string{
+		"RR":    record.RR,
+		"Type":  record.Type,
+		"Value": record.Value,
+	}
+	if record.TTL > 0 {
+		params["TTL"] = strconv.Itoa(int(record.TTL))
+	}
+	if record.Type == "MX" && record.Priority > 0 {
+		params["Priority"] = strconv.Itoa(int(record.Priority))
+	}
+	if record.Line != "" {
+		params["Line"] = record.Line
+	}
+	return params
+}
+
+// AddDomainRecord 新增解析记录，返回阿里云分配的RecordId
+func (c *DNSClient) AddDomainRecord(ctx context.Context, record *models.DNSRecord) (string, error) {
+	params := recordParams(record)
+	params["Action"] = "AddDomainRecord"
+	params["DomainName"] = record.DomainName
+
+	body, err := c.makeRequest(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to add domain record %s.%s: %w", record.RR, record.DomainName, err)
+	}
+
+	var response RecordResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if response.RecordId == "" {
+		return "", fmt.Errorf("add domain record response has no RecordId")
+	}
+
+	return response.RecordId, nil
+}
+
+// UpdateDomainRecord 修改解析记录
+func (c *DNSClient) UpdateDomainRecord(ctx context.Context, record *models.DNSRecord) error {
+	params := recordParams(record)
+	params["Action"] = "UpdateDomainRecord"
+	params["RecordId"] = record.RecordId
+
+	if _, err := c.makeRequest(ctx, params); err != nil {
+		return fmt.Errorf("failed to update domain record %s: %w", record.RecordId, err)
+	}
+
+	return nil
+}
+
+// DeleteDomainRecord 删除解析记录
+func (c *DNSClient) DeleteDomainRecord(ctx context.Context, recordID string) error {
+	params := map[string]string{
+		"Action":   "DeleteDomainRecord",
+		"RecordId": recordID,
+	}
+
+	if _, err := c.makeRequest(ctx, params); err != nil {
+		return fmt.Errorf("failed to delete domain record %s: %w", recordID, err)
+	}
+
+	return nil
+}
+
 // TestConnection 测试连接
-func (c *DNSClient) TestConnection() error {
-	log.Println("Testing Aliyun DNS connection...")
+func (c *DNSClient) TestConnection(ctx context.Context) error {
+	slog.Debug("Testing DNS connection", "provider", "aliyun")
 
 	params := map[string]string{
 		"Action":     "DescribeDomains",
@@ -234,7 +527,7 @@ func (c *DNSClient) TestConnection() error {
 		"PageSize":   "1",
 	}
 
-	body, err := c.makeRequest(params)
+	body, err := c.makeRequest(ctx, params)
 	if err != nil {
 		return fmt.Errorf("failed to test aliyun connection: %w", err)
 	}
@@ -244,6 +537,50 @@ func (c *DNSClient) TestConnection() error {
 		return fmt.Errorf("failed to parse test response: %w", err)
 	}
 
-	log.Println("Aliyun DNS connection test successful")
+	slog.Debug("DNS connection test successful", "provider", "aliyun")
 	return nil
 }
+
+// VerifyDomains 分页拉取账号下的全部域名，检查配置的域名是否都存在
+func (c *DNSClient) VerifyDomains(ctx context.Context, domains []string) error {
+	found := make(map[string]bool)
+	pageSize := int64(100)
+
+	for pageNumber := int64(1); pageNumber <= maxPages; pageNumber++ {
+		params := map[string]string{
+			"Action":     "DescribeDomains",
+			"PageNumber": strconv.FormatInt(pageNumber, 10),
+			"PageSize":   strconv.FormatInt(pageSize, 10),
+		}
+
+		body, err := c.makeRequest(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to list aliyun domains: %w", err)
+		}
+
+		var response DomainsResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return fmt.Errorf("failed to parse domains response: %w", err)
+		}
+
+		for _, domain := range response.Domains.Domain {
+			found[models.NormalizeDomain(domain.DomainName)] = true
+			if domain.PunyCode != "" {
+				found[models.NormalizeDomain(domain.PunyCode)] = true
+			}
+		}
+
+		if int64(len(response.Domains.Domain)) < pageSize || pageNumber*pageSize >= response.TotalCount {
+			break
+		}
+	}
+
+	var missing []string
+	for _, domain := range domains {
+		if !found[models.NormalizeDomain(domain)] {
+			missing = append(missing, domain)
+		}
+	}
+
+	return provider.MissingDomainsError(missing)
+}
@@ -0,0 +1,833 @@
+package aliyun
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/models"
+	"dns-sync/internal/provider"
+)
+
+// newMockRecordsServer 模拟DescribeDomainRecords接口，按PageNumber和PageSize返回records条记录
+// totalCount为响应中的TotalCount，可以与实际记录数不同以模拟过期的计数；repeatFirstPage为true时忽略页码
+func newMockRecordsServer(t *testing.T, records int, totalCount int64, repeatFirstPage bool, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		query := r.URL.Query()
+		if action := query.Get("Action"); action != "DescribeDomainRecords" {
+			http.Error(w, "unexpected action "+action, http.StatusBadRequest)
+			return
+		}
+		pageNumber, _ := strconv.Atoi(query.Get("PageNumber"))
+		pageSize, _ := strconv.Atoi(query.Get("PageSize"))
+		if repeatFirstPage {
+			pageNumber = 1
+		}
+
+		page := []map[string]any{}
+		for i := (pageNumber - 1) * pageSize; i < pageNumber*pageSize && i < records; i++ {
+			page = append(page, map[string]any{
+				"DomainName": "example.com",
+				"RecordId":   strconv.Itoa(1000 + i),
+				"RR":         fmt.Sprintf("host%d", i),
+				"Type":       "A",
+				"Value":      fmt.Sprintf("10.0.%d.%d", i/256, i%256),
+				"Line":       "default",
+				"TTL":        600,
+				"Status":     "ENABLE",
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"TotalCount":    totalCount,
+			"PageNumber":    pageNumber,
+			"PageSize":      pageSize,
+			"RequestId":     "test",
+			"DomainRecords": map[string]any{"Record": page},
+		})
+	}))
+}
+
+func TestGetDomainRecordsPagination(t *testing.T) {
+	tests := []struct {
+		name            string
+		records         int
+		totalCount      int64
+		pageSize        int64
+		repeatFirstPage bool
+		wantRecords     int
+		wantRequests    int32
+		wantErr         string
+	}{
+		{
+			name:         "250 records with a final empty page",
+			records:      250,
+			totalCount:   250,
+			pageSize:     125,
+			wantRecords:  250,
+			wantRequests: 3,
+		},
+		{
+			name:         "short last page",
+			records:      250,
+			totalCount:   250,
+			pageSize:     100,
+			wantRecords:  250,
+			wantRequests: 3,
+		},
+		{
+			name:         "stale total count stops on empty page",
+			records:      250,
+			totalCount:   400,
+			pageSize:     125,
+			wantRecords:  250,
+			wantRequests: 3,
+		},
+		{
+			name:            "repeated page aborts",
+			records:         250,
+			totalCount:      250,
+			pageSize:        125,
+			repeatFirstPage: true,
+			wantRequests:    2,
+			wantErr:         "page 2 for example.com returned only duplicate records",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := newMockRecordsServer(t, tt.records, tt.totalCount, tt.repeatFirstPage, &requests)
+			defer server.Close()
+
+			client, err := NewDNSClient(&config.AliyunConfig{
+				AccessKeyID:        "test-id",
+				AccessKeySecret:    "test-secret",
+				QPS:                1000,
+				PageSize:           tt.pageSize,
+				DisableCompression: true,
+			})
+			if err != nil {
+				t.Fatalf("NewDNSClient() error = %v", err)
+			}
+			client.endpoint = server.URL
+
+			records, err := client.GetDomainRecords(context.Background(), "example.com")
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", got, tt.wantRequests)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetDomainRecords() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetDomainRecords() error = %v", err)
+			}
+			if len(records) != tt.wantRecords {
+				t.Fatalf("got %d records, want %d", len(records), tt.wantRecords)
+			}
+
+			seen := make(map[string]bool, len(records))
+			for _, record := range records {
+				if seen[record.RecordId] {
+					t.Errorf("duplicate record %s", record.RecordId)
+				}
+				seen[record.RecordId] = true
+			}
+		})
+	}
+}
+
+// TestGetDomainRecordsTotalCountChanged 分页过程中TotalCount变化时重新拉取整个域名一次，再次变化时返回错误；
+// 配置了order_by时每页请求都带上OrderBy参数
+func TestGetDomainRecordsTotalCountChanged(t *testing.T) {
+	tests := []struct {
+		name string
+		// totalCounts 按请求顺序返回的TotalCount，超出部分为250
+		totalCounts  []int64
+		wantRecords  int
+		wantRequests int32
+		wantErr      bool
+	}{
+		{name: "stable", wantRecords: 250, wantRequests: 3},
+		{name: "changed once then retried", totalCounts: []int64{250, 251}, wantRecords: 250, wantRequests: 5},
+		{name: "changed on the last page", totalCounts: []int64{250, 250, 249}, wantRecords: 250, wantRequests: 6},
+		{name: "changed again on retry", totalCounts: []int64{250, 251, 251, 252}, wantRequests: 4, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			var orderBy sync.Map
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(requests.Add(1))
+				query := r.URL.Query()
+				orderBy.Store(n, query.Get("OrderBy"))
+				pageNumber, _ := strconv.Atoi(query.Get("PageNumber"))
+				pageSize, _ := strconv.Atoi(query.Get("PageSize"))
+
+				totalCount := int64(250)
+				if n <= len(tt.totalCounts) {
+					totalCount = tt.totalCounts[n-1]
+				}
+				page := []map[string]any{}
+				for i := (pageNumber - 1) * pageSize; i < pageNumber*pageSize && i < 250; i++ {
+					page = append(page, map[string]any{
+						"DomainName": "example.com",
+						"RecordId":   strconv.Itoa(1000 + i),
+						"RR":         fmt.Sprintf("host%d", i),
+						"Type":       "A",
+						"Value":      fmt.Sprintf("10.0.%d.%d", i/256, i%256),
+						"Line":       "default",
+						"TTL":        600,
+						"Status":     "ENABLE",
+					})
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]any{
+					"TotalCount":    totalCount,
+					"PageNumber":    pageNumber,
+					"PageSize":      pageSize,
+					"RequestId":     "test",
+					"DomainRecords": map[string]any{"Record": page},
+				})
+			}))
+			defer server.Close()
+
+			client, err := NewDNSClient(&config.AliyunConfig{
+				AccessKeyID:        "test-id",
+				AccessKeySecret:    "test-secret",
+				QPS:                1000,
+				PageSize:           125,
+				OrderBy:            "RR",
+				DisableCompression: true,
+			})
+			if err != nil {
+				t.Fatalf("NewDNSClient() error = %v", err)
+			}
+			client.endpoint = server.URL
+
+			records, err := client.GetDomainRecords(context.Background(), "example.com")
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", got, tt.wantRequests)
+			}
+			orderBy.Range(func(n, value any) bool {
+				if value != "RR" {
+					t.Errorf("request %d OrderBy = %q, want RR", n, value)
+				}
+				return true
+			})
+			if tt.wantErr {
+				if !errors.Is(err, errTotalCountChanged) {
+					t.Fatalf("GetDomainRecords() error = %v, want errTotalCountChanged", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetDomainRecords() error = %v", err)
+			}
+			if len(records) != tt.wantRecords {
+				t.Errorf("got %d records, want %d", len(records), tt.wantRecords)
+			}
+		})
+	}
+}
+
+// TestGetDomainRecordsPageSize 每页请求带上配置的page_size，未配置时为100，超过接口上限时按500；
+// 700条记录在100条每页时需要最后一个空页确认结束
+func TestGetDomainRecordsPageSize(t *testing.T) {
+	tests := []struct {
+		name         string
+		pageSize     int64
+		want         string
+		wantRequests int32
+	}{
+		{name: "default", want: "100", wantRequests: 8},
+		{name: "configured", pageSize: 250, want: "250", wantRequests: 3},
+		{name: "api maximum", pageSize: 500, want: "500", wantRequests: 2},
+		{name: "clamped", pageSize: 2000, want: "500", wantRequests: 2},
+		{name: "negative", pageSize: -1, want: "100", wantRequests: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			records := newMockRecordsServer(t, 700, 700, false, &requests)
+			defer records.Close()
+			var mu sync.Mutex
+			sent := make(map[string]bool)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				sent[r.URL.Query().Get("PageSize")] = true
+				mu.Unlock()
+				records.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+
+			client, err := NewDNSClient(&config.AliyunConfig{
+				AccessKeyID:        "test-id",
+				AccessKeySecret:    "test-secret",
+				QPS:                1000,
+				PageSize:           tt.pageSize,
+				DisableCompression: true,
+			})
+			if err != nil {
+				t.Fatalf("NewDNSClient() error = %v", err)
+			}
+			client.endpoint = server.URL
+
+			got, err := client.GetDomainRecords(context.Background(), "example.com")
+			if err != nil {
+				t.Fatalf("GetDomainRecords() error = %v", err)
+			}
+			if len(got) != 700 {
+				t.Errorf("got %d records, want 700", len(got))
+			}
+			if len(sent) != 1 || !sent[tt.want] {
+				t.Errorf("PageSize sent = %v, want only %s", sent, tt.want)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+// TestGetDomainRecordsMaxRecords 服务商返回的记录多于max_records时停止分页，按配置返回错误或截断；
+// 接口一直返回新记录时在maxPages页后中止
+func TestGetDomainRecordsMaxRecords(t *testing.T) {
+	tests := []struct {
+		name          string
+		records       int
+		pageSize      int64
+		limit         *provider.RecordLimit
+		wantRecords   int
+		wantRequests  int32
+		wantTruncated bool
+		wantErr       error
+		wantErrText   string
+	}{
+		{
+			name:         "within limit",
+			records:      250,
+			pageSize:     100,
+			limit:        &provider.RecordLimit{Max: 250},
+			wantRecords:  250,
+			wantRequests: 3,
+		},
+		{
+			name:         "exceeds limit",
+			records:      10000,
+			pageSize:     100,
+			limit:        &provider.RecordLimit{Max: 250},
+			wantRequests: 3,
+			wantErr:      provider.ErrTooManyRecords,
+		},
+		{
+			name:          "exceeds limit truncated",
+			records:       10000,
+			pageSize:      100,
+			limit:         &provider.RecordLimit{Max: 250, Truncate: true},
+			wantRecords:   250,
+			wantRequests:  3,
+			wantTruncated: true,
+		},
+		{
+			name:         "page cap",
+			records:      maxPages + 10,
+			pageSize:     1,
+			wantRequests: maxPages,
+			wantErrText:  fmt.Sprintf("exceeded %d pages", maxPages),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := newMockRecordsServer(t, tt.records, int64(tt.records), false, &requests)
+			defer server.Close()
+
+			client, err := NewDNSClient(&config.AliyunConfig{
+				AccessKeyID:        "test-id",
+				AccessKeySecret:    "test-secret",
+				QPS:                100000,
+				PageSize:           tt.pageSize,
+				DisableCompression: true,
+			})
+			if err != nil {
+				t.Fatalf("NewDNSClient() error = %v", err)
+			}
+			client.endpoint = server.URL
+
+			ctx := context.Background()
+			if tt.limit != nil {
+				ctx = provider.WithRecordLimit(ctx, tt.limit)
+			}
+			records, err := client.GetDomainRecords(ctx, "example.com")
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", got, tt.wantRequests)
+			}
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GetDomainRecords() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case tt.wantErrText != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrText) {
+					t.Fatalf("GetDomainRecords() error = %v, want %q", err, tt.wantErrText)
+				}
+				return
+			case err != nil:
+				t.Fatalf("GetDomainRecords() error = %v", err)
+			}
+			if len(records) != tt.wantRecords {
+				t.Errorf("got %d records, want %d", len(records), tt.wantRecords)
+			}
+			if tt.limit != nil && tt.limit.Truncated != tt.wantTruncated {
+				t.Errorf("Truncated = %v, want %v", tt.limit.Truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestGetDomainRecordsWeight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"TotalCount":3,"PageNumber":1,"PageSize":100,"DomainRecords":{"Record":[
+			{"RecordId":"1","RR":"www","Type":"A","Value":"10.0.0.1","Weight":20,"LbaStatus":true},
+			{"RecordId":"2","RR":"api","Type":"A","Value":"10.0.0.2"},
+			{"RecordId":"3","RR":"cdn","Type":"A","Value":"10.0.0.3","Weight":5}]}}`)
+	}))
+	defer server.Close()
+
+	client, err := NewDNSClient(&config.AliyunConfig{AccessKeyID: "test-id", AccessKeySecret: "test-secret",
+		DisableCompression: true})
+	if err != nil {
+		t.Fatalf("NewDNSClient() error = %v", err)
+	}
+	client.endpoint = server.URL
+
+	records, err := client.GetDomainRecords(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("GetDomainRecords() error = %v", err)
+	}
+
+	tests := []struct {
+		recordID      string
+		wantLbaStatus bool
+		wantWeight    int32
+	}{
+		{recordID: "1", wantLbaStatus: true, wantWeight: 20},
+		// 未开启加权轮询时不返回Weight
+		{recordID: "2", wantWeight: 0},
+		// 未返回LbaStatus但返回了Weight
+		{recordID: "3", wantLbaStatus: true, wantWeight: 5},
+	}
+	if len(records) != len(tests) {
+		t.Fatalf("got %d records, want %d", len(records), len(tests))
+	}
+	for i, tt := range tests {
+		record := records[i]
+		if record.RecordId != tt.recordID || record.LbaStatus != tt.wantLbaStatus || record.RecordWeight() != tt.wantWeight {
+			t.Errorf("record %s: lba_status = %v, weight = %d, want %v, %d", record.RecordId,
+				record.LbaStatus, record.RecordWeight(), tt.wantLbaStatus, tt.wantWeight)
+		}
+	}
+}
+
+// TestGetDomainRecordsRemark 解析记录的备注，未设置备注或较早的响应没有Remark字段时为空
+func TestGetDomainRecordsRemark(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"TotalCount":3,"PageNumber":1,"PageSize":100,"DomainRecords":{"Record":[
+			{"RecordId":"1","RR":"www","Type":"A","Value":"10.0.0.1","Remark":"owner: web team"},
+			{"RecordId":"2","RR":"api","Type":"A","Value":"10.0.0.2","Remark":""},
+			{"RecordId":"3","RR":"cdn","Type":"A","Value":"10.0.0.3"}]}}`)
+	}))
+	defer server.Close()
+
+	client, err := NewDNSClient(&config.AliyunConfig{AccessKeyID: "test-id", AccessKeySecret: "test-secret",
+		DisableCompression: true})
+	if err != nil {
+		t.Fatalf("NewDNSClient() error = %v", err)
+	}
+	client.endpoint = server.URL
+
+	records, err := client.GetDomainRecords(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("GetDomainRecords() error = %v", err)
+	}
+	want := []string{"owner: web team", "", ""}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(records), len(want))
+	}
+	for i, record := range records {
+		if record.Remark != want[i] {
+			t.Errorf("record %s remark = %q, want %q", record.RecordId, record.Remark, want[i])
+		}
+	}
+}
+
+// TestSecurityToken 配置了STS临时凭证时，V1签名在查询字符串中携带SecurityToken并参与签名，V3签名放在请求头中
+func TestSecurityToken(t *testing.T) {
+	tests := []struct {
+		name             string
+		securityToken    string
+		signatureVersion string
+		wantQuery        string
+		wantHeader       string
+	}{
+		{name: "v1 with token", securityToken: "sts-token", wantQuery: "sts-token"},
+		{name: "v1 without token"},
+		{name: "v3 with token", securityToken: "sts-token", signatureVersion: "v3", wantHeader: "sts-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query map[string][]string
+			var header string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query, header = r.URL.Query(), r.Header.Get("x-acs-security-token")
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"TotalCount":0,"PageNumber":1,"PageSize":100,"DomainRecords":{"Record":[]}}`)
+			}))
+			defer server.Close()
+
+			client, err := NewDNSClient(&config.AliyunConfig{AccessKeyID: "test-id", AccessKeySecret: "test-secret",
+				SecurityToken: tt.securityToken, SignatureVersion: tt.signatureVersion, DisableCompression: true})
+			if err != nil {
+				t.Fatalf("NewDNSClient() error = %v", err)
+			}
+			client.endpoint = server.URL
+
+			if _, err := client.GetDomainRecords(context.Background(), "example.com"); err != nil {
+				t.Fatalf("GetDomainRecords() error = %v", err)
+			}
+			if got := strings.Join(query["SecurityToken"], ","); got != tt.wantQuery {
+				t.Errorf("SecurityToken query param = %q, want %q", got, tt.wantQuery)
+			}
+			if header != tt.wantHeader {
+				t.Errorf("x-acs-security-token header = %q, want %q", header, tt.wantHeader)
+			}
+			if tt.signatureVersion != "" {
+				return
+			}
+
+			// 服务端按收到的参数重新计算签名，SecurityToken必须包含在签名的参数中
+			params := make(map[string]string, len(query))
+			for k, v := range query {
+				if k != "Signature" {
+					params[k] = v[0]
+				}
+			}
+			if got, want := query["Signature"][0], signV1("test-secret", params); got != want {
+				t.Errorf("Signature = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// TestRecordWrites 新增、修改、删除记录发送的参数，新增返回阿里云分配的RecordId，错误响应解析为APIError
+func TestRecordWrites(t *testing.T) {
+	var requests []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := make(map[string]string)
+		for k, v := range r.URL.Query() {
+			params[k] = v[0]
+		}
+		requests = append(requests, params)
+
+		w.Header().Set("Content-Type", "application/json")
+		if params["RR"] == "dup" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"Code":"DomainRecordDuplicate","Message":"The DNS record already exists.","RequestId":"test"}`)
+			return
+		}
+		fmt.Fprint(w, `{"RequestId":"test","RecordId":"9000"}`)
+	}))
+	defer server.Close()
+
+	client, err := NewDNSClient(&config.AliyunConfig{AccessKeyID: "test-id", AccessKeySecret: "test-secret",
+		DisableCompression: true})
+	if err != nil {
+		t.Fatalf("NewDNSClient() error = %v", err)
+	}
+	client.endpoint = server.URL
+	ctx := context.Background()
+
+	mx := &models.DNSRecord{DomainName: "example.com", RR: "@", Type: "MX", Value: "mail.example.com", Priority: 10,
+		TTL: 600, Line: "default"}
+	recordID, err := client.AddDomainRecord(ctx, mx)
+	if err != nil || recordID != "9000" {
+		t.Fatalf("AddDomainRecord() = %q, %v, want 9000", recordID, err)
+	}
+	mx.RecordId = recordID
+	if err := client.UpdateDomainRecord(ctx, mx); err != nil {
+		t.Fatalf("UpdateDomainRecord() error = %v", err)
+	}
+	if err := client.DeleteDomainRecord(ctx, recordID); err != nil {
+		t.Fatalf("DeleteDomainRecord() error = %v", err)
+	}
+	_, err = client.AddDomainRecord(ctx, &models.DNSRecord{DomainName: "example.com", RR: "dup", Type: "A",
+		Value: "10.0.0.1"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "DomainRecordDuplicate" {
+		t.Errorf("AddDomainRecord() error = %v, want DomainRecordDuplicate", err)
+	}
+
+	want := []map[string]string{
+		{"Action": "AddDomainRecord", "DomainName": "example.com", "RR": "@", "Type": "MX", "Value": "mail.example.com",
+			"Priority": "10", "TTL": "600", "Line": "default"},
+		{"Action": "UpdateDomainRecord", "RecordId": "9000", "RR": "@", "Type": "MX", "Value": "mail.example.com",
+			"Priority": "10", "TTL": "600", "Line": "default"},
+		{"Action": "DeleteDomainRecord", "RecordId": "9000"},
+		{"Action": "AddDomainRecord", "DomainName": "example.com", "RR": "dup", "Type": "A", "Value": "10.0.0.1"},
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("got %d requests, want %d", len(requests), len(want))
+	}
+	for i, params := range want {
+		for k, v := range params {
+			if requests[i][k] != v {
+				t.Errorf("request %d: %s = %q, want %q", i, k, requests[i][k], v)
+			}
+		}
+		// A记录没有优先级，未配置TTL时使用阿里云的默认值
+		if params["Type"] == "A" && (requests[i]["Priority"] != "" || requests[i]["TTL"] != "") {
+			t.Errorf("request %d: unexpected Priority %q or TTL %q", i, requests[i]["Priority"], requests[i]["TTL"])
+		}
+	}
+}
+
+// countingTransport 记录经过的请求，再交给下层Transport发送
+type countingTransport struct {
+	next  http.RoundTripper
+	hosts []string
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.hosts = append(t.hosts, req.URL.Host)
+	return t.next.RoundTrip(req)
+}
+
+// TestHTTPTransport 所有API请求都经过客户端的Transport，配置的proxy_url和timeout应用到该Transport和客户端上
+func TestHTTPTransport(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 经过代理的请求行是完整的URL
+		proxied = append(proxied, r.URL.Host)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"TotalCount":1,"PageNumber":1,"PageSize":100,"DomainRecords":{"Record":[
+			{"RecordId":"1","RR":"www","Type":"A","Value":"10.0.0.1"}]}}`)
+	}))
+	defer proxy.Close()
+
+	client, err := NewDNSClient(&config.AliyunConfig{AccessKeyID: "test-id", AccessKeySecret: "test-secret",
+		ProxyURL: proxy.URL, Timeout: 5 * time.Second, DisableCompression: true})
+	if err != nil {
+		t.Fatalf("NewDNSClient() error = %v", err)
+	}
+	if client.httpClient.Timeout != 5*time.Second {
+		t.Errorf("client timeout = %s, want 5s", client.httpClient.Timeout)
+	}
+	client.endpoint = "http://alidns.test.invalid"
+	transport := &countingTransport{next: client.httpClient.Transport}
+	client.httpClient.Transport = transport
+
+	records, err := client.GetDomainRecords(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("GetDomainRecords() error = %v", err)
+	}
+	if len(records) != 1 {
+		t.Errorf("got %d records, want 1", len(records))
+	}
+	if len(transport.hosts) != 1 || transport.hosts[0] != "alidns.test.invalid" {
+		t.Errorf("transport saw requests to %v, want one to alidns.test.invalid", transport.hosts)
+	}
+	if len(proxied) != 1 || proxied[0] != "alidns.test.invalid" {
+		t.Errorf("proxy saw requests to %v, want one to alidns.test.invalid", proxied)
+	}
+}
+
+func TestNewHTTPClientInvalidProxy(t *testing.T) {
+	_, err := NewDNSClient(&config.AliyunConfig{AccessKeyID: "test-id", AccessKeySecret: "test-secret",
+		ProxyURL: "http://[::1"})
+	if err == nil || !strings.Contains(err.Error(), "invalid proxy url") {
+		t.Errorf("NewDNSClient() error = %v, want invalid proxy url", err)
+	}
+}
+
+// newMockDomainsServer 模拟DescribeDomains接口，账号下有domain0.com到domain149.com和一个中文域名，按页返回
+func newMockDomainsServer(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	domains := []map[string]any{{"DomainName": "例子.com", "PunyCode": "xn--fsqu00a.com"}}
+	for i := 0; i < 150; i++ {
+		domains = append(domains, map[string]any{"DomainName": fmt.Sprintf("domain%d.com", i)})
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		query := r.URL.Query()
+		if action := query.Get("Action"); action != "DescribeDomains" {
+			http.Error(w, "unexpected action "+action, http.StatusBadRequest)
+			return
+		}
+		pageNumber, _ := strconv.Atoi(query.Get("PageNumber"))
+		pageSize, _ := strconv.Atoi(query.Get("PageSize"))
+		start, end := min((pageNumber-1)*pageSize, len(domains)), min(pageNumber*pageSize, len(domains))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"TotalCount": len(domains),
+			"PageNumber": pageNumber,
+			"PageSize":   pageSize,
+			"Domains":    map[string]any{"Domain": domains[start:end]},
+		})
+	}))
+}
+
+func TestVerifyDomains(t *testing.T) {
+	tests := []struct {
+		name    string
+		domains []string
+		wantErr string
+	}{
+		{name: "all present", domains: []string{"domain0.com", "domain149.com"}},
+		{name: "case and trailing dot", domains: []string{"Domain1.COM."}},
+		{name: "idn by unicode and punycode", domains: []string{"例子.com", "xn--fsqu00a.com"}},
+		{name: "missing domains listed", domains: []string{"domain0.com", "example.com", "example.org"},
+			wantErr: "domains not found on account: example.com, example.org"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := newMockDomainsServer(t, &requests)
+			defer server.Close()
+
+			client, err := NewDNSClient(&config.AliyunConfig{AccessKeyID: "test-id", AccessKeySecret: "test-secret",
+				QPS: 1000, DisableCompression: true})
+			if err != nil {
+				t.Fatalf("NewDNSClient() error = %v", err)
+			}
+			client.endpoint = server.URL
+
+			err = client.VerifyDomains(context.Background(), tt.domains)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("VerifyDomains() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("VerifyDomains() error = %v", err)
+			}
+			// 151个域名分两页返回
+			if got := requests.Load(); got != 2 {
+				t.Errorf("got %d requests, want 2", got)
+			}
+		})
+	}
+}
+
+// TestAccountSigners 不同账号的两个域名各自用本账号的AccessKey签名，服务端用对应的Secret能重新算出签名
+func TestAccountSigners(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]url.Values)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		mu.Lock()
+		requests[query.Get("DomainName")] = query
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"TotalCount":0,"PageNumber":1,"PageSize":100,"DomainRecords":{"Record":[]}}`)
+	}))
+	defer server.Close()
+
+	data := `aliyun:
+  access_key_id: "default-id"
+  access_key_secret: "default-secret"
+aliyun_accounts:
+  prod:
+    access_key_id: "prod-id"
+    access_key_secret: "prod-secret"
+domains:
+  - domain: "example.com"
+    domain_id: "domain-1"
+    project_id: "project-1"
+  - domain: "example.org"
+    domain_id: "domain-2"
+    project_id: "project-1"
+    account: "prod"
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadProviderConfig(path)
+	if err != nil {
+		t.Fatalf("LoadProviderConfig() error = %v", err)
+	}
+
+	for _, domainMapping := range cfg.Domains {
+		account, ok := cfg.AliyunAccount(domainMapping.Account)
+		if !ok {
+			t.Fatalf("account %q of %s not found", domainMapping.Account, domainMapping.Domain)
+		}
+		account.DisableCompression = true
+		client, err := NewDNSClient(account)
+		if err != nil {
+			t.Fatalf("NewDNSClient() error = %v", err)
+		}
+		client.endpoint = server.URL
+		if _, err := client.GetDomainRecords(context.Background(), domainMapping.Domain); err != nil {
+			t.Fatalf("GetDomainRecords(%s) error = %v", domainMapping.Domain, err)
+		}
+	}
+
+	tests := []struct {
+		domain string
+		id     string
+		secret string
+	}{
+		{domain: "example.com", id: "default-id", secret: "default-secret"},
+		{domain: "example.org", id: "prod-id", secret: "prod-secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			query, ok := requests[tt.domain]
+			if !ok {
+				t.Fatalf("no request for %s", tt.domain)
+			}
+			if got := query.Get("AccessKeyId"); got != tt.id {
+				t.Errorf("AccessKeyId = %s, want %s", got, tt.id)
+			}
+			params := make(map[string]string, len(query))
+			for k, v := range query {
+				if k != "Signature" {
+					params[k] = v[0]
+				}
+			}
+			if got, want := query.Get("Signature"), signV1(tt.secret, params); got != want {
+				t.Errorf("Signature = %s, want one signed with %s", got, tt.secret)
+			}
+		})
+	}
+}
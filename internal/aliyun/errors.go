@@ -0,0 +1,64 @@
+package aliyun
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dns-sync/internal/provider"
+)
+
+// 域名不存在或不属于当前账号的错误码
+var domainNotFoundCodes = map[string]bool{
+	"InvalidDomainName.NoExist": true,
+	"IncorrectDomainUser":       true,
+	"DomainNotExist":            true,
+}
+
+// 凭证或签名无效、权限不足的错误码
+var authErrorCodes = map[string]bool{
+	"InvalidAccessKeyId.NotFound":                true,
+	"InvalidAccessKeyId.Inactive":                true,
+	"SignatureDoesNotMatch":                      true,
+	"IncompleteSignature":                        true,
+	"InvalidSecurityToken.Expired":               true,
+	"InvalidSecurityToken.Malformed":             true,
+	"InvalidSecurityToken.MismatchWithAccessKey": true,
+	"Forbidden.RAM":                              true,
+	"Forbidden.AccessKeyDisabled":                true,
+	"SignatureNonceUsed":                         true,
+	"InvalidTimeStamp.Expired":                   true,
+}
+
+// APIError 阿里云API返回的错误响应
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"Code"`
+	Message    string `json:"Message"`
+	RequestId  string `json:"RequestId"`
+}
+
+// Error 实现error接口
+func (e *APIError) Error() string {
+	return fmt.Sprintf("aliyun API error %s (status %d, request id %s): %s",
+		e.Code, e.StatusCode, e.RequestId, e.Message)
+}
+
+// Is 将域名不存在和鉴权失败的错误码映射为provider中的通用错误，调用方无需依赖阿里云的错误码
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case provider.ErrDomainNotFound:
+		return domainNotFoundCodes[e.Code]
+	case provider.ErrAuthFailed:
+		return authErrorCodes[e.Code]
+	}
+	return false
+}
+
+// parseAPIError 解析非200响应的错误内容，响应体不是阿里云的错误格式时返回nil
+func parseAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Code == "" {
+		return nil
+	}
+	return apiErr
+}
@@ -0,0 +1,86 @@
+package aliyun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/provider"
+)
+
+// TestAPIErrors 错误响应解析为APIError，域名不存在和鉴权失败的错误码可以用provider中的通用错误判断
+func TestAPIErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		body           string
+		wantCode       string
+		wantNotFound   bool
+		wantAuthFailed bool
+		// wantErr 响应体不是阿里云错误格式时错误信息应包含的内容
+		wantErr string
+	}{
+		{name: "domain does not exist", status: 400, wantCode: "InvalidDomainName.NoExist", wantNotFound: true},
+		{name: "domain on another account", status: 400, wantCode: "IncorrectDomainUser", wantNotFound: true},
+		{name: "signature mismatch", status: 400, wantCode: "SignatureDoesNotMatch", wantAuthFailed: true},
+		{name: "unknown access key", status: 404, wantCode: "InvalidAccessKeyId.NotFound", wantAuthFailed: true},
+		{name: "ram permission", status: 403, wantCode: "Forbidden.RAM", wantAuthFailed: true},
+		{name: "other error", status: 400, wantCode: "InvalidParameter"},
+		{name: "not json", status: 502, body: "<html>Bad Gateway</html>",
+			wantErr: "API request failed with status 502: <html>Bad Gateway</html>"},
+		{name: "json without code", status: 500, body: `{"Message":"oops"}`,
+			wantErr: `API request failed with status 500: {"Message":"oops"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body
+			if body == "" {
+				body = fmt.Sprintf(`{"Code":%q,"Message":"request rejected","RequestId":"req-1"}`, tt.wantCode)
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, body)
+			}))
+			defer server.Close()
+
+			client, err := NewDNSClient(&config.AliyunConfig{AccessKeyID: "test-id", AccessKeySecret: "test-secret",
+				DisableCompression: true})
+			if err != nil {
+				t.Fatalf("NewDNSClient() error = %v", err)
+			}
+			client.endpoint = server.URL
+
+			_, err = client.GetDomainRecords(context.Background(), "example.com")
+			if err == nil {
+				t.Fatal("GetDomainRecords() error = nil")
+			}
+
+			var apiErr *APIError
+			if tt.wantErr != "" {
+				if errors.As(err, &apiErr) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetDomainRecords() error = %v, want untyped %q", err, tt.wantErr)
+				}
+				return
+			}
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("GetDomainRecords() error = %v, want *APIError", err)
+			}
+			if apiErr.Code != tt.wantCode || apiErr.StatusCode != tt.status || apiErr.RequestId != "req-1" ||
+				apiErr.Message != "request rejected" {
+				t.Errorf("APIError = %+v", apiErr)
+			}
+			if got := errors.Is(err, provider.ErrDomainNotFound); got != tt.wantNotFound {
+				t.Errorf("errors.Is(err, ErrDomainNotFound) = %v, want %v", got, tt.wantNotFound)
+			}
+			if got := errors.Is(err, provider.ErrAuthFailed); got != tt.wantAuthFailed {
+				t.Errorf("errors.Is(err, ErrAuthFailed) = %v, want %v", got, tt.wantAuthFailed)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package aliyun
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter 令牌桶限流器，同一账号的所有请求共享，可并发使用
+// 令牌按qps匀速补充，桶容量为qps（至少为1），允许短时间的突发请求
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+// newRateLimiter 创建每秒最多qps个请求的限流器
+func newRateLimiter(qps float64) *rateLimiter {
+	burst := qps
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		interval: time.Duration(float64(time.Second) / qps),
+		burst:    burst,
+		tokens:   burst,
+		last:     time.Now(),
+	}
+}
+
+// Wait 阻塞直到获得一个令牌；context取消时返回context的错误
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	for {
+		delay := l.reserve()
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve 尝试取出一个令牌，成功返回0，否则返回需要等待的时间
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - l.tokens) * float64(l.interval))
+}
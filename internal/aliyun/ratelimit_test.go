@@ -0,0 +1,69 @@
+package aliyun
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRateLimiterMinimumTime 并发请求共享同一个桶，突发容量用完后按qps匀速放行
+func TestRateLimiterMinimumTime(t *testing.T) {
+	tests := []struct {
+		name       string
+		qps        float64
+		requests   int
+		goroutines int
+		// wantMin 突发容量之外的请求至少需要的时间
+		wantMin time.Duration
+	}{
+		{name: "single caller", qps: 20, requests: 26, goroutines: 1, wantMin: 300 * time.Millisecond},
+		{name: "concurrent callers", qps: 20, requests: 30, goroutines: 5, wantMin: 500 * time.Millisecond},
+		{name: "low qps", qps: 2, requests: 3, goroutines: 3, wantMin: 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newRateLimiter(tt.qps)
+			start := time.Now()
+
+			var wg sync.WaitGroup
+			for g := 0; g < tt.goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := g; i < tt.requests; i += tt.goroutines {
+						if err := limiter.Wait(context.Background()); err != nil {
+							t.Errorf("Wait() error = %v", err)
+							return
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+
+			// 允许计时器少量的误差
+			if elapsed := time.Since(start); elapsed < tt.wantMin-10*time.Millisecond {
+				t.Errorf("%d requests took %v, want at least %v", tt.requests, elapsed, tt.wantMin)
+			}
+		})
+	}
+}
+
+// TestRateLimiterContext 等待令牌时context取消立即返回context的错误
+func TestRateLimiterContext(t *testing.T) {
+	limiter := newRateLimiter(0.1)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := limiter.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Wait() returned after %v, want it to stop at the deadline", elapsed)
+	}
+}
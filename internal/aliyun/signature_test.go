@@ -0,0 +1,90 @@
+package aliyun
+
+import "testing"
+
+// TestSignV1 固定参数的签名向量，取自阿里云签名机制文档中DescribeRegions的示例
+func TestSignV1(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		params map[string]string
+		want   string
+	}{
+		{
+			name:   "documented example",
+			secret: "testsecret",
+			params: map[string]string{
+				"Action":           "DescribeRegions",
+				"AccessKeyId":      "testid",
+				"Format":           "XML",
+				"SignatureMethod":  "HMAC-SHA1",
+				"SignatureNonce":   "3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf",
+				"SignatureVersion": "1.0",
+				"Timestamp":        "2016-02-23T12:46:24Z",
+				"Version":          "2014-05-26",
+			},
+			want: "OLeaidS1JvxuMvnyHOwuJ+uX5qY=",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := signV1(tt.secret, tt.params); got != tt.want {
+				t.Errorf("signV1() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignV3(t *testing.T) {
+	headers := map[string]string{
+		"host":                  "alidns.cn-hangzhou.aliyuncs.com",
+		"x-acs-action":          "DescribeDomainRecords",
+		"x-acs-version":         apiVersion,
+		"x-acs-date":            "2024-01-01T00:00:00Z",
+		"x-acs-signature-nonce": "3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf",
+		"x-acs-content-sha256":  hashHex(""),
+	}
+	signedHeaders := "host;x-acs-action;x-acs-content-sha256;x-acs-date;x-acs-signature-nonce;x-acs-version"
+
+	tests := []struct {
+		name  string
+		query map[string]string
+		want  string
+	}{
+		{
+			name:  "describe domain records",
+			query: map[string]string{"DomainName": "example.com", "PageNumber": "1", "PageSize": "100"},
+			want: "ACS3-HMAC-SHA256 Credential=testid,SignedHeaders=" + signedHeaders +
+				",Signature=09fa40724575b9ae1056479304c008b02a1537f3572b84c58288f6ad3409645e",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := signV3("testid", "testsecret", "GET", "/", canonicalQueryString(tt.query), headers, "")
+			if got != tt.want {
+				t.Errorf("signV3() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentEncode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "example.com", want: "example.com"},
+		{input: "a b", want: "a%20b"},
+		{input: "*.example.com", want: "%2A.example.com"},
+		{input: "~user", want: "~user"},
+		{input: "2024-01-01T00:00:00Z", want: "2024-01-01T00%3A00%3A00Z"},
+	}
+
+	for _, tt := range tests {
+		if got := percentEncode(tt.input); got != tt.want {
+			t.Errorf("percentEncode(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
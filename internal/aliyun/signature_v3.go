@@ -0,0 +1,134 @@
+package aliyun
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signatureAlgorithmV3 V3签名算法名称
+const signatureAlgorithmV3 = "ACS3-HMAC-SHA256"
+
+// apiVersion 云解析DNS的API版本
+const apiVersion = "2015-01-09"
+
+// newRequestV3 构建使用ACS3-HMAC-SHA256签名的RPC请求
+// Action和Version放在请求头中，其余参数放在查询字符串里，GET请求的请求体为空
+func (c *DNSClient) newRequestV3(ctx context.Context, params map[string]string) (*http.Request, error) {
+	u, err := url.Parse(c.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	action := params["Action"]
+	query := make(map[string]string, len(params))
+	for k, v := range params {
+		if k != "Action" {
+			query[k] = v
+		}
+	}
+	canonicalQuery := canonicalQueryString(query)
+	u.RawQuery = canonicalQuery
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	headers := map[string]string{
+		"host":                  u.Host,
+		"x-acs-action":          action,
+		"x-acs-version":         apiVersion,
+		"x-acs-date":            time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		"x-acs-signature-nonce": strconv.FormatInt(time.Now().UnixNano(), 10),
+		"x-acs-content-sha256":  hashHex(""),
+	}
+	if c.securityToken != "" {
+		headers["x-acs-security-token"] = c.securityToken
+	}
+
+	authorization := signV3(c.accessKeyID, c.accessKeySecret, http.MethodGet, "/", canonicalQuery, headers, "")
+	for k, v := range headers {
+		if k != "host" {
+			req.Header.Set(k, v)
+		}
+	}
+	req.Header.Set("Authorization", authorization)
+
+	return req, nil
+}
+
+// signV3 计算V3签名，返回Authorization请求头的值
+// headers的键必须为小写，全部参与签名
+func signV3(accessKeyID, accessKeySecret, method, path, canonicalQuery string, headers map[string]string,
+	body string) string {
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		canonicalQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hashHex(body),
+	}, "\n")
+
+	stringToSign := signatureAlgorithmV3 + "\n" + hashHex(canonicalRequest)
+
+	mac := hmac.New(sha256.New, []byte(accessKeySecret))
+	mac.Write([]byte(stringToSign))
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	return fmt.Sprintf("%s Credential=%s,SignedHeaders=%s,Signature=%s",
+		signatureAlgorithmV3, accessKeyID, signedHeaders, signature)
+}
+
+// canonicalQueryString 按参数名排序并进行RFC 3986编码
+func canonicalQueryString(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, percentEncode(k)+"="+percentEncode(params[k]))
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// percentEncode RFC 3986编码：空格为%20，*为%2A，~不编码
+func percentEncode(s string) string {
+	encoded := url.QueryEscape(s)
+	encoded = strings.ReplaceAll(encoded, "+", "%20")
+	encoded = strings.ReplaceAll(encoded, "*", "%2A")
+	encoded = strings.ReplaceAll(encoded, "%7E", "~")
+	return encoded
+}
+
+// hashHex 计算SHA256并返回小写十六进制
+func hashHex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,264 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/models"
+	"dns-sync/internal/provider"
+)
+
+// defaultEndpoint Cloudflare API地址
+const defaultEndpoint = "https://api.cloudflare.com/client/v4"
+
+// maxPages 单个域名最多拉取的页数，防止分页死循环
+const maxPages = 1000
+
+// DNSClient Cloudflare DNS客户端
+type DNSClient struct {
+	apiToken   string
+	endpoint   string
+	httpClient *http.Client
+}
+
+// apiResponse Cloudflare API通用响应结构
+type apiResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result     json.RawMessage `json:"result"`
+	ResultInfo struct {
+		Page       int `json:"page"`
+		PerPage    int `json:"per_page"`
+		TotalPages int `json:"total_pages"`
+		Count      int `json:"count"`
+		TotalCount int `json:"total_count"`
+	} `json:"result_info"`
+}
+
+// zone Cloudflare区域
+type zone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// dnsRecord Cloudflare DNS记录
+type dnsRecord struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Name       string    `json:"name"`
+	Content    string    `json:"content"`
+	TTL        int32     `json:"ttl"`
+	Priority   *int32    `json:"priority,omitempty"`
+	Proxied    bool      `json:"proxied"`
+	Locked     bool      `json:"locked"`
+	CreatedOn  time.Time `json:"created_on"`
+	ModifiedOn time.Time `json:"modified_on"`
+}
+
+// NewDNSClient 创建Cloudflare DNS客户端
+func NewDNSClient(cfg *config.CloudflareConfig) (*DNSClient, error) {
+	if cfg.APIToken == "" {
+		return nil, fmt.Errorf("cloudflare api token is required")
+	}
+
+	endpoint := defaultEndpoint
+	if cfg.Endpoint != "" {
+		endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	}
+
+	return &DNSClient{
+		apiToken:   cfg.APIToken,
+		endpoint:   endpoint,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// makeRequest 发送HTTP请求并校验响应
+func (c *DNSClient) makeRequest(ctx context.Context, path string, query url.Values) (*apiResponse, error) {
+	u := c.endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response failed: %w", err)
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response apiResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if !response.Success {
+		var messages []string
+		for _, e := range response.Errors {
+			messages = append(messages, fmt.Sprintf("%d: %s", e.Code, e.Message))
+		}
+		return nil, fmt.Errorf("API request failed: %s", strings.Join(messages, "; "))
+	}
+
+	return &response, nil
+}
+
+// getZoneID 根据域名查询区域ID
+func (c *DNSClient) getZoneID(ctx context.Context, domain string) (string, error) {
+	response, err := c.makeRequest(ctx, "/zones", url.Values{"name": {domain}})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up zone for %s: %w", domain, err)
+	}
+
+	var zones []zone
+	if err := json.Unmarshal(response.Result, &zones); err != nil {
+		return "", fmt.Errorf("failed to parse zones: %w", err)
+	}
+
+	if len(zones) == 0 {
+		return "", fmt.Errorf("zone %s not found", domain)
+	}
+
+	return zones[0].ID, nil
+}
+
+// GetDomainRecords 获取域名的DNS记录
+func (c *DNSClient) GetDomainRecords(ctx context.Context, domain string) ([]*models.DNSRecord, error) {
+	slog.Debug("Getting DNS records", "provider", "cloudflare", "domain", domain)
+
+	zoneID, err := c.getZoneID(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var allRecords []*models.DNSRecord
+	page := 1
+
+	for {
+		// 防止TotalPages异常导致死循环
+		if page > maxPages {
+			return nil, fmt.Errorf("pagination for %s exceeded %d pages, aborting to avoid infinite loop", domain, maxPages)
+		}
+
+		query := url.Values{
+			"page":     {strconv.Itoa(page)},
+			"per_page": {"100"},
+		}
+
+		response, err := c.makeRequest(ctx, "/zones/"+zoneID+"/dns_records", query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list dns records for %s: %w", domain, err)
+		}
+
+		var records []dnsRecord
+		if err := json.Unmarshal(response.Result, &records); err != nil {
+			return nil, fmt.Errorf("failed to parse dns records: %w", err)
+		}
+
+		for _, record := range records {
+			allRecords = append(allRecords, convertRecord(domain, record))
+		}
+
+		var stop bool
+		if allRecords, stop, err = provider.ApplyRecordLimit(ctx, domain, allRecords); err != nil || stop {
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		if len(records) == 0 || page >= response.ResultInfo.TotalPages {
+			break
+		}
+		page++
+	}
+
+	slog.Info("Retrieved DNS records", "provider", "cloudflare", "domain", domain,
+		"count", len(allRecords), "pages", page)
+	return allRecords, nil
+}
+
+// convertRecord 将Cloudflare记录转换为通用DNS记录
+// Cloudflare返回完整域名，这里拆分出主机记录RR；Cloudflare没有停用状态，统一视为ENABLE
+func convertRecord(domain string, record dnsRecord) *models.DNSRecord {
+	rr := models.RelativeRR(record.Name, domain)
+
+	dnsRecord := &models.DNSRecord{
+		DomainName:      domain,
+		RR:              rr,
+		RecordId:        record.ID,
+		Type:            record.Type,
+		Value:           record.Content,
+		Line:            "default",
+		Status:          "ENABLE",
+		TTL:             record.TTL,
+		Locked:          record.Locked,
+		CreateTimestamp: record.CreatedOn.UnixMilli(),
+		UpdateTimestamp: record.ModifiedOn.UnixMilli(),
+	}
+	if record.Priority != nil {
+		dnsRecord.Priority = *record.Priority
+	}
+
+	return dnsRecord
+}
+
+// TestConnection 测试连接
+func (c *DNSClient) TestConnection(ctx context.Context) error {
+	slog.Debug("Testing DNS connection", "provider", "cloudflare")
+
+	if _, err := c.makeRequest(ctx, "/user/tokens/verify", nil); err != nil {
+		return fmt.Errorf("failed to test cloudflare connection: %w", err)
+	}
+
+	slog.Debug("DNS connection test successful", "provider", "cloudflare")
+	return nil
+}
+
+// VerifyDomains 逐个查询zone，检查配置的域名是否都存在
+func (c *DNSClient) VerifyDomains(ctx context.Context, domains []string) error {
+	var missing []string
+	for _, domain := range domains {
+		response, err := c.makeRequest(ctx, "/zones", url.Values{"name": {domain}})
+		if err != nil {
+			return fmt.Errorf("failed to look up zone for %s: %w", domain, err)
+		}
+
+		var zones []zone
+		if err := json.Unmarshal(response.Result, &zones); err != nil {
+			return fmt.Errorf("failed to parse zones: %w", err)
+		}
+		if len(zones) == 0 {
+			missing = append(missing, domain)
+		}
+	}
+
+	return provider.MissingDomainsError(missing)
+}
@@ -0,0 +1,136 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dns-sync/internal/config"
+)
+
+// newMockAPI 模拟Cloudflare API：example.com的区域ID为zone-1，记录分两页返回
+func newMockAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	pages := map[string]string{
+		"1": `[
+			{"id":"rec-1","type":"A","name":"www.example.com","content":"10.0.0.1","ttl":300,
+			 "created_on":"2024-01-01T00:00:00Z","modified_on":"2024-01-02T00:00:00Z"},
+			{"id":"rec-2","type":"CNAME","name":"api.example.com","content":"lb.example.net","ttl":1,"proxied":true}]`,
+		"2": `[
+			{"id":"rec-3","type":"A","name":"example.com","content":"10.0.0.2","ttl":600,"locked":true},
+			{"id":"rec-4","type":"MX","name":"example.com","content":"mail.example.com","ttl":600,"priority":10}]`,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"success":false,"errors":[{"code":9109,"message":"Invalid access token"}]}`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/zones":
+			if r.URL.Query().Get("name") != "example.com" {
+				fmt.Fprint(w, `{"success":true,"result":[]}`)
+				return
+			}
+			fmt.Fprint(w, `{"success":true,"result":[{"id":"zone-1","name":"example.com"}]}`)
+		case "/zones/zone-1/dns_records":
+			page := r.URL.Query().Get("page")
+			fmt.Fprintf(w, `{"success":true,"result":%s,"result_info":{"page":%s,"per_page":100,"total_pages":2}}`,
+				pages[page], page)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func newTestClient(t *testing.T, endpoint, token string) *DNSClient {
+	t.Helper()
+	client, err := NewDNSClient(&config.CloudflareConfig{APIToken: token, Endpoint: endpoint})
+	if err != nil {
+		t.Fatalf("NewDNSClient() error = %v", err)
+	}
+	return client
+}
+
+func TestGetDomainRecords(t *testing.T) {
+	server := newMockAPI(t)
+	defer server.Close()
+
+	records, err := newTestClient(t, server.URL, "test-token").GetDomainRecords(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("GetDomainRecords() error = %v", err)
+	}
+
+	tests := []struct {
+		recordID string
+		rr       string
+		typ      string
+		value    string
+		ttl      int32
+		priority int32
+		locked   bool
+	}{
+		{recordID: "rec-1", rr: "www", typ: "A", value: "10.0.0.1", ttl: 300},
+		{recordID: "rec-2", rr: "api", typ: "CNAME", value: "lb.example.net", ttl: 1},
+		{recordID: "rec-3", rr: "@", typ: "A", value: "10.0.0.2", ttl: 600, locked: true},
+		{recordID: "rec-4", rr: "@", typ: "MX", value: "mail.example.com", ttl: 600, priority: 10},
+	}
+	if len(records) != len(tests) {
+		t.Fatalf("got %d records, want %d", len(records), len(tests))
+	}
+	for i, tt := range tests {
+		record := records[i]
+		if record.RecordId != tt.recordID || record.RR != tt.rr || record.Type != tt.typ || record.Value != tt.value ||
+			record.TTL != tt.ttl || record.Priority != tt.priority || record.Locked != tt.locked {
+			t.Errorf("record %d = %+v, want %+v", i, record, tt)
+		}
+		if record.DomainName != "example.com" || record.Line != "default" || record.Status != "ENABLE" {
+			t.Errorf("record %s: domain %q, line %q, status %q", record.RecordId, record.DomainName, record.Line,
+				record.Status)
+		}
+	}
+	if records[0].UpdateTimestamp != 1704153600000 {
+		t.Errorf("UpdateTimestamp = %d, want modified_on in milliseconds", records[0].UpdateTimestamp)
+	}
+}
+
+func TestGetDomainRecordsErrors(t *testing.T) {
+	server := newMockAPI(t)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		token   string
+		domain  string
+		wantErr string
+	}{
+		{name: "invalid token", token: "wrong", domain: "example.com", wantErr: "status 403"},
+		{name: "unknown zone", token: "test-token", domain: "example.org", wantErr: "zone example.org not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestClient(t, server.URL, tt.token).GetDomainRecords(context.Background(), tt.domain)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("GetDomainRecords() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyDomains(t *testing.T) {
+	server := newMockAPI(t)
+	defer server.Close()
+
+	err := newTestClient(t, server.URL, "test-token").VerifyDomains(context.Background(),
+		[]string{"example.com", "example.org"})
+	if err == nil || !strings.Contains(err.Error(), "domains not found on account: example.org") {
+		t.Fatalf("VerifyDomains() error = %v, want example.org missing", err)
+	}
+}
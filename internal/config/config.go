@@ -1,16 +1,72 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
 	"gopkg.in/yaml.v2"
+
+	"dns-sync/internal/models"
 )
 
 // AliyunConfig 阿里云配置
 type AliyunConfig struct {
 	AccessKeyID     string `yaml:"access_key_id"`
 	AccessKeySecret string `yaml:"access_key_secret"`
-	Region          string `yaml:"region"`
+	// SecurityToken STS临时凭证的安全令牌，使用RAM角色临时凭证时必填
+	SecurityToken string `yaml:"security_token"`
+	Region        string `yaml:"region"`
+	// Timeout 单次API请求的超时时间，默认30s
+	Timeout time.Duration `yaml:"timeout"`
+	// ProxyURL 访问API使用的代理地址，未配置时读取HTTP_PROXY/HTTPS_PROXY环境变量
+	ProxyURL string `yaml:"proxy_url"`
+	// SignatureVersion 请求签名版本：v1（默认，HMAC-SHA1）或v3（ACS3-HMAC-SHA256）
+	SignatureVersion string `yaml:"signature_version"`
+	// QPS 每秒最多发起的API请求数，分页和并发同步的域名共享，默认10
+	QPS float64 `yaml:"qps"`
+	// DisableCompression 关闭响应的gzip压缩，默认请求gzip压缩以减少记录较多时的流量
+	DisableCompression bool `yaml:"disable_compression"`
+	// OrderBy 拉取记录时传给DescribeDomainRecords的OrderBy参数，使分页使用稳定的排序，默认不传
+	OrderBy string `yaml:"order_by"`
+	// PageSize 拉取记录时每页的记录数，默认100，超过接口上限500时按500
+	PageSize int64 `yaml:"page_size"`
+}
+
+// MaxAliyunPageSize DescribeDomainRecords每页记录数的上限
+const MaxAliyunPageSize = 500
+
+// CloudflareConfig Cloudflare配置
+type CloudflareConfig struct {
+	APIToken string `yaml:"api_token"`
+	Endpoint string `yaml:"endpoint"`
+}
+
+// AWSConfig AWS配置，用于Route53
+type AWSConfig struct {
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	// SessionToken 使用STS临时凭证时的会话令牌
+	SessionToken string `yaml:"session_token"`
+	// Region 签名使用的区域，Route53为全局服务，默认us-east-1
+	Region   string `yaml:"region"`
+	Endpoint string `yaml:"endpoint"`
+}
+
+// DNSPodConfig DNSPod配置，使用DNSPod控制台创建的API Token
+type DNSPodConfig struct {
+	TokenID  string `yaml:"token_id"`
+	Token    string `yaml:"token"`
+	Endpoint string `yaml:"endpoint"`
 }
 
 // MySQLConfig MySQL配置
@@ -20,74 +76,1386 @@ type MySQLConfig struct {
 	Username string `yaml:"username"`
 	Password string `yaml:"password"`
 	Database string `yaml:"database"`
+	// WorkerID 雪花算法工作节点ID（0-1023），多实例同时写入时需要各不相同
+	WorkerID int64 `yaml:"worker_id"`
+	// IDStrategy 新记录的主键生成策略：snowflake（默认）、timestamp或db_auto（id为自增列）
+	IDStrategy string `yaml:"id_strategy"`
+	// MaxOpenConns 最大打开连接数，默认25
+	MaxOpenConns int `yaml:"max_open_conns"`
+	// MaxIdleConns 最大空闲连接数，默认10，不能超过MaxOpenConns
+	MaxIdleConns int `yaml:"max_idle_conns"`
+	// ConnMaxLifetime 连接最长复用时间，默认5m
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	// ConnectTimeout 建立连接的超时时间，写入DSN的timeout参数，默认10s
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	// QueryTimeout 单次数据库调用（一次查询或一条记录、一批记录的写入）的超时时间，默认0表示不限制
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+	// TLSMode TLS模式：disable（默认）、preferred、require、verify-ca或verify-full
+	TLSMode string `yaml:"tls_mode"`
+	// CACert 校验服务端证书的CA证书（PEM）路径，为空时使用系统证书
+	CACert string `yaml:"ca_cert"`
+	// ClientCert、ClientKey 双向TLS的客户端证书和私钥（PEM）路径，需同时配置
+	ClientCert string `yaml:"client_cert"`
+	ClientKey  string `yaml:"client_key"`
+	// MaxRetries 单条记录写入遇到死锁、锁等待超时或连接断开时的最大重试次数，默认3，负数表示不重试
+	MaxRetries int `yaml:"max_retries"`
+	// Replica 只读副本，配置后对比用的只读查询使用副本，写入仍使用主库
+	Replica *MySQLReplicaConfig `yaml:"replica"`
+	// Table 记录表名，默认asset_sub_domain，审计历史表为表名加_history后缀
+	Table string `yaml:"table"`
+	// Params 追加到DSN的连接参数，可覆盖默认的charset=utf8mb4和loc=Local，也可设置sql_mode等会话变量
+	Params map[string]string `yaml:"params"`
+
+	// tlsName 注册的自定义TLS配置名，为空时使用MySQLTLSConfigName
+	tlsName string
+}
+
+// tableNamePattern 允许的表名
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,55}$`)
+
+// postgresTableNamePattern 允许的PostgreSQL表名，标识符最长63个字符，
+// 最长的派生名称idx_表名_history_record_id比表名多22个字符，表名最长41个字符时不会被截断
+var postgresTableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,40}$`)
+
+// paramNamePattern 允许的DSN参数名，驱动参数和会话变量名都只包含字母、数字和下划线
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// MySQLReplicaConfig MySQL只读副本的连接配置，未配置的字段沿用主库的配置
+type MySQLReplicaConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// MySQLTLSConfigName 向mysql驱动注册的自定义TLS配置名，require、verify-ca和verify-full模式使用
+const MySQLTLSConfigName = "dns-sync"
+
+// DefaultRecordTypes 未配置record_types时默认同步的记录类型
+var DefaultRecordTypes = []string{"A", "CNAME"}
+
+// DefaultLines 未配置lines时默认同步的解析线路
+var DefaultLines = []string{"default"}
+
+// DBConfig 存储后端配置
+type DBConfig struct {
+	// Driver 数据库驱动：mysql（默认）或postgres
+	Driver string `yaml:"driver"`
+}
+
+// PostgresConfig PostgreSQL配置
+type PostgresConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Database string `yaml:"database"`
+	// SSLMode 对应lib/pq的sslmode参数，默认disable
+	SSLMode string `yaml:"ssl_mode"`
+	// WorkerID 雪花算法工作节点ID（0-1023）
+	WorkerID int64 `yaml:"worker_id"`
+	// IDStrategy 新记录的主键生成策略，同MySQLConfig.IDStrategy
+	IDStrategy string `yaml:"id_strategy"`
+	// MaxOpenConns 最大打开连接数，默认25
+	MaxOpenConns int `yaml:"max_open_conns"`
+	// MaxIdleConns 最大空闲连接数，默认10，不能超过MaxOpenConns
+	MaxIdleConns int `yaml:"max_idle_conns"`
+	// ConnMaxLifetime 连接最长复用时间，默认5m
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	// QueryTimeout 单次数据库调用的超时时间，同MySQLConfig.QueryTimeout，默认0表示不限制
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+	// Table 记录表名，同MySQLConfig.Table，默认asset_sub_domain；未加引号的标识符不区分大小写
+	Table string `yaml:"table"`
 }
 
 // DomainMapping 域名映射关系
 type DomainMapping struct {
-	ProjectID string `yaml:"project_id"`
-	DomainID  string `yaml:"domain_id"`
-	Domain    string `yaml:"domain"`
+	ProjectID   string   `yaml:"project_id"`
+	DomainID    string   `yaml:"domain_id"`
+	Domain      string   `yaml:"domain"`
+	RecordTypes []string `yaml:"record_types"`
+	// Provider DNS服务商：aliyun（默认）、cloudflare、dnspod、route53，或从本地快照文件读取记录的file
+	Provider string `yaml:"provider"`
+	// Lines 需要同步的解析线路，默认只同步default线路
+	Lines []string `yaml:"lines"`
+	// Account 阿里云账号名，对应aliyun_accounts中的键；默认为default，即顶层aliyun配置
+	Account string `yaml:"account"`
+	// AccessKeyID、AccessKeySecret、Region 只用于该域名的阿里云凭证，不能与account同时配置；
+	// 其它设置沿用顶层aliyun配置，Region为空时使用顶层的区域，凭证相同的域名共享一个客户端
+	AccessKeyID     string `yaml:"access_key_id"`
+	AccessKeySecret string `yaml:"access_key_secret"`
+	Region          string `yaml:"region"`
+	// Include 需要同步的子域名的glob模式，匹配完整子域名，为空表示全部同步
+	Include []string `yaml:"include"`
+	// Exclude 不同步的子域名的glob模式，优先于Include
+	Exclude []string `yaml:"exclude"`
+	// AssetDefaults 新增记录时写入的资产字段，未配置的字段使用全局asset_defaults
+	AssetDefaults AssetFields `yaml:"asset_defaults"`
+	// Source 写入source列的来源标识，每个来源只维护自己的记录；默认按服务商取DefaultSources中的值
+	Source string `yaml:"source"`
+	// Enabled 是否同步该域名，默认true；设为false时保留映射配置但跳过同步
+	Enabled *bool `yaml:"enabled"`
+	// ZoneApex 服务商上注册的主域名，Domain为托管在其下的子区域时配置；
+	// 查询时使用ZoneApex，只同步Domain下的记录，主机记录相对Domain保存
+	ZoneApex string `yaml:"zone_apex"`
+	// Path provider为file时的快照文件路径，YAML或JSON格式，相对路径相对于当前工作目录
+	Path string `yaml:"path"`
+	// MaxRecords 从服务商拉取的最大记录数，0表示沿用sync.max_records
+	MaxRecords int `yaml:"max_records"`
+	// MaxRecordsAction 超出MaxRecords时的处理方式，为空时沿用sync.max_records_action
+	MaxRecordsAction string `yaml:"max_records_action"`
+}
+
+// DefaultSources 各服务商默认的source值，阿里云沿用原有的Aliyun-DNS-Sync
+var DefaultSources = map[string]string{
+	"aliyun":     "Aliyun-DNS-Sync",
+	"cloudflare": "Cloudflare-DNS-Sync",
+	"dnspod":     "DNSPod-DNS-Sync",
+	"route53":    "Route53-DNS-Sync",
+	"file":       "File-DNS-Sync",
+}
+
+// AssetFields 新增记录时写入的资产字段，只在插入时使用，更新时不会覆盖人工维护的值
+type AssetFields struct {
+	AssetLabel      string `yaml:"asset_label"`
+	CreateBy        string `yaml:"create_by"`
+	SysOrgCode      string `yaml:"sys_org_code"`
+	AssetDepartment string `yaml:"asset_department"`
+	Level           string `yaml:"level"`
+}
+
+// merge 用defaults填充未配置的字段
+func (a *AssetFields) merge(defaults AssetFields) {
+	if a.AssetLabel == "" {
+		a.AssetLabel = defaults.AssetLabel
+	}
+	if a.CreateBy == "" {
+		a.CreateBy = defaults.CreateBy
+	}
+	if a.SysOrgCode == "" {
+		a.SysOrgCode = defaults.SysOrgCode
+	}
+	if a.AssetDepartment == "" {
+		a.AssetDepartment = defaults.AssetDepartment
+	}
+	if a.Level == "" {
+		a.Level = defaults.Level
+	}
+}
+
+// DefaultAccount 顶层aliyun配置对应的账号名
+const DefaultAccount = "default"
+
+// IsEnabled 判断该域名是否需要同步，未加载默认值时视为启用
+func (d DomainMapping) IsEnabled() bool {
+	return d.Enabled == nil || *d.Enabled
+}
+
+// QueryDomain 返回向服务商查询和推送记录时使用的域名，配置了zone_apex时为zone_apex
+func (d DomainMapping) QueryDomain() string {
+	if d.ZoneApex != "" {
+		return d.ZoneApex
+	}
+	return d.Domain
+}
+
+// HasCredentials 判断该域名是否配置了自己的凭证
+func (d DomainMapping) HasCredentials() bool {
+	return d.AccessKeyID != "" || d.AccessKeySecret != ""
+}
+
+// credentialAccount 域名自己的凭证对应的账号名，由凭证计算得出，凭证相同的域名得到相同的账号名
+func (d DomainMapping) credentialAccount() string {
+	sum := sha256.Sum256([]byte(d.AccessKeyID + "\x00" + d.AccessKeySecret + "\x00" + d.Region))
+	return "domain-" + hex.EncodeToString(sum[:])[:12]
+}
+
+// ProviderKey 返回该域名使用的服务商客户端的键
+// 阿里云默认账号与其它服务商一样使用服务商名称，其它账号为aliyun/<account>
+func (d DomainMapping) ProviderKey() string {
+	if d.Provider == "aliyun" && d.Account != DefaultAccount {
+		return d.Provider + "/" + d.Account
+	}
+	return d.Provider
+}
+
+// AcceptsType 判断该域名是否需要同步指定类型的记录
+func (d DomainMapping) AcceptsType(recordType string) bool {
+	recordType = strings.TrimSpace(recordType)
+	for _, t := range d.RecordTypes {
+		if strings.EqualFold(t, recordType) {
+			return true
+		}
+	}
+	return false
+}
+
+// AcceptsName 判断完整子域名是否通过include/exclude过滤
+// name应为小写的punycode形式；模式在加载配置时已转为小写并校验过语法
+func (d DomainMapping) AcceptsName(name string) bool {
+	for _, pattern := range d.Exclude {
+		if matched, _ := path.Match(pattern, name); matched {
+			return false
+		}
+	}
+	if len(d.Include) == 0 {
+		return true
+	}
+	for _, pattern := range d.Include {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// AcceptsLine 判断该域名是否需要同步指定解析线路的记录
+func (d DomainMapping) AcceptsLine(line string) bool {
+	for _, l := range d.Lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+// TransformConfig 一条记录值改写规则：lowercase_value、strip_trailing_dot或suffix_strip
+// 配置中不带参数的规则写为名称，带参数的写为单键映射，如 suffix_strip: .internal
+type TransformConfig struct {
+	Name string
+	Arg  string
+}
+
+// UnmarshalYAML 解析名称字符串或单键映射两种写法
+func (t *TransformConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		t.Name = name
+		return nil
+	}
+
+	var withArg map[string]string
+	if err := unmarshal(&withArg); err != nil || len(withArg) != 1 {
+		return fmt.Errorf("transform must be a name or a single-key mapping such as suffix_strip: .internal")
+	}
+	for name, arg := range withArg {
+		t.Name, t.Arg = name, arg
+	}
+	return nil
+}
+
+// SyncConfig 同步行为配置
+type SyncConfig struct {
+	// Timeout 每次同步的超时时间，如"10m"，为0表示不限制
+	Timeout time.Duration `yaml:"timeout"`
+	// Interval 常驻模式下两次同步的间隔，如"5m"，为0表示只同步一次后退出
+	Interval time.Duration `yaml:"interval"`
+	// Concurrency 并发同步的域名数量，默认4
+	Concurrency int `yaml:"concurrency"`
+	// Transaction 是否将单个域名的全部变更放在一个事务中执行
+	Transaction bool `yaml:"transaction"`
+	// StopOnError 事务模式下遇到第一个错误立即回滚，否则执行完全部变更后再整体回滚
+	StopOnError bool `yaml:"stop_on_error"`
+	// MaxDeleteCount 单个域名一次最多允许删除的记录数，为0表示不限制
+	MaxDeleteCount int `yaml:"max_delete_count"`
+	// MaxDeletePercent 单个域名一次最多允许删除的本地记录百分比，默认20，设为100表示不限制
+	MaxDeletePercent float64 `yaml:"max_delete_percent"`
+	// MaxDeletePercentMin 待删除记录数不超过该值时不检查max_delete_percent，避免小域名删除一条记录就超出百分比，
+	// 默认5，设为负数表示始终检查
+	MaxDeletePercentMin int `yaml:"max_delete_percent_min"`
+	// MatchBy 本地记录与服务商记录的匹配方式：record_id（默认）或name_type，
+	// name_type会在RecordId变化但子域名和类型不变时更新原有记录而不是删除重建
+	MatchBy string `yaml:"match_by"`
+	// DeleteMode 删除方式：hard直接删除行，soft只标记为已删除
+	DeleteMode string `yaml:"delete_mode"`
+	// Direction 同步方向：pull（默认）从服务商拉取到数据库，push将本地记录推送到服务商，both先推送再拉取
+	Direction string `yaml:"sync_direction"`
+	// PushSource 需要推送到服务商的本地记录的source值
+	PushSource string `yaml:"push_source"`
+	// StrictDomains 配置的域名在服务商账号中不存在时是否退出，默认true，设为false时只打印警告
+	StrictDomains *bool `yaml:"strict_domains"`
+	// Audit 是否将每次写入记录到asset_sub_domain_history审计表
+	Audit bool `yaml:"audit"`
+	// BatchSize 非事务模式下批量写入和批量删除时每条语句的行数，默认500
+	BatchSize int `yaml:"batch_size"`
+	// LockedRecords 服务商上已锁定记录的处理方式：sync（默认）与普通记录相同，
+	// skip不更新锁定记录，readonly不更新并对有差异的锁定记录打印警告；两者都照常插入新记录
+	LockedRecords string `yaml:"locked_records"`
+	// Since 增量模式：只对比UpdateTimestamp晚于上次同步水位的记录，水位保存在sync_state表中
+	Since bool `yaml:"since"`
+	// CollapseValues 将子域名、类型和线路都相同的多条记录合并为一行资产，记录值为排序后逗号拼接的列表
+	CollapseValues bool `yaml:"collapse_values"`
+	// IgnoreFields 判断记录是否需要更新时忽略的字段：type、value、ttl、priority、weight、line、status、remark
+	IgnoreFields []string `yaml:"ignore_fields"`
+	// LineNames 线路代码对应的名称，覆盖或补充内置名称，写入line_name列
+	LineNames map[string]string `yaml:"line_names"`
+	// Transforms 拉取之后、对比和写入之前按顺序执行的记录值改写，见TransformConfig
+	Transforms []TransformConfig `yaml:"transforms"`
+	// AllowEmpty 服务商返回的记录为空时仍删除本地记录，默认跳过删除并警告，避免服务商临时故障清空本地数据
+	AllowEmpty bool `yaml:"allow_empty"`
+	// DeferDeletes 常规同步只新增和更新，不删除本地记录，删除交给单独调度的prune子命令，默认关闭
+	DeferDeletes bool `yaml:"defer_deletes"`
+	// IncludeSystemRecords 同步域名本身的NS和SOA记录，默认跳过，启用NS类型时也不会导入服务商的区域记录
+	IncludeSystemRecords bool `yaml:"include_system_records"`
+	// MaxRecords 每个域名从服务商拉取的最大记录数，默认0不限制，域名可单独配置
+	MaxRecords int `yaml:"max_records"`
+	// MaxRecordsAction 超出MaxRecords时的处理方式：error（默认）放弃同步该域名，truncate截断并跳过删除
+	MaxRecordsAction string `yaml:"max_records_action"`
+	// ProgressEvery 写入变更时每处理多少条记录输出一次进度日志，默认500，设为负数关闭
+	ProgressEvery int `yaml:"progress_every"`
+	// ProgressInterval 写入变更时距上次进度日志超过该间隔也输出一次，默认10s，设为负数关闭
+	ProgressInterval time.Duration `yaml:"progress_interval"`
+	// CircuitBreakerThreshold 同一服务商连续多少个域名拉取记录失败后，本轮跳过该服务商的其它域名，默认5，设为负数关闭
+	CircuitBreakerThreshold int `yaml:"circuit_breaker_threshold"`
+	// DeleteAnomalyFactor 本次删除数超过该域名最近7次同步删除数平均值的多少倍时标记为删除异常，默认0不检测
+	// 开启后每次同步的变更数量保存在sync_metrics表中
+	DeleteAnomalyFactor float64 `yaml:"delete_anomaly_factor"`
+	// DeleteAnomalyMin 删除数至少达到多少时才可能标记为异常，避免删除数很少的域名误报，默认10
+	DeleteAnomalyMin int `yaml:"delete_anomaly_min"`
+	// ResolveCheck 同步后解析A和CNAME记录的主机名，与本地记录值对比，结果写入resolution_status列，默认关闭
+	ResolveCheck bool `yaml:"resolve_check"`
+	// ResolveConcurrency 解析检查同时进行的查询数，默认8
+	ResolveConcurrency int `yaml:"resolve_concurrency"`
+	// ResolveTimeout 单次解析查询的超时时间，默认2s
+	ResolveTimeout time.Duration `yaml:"resolve_timeout"`
+	// PostSyncSQL 全部域名同步完成后在单个事务中执行的语句，{added}、{updated}、{deleted}、{pushed}替换为本轮合计
+	PostSyncSQL []string `yaml:"post_sync_sql"`
+	// PostSyncSQLFatal post_sync_sql执行失败时以退出码7退出，默认只记录错误
+	PostSyncSQLFatal bool `yaml:"post_sync_sql_fatal"`
+	// RunLock 写入数据库前获取运行锁，同一时间只允许一个实例同步，其它实例直接退出，默认关闭
+	RunLock bool `yaml:"run_lock"`
+	// LockName 运行锁的名称，默认按数据库和表名生成，共用锁名的实例互斥
+	LockName string `yaml:"lock_name"`
+	// DryRun 只打印变更不写入数据库，由命令行参数设置
+	DryRun bool `yaml:"-"`
+	// PruneOnly 只执行删除，不新增、更新或推送记录，由prune子命令设置
+	PruneOnly bool `yaml:"-"`
+}
+
+// HealthConfig 常驻模式下的健康检查HTTP服务配置
+type HealthConfig struct {
+	// Listen 监听地址，如":8080"，为空表示不启动
+	Listen string `yaml:"listen"`
+	// Staleness 最近一次成功同步的最长间隔，超过后/readyz返回503，默认为同步间隔的3倍
+	Staleness time.Duration `yaml:"staleness"`
+	// CheckTimeout 每个依赖连接检查的超时时间，默认5s
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+}
+
+// NotifyConfig 同步完成后的webhook通知配置
+type NotifyConfig struct {
+	// WebhookURL 接收通知的地址，为空表示不通知
+	WebhookURL string `yaml:"webhook_url"`
+	// Format 请求体格式：json（默认，同步摘要）或slack（Slack incoming webhook消息）
+	Format string `yaml:"format"`
+	// On 发送时机：failure（默认，退出码非0时）或always（每次同步后）
+	On string `yaml:"on"`
+	// Timeout 单次通知请求的超时时间，默认10s
+	Timeout time.Duration `yaml:"timeout"`
 }
 
 // Config 应用配置
 type Config struct {
-	Aliyun  AliyunConfig    `yaml:"aliyun"`
-	MySQL   MySQLConfig     `yaml:"mysql"`
-	Domains []DomainMapping `yaml:"domains"`
+	Aliyun     AliyunConfig     `yaml:"aliyun"`
+	// AliyunAccounts 按名称配置的多个阿里云账号，域名通过account引用；顶层aliyun为default账号
+	AliyunAccounts map[string]AliyunConfig `yaml:"aliyun_accounts"`
+	Cloudflare CloudflareConfig `yaml:"cloudflare"`
+	DNSPod     DNSPodConfig     `yaml:"dnspod"`
+	AWS        AWSConfig        `yaml:"aws"`
+	Health     HealthConfig     `yaml:"health"`
+	Notify     NotifyConfig     `yaml:"notify"`
+	DB         DBConfig         `yaml:"db"`
+	MySQL      MySQLConfig      `yaml:"mysql"`
+	Postgres   PostgresConfig   `yaml:"postgres"`
+	Sync       SyncConfig       `yaml:"sync"`
+	Domains    []DomainMapping  `yaml:"domains"`
+	// AssetDefaults 新增记录时写入的资产字段的全局默认值
+	AssetDefaults AssetFields `yaml:"asset_defaults"`
+	// LogFormat 日志格式：text（默认）或json
+	LogFormat string `yaml:"log_format"`
+	// LogLevel 日志级别：debug、info（默认）、warn、error，debug级别会输出逐条记录的变更
+	LogLevel string `yaml:"log_level"`
+	// OtelEndpoint OTLP/HTTP collector地址，设置后每轮同步导出trace，为空时不开启追踪
+	OtelEndpoint string `yaml:"otel_endpoint"`
 }
 
 // LoadConfig 加载配置文件
 func LoadConfig(filepath string) (*Config, error) {
+	return loadConfig(filepath, true)
+}
+
+// LoadProviderConfig 加载配置文件，不验证数据库配置，用于export等不访问数据库的命令
+func LoadProviderConfig(filepath string) (*Config, error) {
+	return loadConfig(filepath, false)
+}
+
+// loadConfig 加载配置文件，requireDB为false时跳过数据库配置的验证
+func loadConfig(filepath string, requireDB bool) (*Config, error) {
+	config, err := parseConfig(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证配置
+	if err := config.validate(requireDB); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return config, nil
+}
+
+// SectionResult 配置检查中一个部分的结果，Err为nil表示通过
+type SectionResult struct {
+	Section string
+	Err     error
+}
+
+// ValidateFile 加载配置文件并逐个部分检查，不连接服务商和数据库
+// 读取、环境变量替换或解析失败时只返回file部分的结果
+func ValidateFile(filepath string) []SectionResult {
+	config, err := parseConfig(filepath)
+	if err != nil {
+		return []SectionResult{{Section: "file", Err: err}}
+	}
+
+	results := []SectionResult{{Section: "file"}}
+	for _, section := range config.sections(true) {
+		results = append(results, SectionResult{Section: section.name, Err: section.check()})
+	}
+	return results
+}
+
+// parseConfig 读取配置文件，严格解析并替换环境变量后填充默认值
+func parseConfig(filepath string) (*Config, error) {
 	data, err := ioutil.ReadFile(filepath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	return parseConfigData(data)
+}
+
+// parseConfigData 解析配置内容，parseConfig和测试共用
+func parseConfigData(data []byte) (*Config, error) {
+	// 严格解析，拼错或不存在的配置项直接报错，避免被静默忽略后得到空值
+	// 错误信息形如"line 3: field acess_key_id not found in type config.AliyunConfig"
 	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file (unknown or misspelled keys are rejected): %w", err)
 	}
 
-	// 验证配置
-	if err := config.validate(); err != nil {
-		return nil, fmt.Errorf("invalid config: %w", err)
+	// 解析后再替换字符串中的环境变量引用，敏感信息可以不写在配置文件中，
+	// 值中的#、": "和引号等YAML语法字符原样保留，注释中的引用不会被解析到
+	if err := expandEnv(&config); err != nil {
+		return nil, fmt.Errorf("failed to expand config file: %w", err)
 	}
 
+	// 填充默认值
+	config.setDefaults()
+
 	return &config, nil
 }
 
-// validate 验证配置的完整性
-func (c *Config) validate() error {
+// envPattern 匹配${VAR}、${VAR:-default}和${VAR-default}形式的环境变量引用
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)((:?-)([^}]*))?\}`)
+
+// expandEnv 替换配置中所有字符串字段里的环境变量引用，包括切片和map中的字符串
+// 与shell一致：${VAR:-default}在变量未设置或为空时使用默认值，${VAR-default}只在未设置时使用默认值；
+// 变量未设置且没有默认值时返回错误，列出所有缺失的变量
+func expandEnv(c *Config) error {
+	var missing []string
+	expandEnvValue(reflect.ValueOf(c).Elem(), &missing)
+
+	if len(missing) > 0 {
+		return fmt.Errorf("environment variables not set: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// expandEnvValue 递归替换v中的字符串，缺失的变量追加到missing
+func expandEnvValue(v reflect.Value, missing *[]string) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(expandEnvString(v.String(), missing))
+	case reflect.Ptr:
+		if !v.IsNil() {
+			expandEnvValue(v.Elem(), missing)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				expandEnvValue(v.Field(i), missing)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			expandEnvValue(v.Index(i), missing)
+		}
+	case reflect.Map:
+		// map的值不可寻址，复制后替换再写回；键保持原样
+		iter := v.MapRange()
+		for iter.Next() {
+			value := reflect.New(iter.Value().Type()).Elem()
+			value.Set(iter.Value())
+			expandEnvValue(value, missing)
+			v.SetMapIndex(iter.Key(), value)
+		}
+	}
+}
+
+// expandEnvString 替换单个字符串中的环境变量引用
+func expandEnvString(s string, missing *[]string) string {
+	return envPattern.ReplaceAllStringFunc(s, func(match string) string {
+		groups := envPattern.FindStringSubmatch(match)
+		name, operator, fallback := groups[1], groups[3], groups[4]
+
+		value, ok := os.LookupEnv(name)
+		switch {
+		case ok && (value != "" || operator != ":-"):
+			return value
+		case operator != "":
+			return fallback
+		}
+
+		*missing = append(*missing, name)
+		return match
+	})
+}
+
+// setDefaults 为未配置的可选项填充默认值
+func (c *Config) setDefaults() {
+	// 阿里云凭证未在配置文件中填写时从标准环境变量读取，便于凭证轮换
 	if c.Aliyun.AccessKeyID == "" {
-		return fmt.Errorf("aliyun access_key_id is required")
+		c.Aliyun.AccessKeyID = os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_ID")
 	}
 	if c.Aliyun.AccessKeySecret == "" {
-		return fmt.Errorf("aliyun access_key_secret is required")
+		c.Aliyun.AccessKeySecret = os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_SECRET")
 	}
-	if c.MySQL.Host == "" {
-		return fmt.Errorf("mysql host is required")
+	if c.Aliyun.SecurityToken == "" {
+		c.Aliyun.SecurityToken = os.Getenv("ALIBABA_CLOUD_SECURITY_TOKEN")
 	}
-	if c.MySQL.Username == "" {
-		return fmt.Errorf("mysql username is required")
+	c.Aliyun.setDefaults()
+	// AWS凭证未在配置文件中填写时从标准环境变量读取
+	if c.AWS.AccessKeyID == "" {
+		c.AWS.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
 	}
-	if c.MySQL.Database == "" {
-		return fmt.Errorf("mysql database is required")
+	if c.AWS.SecretAccessKey == "" {
+		c.AWS.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if c.AWS.SessionToken == "" {
+		c.AWS.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if c.AWS.Region == "" {
+		c.AWS.Region = "us-east-1"
+	}
+	for name, account := range c.AliyunAccounts {
+		account.setDefaults()
+		c.AliyunAccounts[name] = account
+	}
+	if c.DB.Driver == "" {
+		c.DB.Driver = "mysql"
+	}
+	if c.MySQL.Port == 0 {
+		c.MySQL.Port = 3306
+	}
+	if c.MySQL.MaxOpenConns == 0 {
+		c.MySQL.MaxOpenConns = 25
+	}
+	if c.MySQL.MaxIdleConns == 0 {
+		c.MySQL.MaxIdleConns = min(10, c.MySQL.MaxOpenConns)
+	}
+	if c.MySQL.ConnMaxLifetime == 0 {
+		c.MySQL.ConnMaxLifetime = 5 * time.Minute
+	}
+	if c.MySQL.ConnectTimeout == 0 {
+		c.MySQL.ConnectTimeout = 10 * time.Second
+	}
+	if c.MySQL.TLSMode == "" {
+		c.MySQL.TLSMode = "disable"
+	}
+	if c.MySQL.IDStrategy == "" {
+		c.MySQL.IDStrategy = "snowflake"
+	}
+	if c.MySQL.Table == "" {
+		c.MySQL.Table = "asset_sub_domain"
+	}
+	if c.Postgres.IDStrategy == "" {
+		c.Postgres.IDStrategy = "snowflake"
+	}
+	if c.MySQL.MaxRetries == 0 {
+		c.MySQL.MaxRetries = 3
+	}
+	if c.Postgres.Table == "" {
+		c.Postgres.Table = "asset_sub_domain"
+	}
+	if c.Postgres.Port == 0 {
+		c.Postgres.Port = 5432
+	}
+	if c.Postgres.SSLMode == "" {
+		c.Postgres.SSLMode = "disable"
+	}
+	if c.Postgres.MaxOpenConns == 0 {
+		c.Postgres.MaxOpenConns = 25
+	}
+	if c.Postgres.MaxIdleConns == 0 {
+		c.Postgres.MaxIdleConns = min(10, c.Postgres.MaxOpenConns)
+	}
+	if c.Postgres.ConnMaxLifetime == 0 {
+		c.Postgres.ConnMaxLifetime = 5 * time.Minute
+	}
+	if c.Health.CheckTimeout == 0 {
+		c.Health.CheckTimeout = 5 * time.Second
+	}
+	if c.Notify.Format == "" {
+		c.Notify.Format = "json"
+	}
+	if c.Notify.On == "" {
+		c.Notify.On = "failure"
+	}
+	if c.Notify.Timeout == 0 {
+		c.Notify.Timeout = 10 * time.Second
+	}
+	if c.LogFormat == "" {
+		c.LogFormat = "text"
+	}
+	if c.LogLevel == "" {
+		c.LogLevel = "info"
+	}
+	if c.Sync.Concurrency == 0 {
+		c.Sync.Concurrency = 4
+	}
+	if c.Sync.MaxDeletePercent == 0 {
+		c.Sync.MaxDeletePercent = 20
+	}
+	if c.Sync.MaxDeletePercentMin == 0 {
+		c.Sync.MaxDeletePercentMin = 5
+	}
+	if c.Sync.Direction == "" {
+		c.Sync.Direction = "pull"
+	}
+	if c.Sync.PushSource == "" {
+		c.Sync.PushSource = "Manual-Push"
+	}
+	if c.Sync.MatchBy == "" {
+		c.Sync.MatchBy = "record_id"
+	}
+	if c.Sync.DeleteMode == "" {
+		c.Sync.DeleteMode = "hard"
+	}
+	if c.Sync.StrictDomains == nil {
+		strict := true
+		c.Sync.StrictDomains = &strict
+	}
+	if c.Sync.BatchSize == 0 {
+		c.Sync.BatchSize = 500
+	}
+	if c.Sync.LockedRecords == "" {
+		c.Sync.LockedRecords = "sync"
+	}
+	if c.Sync.MaxRecordsAction == "" {
+		c.Sync.MaxRecordsAction = "error"
+	}
+	if c.Sync.ProgressEvery == 0 {
+		c.Sync.ProgressEvery = 500
+	}
+	if c.Sync.ProgressInterval == 0 {
+		c.Sync.ProgressInterval = 10 * time.Second
+	}
+	if c.Sync.CircuitBreakerThreshold == 0 {
+		c.Sync.CircuitBreakerThreshold = 5
+	}
+	if c.Sync.DeleteAnomalyMin == 0 {
+		c.Sync.DeleteAnomalyMin = 10
+	}
+	if c.Sync.ResolveConcurrency == 0 {
+		c.Sync.ResolveConcurrency = 8
+	}
+	if c.Sync.ResolveTimeout == 0 {
+		c.Sync.ResolveTimeout = 2 * time.Second
+	}
+	for i := range c.Domains {
+		if c.Domains[i].Provider == "" {
+			c.Domains[i].Provider = "aliyun"
+		}
+		if c.Domains[i].Provider == "aliyun" && c.Domains[i].Account == "" &&
+			c.Domains[i].AccessKeyID != "" && c.Domains[i].AccessKeySecret != "" {
+			c.Domains[i].Account = c.addCredentialAccount(c.Domains[i])
+		}
+		if c.Domains[i].Provider == "aliyun" && c.Domains[i].Account == "" {
+			c.Domains[i].Account = DefaultAccount
+		}
+		if len(c.Domains[i].RecordTypes) == 0 {
+			c.Domains[i].RecordTypes = append([]string(nil), DefaultRecordTypes...)
+		}
+		for j, t := range c.Domains[i].RecordTypes {
+			c.Domains[i].RecordTypes[j] = strings.ToUpper(strings.TrimSpace(t))
+		}
+		if len(c.Domains[i].Lines) == 0 {
+			c.Domains[i].Lines = append([]string(nil), DefaultLines...)
+		}
+		for j, pattern := range c.Domains[i].Include {
+			c.Domains[i].Include[j] = strings.ToLower(strings.TrimSuffix(pattern, "."))
+		}
+		for j, pattern := range c.Domains[i].Exclude {
+			c.Domains[i].Exclude[j] = strings.ToLower(strings.TrimSuffix(pattern, "."))
+		}
+		c.Domains[i].AssetDefaults.merge(c.AssetDefaults)
+		if c.Domains[i].Source == "" {
+			c.Domains[i].Source = DefaultSources[c.Domains[i].Provider]
+		}
+		if c.Domains[i].Enabled == nil {
+			enabled := true
+			c.Domains[i].Enabled = &enabled
+		}
+		c.Domains[i].ZoneApex = strings.ToLower(strings.TrimSuffix(c.Domains[i].ZoneApex, "."))
+		if c.Domains[i].MaxRecords == 0 {
+			c.Domains[i].MaxRecords = c.Sync.MaxRecords
+		}
+		if c.Domains[i].MaxRecordsAction == "" {
+			c.Domains[i].MaxRecordsAction = c.Sync.MaxRecordsAction
+		}
+	}
+}
+
+// setDefaults 为阿里云账号未配置的可选项填充默认值
+func (a *AliyunConfig) setDefaults() {
+	if a.Region == "" {
+		a.Region = "cn-hangzhou"
+	}
+	if a.Timeout == 0 {
+		a.Timeout = 30 * time.Second
+	}
+	if a.SignatureVersion == "" {
+		a.SignatureVersion = "v1"
+	}
+	if a.QPS == 0 {
+		a.QPS = 10
 	}
+	if a.PageSize == 0 {
+		a.PageSize = 100
+	}
+	if a.PageSize > MaxAliyunPageSize {
+		a.PageSize = MaxAliyunPageSize
+	}
+}
+
+// validate 验证阿里云账号配置，name为错误信息中的前缀
+func (a *AliyunConfig) validate(name string) error {
+	if a.AccessKeyID == "" {
+		return fmt.Errorf("%s access_key_id is required", name)
+	}
+	if a.AccessKeySecret == "" {
+		return fmt.Errorf("%s access_key_secret is required", name)
+	}
+	if a.Timeout < 0 {
+		return fmt.Errorf("%s timeout must not be negative", name)
+	}
+	if a.SignatureVersion != "v1" && a.SignatureVersion != "v3" {
+		return fmt.Errorf("%s signature_version must be v1 or v3, got %q", name, a.SignatureVersion)
+	}
+	if a.QPS < 0 {
+		return fmt.Errorf("%s qps must be positive", name)
+	}
+	if a.PageSize < 0 {
+		return fmt.Errorf("%s page_size must be positive", name)
+	}
+	return nil
+}
+
+// addCredentialAccount 为配置了自己凭证的域名添加账号，其它设置复制自顶层aliyun配置，返回账号名
+// 顶层的安全令牌属于顶层凭证，不会复制
+func (c *Config) addCredentialAccount(domain DomainMapping) string {
+	name := domain.credentialAccount()
+	if _, exists := c.AliyunAccounts[name]; exists {
+		return name
+	}
+
+	account := c.Aliyun
+	account.AccessKeyID = domain.AccessKeyID
+	account.AccessKeySecret = domain.AccessKeySecret
+	account.SecurityToken = ""
+	if domain.Region != "" {
+		account.Region = domain.Region
+	}
+	account.setDefaults()
+
+	if c.AliyunAccounts == nil {
+		c.AliyunAccounts = make(map[string]AliyunConfig)
+	}
+	c.AliyunAccounts[name] = account
+	return name
+}
+
+// AliyunAccount 获取指定名称的阿里云账号配置
+// aliyun_accounts中的同名账号优先，default账号未在其中定义时使用顶层aliyun配置
+func (c *Config) AliyunAccount(name string) (*AliyunConfig, bool) {
+	if account, ok := c.AliyunAccounts[name]; ok {
+		return &account, true
+	}
+	if name == DefaultAccount {
+		return &c.Aliyun, true
+	}
+	return nil, false
+}
+
+// AliyunAccountsInUse 返回阿里云域名引用到的账号名，按名称排序
+func (c *Config) AliyunAccountsInUse() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, domain := range c.Domains {
+		if domain.Provider == "aliyun" && !seen[domain.Account] {
+			seen[domain.Account] = true
+			names = append(names, domain.Account)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// configSection 配置中可以单独检查的一个部分
+type configSection struct {
+	name  string
+	check func() error
+}
+
+// sections 按检查顺序返回配置的各个部分，requireDB为false时跳过数据库配置
+func (c *Config) sections(requireDB bool) []configSection {
+	return []configSection{
+		{"providers", c.validateProviders},
+		{"database", func() error { return c.validateDatabase(requireDB) }},
+		{"logging", c.validateLogging},
+		{"health", c.validateHealth},
+		{"tracing", c.validateTracing},
+		{"notify", c.validateNotify},
+		{"sync", c.validateSync},
+		{"domains", c.validateDomains},
+	}
+}
+
+// validate 验证配置的完整性，requireDB为false时跳过数据库配置
+func (c *Config) validate(requireDB bool) error {
+	for _, section := range c.sections(requireDB) {
+		if err := section.check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateProviders 验证域名用到的服务商的凭证
+func (c *Config) validateProviders() error {
+	for _, name := range c.AliyunAccountsInUse() {
+		account, ok := c.AliyunAccount(name)
+		if !ok {
+			return fmt.Errorf("aliyun account %q is not defined in aliyun_accounts", name)
+		}
+		prefix := "aliyun"
+		if name != DefaultAccount {
+			prefix = fmt.Sprintf("aliyun account %s", name)
+		}
+		if err := account.validate(prefix); err != nil {
+			return err
+		}
+	}
+	if c.UsesProvider("cloudflare") && c.Cloudflare.APIToken == "" {
+		return fmt.Errorf("cloudflare api_token is required")
+	}
+	if c.UsesProvider("route53") && (c.AWS.AccessKeyID == "" || c.AWS.SecretAccessKey == "") {
+		return fmt.Errorf("aws access_key_id and secret_access_key are required")
+	}
+	if c.UsesProvider("dnspod") && (c.DNSPod.TokenID == "" || c.DNSPod.Token == "") {
+		return fmt.Errorf("dnspod token_id and token are required")
+	}
+	return nil
+}
+
+// validateDatabase 验证数据库配置，requireDB为false时跳过
+func (c *Config) validateDatabase(requireDB bool) error {
+	switch {
+	case !requireDB:
+	case c.DB.Driver == "mysql":
+		if err := c.MySQL.validate(); err != nil {
+			return err
+		}
+	case c.DB.Driver == "postgres":
+		if err := c.Postgres.validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("db driver must be mysql or postgres, got %q", c.DB.Driver)
+	}
+	return nil
+}
+
+// validateLogging 验证日志格式和级别
+func (c *Config) validateLogging() error {
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("log_format must be text or json, got %q", c.LogFormat)
+	}
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("log_level must be one of debug, info, warn, error, got %q", c.LogLevel)
+	}
+	return nil
+}
+
+// validateHealth 验证健康检查服务配置
+func (c *Config) validateHealth() error {
+	if c.Health.Staleness < 0 || c.Health.CheckTimeout < 0 {
+		return fmt.Errorf("health staleness and check_timeout must not be negative")
+	}
+	return nil
+}
+
+// validateTracing 验证追踪配置，otel_endpoint需为http或https地址
+func (c *Config) validateTracing() error {
+	if c.OtelEndpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(c.OtelEndpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("otel_endpoint must be an http or https URL, got %q", RedactURL(c.OtelEndpoint))
+	}
+	return nil
+}
+
+// validateSync 验证同步配置
+func (c *Config) validateSync() error {
+	if c.Sync.Timeout < 0 {
+		return fmt.Errorf("sync timeout must not be negative")
+	}
+	if c.Sync.Interval < 0 {
+		return fmt.Errorf("sync interval must not be negative")
+	}
+	if c.Sync.Concurrency < 1 {
+		return fmt.Errorf("sync concurrency must be at least 1")
+	}
+	if c.Sync.MaxDeleteCount < 0 {
+		return fmt.Errorf("sync max_delete_count must not be negative")
+	}
+	if c.Sync.MaxDeletePercent < 0 || c.Sync.MaxDeletePercent > 100 {
+		return fmt.Errorf("sync max_delete_percent must be between 0 and 100")
+	}
+	switch c.Sync.Direction {
+	case "pull", "push", "both":
+	default:
+		return fmt.Errorf("sync sync_direction must be pull, push or both, got %q", c.Sync.Direction)
+	}
+	if c.Sync.MatchBy != "record_id" && c.Sync.MatchBy != "name_type" {
+		return fmt.Errorf("sync match_by must be record_id or name_type, got %q", c.Sync.MatchBy)
+	}
+	if c.Sync.DeleteMode != "hard" && c.Sync.DeleteMode != "soft" {
+		return fmt.Errorf("sync delete_mode must be hard or soft, got %q", c.Sync.DeleteMode)
+	}
+	switch c.Sync.LockedRecords {
+	case "sync", "skip", "readonly":
+	default:
+		return fmt.Errorf("sync locked_records must be sync, skip or readonly, got %q", c.Sync.LockedRecords)
+	}
+	if c.Sync.MaxRecords < 0 {
+		return fmt.Errorf("sync max_records must not be negative")
+	}
+	for _, field := range c.Sync.IgnoreFields {
+		switch field {
+		case "type", "value", "ttl", "priority", "weight", "line", "status", "remark":
+		default:
+			return fmt.Errorf("sync ignore_fields must be type, value, ttl, priority, weight, line, status or remark, got %q",
+				field)
+		}
+	}
+	for i, transform := range c.Sync.Transforms {
+		switch transform.Name {
+		case "lowercase_value", "strip_trailing_dot":
+			if transform.Arg != "" {
+				return fmt.Errorf("sync transforms[%d]: %s does not take an argument", i, transform.Name)
+			}
+		case "suffix_strip":
+			if strings.Trim(transform.Arg, ".") == "" {
+				return fmt.Errorf("sync transforms[%d]: suffix_strip requires a suffix, e.g. suffix_strip: .internal", i)
+			}
+		default:
+			return fmt.Errorf("sync transforms[%d] must be lowercase_value, strip_trailing_dot or suffix_strip, got %q",
+				i, transform.Name)
+		}
+	}
+	if c.Sync.DeleteAnomalyFactor < 0 {
+		return fmt.Errorf("sync delete_anomaly_factor must not be negative")
+	}
+	if c.Sync.DeleteAnomalyMin < 0 {
+		return fmt.Errorf("sync delete_anomaly_min must not be negative")
+	}
+	if c.Sync.ResolveConcurrency < 0 {
+		return fmt.Errorf("sync resolve_concurrency must not be negative")
+	}
+	if c.Sync.ResolveTimeout < 0 {
+		return fmt.Errorf("sync resolve_timeout must not be negative")
+	}
+	for code, name := range c.Sync.LineNames {
+		if code == "" || name == "" {
+			return fmt.Errorf("sync line_names entries must have a non-empty code and name, got %q: %q", code, name)
+		}
+	}
+	for i, statement := range c.Sync.PostSyncSQL {
+		if strings.TrimSpace(statement) == "" {
+			return fmt.Errorf("sync post_sync_sql statement %d is empty", i+1)
+		}
+	}
+	// 每行29个占位符，MySQL单条语句最多65535个占位符
+	if c.Sync.BatchSize < 1 || c.Sync.BatchSize > 2259 {
+		return fmt.Errorf("sync batch_size must be between 1 and 2259, got %d", c.Sync.BatchSize)
+	}
+	return nil
+}
+
+// validateDomains 验证域名映射
+func (c *Config) validateDomains() error {
 	if len(c.Domains) == 0 {
 		return fmt.Errorf("at least one domain mapping is required")
 	}
 
+	seen := make(map[string]int)
 	for i, domain := range c.Domains {
 		if domain.ProjectID == "" || domain.DomainID == "" || domain.Domain == "" {
 			return fmt.Errorf("invalid domain mapping at index %d", i)
 		}
+		// 按规范化后的域名判断重复，Example.com.、example.com和国际化域名的不同写法视为同一个域名
+		name := models.NormalizeDomain(domain.Domain)
+		if first, exists := seen[name]; exists {
+			return fmt.Errorf("duplicate domain %s at index %d (first defined at index %d)", domain.Domain, i, first)
+		}
+		seen[name] = i
+		switch domain.Provider {
+		case "aliyun", "cloudflare", "dnspod", "route53", "file":
+		default:
+			return fmt.Errorf("unsupported provider %q for domain %s", domain.Provider, domain.Domain)
+		}
+		if len(domain.Source) > 50 {
+			return fmt.Errorf("source for domain %s must be at most 50 characters", domain.Domain)
+		}
+		if domain.Source == c.Sync.PushSource {
+			return fmt.Errorf("source for domain %s must differ from sync push_source %q", domain.Domain, c.Sync.PushSource)
+		}
+		if domain.Provider != "aliyun" && domain.Account != "" {
+			return fmt.Errorf("account is only supported for aliyun domains, got %q for domain %s",
+				domain.Account, domain.Domain)
+		}
+		if domain.HasCredentials() || domain.Region != "" {
+			if domain.Provider != "aliyun" {
+				return fmt.Errorf("access_key_id, access_key_secret and region are only supported for aliyun domains, "+
+					"got them for domain %s", domain.Domain)
+			}
+			if domain.AccessKeyID == "" || domain.AccessKeySecret == "" {
+				return fmt.Errorf("domain %s must set both access_key_id and access_key_secret", domain.Domain)
+			}
+			if domain.Account != domain.credentialAccount() {
+				return fmt.Errorf("domain %s cannot set both account and access_key_id", domain.Domain)
+			}
+		}
+		if domain.Provider == "file" && domain.Path == "" {
+			return fmt.Errorf("path is required for file domain %s", domain.Domain)
+		}
+		if domain.Provider != "file" && domain.Path != "" {
+			return fmt.Errorf("path is only supported for file domains, got it for domain %s", domain.Domain)
+		}
+		if domain.MaxRecords < 0 {
+			return fmt.Errorf("max_records for domain %s must not be negative", domain.Domain)
+		}
+		if domain.MaxRecordsAction != "error" && domain.MaxRecordsAction != "truncate" {
+			return fmt.Errorf("max_records_action for domain %s must be error or truncate, got %q",
+				domain.Domain, domain.MaxRecordsAction)
+		}
+		if domain.ZoneApex != "" &&
+			!strings.HasSuffix(strings.ToLower(strings.TrimSuffix(domain.Domain, ".")), "."+domain.ZoneApex) {
+			return fmt.Errorf("domain %s is not a subdomain of zone_apex %s", domain.Domain, domain.ZoneApex)
+		}
+		for _, t := range domain.RecordTypes {
+			if !isSupportedRecordType(t) {
+				return fmt.Errorf("unsupported record type %q for domain %s", t, domain.Domain)
+			}
+		}
+		for _, pattern := range append(append([]string(nil), domain.Include...), domain.Exclude...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid include/exclude pattern %q for domain %s: %w", pattern, domain.Domain, err)
+			}
+		}
 	}
 
 	return nil
 }
 
+// validateNotify 验证webhook通知配置，webhook_url需为http或https地址
+func (c *Config) validateNotify() error {
+	if c.Notify.Format != "json" && c.Notify.Format != "slack" {
+		return fmt.Errorf("notify format must be json or slack, got %q", c.Notify.Format)
+	}
+	if c.Notify.On != "failure" && c.Notify.On != "always" {
+		return fmt.Errorf("notify on must be failure or always, got %q", c.Notify.On)
+	}
+	if c.Notify.Timeout < 0 {
+		return fmt.Errorf("notify timeout must not be negative")
+	}
+	if c.Notify.WebhookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(c.Notify.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("notify webhook_url must be an http or https URL, got %q", RedactURL(c.Notify.WebhookURL))
+	}
+	return nil
+}
+
+// validate 验证MySQL配置
+func (m *MySQLConfig) validate() error {
+	if m.Host == "" {
+		return fmt.Errorf("mysql host is required")
+	}
+	if m.Port < 1 || m.Port > 65535 {
+		return fmt.Errorf("mysql port must be between 1 and 65535, got %d", m.Port)
+	}
+	if m.Username == "" {
+		return fmt.Errorf("mysql username is required")
+	}
+	if m.Database == "" {
+		return fmt.Errorf("mysql database is required")
+	}
+	if m.WorkerID < 0 || m.WorkerID > 1023 {
+		return fmt.Errorf("mysql worker_id must be between 0 and 1023")
+	}
+	if err := validateIDStrategy(m.IDStrategy); err != nil {
+		return fmt.Errorf("mysql %w", err)
+	}
+	// 表名直接拼接进SQL，只允许字母、数字和下划线；审计表需要再加8个字符的_history后缀，MySQL标识符最长64个字符
+	if !tableNamePattern.MatchString(m.Table) {
+		return fmt.Errorf("mysql table must start with a letter or underscore and contain only letters, digits and underscores (at most 56 characters), got %q", m.Table)
+	}
+	if m.MaxOpenConns < 1 {
+		return fmt.Errorf("mysql max_open_conns must be at least 1")
+	}
+	if m.MaxIdleConns < 0 || m.MaxIdleConns > m.MaxOpenConns {
+		return fmt.Errorf("mysql max_idle_conns must be between 0 and max_open_conns (%d), got %d",
+			m.MaxOpenConns, m.MaxIdleConns)
+	}
+	if m.ConnMaxLifetime < 0 {
+		return fmt.Errorf("mysql conn_max_lifetime must not be negative")
+	}
+	if m.ConnectTimeout < 0 {
+		return fmt.Errorf("mysql connect_timeout must not be negative")
+	}
+	if m.QueryTimeout < 0 {
+		return fmt.Errorf("mysql query_timeout must not be negative")
+	}
+	switch m.TLSMode {
+	case "disable", "preferred", "require", "verify-ca", "verify-full":
+	default:
+		return fmt.Errorf("mysql tls_mode must be disable, preferred, require, verify-ca or verify-full, got %q", m.TLSMode)
+	}
+	if (m.ClientCert == "") != (m.ClientKey == "") {
+		return fmt.Errorf("mysql client_cert and client_key must be set together")
+	}
+	if (m.CACert != "" || m.ClientCert != "") && (m.TLSMode == "disable" || m.TLSMode == "preferred") {
+		return fmt.Errorf("mysql ca_cert and client_cert require tls_mode require, verify-ca or verify-full")
+	}
+	for key, value := range m.Params {
+		switch key {
+		case "timeout", "tls":
+			return fmt.Errorf("mysql params must not set %s, use connect_timeout or tls_mode instead", key)
+		case "parseTime":
+			// 扫描create_time等列到time.Time依赖parseTime，不允许关闭
+			if value != "1" && !strings.EqualFold(value, "true") {
+				return fmt.Errorf("mysql params parseTime must stay true, got %q", value)
+			}
+		}
+		if !paramNamePattern.MatchString(key) {
+			return fmt.Errorf("mysql params key must contain only letters, digits and underscores, got %q", key)
+		}
+	}
+	if m.Replica != nil {
+		if m.Replica.Host == "" {
+			return fmt.Errorf("mysql replica host is required")
+		}
+		if m.Replica.Port < 0 || m.Replica.Port > 65535 {
+			return fmt.Errorf("mysql replica port must be between 1 and 65535, got %d", m.Replica.Port)
+		}
+	}
+	return nil
+}
+
+// validate 验证PostgreSQL配置
+func (p *PostgresConfig) validate() error {
+	if p.Host == "" {
+		return fmt.Errorf("postgres host is required")
+	}
+	if p.Port < 1 || p.Port > 65535 {
+		return fmt.Errorf("postgres port must be between 1 and 65535, got %d", p.Port)
+	}
+	if p.Username == "" {
+		return fmt.Errorf("postgres username is required")
+	}
+	if p.Database == "" {
+		return fmt.Errorf("postgres database is required")
+	}
+	if p.WorkerID < 0 || p.WorkerID > 1023 {
+		return fmt.Errorf("postgres worker_id must be between 0 and 1023")
+	}
+	if p.MaxOpenConns < 1 {
+		return fmt.Errorf("postgres max_open_conns must be at least 1")
+	}
+	if p.MaxIdleConns < 0 || p.MaxIdleConns > p.MaxOpenConns {
+		return fmt.Errorf("postgres max_idle_conns must be between 0 and max_open_conns (%d), got %d",
+			p.MaxOpenConns, p.MaxIdleConns)
+	}
+	if p.ConnMaxLifetime < 0 {
+		return fmt.Errorf("postgres conn_max_lifetime must not be negative")
+	}
+	if p.QueryTimeout < 0 {
+		return fmt.Errorf("postgres query_timeout must not be negative")
+	}
+	if !postgresTableNamePattern.MatchString(p.Table) {
+		return fmt.Errorf("postgres table must start with a letter or underscore and contain only letters, digits and underscores (at most 41 characters), got %q", p.Table)
+	}
+	if err := validateIDStrategy(p.IDStrategy); err != nil {
+		return fmt.Errorf("postgres %w", err)
+	}
+	return nil
+}
+
+// validateIDStrategy 验证主键生成策略
+func validateIDStrategy(strategy string) error {
+	switch strategy {
+	case "snowflake", "timestamp", "db_auto":
+		return nil
+	default:
+		return fmt.Errorf("id_strategy must be snowflake, timestamp or db_auto, got %q", strategy)
+	}
+}
+
+// UsesProvider 判断是否有域名使用了指定的DNS服务商
+func (c *Config) UsesProvider(name string) bool {
+	for _, domain := range c.Domains {
+		if domain.Provider == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isSupportedRecordType 判断记录类型是否受支持
+func isSupportedRecordType(recordType string) bool {
+	switch recordType {
+	case "A", "AAAA", "CNAME", "MX", "TXT", "NS", "SRV", "CAA":
+		return true
+	}
+	return false
+}
+
+// RunLockName 获取运行锁的名称，未配置lock_name时为dns-sync:数据库名.表名，写入同一张表的实例互斥
+func (c *Config) RunLockName() string {
+	if c.Sync.LockName != "" {
+		return c.Sync.LockName
+	}
+	if c.DB.Driver == "postgres" {
+		return "dns-sync:" + c.Postgres.Database + "." + c.Postgres.Table
+	}
+	return "dns-sync:" + c.MySQL.Database + "." + c.MySQL.Table
+}
+
 // GetMySQLDSN 获取MySQL连接字符串
 func (c *Config) GetMySQLDSN() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		c.MySQL.Username, c.MySQL.Password, c.MySQL.Host, c.MySQL.Port, c.MySQL.Database)
+	return c.MySQL.DSN()
+}
+
+// defaultMySQLParams DSN的默认连接参数，可被mysql.params覆盖，parseTime不允许关闭
+var defaultMySQLParams = map[string]string{
+	"charset":   "utf8mb4",
+	"parseTime": "True",
+	"loc":       "Local",
+}
+
+// DSN 获取MySQL连接字符串，带上连接参数、建立连接的超时时间和TLS参数
+// 连接参数按名称排序，值经过URL转义，驱动解析时会还原
+func (m *MySQLConfig) DSN() string {
+	params := make(map[string]string, len(defaultMySQLParams)+len(m.Params))
+	for key, value := range defaultMySQLParams {
+		params[key] = value
+	}
+	for key, value := range m.Params {
+		params[key] = value
+	}
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+url.QueryEscape(params[key]))
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s",
+		m.Username, m.Password, m.Host, m.Port, m.Database, strings.Join(pairs, "&"))
+	if m.ConnectTimeout > 0 {
+		dsn += "&timeout=" + m.ConnectTimeout.String()
+	}
+	if tls := m.tlsParam(); tls != "" {
+		dsn += "&tls=" + tls
+	}
+	return dsn
+}
+
+// DSN 获取lib/pq的连接字符串，各值加单引号并转义，密码中的空格、引号和反斜杠不会破坏连接字符串或注入其它参数
+func (p *PostgresConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		quoteDSNValue(p.Host), p.Port, quoteDSNValue(p.Username), quoteDSNValue(p.Password),
+		quoteDSNValue(p.Database), quoteDSNValue(p.SSLMode))
+}
+
+// dsnValueEscaper 转义lib/pq连接字符串中单引号内的反斜杠和单引号
+var dsnValueEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// quoteDSNValue 将值转换为lib/pq连接字符串中单引号包围的形式
+func quoteDSNValue(value string) string {
+	return "'" + dsnValueEscaper.Replace(value) + "'"
+}
+
+// ReplicaConfig 获取只读副本的完整连接配置，连接池、TLS等配置与主库相同；未配置副本时返回nil
+func (m *MySQLConfig) ReplicaConfig() *MySQLConfig {
+	if m.Replica == nil {
+		return nil
+	}
+
+	replica := *m
+	replica.Replica = nil
+	// 副本的主机名不同，verify-full需要单独注册TLS配置
+	replica.tlsName = MySQLTLSConfigName + "-replica"
+	replica.Host = m.Replica.Host
+	if m.Replica.Port != 0 {
+		replica.Port = m.Replica.Port
+	}
+	if m.Replica.Username != "" {
+		replica.Username = m.Replica.Username
+	}
+	if m.Replica.Password != "" {
+		replica.Password = m.Replica.Password
+	}
+	return &replica
+}
+
+// TLSConfigName 获取向mysql驱动注册的自定义TLS配置名
+func (m *MySQLConfig) TLSConfigName() string {
+	if m.tlsName != "" {
+		return m.tlsName
+	}
+	return MySQLTLSConfigName
+}
+
+// tlsParam 获取DSN的tls参数，preferred使用驱动自带的模式，其它需要校验或加载证书的模式使用注册的自定义配置
+func (m *MySQLConfig) tlsParam() string {
+	switch m.TLSMode {
+	case "preferred":
+		return "preferred"
+	case "require", "verify-ca", "verify-full":
+		return m.TLSConfigName()
+	}
+	return ""
 }
@@ -0,0 +1,824 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("DNS_SYNC_TEST_SET", "secret")
+	t.Setenv("DNS_SYNC_TEST_EMPTY", "")
+	t.Setenv("DNS_SYNC_TEST_YAML", `p#ss: "x' # y`)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		missing string
+	}{
+		{
+			name:  "set",
+			input: "mysql:\n  password: \"${DNS_SYNC_TEST_SET}\"\n",
+			want:  "secret",
+		},
+		{
+			name:  "set but empty uses :- default",
+			input: "mysql:\n  password: \"${DNS_SYNC_TEST_EMPTY:-fallback}\"\n",
+			want:  "fallback",
+		},
+		{
+			name:  "set but empty is kept with - default",
+			input: "mysql:\n  password: \"${DNS_SYNC_TEST_EMPTY-fallback}\"\n",
+			want:  "",
+		},
+		{
+			name:  "unset with :- default",
+			input: "mysql:\n  password: \"${DNS_SYNC_TEST_UNSET:-localhost}\"\n",
+			want:  "localhost",
+		},
+		{
+			name:  "unset with - default",
+			input: "mysql:\n  password: ${DNS_SYNC_TEST_UNSET-localhost}\n",
+			want:  "localhost",
+		},
+		{
+			name:    "unset without default",
+			input:   "mysql:\n  password: \"${DNS_SYNC_TEST_UNSET}\"\n",
+			missing: "DNS_SYNC_TEST_UNSET",
+		},
+		{
+			name:  "comment is left alone",
+			input: "mysql:\n  # password: \"${DNS_SYNC_TEST_UNSET}\"\n  password: ${DNS_SYNC_TEST_SET} # or ${DNS_SYNC_TEST_UNSET}\n",
+			want:  "secret",
+		},
+		{
+			name:  "hash inside quotes is not a comment",
+			input: "mysql:\n  password: \"a #${DNS_SYNC_TEST_SET}\" # ${DNS_SYNC_TEST_UNSET}\n",
+			want:  "a #secret",
+		},
+		{
+			name:  "yaml syntax in value is kept verbatim",
+			input: "mysql:\n  password: \"${DNS_SYNC_TEST_YAML}\"\n  username: root\n",
+			want:  `p#ss: "x' # y`,
+		},
+		{
+			name:  "map values are expanded",
+			input: "mysql:\n  password: \"${DNS_SYNC_TEST_SET}\"\n  params:\n    sql_mode: \"${DNS_SYNC_TEST_UNSET:-ANSI}\"\n",
+			want:  "secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseConfigData([]byte(tt.input))
+			if tt.missing != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.missing) {
+					t.Fatalf("parseConfigData() error = %v, want it to name %s", err, tt.missing)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfigData() error = %v", err)
+			}
+			if c.MySQL.Password != tt.want {
+				t.Errorf("password = %q, want %q", c.MySQL.Password, tt.want)
+			}
+			if c.MySQL.Username == "" && strings.Contains(tt.input, "username") {
+				t.Errorf("username after an expanded secret was lost")
+			}
+			if mode, ok := c.MySQL.Params["sql_mode"]; ok && mode != "ANSI" {
+				t.Errorf("params.sql_mode = %q, want ANSI", mode)
+			}
+		})
+	}
+}
+
+// TestParseConfigUnknownKeys 拼错或不存在的配置项导致解析失败，错误信息指出所在行、字段名和所属的配置结构
+func TestParseConfigUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "misspelled aliyun key",
+			input: "aliyun:\n  acess_key_id: test-id\n  access_key_secret: test-secret\n",
+			want:  []string{"line 2", "field acess_key_id not found in type config.AliyunConfig", "misspelled"},
+		},
+		{
+			name:  "misspelled mysql key",
+			input: "mysql:\n  host: localhost\n  pasword: secret\n",
+			want:  []string{"line 3", "field pasword not found in type config.MySQLConfig"},
+		},
+		{
+			name:  "unknown domain key",
+			input: "domains:\n  - domain: example.com\n    domain_id: domain-1\n    project: project-1\n",
+			want:  []string{"line 4", "field project not found in type config.DomainMapping"},
+		},
+		{
+			name:  "unknown top level key",
+			input: "aliyun:\n  access_key_id: test-id\ndatabase:\n  host: localhost\n",
+			want:  []string{"line 3", "field database not found in type config.Config"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfigData([]byte(tt.input))
+			if err == nil {
+				t.Fatal("parseConfigData() error = nil, want unknown field error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("parseConfigData() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+
+	if _, err := parseConfigData([]byte("aliyun:\n  access_key_id: test-id\n  access_key_secret: test-secret\n")); err != nil {
+		t.Errorf("parseConfigData() with known keys error = %v", err)
+	}
+}
+
+func TestValidateDomainsDuplicate(t *testing.T) {
+	tests := []struct {
+		name      string
+		domains   []string
+		duplicate bool
+	}{
+		{name: "distinct domains", domains: []string{"example.com", "example.org"}},
+		{name: "same spelling", domains: []string{"example.com", "example.com"}, duplicate: true},
+		{name: "case and trailing dot", domains: []string{"example.com", "Example.COM."}, duplicate: true},
+		{name: "unicode and punycode", domains: []string{"例子.com", "xn--fsqu00a.com"}, duplicate: true},
+		{name: "full-width spelling", domains: []string{"example.com", "ｅｘａｍｐｌｅ.com"}, duplicate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			for i, domain := range tt.domains {
+				c.Domains = append(c.Domains, DomainMapping{
+					Domain:    domain,
+					DomainID:  fmt.Sprintf("domain-%d", i),
+					ProjectID: "project-1",
+					Provider:  "aliyun",
+				})
+			}
+			c.setDefaults()
+
+			err := c.validateDomains()
+			if got := err != nil && strings.Contains(err.Error(), "duplicate domain"); got != tt.duplicate {
+				t.Errorf("validateDomains() error = %v, want duplicate = %v", err, tt.duplicate)
+			}
+		})
+	}
+}
+
+// TestValidateDisabledDomain 未配置enabled时默认启用，停用的域名仍校验映射字段
+func TestValidateDisabledDomain(t *testing.T) {
+	off := false
+	tests := []struct {
+		name    string
+		domain  DomainMapping
+		enabled bool
+		wantErr string
+	}{
+		{
+			name:    "enabled by default",
+			domain:  DomainMapping{Domain: "example.com", DomainID: "domain-1", ProjectID: "project-1", Provider: "aliyun"},
+			enabled: true,
+		},
+		{
+			name: "disabled",
+			domain: DomainMapping{Domain: "example.com", DomainID: "domain-1", ProjectID: "project-1", Provider: "aliyun",
+				Enabled: &off},
+		},
+		{
+			name:    "disabled without project_id",
+			domain:  DomainMapping{Domain: "example.com", DomainID: "domain-1", Provider: "aliyun", Enabled: &off},
+			wantErr: "invalid domain mapping at index 0",
+		},
+		{
+			name: "disabled with unsupported provider",
+			domain: DomainMapping{Domain: "example.com", DomainID: "domain-1", ProjectID: "project-1", Provider: "bind",
+				Enabled: &off},
+			wantErr: `unsupported provider "bind"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Domains: []DomainMapping{tt.domain}}
+			c.setDefaults()
+
+			err := c.validateDomains()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("validateDomains() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateDomains() error = %v", err)
+			}
+			if got := c.Domains[0].IsEnabled(); got != tt.enabled {
+				t.Errorf("IsEnabled() = %v, want %v", got, tt.enabled)
+			}
+		})
+	}
+}
+
+// TestDomainSource 未配置source时按服务商取默认来源，不同服务商同步到同一张表的行互不影响
+func TestDomainSource(t *testing.T) {
+	tests := []struct {
+		provider string
+		source   string
+		want     string
+	}{
+		{provider: "aliyun", want: "Aliyun-DNS-Sync"},
+		{provider: "cloudflare", want: "Cloudflare-DNS-Sync"},
+		{provider: "dnspod", want: "DNSPod-DNS-Sync"},
+		{provider: "route53", want: "Route53-DNS-Sync"},
+		{provider: "file", want: "File-DNS-Sync"},
+		{provider: "cloudflare", source: "cf-prod", want: "cf-prod"},
+	}
+
+	for _, tt := range tests {
+		c := &Config{Domains: []DomainMapping{{Domain: "example.com", DomainID: "domain-1", ProjectID: "project-1",
+			Provider: tt.provider, Source: tt.source}}}
+		c.setDefaults()
+		if got := c.Domains[0].Source; got != tt.want {
+			t.Errorf("provider %s, source %q: got %q, want %q", tt.provider, tt.source, got, tt.want)
+		}
+	}
+}
+
+// TestMySQLPoolConfig 连接池参数和连接超时的默认值与校验，connect_timeout写入DSN的timeout参数
+func TestMySQLPoolConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		pool        MySQLConfig
+		wantOpen    int
+		wantIdle    int
+		wantLife    time.Duration
+		wantTimeout string
+		wantErr     string
+	}{
+		{name: "defaults", wantOpen: 25, wantIdle: 10, wantLife: 5 * time.Minute, wantTimeout: "timeout=10s"},
+		{name: "custom pool", pool: MySQLConfig{MaxOpenConns: 4, MaxIdleConns: 2, ConnMaxLifetime: time.Minute,
+			ConnectTimeout: 3 * time.Second}, wantOpen: 4, wantIdle: 2, wantLife: time.Minute, wantTimeout: "timeout=3s"},
+		{name: "idle capped by open", pool: MySQLConfig{MaxOpenConns: 4}, wantOpen: 4, wantIdle: 4,
+			wantLife: 5 * time.Minute, wantTimeout: "timeout=10s"},
+		{name: "idle above open", pool: MySQLConfig{MaxOpenConns: 4, MaxIdleConns: 8},
+			wantErr: "mysql max_idle_conns must be between 0 and max_open_conns (4), got 8"},
+		{name: "negative open", pool: MySQLConfig{MaxOpenConns: -1},
+			wantErr: "mysql max_open_conns must be at least 1"},
+		{name: "negative lifetime", pool: MySQLConfig{ConnMaxLifetime: -time.Second},
+			wantErr: "mysql conn_max_lifetime must not be negative"},
+		{name: "negative connect timeout", pool: MySQLConfig{ConnectTimeout: -time.Second},
+			wantErr: "mysql connect_timeout must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{MySQL: tt.pool}
+			c.MySQL.Host, c.MySQL.Username, c.MySQL.Database = "localhost", "root", "assets"
+			c.setDefaults()
+
+			err := c.MySQL.validate()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("validate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validate() error = %v", err)
+			}
+			if c.MySQL.MaxOpenConns != tt.wantOpen || c.MySQL.MaxIdleConns != tt.wantIdle ||
+				c.MySQL.ConnMaxLifetime != tt.wantLife {
+				t.Errorf("pool after defaults = %d open, %d idle, %s lifetime, want %d, %d, %s",
+					c.MySQL.MaxOpenConns, c.MySQL.MaxIdleConns, c.MySQL.ConnMaxLifetime,
+					tt.wantOpen, tt.wantIdle, tt.wantLife)
+			}
+			if dsn := c.MySQL.DSN(); !strings.Contains(dsn, "&"+tt.wantTimeout) {
+				t.Errorf("DSN() = %s, want %s", dsn, tt.wantTimeout)
+			}
+		})
+	}
+}
+
+// TestMySQLDSNParams mysql.params追加到DSN并覆盖默认的charset和loc，按名称排序并转义；
+// 关闭parseTime、设置timeout或tls以及非法的参数名都在校验时报错
+func TestMySQLDSNParams(t *testing.T) {
+	const prefix = "root:secret@tcp(localhost:3306)/assets?"
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    string
+		wantErr string
+	}{
+		{name: "defaults", want: prefix + "charset=utf8mb4&loc=Local&parseTime=True"},
+		{
+			name:   "override charset and loc",
+			params: map[string]string{"charset": "utf8", "loc": "UTC"},
+			want:   prefix + "charset=utf8&loc=UTC&parseTime=True",
+		},
+		{
+			name:   "extra params sorted",
+			params: map[string]string{"collation": "utf8mb4_unicode_ci", "allowNativePasswords": "true"},
+			want:   prefix + "allowNativePasswords=true&charset=utf8mb4&collation=utf8mb4_unicode_ci&loc=Local&parseTime=True",
+		},
+		{
+			name:   "escaped values",
+			params: map[string]string{"loc": "Asia/Shanghai", "sql_mode": "'STRICT_TRANS_TABLES,NO_ZERO_DATE'"},
+			want: prefix + "charset=utf8mb4&loc=Asia%2FShanghai&parseTime=True" +
+				"&sql_mode=%27STRICT_TRANS_TABLES%2CNO_ZERO_DATE%27",
+		},
+		{name: "parseTime kept on", params: map[string]string{"parseTime": "true"},
+			want: prefix + "charset=utf8mb4&loc=Local&parseTime=true"},
+		{name: "parseTime off", params: map[string]string{"parseTime": "false"},
+			wantErr: `mysql params parseTime must stay true, got "false"`},
+		{name: "timeout", params: map[string]string{"timeout": "5s"},
+			wantErr: "mysql params must not set timeout, use connect_timeout or tls_mode instead"},
+		{name: "tls", params: map[string]string{"tls": "skip-verify"},
+			wantErr: "mysql params must not set tls, use connect_timeout or tls_mode instead"},
+		{name: "invalid key", params: map[string]string{"sql mode": "ANSI"},
+			wantErr: `mysql params key must contain only letters, digits and underscores, got "sql mode"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{MySQL: MySQLConfig{Host: "localhost", Username: "root", Password: "secret",
+				Database: "assets", Params: tt.params}}
+			c.setDefaults()
+			c.MySQL.ConnectTimeout = 0
+
+			err := c.MySQL.validate()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("validate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validate() error = %v", err)
+			}
+			dsn := c.MySQL.DSN()
+			if dsn != tt.want {
+				t.Errorf("DSN() = %s, want %s", dsn, tt.want)
+			}
+
+			parsed, err := mysql.ParseDSN(dsn)
+			if err != nil {
+				t.Fatalf("mysql.ParseDSN() error = %v", err)
+			}
+			if !parsed.ParseTime {
+				t.Error("parsed DSN has parseTime disabled")
+			}
+			if loc := tt.params["loc"]; loc != "" && parsed.Loc.String() != loc {
+				t.Errorf("parsed loc = %s, want %s", parsed.Loc, loc)
+			}
+			if mode := tt.params["sql_mode"]; mode != "" && parsed.Params["sql_mode"] != mode {
+				t.Errorf("parsed sql_mode = %q, want %q", parsed.Params["sql_mode"], mode)
+			}
+		})
+	}
+}
+
+func TestPostgresPoolConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		pool    PostgresConfig
+		wantErr string
+	}{
+		{name: "defaults"},
+		{name: "custom pool", pool: PostgresConfig{MaxOpenConns: 50, MaxIdleConns: 50, ConnMaxLifetime: time.Hour}},
+		{name: "idle capped by open", pool: PostgresConfig{MaxOpenConns: 4}},
+		{name: "idle above open", pool: PostgresConfig{MaxOpenConns: 4, MaxIdleConns: 8},
+			wantErr: "postgres max_idle_conns must be between 0 and max_open_conns (4), got 8"},
+		{name: "negative open", pool: PostgresConfig{MaxOpenConns: -1},
+			wantErr: "postgres max_open_conns must be at least 1"},
+		{name: "negative lifetime", pool: PostgresConfig{ConnMaxLifetime: -time.Second},
+			wantErr: "postgres conn_max_lifetime must not be negative"},
+		{name: "negative query timeout", pool: PostgresConfig{QueryTimeout: -time.Second},
+			wantErr: "postgres query_timeout must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Postgres: tt.pool}
+			c.Postgres.Host, c.Postgres.Username, c.Postgres.Database = "localhost", "postgres", "assets"
+			c.setDefaults()
+
+			err := c.Postgres.validate()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("validate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validate() error = %v", err)
+			}
+			if c.Postgres.MaxIdleConns > c.Postgres.MaxOpenConns || c.Postgres.ConnMaxLifetime <= 0 {
+				t.Errorf("pool after defaults = %d open, %d idle, %s lifetime",
+					c.Postgres.MaxOpenConns, c.Postgres.MaxIdleConns, c.Postgres.ConnMaxLifetime)
+			}
+		})
+	}
+}
+
+func TestAcceptsType(t *testing.T) {
+	// zone 同一域名下混合的A/MX/TXT记录
+	zone := []string{"A", "MX", "TXT", "txt", "A", "CNAME"}
+
+	tests := []struct {
+		name        string
+		recordTypes []string
+		want        []string
+	}{
+		{name: "defaults to A and CNAME", want: []string{"A", "A", "CNAME"}},
+		{name: "TXT only", recordTypes: []string{"txt"}, want: []string{"TXT", "txt"}},
+		{name: "mail records", recordTypes: []string{" MX ", "TXT"}, want: []string{"MX", "TXT", "txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Domains: []DomainMapping{{Domain: "example.com", RecordTypes: tt.recordTypes}}}
+			c.setDefaults()
+
+			var got []string
+			for _, recordType := range zone {
+				if c.Domains[0].AcceptsType(recordType) {
+					got = append(got, recordType)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("accepted types = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresDSN(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     string
+	}{
+		{name: "plain", password: "secret",
+			want: `host='db' port=5432 user='postgres' password='secret' dbname='assets' sslmode='disable'`},
+		{name: "empty", password: "",
+			want: `host='db' port=5432 user='postgres' password='' dbname='assets' sslmode='disable'`},
+		{name: "space does not inject parameters", password: "a sslmode=require",
+			want: `host='db' port=5432 user='postgres' password='a sslmode=require' dbname='assets' sslmode='disable'`},
+		{name: "quote and backslash are escaped", password: `it's\x`,
+			want: `host='db' port=5432 user='postgres' password='it\'s\\x' dbname='assets' sslmode='disable'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PostgresConfig{Host: "db", Port: 5432, Username: "postgres", Password: tt.password,
+				Database: "assets", SSLMode: "disable"}
+			dsn := p.DSN()
+			if dsn != tt.want {
+				t.Errorf("DSN() = %s, want %s", dsn, tt.want)
+			}
+			if _, err := pq.NewConnector(dsn); err != nil {
+				t.Errorf("lib/pq rejected DSN %s: %v", dsn, err)
+			}
+		})
+	}
+}
+
+func TestTableName(t *testing.T) {
+	tests := []struct {
+		name    string
+		driver  string
+		table   string
+		wantErr string
+	}{
+		{name: "mysql default", driver: "mysql"},
+		{name: "mysql prefixed", driver: "mysql", table: "tenant1_asset_sub_domain"},
+		{name: "mysql injection", driver: "mysql", table: "assets; DROP TABLE users",
+			wantErr: "mysql table must start with a letter or underscore"},
+		{name: "mysql too long", driver: "mysql", table: strings.Repeat("a", 57),
+			wantErr: "mysql table must start with a letter or underscore"},
+		{name: "postgres default", driver: "postgres"},
+		{name: "postgres prefixed", driver: "postgres", table: "tenant1_asset_sub_domain"},
+		{name: "postgres quoted identifier", driver: "postgres", table: `"assets"`,
+			wantErr: "postgres table must start with a letter or underscore"},
+		{name: "postgres too long for derived index names", driver: "postgres", table: strings.Repeat("a", 42),
+			wantErr: "postgres table must start with a letter or underscore"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{
+				MySQL:    MySQLConfig{Host: "localhost", Username: "root", Database: "assets", Table: tt.table},
+				Postgres: PostgresConfig{Host: "localhost", Username: "postgres", Database: "assets", Table: tt.table},
+			}
+			c.setDefaults()
+
+			err := c.MySQL.validate()
+			if tt.driver == "postgres" {
+				err = c.Postgres.validate()
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunLockName(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{name: "mysql", config: Config{MySQL: MySQLConfig{Database: "assets", Table: "tenant_assets"}},
+			want: "dns-sync:assets.tenant_assets"},
+		{name: "postgres", config: Config{DB: DBConfig{Driver: "postgres"},
+			Postgres: PostgresConfig{Database: "assets", Table: "tenant_assets"}},
+			want: "dns-sync:assets.tenant_assets"},
+		{name: "configured", config: Config{Sync: SyncConfig{LockName: "custom"}}, want: "custom"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.config.RunLockName(); got != tt.want {
+			t.Errorf("%s: RunLockName() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// testConfigYAML 通过校验的最小配置
+const testConfigYAML = `aliyun:
+  access_key_id: "test-id"
+  access_key_secret: "test-secret"
+mysql:
+  host: "db.internal"
+  username: "root"
+  database: "assets"
+domains:
+  - domain: "example.com"
+    domain_id: "domain-1"
+    project_id: "project-1"
+`
+
+// TestValidateDefaults MySQL端口和阿里云区域未配置时使用默认值，每个校验分支返回对应的错误
+func TestValidateDefaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		replace []string
+		wantErr string
+		check   func(t *testing.T, c *Config)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, c *Config) {
+				if c.MySQL.Port != 3306 {
+					t.Errorf("mysql port = %d, want 3306", c.MySQL.Port)
+				}
+				if c.Aliyun.Region != "cn-hangzhou" {
+					t.Errorf("aliyun region = %q, want cn-hangzhou", c.Aliyun.Region)
+				}
+				if c.Aliyun.PageSize != 100 {
+					t.Errorf("aliyun page_size = %d, want 100", c.Aliyun.PageSize)
+				}
+			},
+		},
+		{
+			name: "explicit port and region",
+			replace: []string{
+				`host: "db.internal"`, "host: \"db.internal\"\n  port: 3307",
+				`access_key_secret: "test-secret"`, "access_key_secret: \"test-secret\"\n  region: \"cn-shanghai\"",
+			},
+			check: func(t *testing.T, c *Config) {
+				if c.MySQL.Port != 3307 || c.Aliyun.Region != "cn-shanghai" {
+					t.Errorf("port = %d, region = %q, want 3307, cn-shanghai", c.MySQL.Port, c.Aliyun.Region)
+				}
+			},
+		},
+		{name: "port too large", replace: []string{`host: "db.internal"`, "host: \"db.internal\"\n  port: 65536"},
+			wantErr: "mysql port must be between 1 and 65535, got 65536"},
+		{name: "negative port", replace: []string{`host: "db.internal"`, "host: \"db.internal\"\n  port: -1"},
+			wantErr: "mysql port must be between 1 and 65535, got -1"},
+		{name: "missing host", replace: []string{`host: "db.internal"`, `host: ""`}, wantErr: "mysql host is required"},
+		{name: "missing username", replace: []string{`username: "root"`, `username: ""`}, wantErr: "mysql username is required"},
+		{name: "missing database", replace: []string{`database: "assets"`, `database: ""`}, wantErr: "mysql database is required"},
+		{
+			name: "page size clamped",
+			replace: []string{`access_key_secret: "test-secret"`,
+				"access_key_secret: \"test-secret\"\n  page_size: 1000"},
+			check: func(t *testing.T, c *Config) {
+				if c.Aliyun.PageSize != MaxAliyunPageSize {
+					t.Errorf("aliyun page_size = %d, want %d", c.Aliyun.PageSize, MaxAliyunPageSize)
+				}
+			},
+		},
+		{name: "negative page size", replace: []string{`access_key_secret: "test-secret"`,
+			"access_key_secret: \"test-secret\"\n  page_size: -1"}, wantErr: "aliyun page_size must be positive"},
+		{name: "missing access key id", replace: []string{`access_key_id: "test-id"`, `access_key_id: ""`},
+			wantErr: "aliyun access_key_id is required"},
+		{name: "missing access key secret", replace: []string{`access_key_secret: "test-secret"`, `access_key_secret: ""`},
+			wantErr: "aliyun access_key_secret is required"},
+		{
+			name: "duplicate domain",
+			replace: []string{`project_id: "project-1"`,
+				"project_id: \"project-1\"\n  - domain: \"Example.com.\"\n    domain_id: \"domain-2\"\n    project_id: \"project-1\""},
+			wantErr: "duplicate domain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALIBABA_CLOUD_ACCESS_KEY_ID", "")
+			t.Setenv("ALIBABA_CLOUD_ACCESS_KEY_SECRET", "")
+			data := strings.NewReplacer(tt.replace...).Replace(testConfigYAML)
+			c, err := parseConfigData([]byte(data))
+			if err != nil {
+				t.Fatalf("parseConfigData() error = %v", err)
+			}
+
+			err = c.validate(true)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("validate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validate() error = %v", err)
+			}
+			tt.check(t, c)
+		})
+	}
+}
+
+// TestValidateDomainCredentials 没有顶层凭证时每个域名需要有自己的凭证，凭证不同的域名使用不同的账号
+func TestValidateDomainCredentials(t *testing.T) {
+	const data = `mysql:
+  host: "db.internal"
+  username: "root"
+  database: "assets"
+domains:
+  - domain: "example.com"
+    domain_id: "domain-1"
+    project_id: "project-1"
+    access_key_id: "sub-a-id"
+    access_key_secret: "sub-a-secret"
+  - domain: "example.net"
+    domain_id: "domain-2"
+    project_id: "project-1"
+    access_key_id: "sub-b-id"
+    access_key_secret: "sub-b-secret"
+    region: "cn-shanghai"
+`
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "per-domain credentials only", data: data},
+		{name: "domain without credentials", data: data + `  - domain: "example.org"
+    domain_id: "domain-3"
+    project_id: "project-1"
+`, wantErr: "aliyun access_key_id is required"},
+		{name: "secret missing", data: "aliyun:\n  access_key_id: \"test-id\"\n  access_key_secret: \"test-secret\"\n" +
+			strings.Replace(data, `    access_key_secret: "sub-b-secret"
+`, "", 1), wantErr: "domain example.net must set both access_key_id and access_key_secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALIBABA_CLOUD_ACCESS_KEY_ID", "")
+			t.Setenv("ALIBABA_CLOUD_ACCESS_KEY_SECRET", "")
+			c, err := parseConfigData([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("parseConfigData() error = %v", err)
+			}
+
+			err = c.validate(true)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("validate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validate() error = %v", err)
+			}
+
+			a, b := c.Domains[0].Account, c.Domains[1].Account
+			if a == b || a == DefaultAccount || b == DefaultAccount {
+				t.Fatalf("accounts = %q, %q, want distinct per-domain accounts", a, b)
+			}
+			accountA, _ := c.AliyunAccount(a)
+			accountB, _ := c.AliyunAccount(b)
+			if accountA.AccessKeyID != "sub-a-id" || accountA.Region != "cn-hangzhou" {
+				t.Errorf("account for example.com = %s in %s, want sub-a-id in cn-hangzhou", accountA.AccessKeyID,
+					accountA.Region)
+			}
+			if accountB.AccessKeyID != "sub-b-id" || accountB.Region != "cn-shanghai" {
+				t.Errorf("account for example.net = %s in %s, want sub-b-id in cn-shanghai", accountB.AccessKeyID,
+					accountB.Region)
+			}
+		})
+	}
+}
+
+// TestAssetDefaultsMerge 域名未配置的资产字段使用全局asset_defaults，域名配置的字段优先
+func TestAssetDefaultsMerge(t *testing.T) {
+	data := testConfigYAML + `  - domain: "example.org"
+    domain_id: "domain-2"
+    project_id: "project-1"
+    asset_defaults:
+      asset_label: "legacy"
+      level: "1"
+asset_defaults:
+  asset_label: "dns"
+  create_by: "dns-sync"
+  sys_org_code: "A01"
+`
+	c, err := parseConfigData([]byte(data))
+	if err != nil {
+		t.Fatalf("parseConfigData() error = %v", err)
+	}
+
+	want := map[string]AssetFields{
+		"example.com": {AssetLabel: "dns", CreateBy: "dns-sync", SysOrgCode: "A01"},
+		"example.org": {AssetLabel: "legacy", CreateBy: "dns-sync", SysOrgCode: "A01", Level: "1"},
+	}
+	for _, domain := range c.Domains {
+		if domain.AssetDefaults != want[domain.Domain] {
+			t.Errorf("%s asset_defaults = %+v, want %+v", domain.Domain, domain.AssetDefaults, want[domain.Domain])
+		}
+	}
+}
+
+// TestSyncTransforms sync.transforms中不带参数的规则写为名称，带参数的写为单键映射，保持配置的顺序；
+// 未知的规则、缺少或多余的参数返回错误
+func TestSyncTransforms(t *testing.T) {
+	tests := []struct {
+		name       string
+		transforms string
+		want       []TransformConfig
+		wantErr    string
+	}{
+		{
+			name:       "names and mapping",
+			transforms: "    - strip_trailing_dot\n    - suffix_strip: .internal\n    - lowercase_value\n",
+			want: []TransformConfig{{Name: "strip_trailing_dot"}, {Name: "suffix_strip", Arg: ".internal"},
+				{Name: "lowercase_value"}},
+		},
+		{name: "unknown transform", transforms: "    - uppercase_value\n",
+			wantErr: `sync transforms[0] must be lowercase_value, strip_trailing_dot or suffix_strip, got "uppercase_value"`},
+		{name: "missing suffix", transforms: "    - strip_trailing_dot\n    - suffix_strip: \".\"\n",
+			wantErr: "sync transforms[1]: suffix_strip requires a suffix"},
+		{name: "unexpected argument", transforms: "    - lowercase_value: yes\n",
+			wantErr: "sync transforms[0]: lowercase_value does not take an argument"},
+		{name: "several keys", transforms: "    - suffix_strip: .internal\n      lowercase_value: x\n",
+			wantErr: "transform must be a name or a single-key mapping"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseConfigData([]byte(testConfigYAML + "sync:\n  transforms:\n" + tt.transforms))
+			if err == nil {
+				err = c.validate(true)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if len(c.Sync.Transforms) != len(tt.want) {
+				t.Fatalf("transforms = %+v, want %+v", c.Sync.Transforms, tt.want)
+			}
+			for i := range tt.want {
+				if c.Sync.Transforms[i] != tt.want[i] {
+					t.Errorf("transforms[%d] = %+v, want %+v", i, c.Sync.Transforms[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigEnv 指定配置文件路径的环境变量
+const ConfigEnv = "DNS_SYNC_CONFIG"
+
+// defaultConfigPaths 未指定配置文件时依次查找的路径
+var defaultConfigPaths = []string{
+	filepath.Join("config", "config.yaml"),
+	"/etc/dns-sync/config.yaml",
+}
+
+// ResolveConfigPath 确定要加载的配置文件
+// 查找顺序：命令行参数 → DNS_SYNC_CONFIG环境变量 → ./config/config.yaml → /etc/dns-sync/config.yaml；
+// 显式指定的文件不存在时直接报错，不再回退到默认路径
+func ResolveConfigPath(flagPath string) (string, error) {
+	if flagPath != "" {
+		return checkConfigPath(flagPath, "--config")
+	}
+	if envPath := os.Getenv(ConfigEnv); envPath != "" {
+		return checkConfigPath(envPath, ConfigEnv)
+	}
+
+	for _, path := range defaultConfigPaths {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("no config file found in %s; use --config or %s to specify one",
+		strings.Join(defaultConfigPaths, ", "), ConfigEnv)
+}
+
+// checkConfigPath 检查显式指定的配置文件是否存在
+func checkConfigPath(path, source string) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("config file %s from %s: %w", path, source, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestResolveConfigPath 按命令行参数、环境变量、默认路径的顺序查找配置文件
+func TestResolveConfigPath(t *testing.T) {
+	dir := t.TempDir()
+	file := func(name string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("database: {}\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	flagFile, envFile := file("flag.yaml"), file("env.yaml")
+	localFile, etcFile := file("local.yaml"), file("etc.yaml")
+	missing := filepath.Join(dir, "missing.yaml")
+
+	tests := []struct {
+		name     string
+		flag     string
+		env      string
+		defaults []string
+		want     string
+		wantErr  string
+	}{
+		{name: "flag first", flag: flagFile, env: envFile, defaults: []string{localFile, etcFile}, want: flagFile},
+		{name: "env before defaults", env: envFile, defaults: []string{localFile, etcFile}, want: envFile},
+		{name: "first default", defaults: []string{localFile, etcFile}, want: localFile},
+		{name: "second default", defaults: []string{missing, etcFile}, want: etcFile},
+		{name: "missing flag does not fall back", flag: missing, env: envFile, defaults: []string{localFile},
+			wantErr: "config file " + missing + " from --config"},
+		{name: "missing env does not fall back", env: missing, defaults: []string{localFile},
+			wantErr: "config file " + missing + " from DNS_SYNC_CONFIG"},
+		{name: "none found", defaults: []string{missing},
+			wantErr: "no config file found in " + missing + "; use --config or DNS_SYNC_CONFIG"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(ConfigEnv, tt.env)
+			saved := defaultConfigPaths
+			defaultConfigPaths = tt.defaults
+			defer func() { defaultConfigPaths = saved }()
+
+			got, err := ResolveConfigPath(tt.flag)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ResolveConfigPath() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveConfigPath() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveConfigPath() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
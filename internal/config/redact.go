@@ -0,0 +1,95 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Mask 日志和错误信息中代替敏感值的占位符
+const Mask = "****"
+
+// RedactedDSN 获取密码替换为****的MySQL连接字符串，用于日志和错误信息
+func (m *MySQLConfig) RedactedDSN() string {
+	redacted := *m
+	if redacted.Password != "" {
+		redacted.Password = Mask
+	}
+	return redacted.DSN()
+}
+
+// RedactedDSN 获取密码替换为****的PostgreSQL连接字符串，用于日志和错误信息
+func (p *PostgresConfig) RedactedDSN() string {
+	redacted := *p
+	if redacted.Password != "" {
+		redacted.Password = Mask
+	}
+	return redacted.DSN()
+}
+
+// RedactedDSN 获取当前数据库驱动的连接字符串，密码替换为****
+func (c *Config) RedactedDSN() string {
+	if c.DB.Driver == "postgres" {
+		return c.Postgres.RedactedDSN()
+	}
+	return c.MySQL.RedactedDSN()
+}
+
+// RedactURL 将URL中的密码替换为****；无法解析且可能带有凭证时整体替换
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		if strings.Contains(raw, "@") {
+			return Mask
+		}
+		return raw
+	}
+	// Redacted把密码替换为xxxxx，这里统一为****
+	return strings.Replace(u.Redacted(), ":xxxxx@", ":"+Mask+"@", 1)
+}
+
+// Secrets 获取配置中的全部密码、密钥和令牌，以及URL中的密码和webhook地址，用于在日志输出中屏蔽
+func (c *Config) Secrets() []string {
+	secrets := []string{
+		c.MySQL.Password,
+		c.Postgres.Password,
+		c.Cloudflare.APIToken,
+		c.AWS.SecretAccessKey,
+		c.AWS.SessionToken,
+		c.DNSPod.Token,
+		urlPassword(c.OtelEndpoint),
+		// Slack等webhook地址本身就是凭证
+		c.Notify.WebhookURL,
+	}
+	if c.MySQL.Replica != nil {
+		secrets = append(secrets, c.MySQL.Replica.Password)
+	}
+
+	accounts := []AliyunConfig{c.Aliyun}
+	for _, account := range c.AliyunAccounts {
+		accounts = append(accounts, account)
+	}
+	for _, account := range accounts {
+		secrets = append(secrets, account.AccessKeySecret, account.SecurityToken, urlPassword(account.ProxyURL))
+	}
+	for _, domainMapping := range c.Domains {
+		secrets = append(secrets, domainMapping.AccessKeySecret)
+	}
+
+	result := secrets[:0]
+	for _, secret := range secrets {
+		if secret != "" {
+			result = append(result, secret)
+		}
+	}
+	return result
+}
+
+// urlPassword 获取URL中的密码，没有密码或无法解析时返回空字符串
+func urlPassword(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return ""
+	}
+	password, _ := u.User.Password()
+	return password
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// 审计记录的操作类型
+const (
+	AuditInsert = "INSERT"
+	AuditUpdate = "UPDATE"
+	AuditDelete = "DELETE"
+)
+
+// auditConfig 审计配置，开启后每次写入asset_sub_domain都会在asset_sub_domain_history中记录一行
+type auditConfig struct {
+	enabled bool
+	runID   string
+}
+
+// withAudit 执行一次写操作；开启审计时放在事务中，保证记录的变更和审计行同时提交
+func withAudit(ctx context.Context, db *sql.DB, audit auditConfig, fn func(exec execer) error) error {
+	if !audit.enabled {
+		return fn(db)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// auditValue 将可能为空的dns_record转换为审计值
+func auditValue(value *string) sql.NullString {
+	if value == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *value, Valid: true}
+}
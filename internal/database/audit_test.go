@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"dns-sync/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// recordUpdater MySQL和PostgreSQL客户端共有的单条更新方法
+type recordUpdater interface {
+	UpdateRecord(ctx context.Context, localID string, aliyunRecord *models.DNSRecord) error
+}
+
+// TestAuditUpdate 开启审计后更新一条记录只写入一行审计，before和after分别为更新前后的记录值，与更新在同一事务中提交
+func TestAuditUpdate(t *testing.T) {
+	tests := []struct {
+		name        string
+		newClient   func(t *testing.T, audit auditConfig) (recordUpdater, sqlmock.Sqlmock)
+		selectQuery string
+	}{
+		{
+			name: "mysql",
+			newClient: func(t *testing.T, audit auditConfig) (recordUpdater, sqlmock.Sqlmock) {
+				client, mock := newMockMySQL(t)
+				client.audit = audit
+				return client, mock
+			},
+			selectQuery: `SELECT dns_record FROM asset_sub_domain WHERE id = \?`,
+		},
+		{
+			name: "postgres",
+			newClient: func(t *testing.T, audit auditConfig) (recordUpdater, sqlmock.Sqlmock) {
+				client, mock := newMockPostgres(t)
+				client.audit = audit
+				return client, mock
+			},
+			selectQuery: `SELECT dns_record FROM asset_sub_domain WHERE id = \$1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mock := tt.newClient(t, auditConfig{enabled: true, runID: "run-1"})
+
+			// sqlmock按顺序匹配，多出的审计语句或缺少的语句都会导致测试失败
+			mock.ExpectBegin()
+			mock.ExpectQuery(tt.selectQuery).
+				WithArgs("id-1").
+				WillReturnRows(sqlmock.NewRows([]string{"dns_record"}).AddRow("10.0.0.1"))
+			mock.ExpectExec(`UPDATE asset_sub_domain\s`).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(`INSERT INTO asset_sub_domain_history \(record_id, action, before_value, after_value, run_id, create_time\)`).
+				WithArgs("id-1", AuditUpdate, "10.0.0.1", "10.0.0.2", "run-1").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+
+			record := &models.DNSRecord{DomainName: "example.com", RR: "www", RecordId: "1000", Type: "A",
+				Value: "10.0.0.2", TTL: 600, Line: "default", Status: "ENABLE"}
+			if err := client.UpdateRecord(context.Background(), "id-1", record); err != nil {
+				t.Fatalf("UpdateRecord() error = %v", err)
+			}
+		})
+	}
+}
+
+// recordInserter MySQL和PostgreSQL客户端共有的单条插入方法
+type recordInserter interface {
+	InsertRecord(ctx context.Context, record *models.AssetSubDomain) error
+}
+
+// TestInsertRecordTwice 重新插入已存在的记录时命中(source, domain_id, aliyun_record_id)唯一索引，更新已有的行，
+// 两次插入得到同一行的ID，只有第一次审计为新增
+func TestInsertRecordTwice(t *testing.T) {
+	tests := []struct {
+		name      string
+		newClient func(t *testing.T, audit auditConfig) (recordInserter, sqlmock.Sqlmock)
+		// expectInsert 设置一次插入的预期，inserted为false表示命中唯一索引，已有行的ID为first-id
+		expectInsert func(mock sqlmock.Sqlmock, inserted bool)
+		// wantFirstID 第一次插入后的ID，为空表示使用生成的ID
+		wantFirstID string
+	}{
+		{
+			name: "mysql",
+			newClient: func(t *testing.T, audit auditConfig) (recordInserter, sqlmock.Sqlmock) {
+				client, mock := newMockMySQL(t)
+				client.audit, client.idGen = audit, &timestampID{}
+				return client, mock
+			},
+			expectInsert: func(mock sqlmock.Sqlmock, inserted bool) {
+				// 新插入时影响1行，更新已有行时为2
+				rows := int64(1)
+				if !inserted {
+					rows = 2
+				}
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO asset_sub_domain (id, ") + `.*` +
+					regexp.QuoteMeta(" ON DUPLICATE KEY UPDATE ")).
+					WillReturnResult(sqlmock.NewResult(0, rows))
+				if !inserted {
+					mock.ExpectQuery(regexp.QuoteMeta(
+						"SELECT id FROM asset_sub_domain WHERE source = ? AND domain_id = ? AND aliyun_record_id = ?")).
+						WithArgs("Aliyun-DNS-Sync", "domain-1", "1000").
+						WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("first-id"))
+				}
+			},
+		},
+		{
+			name: "postgres",
+			newClient: func(t *testing.T, audit auditConfig) (recordInserter, sqlmock.Sqlmock) {
+				client, mock := newMockPostgres(t)
+				client.audit, client.idGen = audit, &timestampID{}
+				return client, mock
+			},
+			expectInsert: func(mock sqlmock.Sqlmock, inserted bool) {
+				mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO asset_sub_domain (id, ") + `.*` +
+					regexp.QuoteMeta(" ON CONFLICT (source, domain_id, aliyun_record_id) DO UPDATE SET ")).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "inserted"}).AddRow("first-id", inserted))
+			},
+			wantFirstID: "first-id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mock := tt.newClient(t, auditConfig{enabled: true, runID: "run-1"})
+
+			mock.ExpectBegin()
+			tt.expectInsert(mock, true)
+			mock.ExpectExec(regexp.QuoteMeta("INSERT INTO asset_sub_domain_history")).
+				WithArgs(sqlmock.AnyArg(), AuditInsert, nil, "10.0.0.0", "run-1").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+			mock.ExpectBegin()
+			tt.expectInsert(mock, false)
+			mock.ExpectExec(regexp.QuoteMeta("INSERT INTO asset_sub_domain_history")).
+				WithArgs("first-id", AuditUpdate, nil, "10.0.0.0", "run-1").
+				WillReturnResult(sqlmock.NewResult(2, 1))
+			mock.ExpectCommit()
+
+			first := testAssets(1)[0]
+			if err := client.InsertRecord(context.Background(), first); err != nil {
+				t.Fatalf("first InsertRecord() error = %v", err)
+			}
+			if first.ID == "" || (tt.wantFirstID != "" && first.ID != tt.wantFirstID) {
+				t.Errorf("first record.ID = %q, want %q", first.ID, tt.wantFirstID)
+			}
+			second := testAssets(1)[0]
+			if err := client.InsertRecord(context.Background(), second); err != nil {
+				t.Fatalf("second InsertRecord() error = %v", err)
+			}
+			if second.ID != "first-id" {
+				t.Errorf("second record.ID = %q, want the existing row first-id", second.ID)
+			}
+		})
+	}
+}
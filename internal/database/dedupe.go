@@ -0,0 +1,31 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// duplicateIDs 从按aliyun_record_id、create_time、id排序的(id, aliyun_record_id)结果中，
+// 找出每组重复记录中除最早一条以外的本地ID
+func duplicateIDs(rows *sql.Rows) ([]string, error) {
+	var ids []string
+	previous := ""
+
+	for rows.Next() {
+		var id, recordID string
+		if err := rows.Scan(&id, &recordID); err != nil {
+			return nil, fmt.Errorf("failed to scan record: %w", err)
+		}
+
+		if recordID == previous {
+			ids = append(ids, id)
+		}
+		previous = recordID
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate records: %w", err)
+	}
+
+	return ids, nil
+}
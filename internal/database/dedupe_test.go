@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// dedupeClient 两种数据库客户端共有的去重方法
+type dedupeClient interface {
+	DedupeLocalRecords(ctx context.Context, domainID, source string) (int, error)
+}
+
+// TestDedupeLocalRecords 查询按创建时间排序，同一aliyun_record_id只保留第一行（最早的一行），其余物理删除
+func TestDedupeLocalRecords(t *testing.T) {
+	tests := []struct {
+		name   string
+		delete string
+	}{
+		{name: "mysql", delete: `DELETE FROM asset_sub_domain WHERE id = \?`},
+		{name: "postgres", delete: `DELETE FROM asset_sub_domain WHERE id = \$1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var client dedupeClient
+			var mock sqlmock.Sqlmock
+			if tt.name == "mysql" {
+				client, mock = newMockMySQL(t)
+			} else {
+				client, mock = newMockPostgres(t)
+			}
+
+			mock.ExpectQuery(`(?s)SELECT id, aliyun_record_id FROM asset_sub_domain.*ORDER BY aliyun_record_id, create_time, id`).
+				WithArgs("domain-1", "Aliyun-DNS-Sync").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "aliyun_record_id"}).
+					AddRow("oldest", "1000").
+					AddRow("newer", "1000").
+					AddRow("newest", "1000").
+					AddRow("only", "1001"))
+			mock.ExpectExec(tt.delete).WithArgs("newer").WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(tt.delete).WithArgs("newest").WillReturnResult(sqlmock.NewResult(0, 1))
+
+			removed, err := client.DedupeLocalRecords(context.Background(), "domain-1", "Aliyun-DNS-Sync")
+			if err != nil {
+				t.Fatalf("DedupeLocalRecords() error = %v", err)
+			}
+			if removed != 2 {
+				t.Errorf("DedupeLocalRecords() removed = %d, want 2", removed)
+			}
+		})
+	}
+}
+
+// TestGetLocalRecordsDuplicates 读取时遇到重复的aliyun_record_id保留最早的一行，不被后面的行覆盖
+func TestGetLocalRecordsDuplicates(t *testing.T) {
+	client, mock := newMockMySQL(t)
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	rows := sqlmock.NewRows([]string{"id", "sub_domain", "type", "dns_record", "aliyun_record_id", "create_time",
+		"update_time", "ttl", "weight", "priority", "line", "content_hash", "status", "rr", "domain_name", "remark",
+		"line_name"}).
+		AddRow("oldest", "www.example.com", "A", "10.0.0.1", "1000", older, older, 600, 0, 0, "default", "hash",
+			"ENABLE", "www", "example.com", "", "默认").
+		AddRow("duplicate", "www.example.com", "A", "10.0.0.2", "1000", newer, newer, 600, 0, 0, "default", "hash",
+			"ENABLE", "www", "example.com", "", "默认")
+	mock.ExpectQuery(`(?s)FROM asset_sub_domain.*ORDER BY create_time, id`).
+		WithArgs("domain-1", "Aliyun-DNS-Sync").WillReturnRows(rows)
+
+	records, err := client.GetLocalRecords(context.Background(), "domain-1", "Aliyun-DNS-Sync")
+	if err != nil {
+		t.Fatalf("GetLocalRecords() error = %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("GetLocalRecords() returned %d records, want 1", len(records))
+	}
+	if got := records["1000"]; got.ID != "oldest" || got.DNSRecord == nil || *got.DNSRecord != "10.0.0.1" {
+		t.Errorf("records[1000] = %+v, want the oldest row", got)
+	}
+}
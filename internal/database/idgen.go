@@ -0,0 +1,65 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 主键生成策略，对应配置中的id_strategy
+const (
+	// IDStrategySnowflake 雪花算法生成的数字ID（默认）
+	IDStrategySnowflake = "snowflake"
+	// IDStrategyTimestamp 毫秒时间戳ID，兼容早期版本写入的ID格式
+	IDStrategyTimestamp = "timestamp"
+	// IDStrategyDBAuto 插入时不写id列，由数据库自增生成后读回
+	IDStrategyDBAuto = "db_auto"
+)
+
+// IDGenerator 生成新记录的主键
+// NextIDString返回空字符串表示主键由数据库生成，插入时省略id列
+type IDGenerator interface {
+	NextIDString() (string, error)
+}
+
+// NewIDGenerator 按策略创建主键生成器，workerID只用于雪花算法
+func NewIDGenerator(strategy string, workerID int64) (IDGenerator, error) {
+	switch strategy {
+	case IDStrategySnowflake, "":
+		return NewSnowflake(workerID)
+	case IDStrategyTimestamp:
+		return &timestampID{}, nil
+	case IDStrategyDBAuto:
+		return dbAutoID{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported id strategy %q", strategy)
+	}
+}
+
+// timestampID 毫秒时间戳ID生成器，同一毫秒内多次生成时顺延1，保证单进程内不重复，并发安全
+type timestampID struct {
+	mu   sync.Mutex
+	last int64
+}
+
+// NextIDString 生成字符串形式的下一个ID
+func (t *timestampID) NextIDString() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id := time.Now().UnixMilli()
+	if id <= t.last {
+		id = t.last + 1
+	}
+	t.last = id
+	return strconv.FormatInt(id, 10), nil
+}
+
+// dbAutoID 由数据库自增生成主键
+type dbAutoID struct{}
+
+// NextIDString 始终返回空字符串
+func (dbAutoID) NextIDString() (string, error) {
+	return "", nil
+}
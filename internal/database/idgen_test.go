@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestNewIDGenerator db_auto策略返回空ID表示由数据库生成，不支持的策略返回错误
+func TestNewIDGenerator(t *testing.T) {
+	tests := []struct {
+		strategy  string
+		wantEmpty bool
+		wantErr   bool
+	}{
+		{strategy: ""},
+		{strategy: IDStrategySnowflake},
+		{strategy: IDStrategyTimestamp},
+		{strategy: IDStrategyDBAuto, wantEmpty: true},
+		{strategy: "uuid", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		gen, err := NewIDGenerator(tt.strategy, 1)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewIDGenerator(%q) error = %v, wantErr %v", tt.strategy, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		id, err := gen.NextIDString()
+		if err != nil {
+			t.Errorf("NextIDString() for %q error = %v", tt.strategy, err)
+		}
+		if (id == "") != tt.wantEmpty {
+			t.Errorf("NextIDString() for %q = %q, want empty = %v", tt.strategy, id, tt.wantEmpty)
+		}
+	}
+}
+
+// autoIDInsertPattern 匹配省略id列的INSERT，占位符个数与autoIDColumns一致
+func autoIDInsertPattern(placeholders []string) string {
+	return regexp.QuoteMeta("INSERT INTO asset_sub_domain (sub_domain, type, ") + `[a-z_, ]+` +
+		regexp.QuoteMeta(") VALUES ("+strings.Join(placeholders, ", ")+")")
+}
+
+// TestMySQLInsertAutoID db_auto策略下插入省略id列，新插入和命中唯一索引时都通过LastInsertId读回ID
+func TestMySQLInsertAutoID(t *testing.T) {
+	placeholders := strings.Split(strings.Repeat("?", len(autoIDColumns)), "")
+
+	tests := []struct {
+		name   string
+		result [2]int64
+		wantID string
+	}{
+		{name: "new row", result: [2]int64{42, 1}, wantID: "42"},
+		// ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)使LastInsertId返回已有行的ID
+		{name: "existing row", result: [2]int64{7, 2}, wantID: "7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mock := newMockMySQL(t)
+			client.idGen = dbAutoID{}
+			mock.ExpectExec(autoIDInsertPattern(placeholders) +
+				regexp.QuoteMeta(" ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), ")).
+				WillReturnResult(sqlmock.NewResult(tt.result[0], tt.result[1]))
+
+			record := testAssets(1)[0]
+			if err := client.insertRecord(context.Background(), client.db, record); err != nil {
+				t.Fatalf("insertRecord() error = %v", err)
+			}
+			if record.ID != tt.wantID {
+				t.Errorf("record.ID = %q, want %q", record.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+// TestMySQLInsertSubDomainsAutoID 批量插入时db_auto的记录逐条INSERT IGNORE，忽略的重复记录没有生成ID
+func TestMySQLInsertSubDomainsAutoID(t *testing.T) {
+	client, mock := newMockMySQL(t)
+	client.idGen = dbAutoID{}
+	placeholders := strings.Split(strings.Repeat("?", len(autoIDColumns)), "")
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexp.QuoteMeta("INSERT IGNORE INTO asset_sub_domain (id, "))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT IGNORE") + " INTO" +
+		strings.TrimPrefix(autoIDInsertPattern(placeholders), "INSERT INTO")).
+		WillReturnResult(sqlmock.NewResult(42, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT IGNORE INTO asset_sub_domain (sub_domain, ")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	records := testAssets(2)
+	if err := client.InsertSubDomains(context.Background(), records); err != nil {
+		t.Fatalf("InsertSubDomains() error = %v", err)
+	}
+	if records[0].ID != "42" || records[1].ID != "" {
+		t.Errorf("record ids = %q, %q, want 42 and empty", records[0].ID, records[1].ID)
+	}
+}
+
+// TestPostgresInsertAutoID db_auto策略下插入省略id列，通过RETURNING读回数据库生成的ID
+func TestPostgresInsertAutoID(t *testing.T) {
+	client, mock := newMockPostgres(t)
+	client.idGen = dbAutoID{}
+
+	mock.ExpectQuery(autoIDInsertPattern(postgresPlaceholders(len(autoIDColumns))) +
+		regexp.QuoteMeta(" ON CONFLICT (source, domain_id, aliyun_record_id) DO UPDATE SET ") + `.*` +
+		regexp.QuoteMeta(" RETURNING id, (xmax = 0)")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "inserted"}).AddRow("43", true))
+
+	record := testAssets(1)[0]
+	if err := client.insertRecord(context.Background(), client.db, record); err != nil {
+		t.Fatalf("insertRecord() error = %v", err)
+	}
+	if record.ID != "43" {
+		t.Errorf("record.ID = %q, want 43", record.ID)
+	}
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrLockHeld 运行锁已被另一个实例持有
+var ErrLockHeld = errors.New("another instance is already running")
+
+// RunLock 数据库会话级的运行锁（MySQL的GET_LOCK、PostgreSQL的advisory lock），在专用连接上持有
+// 进程异常退出或连接断开时数据库自动释放，不会留下需要人工清理的锁
+type RunLock struct {
+	db      *sql.DB
+	conn    *sql.Conn
+	acquire string
+	release string
+	arg     interface{}
+}
+
+// acquireRunLock 从连接池取出一个专用连接并获取锁，锁已被持有时返回ErrLockHeld
+// acquire查询不等待，返回true表示获取成功；release查询释放锁
+func acquireRunLock(ctx context.Context, db *sql.DB, acquire, release string, arg interface{}) (*RunLock, error) {
+	lock := &RunLock{db: db, acquire: acquire, release: release, arg: arg}
+	if err := lock.lock(ctx); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// lock 在新的专用连接上获取锁，失败时归还连接
+func (l *RunLock) lock(ctx context.Context) error {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open lock connection: %w", err)
+	}
+
+	var acquired sql.NullBool
+	if err := conn.QueryRowContext(ctx, l.acquire, l.arg).Scan(&acquired); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to acquire run lock: %w", err)
+	}
+	if !acquired.Bool {
+		conn.Close()
+		return ErrLockHeld
+	}
+	l.conn = conn
+	return nil
+}
+
+// Refresh 确认持有锁的连接仍然可用，常驻模式下每轮同步前调用，同时避免连接因空闲超时被服务端断开
+// 连接已断开时锁已被数据库释放，重新获取；期间被其它实例获取时返回ErrLockHeld
+func (l *RunLock) Refresh(ctx context.Context) error {
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err == nil {
+			return nil
+		}
+		l.conn.Close()
+		l.conn = nil
+	}
+	return l.lock(ctx)
+}
+
+// Release 释放锁并归还连接，重复调用时直接返回
+func (l *RunLock) Release(ctx context.Context) error {
+	if l.conn == nil {
+		return nil
+	}
+	defer func() {
+		l.conn.Close()
+		l.conn = nil
+	}()
+
+	var released sql.NullBool
+	if err := l.conn.QueryRowContext(ctx, l.release, l.arg).Scan(&released); err != nil {
+		return fmt.Errorf("failed to release run lock: %w", err)
+	}
+	return nil
+}
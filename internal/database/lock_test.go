@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestMySQLRunLock 第一个实例持有锁时第二个实例的GET_LOCK返回0，得到ErrLockHeld；释放后可以再次获取
+func TestMySQLRunLock(t *testing.T) {
+	first, firstMock := newMockMySQL(t)
+	second, secondMock := newMockMySQL(t)
+	ctx := context.Background()
+
+	acquire := regexp.QuoteMeta("SELECT GET_LOCK(?, 0)")
+	release := regexp.QuoteMeta("SELECT RELEASE_LOCK(?)")
+	firstMock.ExpectQuery(acquire).WithArgs("dns-sync:test").WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+	secondMock.ExpectQuery(acquire).WithArgs("dns-sync:test").WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(0))
+	firstMock.ExpectQuery(release).WithArgs("dns-sync:test").WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(1))
+	secondMock.ExpectQuery(acquire).WithArgs("dns-sync:test").WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+
+	lock, err := first.AcquireRunLock(ctx, "dns-sync:test")
+	if err != nil {
+		t.Fatalf("first AcquireRunLock() error = %v", err)
+	}
+	if _, err := second.AcquireRunLock(ctx, "dns-sync:test"); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("second AcquireRunLock() error = %v, want ErrLockHeld", err)
+	}
+
+	if err := lock.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	// 重复释放不再执行RELEASE_LOCK
+	if err := lock.Release(ctx); err != nil {
+		t.Fatalf("second Release() error = %v", err)
+	}
+	if _, err := second.AcquireRunLock(ctx, "dns-sync:test"); err != nil {
+		t.Fatalf("AcquireRunLock() after release error = %v", err)
+	}
+}
+
+// TestPostgresRunLock pg_try_advisory_lock返回false时得到ErrLockHeld
+func TestPostgresRunLock(t *testing.T) {
+	client, mock := newMockPostgres(t)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_lock($1)")).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(false))
+
+	if _, err := client.AcquireRunLock(context.Background(), "dns-sync:test"); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("AcquireRunLock() error = %v, want ErrLockHeld", err)
+	}
+}
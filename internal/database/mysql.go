@@ -1,10 +1,16 @@
 package database
 
 import (
+	"context"
+	"crypto/sha1"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -13,89 +19,239 @@ import (
 )
 
 // MySQLClient MySQL客户端
+//
+// ttl、weight、priority、line四列为后续新增，已有表需要先执行迁移：
+//
+//	ALTER TABLE asset_sub_domain
+//	  ADD COLUMN `ttl` int DEFAULT NULL COMMENT 'TTL',
+//	  ADD COLUMN `weight` int DEFAULT NULL COMMENT '权重',
+//	  ADD COLUMN `priority` int DEFAULT NULL COMMENT 'MX优先级',
+//	  ADD COLUMN `line` varchar(50) DEFAULT NULL COMMENT '解析线路';
+//
+// content_hash用于判断记录是否变化，为空的旧数据会在下次同步时补齐；
+// status记录ACTIVE、DISABLED（服务商上已停用）或DELETED（软删除）：
+//
+//	ALTER TABLE asset_sub_domain
+//	  ADD COLUMN `content_hash` char(40) DEFAULT NULL COMMENT '记录内容哈希',
+//	  ADD COLUMN `status` varchar(20) DEFAULT NULL COMMENT '记录状态';
+//
+// rr和domain_name拆分保存主机记录和主域名，为空的旧数据会在下次同步时补齐：
+//
+//	ALTER TABLE asset_sub_domain
+//	  ADD COLUMN `rr` varchar(255) DEFAULT NULL COMMENT '主机记录',
+//	  ADD COLUMN `domain_name` varchar(255) DEFAULT NULL COMMENT '主域名';
+//
+// remark保存服务商上的记录备注：
+//
+//	ALTER TABLE asset_sub_domain
+//	  ADD COLUMN `remark` varchar(500) DEFAULT NULL COMMENT '记录备注';
+//
+// line_name保存线路代码对应的名称，为空的旧数据会在下次同步时补齐：
+//
+//	ALTER TABLE asset_sub_domain
+//	  ADD COLUMN `line_name` varchar(100) DEFAULT NULL COMMENT '解析线路名称';
+//
+// raw_record保存服务商返回的完整记录，供排查问题时查询，新增和更新记录时写入：
+//
+//	ALTER TABLE asset_sub_domain
+//	  ADD COLUMN `raw_record` json DEFAULT NULL COMMENT '服务商原始记录';
+//
+// 开启sync.resolve_check时需要resolution_status列，未开启时不读写该列：
+//
+//	ALTER TABLE asset_sub_domain
+//	  ADD COLUMN `resolution_status` varchar(20) DEFAULT NULL COMMENT '解析检查结果';
+//
+// 开启软删除时还需要deleted_at列：
+//
+//	ALTER TABLE asset_sub_domain
+//	  ADD COLUMN `deleted_at` datetime DEFAULT NULL COMMENT '删除时间';
 type MySQLClient struct {
-	db *sql.DB
+	db         *sql.DB
+	idGen      IDGenerator
+	softDelete bool
+	audit      auditConfig
+	// maxRetries 写操作遇到临时性错误时的最大重试次数
+	maxRetries int
+	// replica 只读副本连接，未配置时为nil，读操作使用db
+	replica *sql.DB
+	// table 记录表名，默认asset_sub_domain，加载配置时已校验为合法标识符
+	table string
+	// queryTimeout 单次数据库调用的超时时间，为0时不限制
+	queryTimeout time.Duration
 }
 
-// NewMySQLClient 创建MySQL客户端
+// NewMySQLClient 创建MySQL客户端，配置了mysql.replica时同时连接只读副本
 func NewMySQLClient(cfg *config.MySQLConfig) (*MySQLClient, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.Database)
+	idGen, err := NewIDGenerator(cfg.IDStrategy, cfg.WorkerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create id generator: %w", err)
+	}
 
-	db, err := sql.Open("mysql", dsn)
+	db, err := openMySQL(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &MySQLClient{
+		db:           db,
+		idGen:        idGen,
+		maxRetries:   max(cfg.MaxRetries, 0),
+		table:        cfg.Table,
+		queryTimeout: cfg.QueryTimeout,
+	}
+
+	if replicaCfg := cfg.ReplicaConfig(); replicaCfg != nil {
+		if client.replica, err = openMySQL(replicaCfg); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("replica: %w", err)
+		}
+		slog.Info("Using MySQL read replica", "host", replicaCfg.Host, "port", replicaCfg.Port)
+	}
+
+	return client, nil
+}
+
+// openMySQL 按配置打开连接池并测试连接
+func openMySQL(cfg *config.MySQLConfig) (*sql.DB, error) {
+	// 需要自定义TLS配置时须在打开连接前注册
+	if err := registerMySQLTLS(cfg); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("mysql", cfg.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// 设置连接池参数
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(10)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	configurePool(db, cfg.MaxOpenConns, cfg.MaxIdleConns, cfg.ConnMaxLifetime)
 
 	// 测试连接
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &MySQLClient{
-		db: db,
-	}, nil
+	return db, nil
+}
+
+// historyTable 审计历史表名，为记录表名加_history后缀
+func (c *MySQLClient) historyTable() string {
+	return c.table + "_history"
+}
+
+// readDB 获取只读查询使用的连接：配置了只读副本且上下文未要求主库时使用副本
+func (c *MySQLClient) readDB(ctx context.Context) *sql.DB {
+	if c.replica == nil || usePrimary(ctx) {
+		return c.db
+	}
+	return c.replica
+}
+
+// SetSoftDelete 设置是否使用软删除
+// 软删除只将status置为DELETED并记录deleted_at，保留人工维护的资产信息
+func (c *MySQLClient) SetSoftDelete(enabled bool) {
+	c.softDelete = enabled
+}
+
+// SetAudit 设置是否记录审计历史，runID标识本次运行
+func (c *MySQLClient) SetAudit(enabled bool, runID string) {
+	c.audit = auditConfig{enabled: enabled, runID: runID}
 }
 
 // Close 关闭数据库连接
 func (c *MySQLClient) Close() error {
+	if c.replica != nil {
+		c.replica.Close()
+	}
 	return c.db.Close()
 }
 
 // TestConnection 测试数据库连接
-func (c *MySQLClient) TestConnection() error {
-	return c.db.Ping()
+func (c *MySQLClient) TestConnection(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
+// Prewarm 预先建立主库连接，配置了只读副本时同样预先建立副本的连接
+func (c *MySQLClient) Prewarm(ctx context.Context, conns int) error {
+	if err := prewarm(ctx, c.db, conns); err != nil {
+		return err
+	}
+	if c.replica != nil {
+		if err := prewarm(ctx, c.replica, conns); err != nil {
+			return fmt.Errorf("replica: %w", err)
+		}
+	}
+	return nil
+}
+
+// maxLockNameLength GET_LOCK锁名的最大长度
+const maxLockNameLength = 64
+
+// AcquireRunLock 使用GET_LOCK获取运行锁，锁名在整个MySQL服务器范围内有效，超出64个字符时使用其sha1
+func (c *MySQLClient) AcquireRunLock(ctx context.Context, name string) (*RunLock, error) {
+	if len(name) > maxLockNameLength {
+		sum := sha1.Sum([]byte(name))
+		name = "dns-sync:" + hex.EncodeToString(sum[:])
+	}
+	return acquireRunLock(ctx, c.db, `SELECT GET_LOCK(?, 0)`, `SELECT RELEASE_LOCK(?)`, name)
 }
 
 // GetNextID 获取下一个ID
+// 默认使用雪花算法生成，同一毫秒内批量插入也不会产生重复ID；id_strategy为db_auto时返回空字符串
 func (c *MySQLClient) GetNextID() (string, error) {
-	// 这里使用一个简单的方法生成ID，实际使用中可能需要更复杂的ID生成策略
-	// 比如雪花算法等
-	timestamp := time.Now().UnixNano() / int64(time.Millisecond)
-	return strconv.FormatInt(timestamp, 10), nil
+	return c.idGen.NextIDString()
 }
 
-// ClearDomainRecords 清除指定域名的现有记录（可选功能）
-func (c *MySQLClient) ClearDomainRecords(domainID string) error {
-	query := `DELETE FROM asset_sub_domain WHERE domain_id = ? AND source = 'Aliyun-DNS-Sync'`
+// RebuildDomainTx 在单个事务中清除域名下source的全部记录并重新插入，见rebuildDomainTx
+func (c *MySQLClient) RebuildDomainTx(ctx context.Context, domainID, source string,
+	records []*models.AssetSubDomain) (*SyncResult, error) {
+	return rebuildDomainTx(ctx, c.db, c, domainID, source, records)
+}
+
+// clearDomainRecords 物理删除指定域名下source的全部记录，返回删除的行数
+// 开启审计时逐条删除以便记录每一行的DELETE
+func (c *MySQLClient) clearDomainRecords(ctx context.Context, exec execer, domainID, source string) (int, error) {
+	if c.audit.enabled {
+		return clearRecordsByID(ctx, exec, `SELECT id FROM `+c.table+` WHERE domain_id = ? AND source = ?`,
+			[]interface{}{domainID, source}, c.purgeRecord)
+	}
+
+	query := `DELETE FROM ` + c.table + ` WHERE domain_id = ? AND source = ?`
 	
-	result, err := c.db.Exec(query, domainID)
+	var rowsAffected int64
+	err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		result, err := exec.ExecContext(ctx, query, domainID, source)
+		if err != nil {
+			return err
+		}
+		rowsAffected, _ = result.RowsAffected()
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to clear domain records: %w", err)
+		return 0, err
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	log.Printf("Cleared %d existing records for domain_id: %s", rowsAffected, domainID)
-	
-	return nil
+	return int(rowsAffected), nil
 }
 
 // InsertSubDomains 批量插入子域名记录
-func (c *MySQLClient) InsertSubDomains(records []*models.AssetSubDomain) error {
+func (c *MySQLClient) InsertSubDomains(ctx context.Context, records []*models.AssetSubDomain) error {
 	if len(records) == 0 {
 		return nil
 	}
 
 	// 开启事务
-	tx, err := c.db.Begin()
+	tx, err := c.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
 	// 准备批量插入语句，使用INSERT IGNORE忽略重复记录
-	query := `INSERT IGNORE INTO asset_sub_domain 
-		(id, sub_domain, type, create_time, update_by, create_by, update_time, 
-		 sys_org_code, dns_record, name_server, asset_label, asset_manager, 
-		 asset_department, level, domain_id, source, project_id, aliyun_record_id) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	query := "INSERT IGNORE INTO " + c.table + " (" + strings.Join(recordColumns, ", ") + ") VALUES (" +
+		strings.TrimSuffix(strings.Repeat("?, ", len(recordColumns)), ", ") + ")"
 
-	stmt, err := tx.Prepare(query)
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
@@ -106,35 +262,20 @@ func (c *MySQLClient) InsertSubDomains(records []*models.AssetSubDomain) error {
 		// 生成ID
 		id, err := c.GetNextID()
 		if err != nil {
-			log.Printf("Failed to generate ID for record %s: %v", record.SubDomain, err)
+			slog.Error("Failed to generate ID", "sub_domain", record.SubDomain, "error", err)
 			continue
 		}
 		record.ID = id
 
-		// 执行插入
-		_, err = stmt.Exec(
-			record.ID,
-			record.SubDomain,
-			record.Type,
-			record.CreateTime,
-			record.UpdateBy,
-			record.CreateBy,
-			record.UpdateTime,
-			record.SysOrgCode,
-			record.DNSRecord,
-			record.NameServer,
-			record.AssetLabel,
-			record.AssetManager,
-			record.AssetDepartment,
-			record.Level,
-			record.DomainID,
-			record.Source,
-			record.ProjectID,
-			record.AliyunRecordID,
-		)
+		// 执行插入，主键由数据库生成时不写id列并读回生成的ID
+		if record.ID == "" {
+			err = c.insertAutoID(ctx, tx, "INSERT IGNORE", record)
+		} else {
+			_, err = stmt.ExecContext(ctx, recordValues(record)...)
+		}
 
 		if err != nil {
-			log.Printf("Failed to insert record %s: %v", record.SubDomain, err)
+			slog.Error("Failed to insert record", "action", "insert", "sub_domain", record.SubDomain, "error", err)
 			continue
 		}
 		
@@ -146,46 +287,98 @@ func (c *MySQLClient) InsertSubDomains(records []*models.AssetSubDomain) error {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	log.Printf("Successfully inserted %d/%d records", successCount, len(records))
+	slog.Info("Inserted records", "count", successCount, "total", len(records))
 	return nil
 }
 
+// InitSchema 创建asset_sub_domain及审计历史表，已存在时不做修改
+func (c *MySQLClient) InitSchema(ctx context.Context) error {
+	// 内置语句使用默认表名，按配置替换记录表和审计历史表的表名
+	schema := strings.ReplaceAll(mysqlSchema, "`asset_sub_domain", "`"+c.table)
+	return initSchema(ctx, c.db, schema)
+}
+
 // CheckTableExists 检查表是否存在
-func (c *MySQLClient) CheckTableExists() error {
-	query := `SELECT COUNT(*) FROM information_schema.tables 
-			  WHERE table_schema = DATABASE() AND table_name = 'asset_sub_domain'`
+func (c *MySQLClient) CheckTableExists(ctx context.Context) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		query := `SELECT COUNT(*) FROM information_schema.tables 
+				  WHERE table_schema = DATABASE() AND table_name = ?`
 	
-	var count int
-	err := c.db.QueryRow(query).Scan(&count)
-	if err != nil {
-		return fmt.Errorf("failed to check table existence: %w", err)
-	}
+		var count int
+		err := c.db.QueryRowContext(ctx, query, c.table).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to check table existence: %w", err)
+		}
 
-	if count == 0 {
-		return fmt.Errorf("table 'asset_sub_domain' does not exist")
-	}
+		if count == 0 {
+			return fmt.Errorf("table '%s' does not exist", c.table)
+		}
 
-	return nil
+		return nil
+	})
+}
+
+// CheckColumns 检查表中是否存在同步会写入的全部列
+func (c *MySQLClient) CheckColumns(ctx context.Context) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		query := `SELECT column_name FROM information_schema.columns
+				  WHERE table_schema = DATABASE() AND table_name = ?`
+
+		return checkColumns(ctx, c.db, c.table, requiredColumns(c.softDelete), query, c.table)
+	})
 }
 
 // GetLocalRecords 获取数据库中指定域名的所有记录
-func (c *MySQLClient) GetLocalRecords(domainID string) (map[string]*models.AssetSubDomain, error) {
-	query := `SELECT id, sub_domain, type, dns_record, aliyun_record_id, create_time, update_time
-			  FROM asset_sub_domain 
-			  WHERE domain_id = ? AND source = 'Aliyun-DNS-Sync' AND aliyun_record_id IS NOT NULL`
-	
-	rows, err := c.db.Query(query, domainID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query local records: %w", err)
+// 软删除模式下不包含已软删除的记录
+func (c *MySQLClient) GetLocalRecords(ctx context.Context, domainID, source string) (map[string]*models.AssetSubDomain, error) {
+	condition := ""
+	if c.softDelete {
+		condition = " AND (status IS NULL OR status <> 'DELETED')"
 	}
-	defer rows.Close()
+	return c.queryLocalRecords(ctx, domainID, source, condition)
+}
 
+// GetSoftDeletedRecords 获取指定域名已软删除的记录，用于记录在阿里云重新出现时恢复
+func (c *MySQLClient) GetSoftDeletedRecords(ctx context.Context, domainID, source string) (map[string]*models.AssetSubDomain, error) {
+	if !c.softDelete {
+		return map[string]*models.AssetSubDomain{}, nil
+	}
+	return c.queryLocalRecords(ctx, domainID, source, " AND status = 'DELETED'")
+}
+
+// queryLocalRecords 按附加条件查询本地记录，以阿里云记录ID为键
+func (c *MySQLClient) queryLocalRecords(ctx context.Context, domainID, source, condition string) (map[string]*models.AssetSubDomain, error) {
+	query := `SELECT id, sub_domain, type, dns_record, aliyun_record_id, create_time, update_time,
+			  ttl, weight, priority, line, content_hash, status, rr, domain_name, remark, line_name
+			  FROM ` + c.table + ` 
+			  WHERE domain_id = ? AND source = ? AND aliyun_record_id IS NOT NULL` + condition +
+		` ORDER BY create_time, id`
+	
+	var records map[string]*models.AssetSubDomain
+	err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		rows, err := c.readDB(ctx).QueryContext(ctx, query, domainID, source)
+		if err != nil {
+			return fmt.Errorf("failed to query local records: %w", err)
+		}
+		defer rows.Close()
+
+		records, err = scanLocalRecords(rows, domainID)
+		return err
+	})
+	return records, err
+}
+
+// scanLocalRecords 读取本地记录查询结果，以阿里云记录ID为键
+func scanLocalRecords(rows *sql.Rows, domainID string) (map[string]*models.AssetSubDomain, error) {
 	localRecords := make(map[string]*models.AssetSubDomain)
+	duplicates := make(map[string][]string)
 	
 	for rows.Next() {
 		record := &models.AssetSubDomain{}
 		var aliyunRecordID sql.NullString
 		var dnsRecord sql.NullString
+		var ttl, weight, priority sql.NullInt32
+		var line, contentHash, status, rr, domainName, remark, lineName sql.NullString
 		
 		err := rows.Scan(
 			&record.ID,
@@ -195,130 +388,717 @@ func (c *MySQLClient) GetLocalRecords(domainID string) (map[string]*models.Asset
 			&aliyunRecordID,
 			&record.CreateTime,
 			&record.UpdateTime,
+			&ttl,
+			&weight,
+			&priority,
+			&line,
+			&contentHash,
+			&status,
+			&rr,
+			&domainName,
+			&remark,
+			&lineName,
 		)
 		if err != nil {
-			log.Printf("Failed to scan record: %v", err)
+			slog.Warn("Failed to scan record", "domain_id", domainID, "error", err)
 			continue
 		}
+
+		// 旧数据这几列可能为NULL，按零值处理，下次同步时会被补齐
+		record.TTL = ttl.Int32
+		record.Weight = weight.Int32
+		record.Priority = priority.Int32
+		record.Line = line.String
+		record.ContentHash = contentHash.String
+		record.Status = status.String
+		record.RR = rr.String
+		record.DomainName = domainName.String
+		record.Remark = remark.String
+		record.LineName = lineName.String
 		
 		if aliyunRecordID.Valid {
 			record.AliyunRecordID = &aliyunRecordID.String
 			if dnsRecord.Valid {
 				record.DNSRecord = &dnsRecord.String
 			}
+			// 同一RecordId有多行时保留最早的一行，其余记录下来告警
+			if existing, exists := localRecords[aliyunRecordID.String]; exists {
+				if len(duplicates[aliyunRecordID.String]) == 0 {
+					duplicates[aliyunRecordID.String] = []string{existing.ID}
+				}
+				duplicates[aliyunRecordID.String] = append(duplicates[aliyunRecordID.String], record.ID)
+				continue
+			}
 			localRecords[aliyunRecordID.String] = record
 		}
 	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate local records: %w", err)
+	}
+
+	for recordID, ids := range duplicates {
+		slog.Warn("Duplicate aliyun_record_id in local records, run with --dedupe to remove",
+			"domain_id", domainID, "record_id", recordID, "ids", strings.Join(ids, ","))
+	}
 	
 	return localRecords, nil
 }
 
-// InsertRecord 插入单条记录
-func (c *MySQLClient) InsertRecord(record *models.AssetSubDomain) error {
-	// 生成ID
-	id, err := c.GetNextID()
+// InsertRecord 插入单条记录，临时性错误会自动重试
+func (c *MySQLClient) InsertRecord(ctx context.Context, record *models.AssetSubDomain) error {
+	return withRetry(ctx, c.db, c.maxRetries, func() error {
+		return withAudit(ctx, c.db, c.audit, func(exec execer) error {
+			return c.insertRecord(ctx, exec, record)
+		})
+	})
+}
+
+// insertRecord 使用指定的执行对象插入单条记录
+// 按(source, domain_id, aliyun_record_id)的唯一索引uk_aliyun_record_id upsert：上次运行插入后异常退出时，重新插入同一条记录只会更新已有的行，
+// 此时record.ID改为已有行的ID，审计记为更新
+func (c *MySQLClient) insertRecord(ctx context.Context, exec execer, record *models.AssetSubDomain) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		// 生成ID
+		id, err := c.GetNextID()
+		if err != nil {
+			return fmt.Errorf("failed to generate ID: %w", err)
+		}
+		record.ID = id
+
+		var inserted bool
+		if record.ID == "" {
+			inserted, err = c.upsertAutoID(ctx, exec, record)
+		} else {
+			inserted, err = c.upsertRecord(ctx, exec, record)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to insert record: %w", err)
+		}
+
+		if c.audit.enabled {
+			action := AuditInsert
+			if !inserted {
+				action = AuditUpdate
+			}
+			return c.writeAudit(ctx, exec, record.ID, action, sql.NullString{}, auditValue(record.DNSRecord))
+		}
+
+		return nil
+	})
+}
+
+// upsertRecord 插入带ID的记录，同一来源和域名下aliyun_record_id已存在时更新已有行并读回其ID，返回是否插入了新行
+func (c *MySQLClient) upsertRecord(ctx context.Context, exec execer, record *models.AssetSubDomain) (bool, error) {
+	query := "INSERT INTO " + c.table + " (" + strings.Join(recordColumns, ", ") + ") VALUES (" +
+		strings.TrimSuffix(strings.Repeat("?, ", len(recordColumns)), ", ") + ") ON DUPLICATE KEY UPDATE " +
+		c.upsertAssignments()
+
+	result, err := exec.ExecContext(ctx, query, recordValues(record)...)
 	if err != nil {
-		return fmt.Errorf("failed to generate ID: %w", err)
-	}
-	record.ID = id
-
-	query := `INSERT INTO asset_sub_domain 
-		(id, sub_domain, type, create_time, update_by, create_by, update_time, 
-		 sys_org_code, dns_record, name_server, asset_label, asset_manager, 
-		 asset_department, level, domain_id, source, project_id, aliyun_record_id) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-
-	_, err = c.db.Exec(
-		query,
-		record.ID,
-		record.SubDomain,
-		record.Type,
-		record.CreateTime,
-		record.UpdateBy,
-		record.CreateBy,
-		record.UpdateTime,
-		record.SysOrgCode,
-		record.DNSRecord,
-		record.NameServer,
-		record.AssetLabel,
-		record.AssetManager,
-		record.AssetDepartment,
-		record.Level,
-		record.DomainID,
-		record.Source,
-		record.ProjectID,
-		record.AliyunRecordID,
-	)
+		return false, err
+	}
+	// 新插入时影响1行，更新已有行时为2，已有行内容相同时为0
+	if rows, _ := result.RowsAffected(); rows == 1 {
+		return true, nil
+	}
 
+	// 按与uk_aliyun_record_id相同的范围读回，其它来源或域名下相同RecordId的行不会被匹配
+	query = "SELECT id FROM " + c.table + " WHERE source = ? AND domain_id = ? AND aliyun_record_id = ?"
+	err = exec.QueryRowContext(ctx, query, record.Source, record.DomainID, record.AliyunRecordID).Scan(&record.ID)
 	if err != nil {
-		return fmt.Errorf("failed to insert record: %w", err)
+		return false, fmt.Errorf("failed to read existing record id: %w", err)
 	}
+	return false, nil
+}
 
-	return nil
+// upsertAutoID 插入主键由数据库生成的记录，同一来源和域名下aliyun_record_id已存在时更新已有行，
+// 通过LAST_INSERT_ID(id)读回已有行的ID；返回是否插入了新行
+func (c *MySQLClient) upsertAutoID(ctx context.Context, exec execer, record *models.AssetSubDomain) (bool, error) {
+	query := "INSERT INTO " + c.table + " (" + strings.Join(autoIDColumns, ", ") + ") VALUES (" +
+		strings.TrimSuffix(strings.Repeat("?, ", len(autoIDColumns)), ", ") + ") ON DUPLICATE KEY UPDATE " +
+		"id = LAST_INSERT_ID(id), " + c.upsertAssignments()
+
+	result, err := exec.ExecContext(ctx, query, autoIDValues(record)...)
+	if err != nil {
+		return false, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, fmt.Errorf("failed to read generated id: %w", err)
+	}
+	record.ID = strconv.FormatInt(id, 10)
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
 }
 
-// UpdateRecord 更新记录
-func (c *MySQLClient) UpdateRecord(localID string, aliyunRecord *models.DNSRecord) error {
-	// 组合子域名
-	subDomain := aliyunRecord.DomainName
-	if aliyunRecord.RR != "" && aliyunRecord.RR != "@" {
-		subDomain = aliyunRecord.RR + "." + aliyunRecord.DomainName
+// upsertAssignments 记录已存在时ON DUPLICATE KEY UPDATE的赋值，只覆盖upsertUpdateColumns，软删除模式下同时恢复记录
+func (c *MySQLClient) upsertAssignments() string {
+	updates := make([]string, 0, len(upsertUpdateColumns)+1)
+	for _, column := range upsertUpdateColumns {
+		updates = append(updates, column+" = VALUES("+column+")")
+	}
+	if c.softDelete {
+		updates = append(updates, "deleted_at = NULL")
 	}
+	return strings.Join(updates, ", ")
+}
 
-	query := `UPDATE asset_sub_domain 
-			  SET sub_domain = ?, type = ?, dns_record = ?, update_time = NOW() 
-			  WHERE id = ?`
+// insertAutoID 插入记录时省略id列，由数据库自增生成主键并写回record.ID
+// verb为INSERT或INSERT IGNORE；INSERT IGNORE忽略了重复记录时没有生成主键，record.ID保持为空
+func (c *MySQLClient) insertAutoID(ctx context.Context, exec execer, verb string, record *models.AssetSubDomain) error {
+	query := verb + " INTO " + c.table + " (" + strings.Join(autoIDColumns, ", ") + ") VALUES (" +
+		strings.TrimSuffix(strings.Repeat("?, ", len(autoIDColumns)), ", ") + ")"
 
-	_, err := c.db.Exec(query, subDomain, aliyunRecord.Type, aliyunRecord.Value, localID)
+	result, err := exec.ExecContext(ctx, query, autoIDValues(record)...)
 	if err != nil {
-		return fmt.Errorf("failed to update record: %w", err)
+		return err
 	}
 
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read generated id: %w", err)
+	}
+	record.ID = strconv.FormatInt(id, 10)
 	return nil
 }
 
-// DeleteRecord 删除记录
-func (c *MySQLClient) DeleteRecord(localID string) error {
-	query := `DELETE FROM asset_sub_domain WHERE id = ?`
-	
-	_, err := c.db.Exec(query, localID)
+// UpdateRecord 更新记录，临时性错误会自动重试
+func (c *MySQLClient) UpdateRecord(ctx context.Context, localID string, aliyunRecord *models.DNSRecord) error {
+	return withRetry(ctx, c.db, c.maxRetries, func() error {
+		return withAudit(ctx, c.db, c.audit, func(exec execer) error {
+			return c.updateRecord(ctx, exec, localID, aliyunRecord)
+		})
+	})
+}
+
+// updateRecord 使用指定的执行对象更新记录
+func (c *MySQLClient) updateRecord(ctx context.Context, exec execer, localID string, aliyunRecord *models.DNSRecord) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		subDomain := aliyunRecord.FullDomain()
+
+		// status随服务商状态更新，软删除模式下同时清除删除时间，使重新出现的记录恢复
+		restore := ""
+		if c.softDelete {
+			restore = "deleted_at = NULL, "
+		}
+
+		query := `UPDATE ` + c.table + ` 
+				  SET sub_domain = ?, type = ?, dns_record = ?, ttl = ?, weight = ?, priority = ?, line = ?,
+				  content_hash = ?, status = ?, aliyun_record_id = ?, rr = ?, domain_name = ?, remark = ?, line_name = ?, raw_record = ?, ` + restore + `update_time = ? 
+				  WHERE id = ?`
+
+		var before sql.NullString
+		if c.audit.enabled {
+			var err error
+			if before, err = c.currentValue(ctx, exec, localID); err != nil {
+				return err
+			}
+		}
+
+		_, err := exec.ExecContext(ctx, query, subDomain, aliyunRecord.RecordType(), aliyunRecord.RecordValue(),
+			aliyunRecord.TTL, aliyunRecord.RecordWeight(), aliyunRecord.Priority, aliyunRecord.Line, aliyunRecord.ContentHash(),
+			aliyunRecord.AssetStatus(), aliyunRecord.RecordId, aliyunRecord.HostRecord(), aliyunRecord.ZoneName(),
+			aliyunRecord.Remark, aliyunRecord.LineName, aliyunRecord.RawJSON(), aliyunRecord.UpdateTime(), localID)
+		if err != nil {
+			return fmt.Errorf("failed to update record: %w", err)
+		}
+
+		if c.audit.enabled {
+			return c.writeAudit(ctx, exec, localID, AuditUpdate, before,
+				sql.NullString{String: aliyunRecord.RecordValue(), Valid: true})
+		}
+
+		return nil
+	})
+}
+
+// DeleteRecord 删除记录，临时性错误会自动重试
+func (c *MySQLClient) DeleteRecord(ctx context.Context, localID string) error {
+	return withRetry(ctx, c.db, c.maxRetries, func() error {
+		return withAudit(ctx, c.db, c.audit, func(exec execer) error {
+			return c.deleteRecord(ctx, exec, localID)
+		})
+	})
+}
+
+// deleteRecord 使用指定的执行对象删除记录
+func (c *MySQLClient) deleteRecord(ctx context.Context, exec execer, localID string) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		query := `DELETE FROM ` + c.table + ` WHERE id = ?`
+		if c.softDelete {
+			query = `UPDATE ` + c.table + ` SET status = 'DELETED', deleted_at = NOW(), update_time = NOW() WHERE id = ?`
+		}
+
+		var before sql.NullString
+		if c.audit.enabled {
+			var err error
+			if before, err = c.currentValue(ctx, exec, localID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := exec.ExecContext(ctx, query, localID); err != nil {
+			return fmt.Errorf("failed to delete record: %w", err)
+		}
+
+		if c.audit.enabled {
+			return c.writeAudit(ctx, exec, localID, AuditDelete, before, sql.NullString{})
+		}
+
+		return nil
+	})
+}
+
+// DeleteRecords 按batchSize分批删除记录，批次失败时逐条重试，返回删除失败的记录及其错误，见deleteRecords
+func (c *MySQLClient) DeleteRecords(ctx context.Context, ids []string, batchSize int) map[string]error {
+	return deleteRecords(ids, batchSize, func(chunk []string) error {
+		return withRetry(ctx, c.db, c.maxRetries, func() error {
+			return withAudit(ctx, c.db, c.audit, func(exec execer) error {
+				return c.deleteChunk(ctx, exec, chunk)
+			})
+		})
+	}, func(id string) error {
+		return c.DeleteRecord(ctx, id)
+	})
+}
+
+// deleteChunk 使用一条DELETE ... WHERE id IN (...)删除一批记录，软删除模式下改为批量标记
+func (c *MySQLClient) deleteChunk(ctx context.Context, exec execer, ids []string) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		placeholders, args := inPlaceholders(ids)
+		query := `DELETE FROM ` + c.table + ` WHERE id IN (` + placeholders + `)`
+		if c.softDelete {
+			query = `UPDATE ` + c.table + ` SET status = 'DELETED', deleted_at = NOW(), update_time = NOW() WHERE id IN (` +
+				placeholders + `)`
+		}
+
+		var before map[string]sql.NullString
+		if c.audit.enabled {
+			var err error
+			if before, err = c.currentValues(ctx, exec, ids); err != nil {
+				return err
+			}
+		}
+
+		if _, err := exec.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to delete records: %w", err)
+		}
+
+		if c.audit.enabled {
+			for _, id := range ids {
+				if err := c.writeAudit(ctx, exec, id, AuditDelete, before[id], sql.NullString{}); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// inPlaceholders 生成IN子句的?占位符和对应的参数
+func inPlaceholders(ids []string) (string, []interface{}) {
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "), args
+}
+
+// DedupeLocalRecords 删除同一aliyun_record_id的重复行，只保留创建时间最早的一行，返回删除的行数
+// 重复行通常来自中途失败的同步，直接物理删除而不是软删除
+func (c *MySQLClient) DedupeLocalRecords(ctx context.Context, domainID, source string) (int, error) {
+	query := `SELECT id, aliyun_record_id FROM ` + c.table + `
+			  WHERE domain_id = ? AND source = ? AND aliyun_record_id IS NOT NULL
+			  ORDER BY aliyun_record_id, create_time, id`
+
+	var ids []string
+	err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		rows, err := c.db.QueryContext(ctx, query, domainID, source)
+		if err != nil {
+			return fmt.Errorf("failed to query local records: %w", err)
+		}
+		defer rows.Close()
+
+		ids, err = duplicateIDs(rows)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, id := range ids {
+		err := withAudit(ctx, c.db, c.audit, func(exec execer) error {
+			return c.purgeRecord(ctx, exec, id)
+		})
+		if err != nil {
+			return removed, fmt.Errorf("failed to remove duplicate record %s: %w", id, err)
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// purgeRecord 物理删除单条记录，开启审计时记录DELETE
+func (c *MySQLClient) purgeRecord(ctx context.Context, exec execer, localID string) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		var before sql.NullString
+		if c.audit.enabled {
+			var err error
+			if before, err = c.currentValue(ctx, exec, localID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := exec.ExecContext(ctx, `DELETE FROM `+c.table+` WHERE id = ?`, localID); err != nil {
+			return err
+		}
+
+		if c.audit.enabled {
+			return c.writeAudit(ctx, exec, localID, AuditDelete, before, sql.NullString{})
+		}
+
+		return nil
+	})
+}
+
+// BatchUpsert 使用多行INSERT ... ON DUPLICATE KEY UPDATE分批写入记录
+// 需要(source, domain_id, aliyun_record_id)上的唯一索引uk_aliyun_record_id；已有行按该索引冲突更新，
+// 不会改写aliyun_record_id，重新关联等RecordId变化的更新需要使用UpdateRecord。
+// 每批单独提交，遇到死锁或锁等待超时时按max_retries重试该批，出错时返回已成功写入的记录数
+func (c *MySQLClient) BatchUpsert(ctx context.Context, records []*models.AssetSubDomain, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	for _, record := range records {
+		if record.ID == "" {
+			id, err := c.GetNextID()
+			if err != nil {
+				return 0, fmt.Errorf("failed to generate ID: %w", err)
+			}
+			record.ID = id
+		}
+	}
+
+	written := 0
+	for start := 0; start < len(records); start += batchSize {
+		end := start + batchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		chunk := records[start:end]
+
+		// 每批在自己的事务中执行且ID已在上面分配，重试整批不会产生重复行
+		err := withRetry(ctx, c.db, c.maxRetries, func() error {
+			return withAudit(ctx, c.db, c.audit, func(exec execer) error {
+				return c.upsertChunk(ctx, exec, chunk)
+			})
+		})
+		if err != nil {
+			return written, fmt.Errorf("failed to upsert records %d-%d: %w", start, end, err)
+		}
+		written += len(chunk)
+	}
+
+	return written, nil
+}
+
+// buildUpsertQuery 构建一批记录的多行upsert语句
+func (c *MySQLClient) buildUpsertQuery(records []*models.AssetSubDomain) (string, []interface{}) {
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(recordColumns)), ", ") + ")"
+
+	rows := make([]string, 0, len(records))
+	args := make([]interface{}, 0, len(records)*len(recordColumns))
+	for _, record := range records {
+		rows = append(rows, row)
+		args = append(args, recordValues(record)...)
+	}
+
+	query := "INSERT INTO " + c.table + " (" + strings.Join(recordColumns, ", ") + ") VALUES " +
+		strings.Join(rows, ", ") + " ON DUPLICATE KEY UPDATE " + c.upsertAssignments()
+
+	return query, args
+}
+
+// upsertChunk 写入一批记录，开启审计时同时为每条记录写入审计行
+// 主键由数据库生成的新记录无法在多行语句中读回ID，逐条插入
+func (c *MySQLClient) upsertChunk(ctx context.Context, exec execer, records []*models.AssetSubDomain) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		records, err := insertAutoIDRecords(ctx, exec, c, records)
+		if err != nil || len(records) == 0 {
+			return err
+		}
+
+		var before map[string]sql.NullString
+		if c.audit.enabled {
+			if before, err = c.currentValues(ctx, exec, recordIDs(records)); err != nil {
+				return err
+			}
+		}
+
+		query, args := c.buildUpsertQuery(records)
+		if _, err := exec.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+
+		if !c.audit.enabled {
+			return nil
+		}
+		for _, record := range records {
+			action := AuditInsert
+			value, exists := before[record.ID]
+			if exists {
+				action = AuditUpdate
+			}
+			err := c.writeAudit(ctx, exec, record.ID, action, value, auditValue(record.DNSRecord))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// currentValue 查询记录当前的dns_record，记录不存在时返回无效值
+func (c *MySQLClient) currentValue(ctx context.Context, exec execer, localID string) (sql.NullString, error) {
+	var value sql.NullString
+	err := exec.QueryRowContext(ctx, `SELECT dns_record FROM `+c.table+` WHERE id = ?`, localID).Scan(&value)
+	if err == sql.ErrNoRows {
+		return sql.NullString{}, nil
+	}
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to query current record value: %w", err)
+	}
+
+	return value, nil
+}
+
+// currentValues 批量查询已存在记录当前的dns_record，以本地ID为键
+func (c *MySQLClient) currentValues(ctx context.Context, exec execer, ids []string) (map[string]sql.NullString, error) {
+	placeholders, args := inPlaceholders(ids)
+
+	rows, err := exec.QueryContext(ctx,
+		`SELECT id, dns_record FROM `+c.table+` WHERE id IN (`+placeholders+`)`, args...)
 	if err != nil {
-		return fmt.Errorf("failed to delete record: %w", err)
+		return nil, fmt.Errorf("failed to query current record values: %w", err)
+	}
+	defer rows.Close()
+
+	values := make(map[string]sql.NullString)
+	for rows.Next() {
+		var id string
+		var value sql.NullString
+		if err := rows.Scan(&id, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan current record value: %w", err)
+		}
+		values[id] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate current record values: %w", err)
+	}
+
+	return values, nil
+}
+
+// writeAudit 写入一条审计记录
+func (c *MySQLClient) writeAudit(ctx context.Context, exec execer, localID, action string, before, after sql.NullString) error {
+	query := `INSERT INTO ` + c.historyTable() + ` (record_id, action, before_value, after_value, run_id, create_time)
+			  VALUES (?, ?, ?, ?, ?, NOW())`
+
+	if _, err := exec.ExecContext(ctx, query, localID, action, before, after, c.audit.runID); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
 	}
 
 	return nil
 }
 
 // NeedUpdate 检查记录是否需要更新
+// 比较记录内容哈希，旧数据没有哈希或没有rr时同样视为需要更新，更新后会补齐；
+// 主域名记录的子域名保存为"@.域名"时同样更新一次，改写为域名本身；
+// 哈希按大写的类型计算，本地类型大小写不同时也需要更新一次，之后保持一致；
+// 线路名称不计入哈希，与本地不同时也需要更新，忽略line字段时除外
 func NeedUpdate(aliyunRecord *models.DNSRecord, localRecord *models.AssetSubDomain) bool {
-	// 组合阿里云记录的完整域名
-	aliyunSubDomain := aliyunRecord.DomainName
-	if aliyunRecord.RR != "" && aliyunRecord.RR != "@" {
-		aliyunSubDomain = aliyunRecord.RR + "." + aliyunRecord.DomainName
-	}
-
-	// 比较关键字段
-	if localRecord.SubDomain != aliyunSubDomain {
+	if localRecord.ContentHash != aliyunRecord.ContentHash() || localRecord.RR == "" || strings.HasPrefix(localRecord.SubDomain, "@") {
 		return true
 	}
-	
-	if localRecord.Type != aliyunRecord.Type {
+	if localRecord.Type != aliyunRecord.RecordType() && !slices.Contains(aliyunRecord.IgnoreFields, "type") {
 		return true
 	}
-	
-	if localRecord.DNSRecord == nil || *localRecord.DNSRecord != aliyunRecord.Value {
-		return true
+	return localRecord.LineName != aliyunRecord.LineName && !slices.Contains(aliyunRecord.IgnoreFields, "line")
+}
+
+// GetPendingPushRecords 获取需要推送到服务商的本地记录
+// 即指定来源、尚未关联阿里云RecordId的记录
+func (c *MySQLClient) GetPendingPushRecords(ctx context.Context, domainID, source string) ([]*models.AssetSubDomain, error) {
+	query := `SELECT id, sub_domain, type, dns_record, ttl, priority, line
+			  FROM ` + c.table + ` 
+			  WHERE domain_id = ? AND source = ? AND aliyun_record_id IS NULL`
+	if c.softDelete {
+		query += " AND (status IS NULL OR status <> 'DELETED')"
 	}
 
-	return false
+	var records []*models.AssetSubDomain
+	err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		rows, err := c.db.QueryContext(ctx, query, domainID, source)
+		if err != nil {
+			return fmt.Errorf("failed to query pending push records: %w", err)
+		}
+		defer rows.Close()
+
+		records, err = scanPendingPushRecords(rows)
+		return err
+	})
+	return records, err
+}
+
+// scanPendingPushRecords 读取待推送记录查询结果
+func scanPendingPushRecords(rows *sql.Rows) ([]*models.AssetSubDomain, error) {
+	var records []*models.AssetSubDomain
+	for rows.Next() {
+		record := &models.AssetSubDomain{}
+		var dnsRecord, line sql.NullString
+		var ttl, priority sql.NullInt32
+
+		if err := rows.Scan(&record.ID, &record.SubDomain, &record.Type, &dnsRecord, &ttl, &priority, &line); err != nil {
+			return nil, fmt.Errorf("failed to scan pending push record: %w", err)
+		}
+
+		if dnsRecord.Valid {
+			record.DNSRecord = &dnsRecord.String
+		}
+		record.TTL = ttl.Int32
+		record.Priority = priority.Int32
+		record.Line = line.String
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate pending push records: %w", err)
+	}
+
+	return records, nil
+}
+
+// MarkRecordPushed 记录推送成功后回写RecordId，并将来源改为同步来源，之后由拉取流程维护
+func (c *MySQLClient) MarkRecordPushed(ctx context.Context, localID, recordID, source string) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		query := `UPDATE ` + c.table + ` 
+				  SET aliyun_record_id = ?, source = ?, update_time = NOW() 
+				  WHERE id = ?`
+
+		if _, err := c.db.ExecContext(ctx, query, recordID, source, localID); err != nil {
+			return fmt.Errorf("failed to mark record pushed: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetWatermark 获取域名在该来源的同步水位，sync_state中没有时返回0
+func (c *MySQLClient) GetWatermark(ctx context.Context, domainID, source string) (int64, error) {
+	query := `SELECT watermark FROM sync_state WHERE source = ? AND domain_id = ?`
+
+	var watermark int64
+	err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		return c.readDB(ctx).QueryRowContext(ctx, query, source, domainID).Scan(&watermark)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get watermark: %w", err)
+	}
+
+	return watermark, nil
+}
+
+// SaveWatermark 保存域名在该来源的同步水位
+func (c *MySQLClient) SaveWatermark(ctx context.Context, domainID, source string, watermark int64) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		query := `INSERT INTO sync_state (source, domain_id, watermark, update_time) VALUES (?, ?, ?, NOW())
+				  ON DUPLICATE KEY UPDATE watermark = VALUES(watermark), update_time = VALUES(update_time)`
+
+		if _, err := c.db.ExecContext(ctx, query, source, domainID, watermark); err != nil {
+			return fmt.Errorf("failed to save watermark: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetRecentDeletes 获取域名最近runs次同步的删除记录数，sync_metrics中没有该域名时返回空
+func (c *MySQLClient) GetRecentDeletes(ctx context.Context, domainID string, runs int) ([]int, error) {
+	query := `SELECT deleted FROM sync_metrics WHERE domain_id = ? ORDER BY id DESC LIMIT ?`
+
+	var deletes []int
+	err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		rows, err := c.readDB(ctx).QueryContext(ctx, query, domainID, runs)
+		if err != nil {
+			return fmt.Errorf("failed to query sync metrics: %w", err)
+		}
+		defer rows.Close()
+
+		deletes, err = scanDeletes(rows)
+		return err
+	})
+	return deletes, err
+}
+
+// SaveSyncMetrics 保存域名本次同步的变更数量
+func (c *MySQLClient) SaveSyncMetrics(ctx context.Context, domainID string, added, updated, deleted int) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		query := `INSERT INTO sync_metrics (domain_id, added, updated, deleted, create_time) VALUES (?, ?, ?, ?, NOW())`
+
+		if _, err := c.db.ExecContext(ctx, query, domainID, added, updated, deleted); err != nil {
+			return fmt.Errorf("failed to save sync metrics: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// SaveResolutionStatus 按检查结果分批更新resolution_status，不修改update_time
+func (c *MySQLClient) SaveResolutionStatus(ctx context.Context, statuses map[string][]string) error {
+	for status, ids := range statuses {
+		for start := 0; start < len(ids); start += DefaultBatchSize {
+			chunk := ids[start:min(start+DefaultBatchSize, len(ids))]
+			placeholders, args := inPlaceholders(chunk)
+			query := `UPDATE ` + c.table + ` SET resolution_status = ? WHERE id IN (` + placeholders + `)`
+			err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+				_, err := c.db.ExecContext(ctx, query, append([]interface{}{status}, args...)...)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to save resolution status: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// ExecPostSync 在主库上执行同步后的语句
+func (c *MySQLClient) ExecPostSync(ctx context.Context, statements []string, totals PostSyncTotals) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		return execPostSync(ctx, c.db, statements, totals)
+	})
 }
 
 // GetRecordCount 获取记录总数（用于统计）
-func (c *MySQLClient) GetRecordCount(domainID string) (int, error) {
-	query := `SELECT COUNT(*) FROM asset_sub_domain WHERE domain_id = ? AND source = 'Aliyun-DNS-Sync'`
+func (c *MySQLClient) GetRecordCount(ctx context.Context, domainID, source string) (int, error) {
+	query := `SELECT COUNT(*) FROM ` + c.table + ` WHERE domain_id = ? AND source = ?`
 	
 	var count int
-	err := c.db.QueryRow(query, domainID).Scan(&count)
+	err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		return c.readDB(ctx).QueryRowContext(ctx, query, domainID, source).Scan(&count)
+	})
 	if err != nil {
 		return 0, fmt.Errorf("failed to get record count: %w", err)
 	}
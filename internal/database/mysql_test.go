@@ -0,0 +1,604 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+
+	"dns-sync/internal/models"
+)
+
+// newMockMySQL 创建连接到sqlmock的MySQL客户端，测试结束时检查预期的语句都已执行
+func newMockMySQL(t *testing.T) (*MySQLClient, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return &MySQLClient{db: db, table: "asset_sub_domain"}, mock
+}
+
+// testAssets 生成n条测试用的本地记录
+func testAssets(n int) []*models.AssetSubDomain {
+	records := make([]*models.AssetSubDomain, 0, n)
+	for i := 0; i < n; i++ {
+		record := &models.DNSRecord{
+			DomainName: "example.com",
+			RR:         fmt.Sprintf("host%d", i),
+			RecordId:   fmt.Sprintf("%d", 1000+i),
+			Type:       "A",
+			Value:      fmt.Sprintf("10.0.0.%d", i%256),
+			TTL:        600,
+			Line:       "default",
+			Status:     "ENABLE",
+		}
+		asset := record.ConvertToAssetSubDomain("domain-1", "project-1", "Aliyun-DNS-Sync")
+		asset.ID = fmt.Sprintf("id-%d", i)
+		records = append(records, asset)
+	}
+	return records
+}
+
+func TestNeedUpdate(t *testing.T) {
+	mx := &models.DNSRecord{
+		DomainName: "example.com",
+		RR:         "@",
+		RecordId:   "2000",
+		Type:       "MX",
+		Value:      "mail.example.com",
+		Priority:   10,
+		TTL:        600,
+		Line:       "default",
+		Status:     "ENABLE",
+	}
+
+	tests := []struct {
+		name   string
+		change func(r *models.DNSRecord)
+		want   bool
+	}{
+		{name: "unchanged", change: func(*models.DNSRecord) {}},
+		{name: "MX priority only", change: func(r *models.DNSRecord) { r.Priority = 20 }, want: true},
+		{name: "MX value", change: func(r *models.DNSRecord) { r.Value = "mx2.example.com" }, want: true},
+		{name: "weight only without WRR", change: func(r *models.DNSRecord) { r.Weight = 5 }},
+		{name: "weight only with WRR", change: func(r *models.DNSRecord) { r.Weight, r.LbaStatus = 5, true }, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := mx.ConvertToAssetSubDomain("domain-1", "project-1", "Aliyun-DNS-Sync")
+			remote := *mx
+			tt.change(&remote)
+
+			if got := NeedUpdate(&remote, local); got != tt.want {
+				t.Errorf("NeedUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNeedUpdateNormalizedValues 记录值只有尾部的点、大小写或首尾空白不同时不判为更新
+func TestNeedUpdateNormalizedValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		recordType string
+		stored     string
+		remote     string
+		want       bool
+	}{
+		{name: "CNAME trailing dot", recordType: "CNAME", stored: "target.example.com", remote: "target.example.com."},
+		{name: "CNAME case", recordType: "CNAME", stored: "target.example.com", remote: "Target.Example.COM"},
+		{name: "CNAME stored with dot", recordType: "CNAME", stored: "Target.Example.com.", remote: "target.example.com"},
+		{name: "CNAME changed", recordType: "CNAME", stored: "target.example.com", remote: "other.example.com.",
+			want: true},
+		{name: "A whitespace", recordType: "A", stored: "10.0.0.1", remote: " 10.0.0.1 "},
+		{name: "A changed", recordType: "A", stored: "10.0.0.1", remote: "10.0.0.2", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &models.DNSRecord{DomainName: "example.com", RR: "www", RecordId: "4000", Type: tt.recordType,
+				Value: tt.stored, TTL: 600, Line: "default", Status: "ENABLE"}
+			local := record.ConvertToAssetSubDomain("domain-1", "project-1", "Aliyun-DNS-Sync")
+			remote := *record
+			remote.Value = tt.remote
+
+			if got := NeedUpdate(&remote, local); got != tt.want {
+				t.Errorf("NeedUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNeedUpdateApex 主域名记录的主机记录为空或为@时互不判为更新，子域名保存为"@.域名"的旧数据需要更新
+func TestNeedUpdateApex(t *testing.T) {
+	tests := []struct {
+		name      string
+		storedRR  string
+		remoteRR  string
+		subDomain string
+		want      bool
+	}{
+		{name: "stored empty", storedRR: "", remoteRR: "@"},
+		{name: "stored at", storedRR: "@", remoteRR: ""},
+		{name: "stored with @ prefix", storedRR: "@", remoteRR: "@", subDomain: "@.example.com", want: true},
+		{name: "stored as @", storedRR: "@", remoteRR: "", subDomain: "@", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &models.DNSRecord{DomainName: "example.com", RR: tt.storedRR, RecordId: "4000", Type: "A",
+				Value: "10.0.0.1", TTL: 600, Line: "default", Status: "ENABLE"}
+			local := record.ConvertToAssetSubDomain("domain-1", "project-1", "Aliyun-DNS-Sync")
+			if local.SubDomain != "example.com" || local.RR != "@" {
+				t.Errorf("stored sub_domain = %q, rr = %q, want example.com and @", local.SubDomain, local.RR)
+			}
+			if tt.subDomain != "" {
+				local.SubDomain = tt.subDomain
+			}
+			remote := *record
+			remote.RR = tt.remoteRR
+
+			if got := NeedUpdate(&remote, local); got != tt.want {
+				t.Errorf("NeedUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNeedUpdateTypeCase 服务商返回的记录类型大小写不一致时不判为更新，存储的类型统一为大写
+func TestNeedUpdateTypeCase(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored string
+		remote string
+		want   bool
+	}{
+		{name: "lower case remote", stored: "CNAME", remote: "cname"},
+		{name: "mixed case remote", stored: "cname", remote: "CName"},
+		{name: "whitespace", stored: "A", remote: " a "},
+		{name: "type changed", stored: "CNAME", remote: "a", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &models.DNSRecord{DomainName: "example.com", RR: "www", RecordId: "4000", Type: tt.stored,
+				Value: "target.example.com", TTL: 600, Line: "default", Status: "ENABLE"}
+			local := record.ConvertToAssetSubDomain("domain-1", "project-1", "Aliyun-DNS-Sync")
+			if local.Type != strings.ToUpper(strings.TrimSpace(tt.stored)) {
+				t.Errorf("stored type = %q, want upper case", local.Type)
+			}
+			remote := *record
+			remote.Type = tt.remote
+
+			if got := NeedUpdate(&remote, local); got != tt.want {
+				t.Errorf("NeedUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNeedUpdateStoredFields TTL、Line等持久化的字段单独变化时需要更新，旧版本写入的缺少这些列的行也会被补齐
+func TestNeedUpdateStoredFields(t *testing.T) {
+	a := &models.DNSRecord{
+		DomainName: "example.com",
+		RR:         "www",
+		RecordId:   "3000",
+		Type:       "A",
+		Value:      "10.0.0.1",
+		TTL:        600,
+		Line:       "default",
+		LineName:   "默认",
+		Status:     "ENABLE",
+	}
+
+	tests := []struct {
+		name   string
+		remote func(r *models.DNSRecord)
+		local  func(l *models.AssetSubDomain)
+		want   bool
+	}{
+		{name: "unchanged"},
+		{name: "TTL only", remote: func(r *models.DNSRecord) { r.TTL = 60 }, want: true},
+		{name: "line only", remote: func(r *models.DNSRecord) { r.Line = "telecom" }, want: true},
+		{name: "line name only", remote: func(r *models.DNSRecord) { r.LineName = "电信" }, want: true},
+		{name: "status only", remote: func(r *models.DNSRecord) { r.Status = "DISABLE" }, want: true},
+		{name: "remark added", remote: func(r *models.DNSRecord) { r.Remark = "owner: web team" }, want: true},
+		{
+			name:   "remark changed",
+			remote: func(r *models.DNSRecord) { r.Remark = "owner: api team" },
+			local: func(l *models.AssetSubDomain) {
+				l.ContentHash = (&models.DNSRecord{DomainName: "example.com", RR: "www", Type: "A", Value: "10.0.0.1",
+					TTL: 600, Line: "default", Status: "ENABLE", Remark: "owner: web team"}).ContentHash()
+			},
+			want: true,
+		},
+		{
+			name: "remark removed",
+			local: func(l *models.AssetSubDomain) {
+				l.ContentHash = (&models.DNSRecord{DomainName: "example.com", RR: "www", Type: "A", Value: "10.0.0.1",
+					TTL: 600, Line: "default", Status: "ENABLE", Remark: "owner: web team"}).ContentHash()
+			},
+			want: true,
+		},
+		{
+			name:   "ignored remark",
+			remote: func(r *models.DNSRecord) { r.Remark, r.IgnoreFields = "owner: web team", []string{"remark"} },
+		},
+		{name: "legacy row without content hash", local: func(l *models.AssetSubDomain) { l.ContentHash = "" }, want: true},
+		{name: "legacy row without rr", local: func(l *models.AssetSubDomain) { l.RR = "" }, want: true},
+		{name: "lowercase stored type", local: func(l *models.AssetSubDomain) { l.Type = "a" }, want: true},
+		{
+			name:   "ignored line",
+			remote: func(r *models.DNSRecord) { r.LineName, r.IgnoreFields = "电信", []string{"line"} },
+			local: func(l *models.AssetSubDomain) {
+				l.ContentHash = (&models.DNSRecord{DomainName: "example.com", RR: "www", Type: "A", Value: "10.0.0.1",
+					TTL: 600, Line: "default", Status: "ENABLE", IgnoreFields: []string{"line"}}).ContentHash()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := a.ConvertToAssetSubDomain("domain-1", "project-1", "Aliyun-DNS-Sync")
+			if tt.local != nil {
+				tt.local(local)
+			}
+			remote := *a
+			if tt.remote != nil {
+				tt.remote(&remote)
+			}
+
+			if got := NeedUpdate(&remote, local); got != tt.want {
+				t.Errorf("NeedUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNeedUpdateIgnoreFields 本地哈希按相同的ignore_fields计算时，只有忽略的字段变化不触发更新，其它字段变化照常更新
+func TestNeedUpdateIgnoreFields(t *testing.T) {
+	ignore := []string{"ttl", "line"}
+	a := &models.DNSRecord{DomainName: "example.com", RR: "www", RecordId: "3000", Type: "A", Value: "10.0.0.1",
+		TTL: 600, Line: "default", LineName: "默认", Status: "ENABLE", IgnoreFields: ignore}
+
+	tests := []struct {
+		name   string
+		ignore []string
+		remote func(r *models.DNSRecord)
+		local  func(l *models.AssetSubDomain)
+		want   bool
+	}{
+		{name: "unchanged", ignore: ignore},
+		{name: "ignored ttl", ignore: ignore, remote: func(r *models.DNSRecord) { r.TTL = 60 }},
+		{name: "ignored line", ignore: ignore, remote: func(r *models.DNSRecord) { r.Line, r.LineName = "telecom", "电信" }},
+		{name: "ignored ttl and line", ignore: ignore, remote: func(r *models.DNSRecord) {
+			r.TTL, r.Line, r.LineName = 60, "telecom", "电信"
+		}},
+		{name: "value", ignore: ignore, remote: func(r *models.DNSRecord) { r.Value = "10.0.0.2" }, want: true},
+		{name: "status", ignore: ignore, remote: func(r *models.DNSRecord) { r.Status = "DISABLE" }, want: true},
+		{name: "ignored ttl and value", ignore: ignore, remote: func(r *models.DNSRecord) {
+			r.TTL, r.Value = 60, "10.0.0.2"
+		}, want: true},
+		{name: "ttl not ignored", remote: func(r *models.DNSRecord) { r.TTL = 60 }, want: true},
+		{name: "ignored stored type casing", ignore: []string{"type"}, local: func(l *models.AssetSubDomain) { l.Type = "a" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := *a
+			base.IgnoreFields = tt.ignore
+			local := base.ConvertToAssetSubDomain("domain-1", "project-1", "Aliyun-DNS-Sync")
+			if tt.local != nil {
+				tt.local(local)
+			}
+			remote := base
+			if tt.remote != nil {
+				tt.remote(&remote)
+			}
+
+			if got := NeedUpdate(&remote, local); got != tt.want {
+				t.Errorf("NeedUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUpdateRecordSubDomain 更新时写入的sub_domain与插入时ConvertToAssetSubDomain组合的完整域名一致
+func TestUpdateRecordSubDomain(t *testing.T) {
+	for _, rr := range []string{"@", "", "*", "api.dev", "WWW"} {
+		t.Run(rr, func(t *testing.T) {
+			client, mock := newMockMySQL(t)
+			record := &models.DNSRecord{DomainName: "Example.com", RR: rr, RecordId: "1000", Type: "A",
+				Value: "10.0.0.1", TTL: 600, Line: "default", Status: "ENABLE"}
+			want := record.ConvertToAssetSubDomain("domain-1", "project-1", "Aliyun-DNS-Sync").SubDomain
+
+			// sub_domain之后还有15个更新列和WHERE中的id
+			args := []driver.Value{want}
+			for i := 0; i < 16; i++ {
+				args = append(args, sqlmock.AnyArg())
+			}
+			mock.ExpectExec(`(?s)UPDATE asset_sub_domain\s+SET sub_domain = \?`).
+				WithArgs(args...).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			if err := client.updateRecord(context.Background(), client.db, "id-1", record); err != nil {
+				t.Fatalf("updateRecord() error = %v", err)
+			}
+		})
+	}
+}
+
+// TestMySQLWatermark 水位按来源和domain_id读写，不同来源的水位互不影响
+func TestMySQLWatermark(t *testing.T) {
+	client, mock := newMockMySQL(t)
+	ctx := context.Background()
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO sync_state (source, domain_id, watermark, update_time) VALUES (?, ?, ?, NOW())")).
+		WithArgs("Aliyun-DNS-Sync", "domain-1", int64(1700000000000)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT watermark FROM sync_state WHERE source = ? AND domain_id = ?")).
+		WithArgs("Aliyun-DNS-Sync", "domain-1").
+		WillReturnRows(sqlmock.NewRows([]string{"watermark"}).AddRow(int64(1700000000000)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT watermark FROM sync_state WHERE source = ? AND domain_id = ?")).
+		WithArgs("Cloudflare-DNS-Sync", "domain-1").
+		WillReturnRows(sqlmock.NewRows([]string{"watermark"}))
+
+	if err := client.SaveWatermark(ctx, "domain-1", "Aliyun-DNS-Sync", 1700000000000); err != nil {
+		t.Fatalf("SaveWatermark() error = %v", err)
+	}
+	tests := []struct {
+		source string
+		want   int64
+	}{
+		{source: "Aliyun-DNS-Sync", want: 1700000000000},
+		{source: "Cloudflare-DNS-Sync", want: 0},
+	}
+	for _, tt := range tests {
+		got, err := client.GetWatermark(ctx, "domain-1", tt.source)
+		if err != nil {
+			t.Fatalf("GetWatermark(%s) error = %v", tt.source, err)
+		}
+		if got != tt.want {
+			t.Errorf("GetWatermark(%s) = %d, want %d", tt.source, got, tt.want)
+		}
+	}
+}
+
+// TestMySQLConfiguredTable 语句和审计历史表都使用配置的表名
+func TestMySQLConfiguredTable(t *testing.T) {
+	client, mock := newMockMySQL(t)
+	client.table = "tenant_assets"
+	client.softDelete = true
+	client.audit = auditConfig{enabled: true, runID: "run-1"}
+	ctx := context.Background()
+
+	mock.ExpectQuery(`(?s)FROM information_schema\.tables.*table_name = \?`).
+		WithArgs("tenant_assets").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT dns_record FROM tenant_assets WHERE id = ?")).
+		WithArgs("id-1").
+		WillReturnRows(sqlmock.NewRows([]string{"dns_record"}).AddRow("10.0.0.1"))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE tenant_assets SET status = 'DELETED'")).
+		WithArgs("id-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO tenant_assets_history (record_id")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := client.CheckTableExists(ctx); err != nil {
+		t.Fatalf("CheckTableExists() error = %v", err)
+	}
+	if err := client.deleteRecord(ctx, client.db, "id-1"); err != nil {
+		t.Fatalf("deleteRecord() error = %v", err)
+	}
+
+	query, _ := client.buildUpsertQuery(testAssets(1))
+	if !strings.HasPrefix(query, "INSERT INTO tenant_assets (") {
+		t.Errorf("upsert query does not use the configured table: %.60s", query)
+	}
+}
+
+func TestMySQLBuildUpsertQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows       int
+		softDelete bool
+	}{
+		{name: "single row", rows: 1},
+		{name: "full chunk", rows: DefaultBatchSize},
+		{name: "soft delete restores rows", rows: 3, softDelete: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &MySQLClient{table: "asset_sub_domain", softDelete: tt.softDelete}
+			query, args := client.buildUpsertQuery(testAssets(tt.rows))
+
+			if want := tt.rows * len(recordColumns); len(args) != want {
+				t.Errorf("got %d args, want %d", len(args), want)
+			}
+			if got := strings.Count(query, "?"); got != len(args) {
+				t.Errorf("got %d placeholders for %d args", got, len(args))
+			}
+			if !strings.HasPrefix(query, "INSERT INTO asset_sub_domain (id, ") {
+				t.Errorf("unexpected query prefix: %.60s", query)
+			}
+
+			values, updates, ok := strings.Cut(query, " ON DUPLICATE KEY UPDATE ")
+			if !ok {
+				t.Fatalf("query has no ON DUPLICATE KEY UPDATE clause: %.60s", query)
+			}
+			if got := strings.Count(values, "), ("); got != tt.rows-1 {
+				t.Errorf("got %d row separators, want %d", got, tt.rows-1)
+			}
+			// 冲突键中的列不能被覆盖，否则会把已有行移到别的来源或域名下
+			for _, column := range []string{"source", "domain_id", "aliyun_record_id", "id"} {
+				if strings.Contains(updates, " "+column+" = ") || strings.HasPrefix(updates, column+" = ") {
+					t.Errorf("update clause overwrites key column %s", column)
+				}
+			}
+			if got := strings.Contains(updates, "deleted_at = NULL"); got != tt.softDelete {
+				t.Errorf("deleted_at restore = %v, want %v", got, tt.softDelete)
+			}
+		})
+	}
+}
+
+// TestMySQLBuildUpsertQueryPlaceholderLimit 默认批次的占位符数需要低于MySQL的65535上限
+func TestMySQLBuildUpsertQueryPlaceholderLimit(t *testing.T) {
+	client := &MySQLClient{table: "asset_sub_domain"}
+	_, args := client.buildUpsertQuery(testAssets(DefaultBatchSize))
+	if len(args) >= 65535 {
+		t.Errorf("default batch of %d rows uses %d placeholders", DefaultBatchSize, len(args))
+	}
+}
+
+// TestMySQLBatchUpsertRetry 死锁和锁等待超时时重试当前批次，主键冲突等错误不重试
+func TestMySQLBatchUpsertRetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxRetries  int
+		errs        []error
+		wantWritten int
+		wantErr     bool
+	}{
+		{name: "deadlock then success", maxRetries: 1, errs: []error{&mysql.MySQLError{Number: mysqlErrDeadlock}}, wantWritten: 2},
+		{name: "lock wait timeout then success", maxRetries: 2, errs: []error{&mysql.MySQLError{Number: mysqlErrLockWaitTimeout}}, wantWritten: 2},
+		{name: "retries exhausted", maxRetries: 1, errs: []error{&mysql.MySQLError{Number: mysqlErrDeadlock},
+			&mysql.MySQLError{Number: mysqlErrDeadlock}}, wantErr: true},
+		{name: "duplicate key is not retried", maxRetries: 3, errs: []error{&mysql.MySQLError{Number: 1062}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mock := newMockMySQL(t)
+			client.maxRetries = tt.maxRetries
+
+			for _, err := range tt.errs {
+				mock.ExpectExec("INSERT INTO asset_sub_domain").WillReturnError(err)
+			}
+			if !tt.wantErr {
+				mock.ExpectExec("INSERT INTO asset_sub_domain").WillReturnResult(sqlmock.NewResult(0, 2))
+			}
+
+			written, err := client.BatchUpsert(context.Background(), testAssets(2), 10)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BatchUpsert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if written != tt.wantWritten {
+				t.Errorf("BatchUpsert() written = %d, want %d", written, tt.wantWritten)
+			}
+		})
+	}
+}
+
+// TestMySQLBatchUpsertChunks 按batchSize拆分为多条INSERT，每条语句的参数与本批行数一致
+func TestMySQLBatchUpsertChunks(t *testing.T) {
+	tests := []struct {
+		name      string
+		rows      int
+		batchSize int
+		want      []int
+	}{
+		{name: "single chunk", rows: 3, batchSize: 10, want: []int{3}},
+		{name: "exact chunks", rows: 4, batchSize: 2, want: []int{2, 2}},
+		{name: "short last chunk", rows: 5, batchSize: 2, want: []int{2, 2, 1}},
+		{name: "default batch size", rows: 3, batchSize: 0, want: []int{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mock := newMockMySQL(t)
+			for _, rows := range tt.want {
+				args := make([]driver.Value, rows*len(recordColumns))
+				for i := range args {
+					args[i] = sqlmock.AnyArg()
+				}
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO asset_sub_domain (id, ") + ".* ON DUPLICATE KEY UPDATE ").
+					WithArgs(args...).
+					WillReturnResult(sqlmock.NewResult(0, int64(rows)))
+			}
+
+			written, err := client.BatchUpsert(context.Background(), testAssets(tt.rows), tt.batchSize)
+			if err != nil {
+				t.Fatalf("BatchUpsert() error = %v", err)
+			}
+			if written != tt.rows {
+				t.Errorf("BatchUpsert() written = %d, want %d", written, tt.rows)
+			}
+		})
+	}
+}
+
+func BenchmarkMySQLBuildUpsertQuery(b *testing.B) {
+	client := &MySQLClient{table: "asset_sub_domain"}
+	records := testAssets(DefaultBatchSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.buildUpsertQuery(records)
+	}
+}
+
+// TestAssetFieldsNotUpdated 更新语句和upsert冲突时的赋值都不包含资产字段，人工修改的值不会被同步覆盖
+func TestAssetFieldsNotUpdated(t *testing.T) {
+	assetColumns := []string{"asset_label", "create_by", "sys_org_code", "asset_department", "level", "project_id"}
+	for _, column := range assetColumns {
+		if slices.Contains(upsertUpdateColumns, column) {
+			t.Errorf("upsertUpdateColumns contains asset column %s", column)
+		}
+	}
+
+	record := &models.DNSRecord{DomainName: "example.com", RR: "www", RecordId: "1000", Type: "A",
+		Value: "10.0.0.2", TTL: 600, Line: "default", Status: "ENABLE"}
+	tests := []struct {
+		name   string
+		update func(db *sql.DB) error
+	}{
+		{name: "mysql", update: func(db *sql.DB) error {
+			client := &MySQLClient{db: db, table: "asset_sub_domain"}
+			return client.updateRecord(context.Background(), db, "id-1", record)
+		}},
+		{name: "postgres", update: func(db *sql.DB) error {
+			client := &PostgresClient{db: db, table: "asset_sub_domain"}
+			return client.updateRecord(context.Background(), db, "id-1", record)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var statement string
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(func(_, actual string) error {
+				statement = actual
+				return nil
+			})))
+			if err != nil {
+				t.Fatalf("sqlmock.New() error = %v", err)
+			}
+			defer db.Close()
+			mock.ExpectExec("UPDATE").WillReturnResult(sqlmock.NewResult(0, 1))
+
+			if err := tt.update(db); err != nil {
+				t.Fatalf("updateRecord() error = %v", err)
+			}
+			for _, column := range assetColumns {
+				if regexp.MustCompile(`\b` + column + `\s*=`).MatchString(statement) {
+					t.Errorf("update statement sets asset column %s: %s", column, statement)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package database
+
+import "time"
+
+// poolSetter 连接池参数的设置方法，由*sql.DB实现
+type poolSetter interface {
+	SetMaxOpenConns(n int)
+	SetMaxIdleConns(n int)
+	SetConnMaxLifetime(d time.Duration)
+}
+
+// configurePool 设置连接池参数，默认值在加载配置时填充
+func configurePool(db poolSetter, maxOpen, maxIdle int, lifetime time.Duration) {
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(lifetime)
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"dns-sync/internal/config"
+)
+
+// recordingPool 记录设置的连接池参数
+type recordingPool struct {
+	maxOpen  int
+	maxIdle  int
+	lifetime time.Duration
+}
+
+func (p *recordingPool) SetMaxOpenConns(n int)              { p.maxOpen = n }
+func (p *recordingPool) SetMaxIdleConns(n int)              { p.maxIdle = n }
+func (p *recordingPool) SetConnMaxLifetime(d time.Duration) { p.lifetime = d }
+
+// TestConfigurePool 连接池参数按MySQL配置设置，*sql.DB上设置的最大连接数可以从Stats读到
+func TestConfigurePool(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.MySQLConfig
+	}{
+		{name: "defaults", cfg: config.MySQLConfig{MaxOpenConns: 25, MaxIdleConns: 10, ConnMaxLifetime: 5 * time.Minute}},
+		{name: "small instance", cfg: config.MySQLConfig{MaxOpenConns: 4, MaxIdleConns: 2, ConnMaxLifetime: time.Minute}},
+		{name: "no idle connections", cfg: config.MySQLConfig{MaxOpenConns: 8, ConnMaxLifetime: time.Hour}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &recordingPool{}
+			configurePool(pool, tt.cfg.MaxOpenConns, tt.cfg.MaxIdleConns, tt.cfg.ConnMaxLifetime)
+			if pool.maxOpen != tt.cfg.MaxOpenConns || pool.maxIdle != tt.cfg.MaxIdleConns ||
+				pool.lifetime != tt.cfg.ConnMaxLifetime {
+				t.Errorf("pool = %d open, %d idle, %s lifetime, want %d, %d, %s", pool.maxOpen, pool.maxIdle,
+					pool.lifetime, tt.cfg.MaxOpenConns, tt.cfg.MaxIdleConns, tt.cfg.ConnMaxLifetime)
+			}
+
+			// sql.Open不会建立连接，这里只检查参数是否设置到连接池上
+			db, err := sql.Open("mysql", tt.cfg.DSN())
+			if err != nil {
+				t.Fatalf("sql.Open() error = %v", err)
+			}
+			defer db.Close()
+			configurePool(db, tt.cfg.MaxOpenConns, tt.cfg.MaxIdleConns, tt.cfg.ConnMaxLifetime)
+			if got := db.Stats().MaxOpenConnections; got != tt.cfg.MaxOpenConns {
+				t.Errorf("MaxOpenConnections = %d, want %d", got, tt.cfg.MaxOpenConns)
+			}
+		})
+	}
+}
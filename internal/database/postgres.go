@@ -0,0 +1,733 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// PostgresClient PostgreSQL客户端，表结构与MySQL版本的asset_sub_domain一致
+type PostgresClient struct {
+	db         *sql.DB
+	idGen      IDGenerator
+	softDelete bool
+	audit      auditConfig
+	// queryTimeout 单次数据库调用的超时时间，为0时不限制
+	queryTimeout time.Duration
+	// table 记录表名，默认asset_sub_domain，加载配置时已校验为合法标识符
+	table string
+}
+
+// NewPostgresClient 创建PostgreSQL客户端
+func NewPostgresClient(cfg *config.PostgresConfig) (*PostgresClient, error) {
+	dsn := cfg.DSN()
+
+	idGen, err := NewIDGenerator(cfg.IDStrategy, cfg.WorkerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create id generator: %w", err)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	configurePool(db, cfg.MaxOpenConns, cfg.MaxIdleConns, cfg.ConnMaxLifetime)
+
+	// 测试连接
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return &PostgresClient{
+		db:           db,
+		idGen:        idGen,
+		queryTimeout: cfg.QueryTimeout,
+		table:        cfg.Table,
+	}, nil
+}
+
+// historyTable 审计历史表名，为记录表名加_history后缀
+func (c *PostgresClient) historyTable() string {
+	return c.table + "_history"
+}
+
+// SetSoftDelete 设置是否使用软删除
+func (c *PostgresClient) SetSoftDelete(enabled bool) {
+	c.softDelete = enabled
+}
+
+// SetAudit 设置是否记录审计历史，runID标识本次运行
+func (c *PostgresClient) SetAudit(enabled bool, runID string) {
+	c.audit = auditConfig{enabled: enabled, runID: runID}
+}
+
+// Close 关闭数据库连接
+func (c *PostgresClient) Close() error {
+	return c.db.Close()
+}
+
+// TestConnection 测试数据库连接
+func (c *PostgresClient) TestConnection(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
+// AcquireRunLock 使用会话级advisory lock获取运行锁，锁名按FNV-1a哈希为advisory lock的64位键
+func (c *PostgresClient) AcquireRunLock(ctx context.Context, name string) (*RunLock, error) {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	key := int64(hash.Sum64())
+	return acquireRunLock(ctx, c.db, `SELECT pg_try_advisory_lock($1)`, `SELECT pg_advisory_unlock($1)`, key)
+}
+
+// Prewarm 预先建立连接并放回连接池
+func (c *PostgresClient) Prewarm(ctx context.Context, conns int) error {
+	return prewarm(ctx, c.db, conns)
+}
+
+// InitSchema 创建asset_sub_domain及审计历史表，已存在时不做修改
+func (c *PostgresClient) InitSchema(ctx context.Context) error {
+	// 内置语句使用默认表名，按配置替换记录表、审计历史表和索引名中的表名
+	schema := strings.ReplaceAll(postgresSchema, "asset_sub_domain", c.table)
+	return initSchema(ctx, c.db, schema)
+}
+
+// CheckTableExists 检查表是否存在
+func (c *PostgresClient) CheckTableExists(ctx context.Context) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		query := `SELECT COUNT(*) FROM information_schema.tables
+				  WHERE table_schema = current_schema() AND table_name = lower($1)`
+
+		var count int
+		if err := c.db.QueryRowContext(ctx, query, c.table).Scan(&count); err != nil {
+			return fmt.Errorf("failed to check table existence: %w", err)
+		}
+
+		if count == 0 {
+			return fmt.Errorf("table '%s' does not exist", c.table)
+		}
+
+		return nil
+	})
+}
+
+// CheckColumns 检查表中是否存在同步会写入的全部列
+func (c *PostgresClient) CheckColumns(ctx context.Context) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		query := `SELECT column_name FROM information_schema.columns
+				  WHERE table_schema = current_schema() AND table_name = lower($1)`
+
+		return checkColumns(ctx, c.db, c.table, requiredColumns(c.softDelete), query, c.table)
+	})
+}
+
+// GetLocalRecords 获取数据库中指定域名的所有记录
+// 软删除模式下不包含已软删除的记录
+func (c *PostgresClient) GetLocalRecords(ctx context.Context, domainID, source string) (map[string]*models.AssetSubDomain, error) {
+	condition := ""
+	if c.softDelete {
+		condition = " AND (status IS NULL OR status <> 'DELETED')"
+	}
+	return c.queryLocalRecords(ctx, domainID, source, condition)
+}
+
+// GetSoftDeletedRecords 获取指定域名已软删除的记录
+func (c *PostgresClient) GetSoftDeletedRecords(ctx context.Context, domainID, source string) (map[string]*models.AssetSubDomain, error) {
+	if !c.softDelete {
+		return map[string]*models.AssetSubDomain{}, nil
+	}
+	return c.queryLocalRecords(ctx, domainID, source, " AND status = 'DELETED'")
+}
+
+// queryLocalRecords 按附加条件查询本地记录，以阿里云记录ID为键
+func (c *PostgresClient) queryLocalRecords(ctx context.Context, domainID, source, condition string) (map[string]*models.AssetSubDomain, error) {
+	query := `SELECT id, sub_domain, type, dns_record, aliyun_record_id, create_time, update_time,
+			  ttl, weight, priority, line, content_hash, status, rr, domain_name, remark, line_name
+			  FROM ` + c.table + `
+			  WHERE domain_id = $1 AND source = $2 AND aliyun_record_id IS NOT NULL` + condition +
+		` ORDER BY create_time, id`
+
+	var records map[string]*models.AssetSubDomain
+	err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		rows, err := c.db.QueryContext(ctx, query, domainID, source)
+		if err != nil {
+			return fmt.Errorf("failed to query local records: %w", err)
+		}
+		defer rows.Close()
+
+		records, err = scanLocalRecords(rows, domainID)
+		return err
+	})
+	return records, err
+}
+
+// InsertRecord 插入单条记录
+func (c *PostgresClient) InsertRecord(ctx context.Context, record *models.AssetSubDomain) error {
+	return withAudit(ctx, c.db, c.audit, func(exec execer) error {
+		return c.insertRecord(ctx, exec, record)
+	})
+}
+
+// insertRecord 使用指定的执行对象插入单条记录
+// 按(source, domain_id, aliyun_record_id)的唯一索引upsert：上次运行插入后异常退出时，重新插入同一条记录只会更新已有的行，
+// 此时record.ID改为已有行的ID，审计记为更新
+func (c *PostgresClient) insertRecord(ctx context.Context, exec execer, record *models.AssetSubDomain) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		id, err := c.idGen.NextIDString()
+		if err != nil {
+			return fmt.Errorf("failed to generate ID: %w", err)
+		}
+		record.ID = id
+
+		// 主键由数据库生成时省略id列；通过RETURNING读回实际写入的行的ID，xmax为0表示新插入的行
+		columns, values := recordColumns, recordValues(record)
+		if record.ID == "" {
+			columns, values = autoIDColumns, autoIDValues(record)
+		}
+		query := "INSERT INTO " + c.table + " (" + strings.Join(columns, ", ") + ") VALUES (" +
+			strings.Join(postgresPlaceholders(len(columns)), ", ") + ") ON CONFLICT (source, domain_id, aliyun_record_id) DO UPDATE SET " +
+			c.upsertAssignments() + " RETURNING id, (xmax = 0)"
+
+		var inserted bool
+		if err := exec.QueryRowContext(ctx, query, values...).Scan(&record.ID, &inserted); err != nil {
+			return fmt.Errorf("failed to insert record: %w", err)
+		}
+
+		if c.audit.enabled {
+			action := AuditInsert
+			if !inserted {
+				action = AuditUpdate
+			}
+			return c.writeAudit(ctx, exec, record.ID, action, sql.NullString{}, auditValue(record.DNSRecord))
+		}
+
+		return nil
+	})
+}
+
+// upsertAssignments 记录已存在时ON CONFLICT DO UPDATE的赋值，只覆盖upsertUpdateColumns，软删除模式下同时恢复记录
+func (c *PostgresClient) upsertAssignments() string {
+	updates := make([]string, 0, len(upsertUpdateColumns)+1)
+	for _, column := range upsertUpdateColumns {
+		updates = append(updates, column+" = EXCLUDED."+column)
+	}
+	if c.softDelete {
+		updates = append(updates, "deleted_at = NULL")
+	}
+	return strings.Join(updates, ", ")
+}
+
+// postgresPlaceholders 生成$1到$n的占位符
+func postgresPlaceholders(n int) []string {
+	placeholders := make([]string, n)
+	for i := range placeholders {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+	}
+	return placeholders
+}
+
+// UpdateRecord 更新记录
+func (c *PostgresClient) UpdateRecord(ctx context.Context, localID string, aliyunRecord *models.DNSRecord) error {
+	return withAudit(ctx, c.db, c.audit, func(exec execer) error {
+		return c.updateRecord(ctx, exec, localID, aliyunRecord)
+	})
+}
+
+// updateRecord 使用指定的执行对象更新记录
+func (c *PostgresClient) updateRecord(ctx context.Context, exec execer, localID string, aliyunRecord *models.DNSRecord) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		subDomain := aliyunRecord.FullDomain()
+
+		// status随服务商状态更新，软删除模式下同时清除删除时间
+		restore := ""
+		if c.softDelete {
+			restore = "deleted_at = NULL, "
+		}
+
+		query := `UPDATE ` + c.table + `
+				  SET sub_domain = $1, type = $2, dns_record = $3, ttl = $4, weight = $5, priority = $6, line = $7,
+				  content_hash = $8, status = $9, aliyun_record_id = $10, rr = $11, domain_name = $12, remark = $13, line_name = $14, raw_record = $15, ` + restore + `update_time = $16
+				  WHERE id = $17`
+
+		var before sql.NullString
+		if c.audit.enabled {
+			var err error
+			if before, err = c.currentValue(ctx, exec, localID); err != nil {
+				return err
+			}
+		}
+
+		_, err := exec.ExecContext(ctx, query, subDomain, aliyunRecord.RecordType(), aliyunRecord.RecordValue(),
+			aliyunRecord.TTL, aliyunRecord.RecordWeight(), aliyunRecord.Priority, aliyunRecord.Line, aliyunRecord.ContentHash(),
+			aliyunRecord.AssetStatus(), aliyunRecord.RecordId, aliyunRecord.HostRecord(), aliyunRecord.ZoneName(),
+			aliyunRecord.Remark, aliyunRecord.LineName, aliyunRecord.RawJSON(), aliyunRecord.UpdateTime(), localID)
+		if err != nil {
+			return fmt.Errorf("failed to update record: %w", err)
+		}
+
+		if c.audit.enabled {
+			return c.writeAudit(ctx, exec, localID, AuditUpdate, before,
+				sql.NullString{String: aliyunRecord.RecordValue(), Valid: true})
+		}
+
+		return nil
+	})
+}
+
+// DeleteRecord 删除记录
+func (c *PostgresClient) DeleteRecord(ctx context.Context, localID string) error {
+	return withAudit(ctx, c.db, c.audit, func(exec execer) error {
+		return c.deleteRecord(ctx, exec, localID)
+	})
+}
+
+// deleteRecord 使用指定的执行对象删除记录
+func (c *PostgresClient) deleteRecord(ctx context.Context, exec execer, localID string) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		query := `DELETE FROM ` + c.table + ` WHERE id = $1`
+		if c.softDelete {
+			query = `UPDATE ` + c.table + ` SET status = 'DELETED', deleted_at = NOW(), update_time = NOW() WHERE id = $1`
+		}
+
+		var before sql.NullString
+		if c.audit.enabled {
+			var err error
+			if before, err = c.currentValue(ctx, exec, localID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := exec.ExecContext(ctx, query, localID); err != nil {
+			return fmt.Errorf("failed to delete record: %w", err)
+		}
+
+		if c.audit.enabled {
+			return c.writeAudit(ctx, exec, localID, AuditDelete, before, sql.NullString{})
+		}
+
+		return nil
+	})
+}
+
+// DeleteRecords 按batchSize分批删除记录，批次失败时逐条重试，返回删除失败的记录及其错误，见deleteRecords
+func (c *PostgresClient) DeleteRecords(ctx context.Context, ids []string, batchSize int) map[string]error {
+	return deleteRecords(ids, batchSize, func(chunk []string) error {
+		return withAudit(ctx, c.db, c.audit, func(exec execer) error {
+			return c.deleteChunk(ctx, exec, chunk)
+		})
+	}, func(id string) error {
+		return c.DeleteRecord(ctx, id)
+	})
+}
+
+// deleteChunk 使用一条DELETE ... WHERE id = ANY($1)删除一批记录，软删除模式下改为批量标记
+func (c *PostgresClient) deleteChunk(ctx context.Context, exec execer, ids []string) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		query := `DELETE FROM ` + c.table + ` WHERE id = ANY($1)`
+		if c.softDelete {
+			query = `UPDATE ` + c.table + ` SET status = 'DELETED', deleted_at = NOW(), update_time = NOW() WHERE id = ANY($1)`
+		}
+
+		var before map[string]sql.NullString
+		if c.audit.enabled {
+			var err error
+			if before, err = c.currentValues(ctx, exec, ids); err != nil {
+				return err
+			}
+		}
+
+		if _, err := exec.ExecContext(ctx, query, pq.Array(ids)); err != nil {
+			return fmt.Errorf("failed to delete records: %w", err)
+		}
+
+		if c.audit.enabled {
+			for _, id := range ids {
+				if err := c.writeAudit(ctx, exec, id, AuditDelete, before[id], sql.NullString{}); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// DedupeLocalRecords 删除同一aliyun_record_id的重复行，只保留创建时间最早的一行，返回删除的行数
+// 重复行通常来自中途失败的同步，直接物理删除而不是软删除
+func (c *PostgresClient) DedupeLocalRecords(ctx context.Context, domainID, source string) (int, error) {
+	query := `SELECT id, aliyun_record_id FROM ` + c.table + `
+			  WHERE domain_id = $1 AND source = $2 AND aliyun_record_id IS NOT NULL
+			  ORDER BY aliyun_record_id, create_time, id`
+
+	var ids []string
+	err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		rows, err := c.db.QueryContext(ctx, query, domainID, source)
+		if err != nil {
+			return fmt.Errorf("failed to query local records: %w", err)
+		}
+		defer rows.Close()
+
+		ids, err = duplicateIDs(rows)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, id := range ids {
+		err := withAudit(ctx, c.db, c.audit, func(exec execer) error {
+			return c.purgeRecord(ctx, exec, id)
+		})
+		if err != nil {
+			return removed, fmt.Errorf("failed to remove duplicate record %s: %w", id, err)
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// purgeRecord 物理删除单条记录，开启审计时记录DELETE
+func (c *PostgresClient) purgeRecord(ctx context.Context, exec execer, localID string) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		var before sql.NullString
+		if c.audit.enabled {
+			var err error
+			if before, err = c.currentValue(ctx, exec, localID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := exec.ExecContext(ctx, `DELETE FROM `+c.table+` WHERE id = $1`, localID); err != nil {
+			return err
+		}
+
+		if c.audit.enabled {
+			return c.writeAudit(ctx, exec, localID, AuditDelete, before, sql.NullString{})
+		}
+
+		return nil
+	})
+}
+
+// RebuildDomainTx 在单个事务中清除域名下source的全部记录并重新插入，见rebuildDomainTx
+func (c *PostgresClient) RebuildDomainTx(ctx context.Context, domainID, source string,
+	records []*models.AssetSubDomain) (*SyncResult, error) {
+	return rebuildDomainTx(ctx, c.db, c, domainID, source, records)
+}
+
+// clearDomainRecords 物理删除指定域名下source的全部记录，返回删除的行数
+// 开启审计时逐条删除以便记录每一行的DELETE
+func (c *PostgresClient) clearDomainRecords(ctx context.Context, exec execer, domainID, source string) (int, error) {
+	if c.audit.enabled {
+		return clearRecordsByID(ctx, exec, `SELECT id FROM `+c.table+` WHERE domain_id = $1 AND source = $2`,
+			[]interface{}{domainID, source}, c.purgeRecord)
+	}
+
+	var rowsAffected int64
+	err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		result, err := exec.ExecContext(ctx, `DELETE FROM `+c.table+` WHERE domain_id = $1 AND source = $2`,
+			domainID, source)
+		if err != nil {
+			return err
+		}
+		rowsAffected, _ = result.RowsAffected()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rowsAffected), nil
+}
+
+// BatchUpsert 使用多行INSERT ... ON CONFLICT分批写入记录
+// 与insertRecord使用相同的冲突目标(source, domain_id, aliyun_record_id)：已有行更新，新记录使用新ID插入；
+// 不会改写aliyun_record_id，重新关联等RecordId变化的更新需要使用UpdateRecord。
+// 每批单独提交，出错时返回已成功写入的记录数
+func (c *PostgresClient) BatchUpsert(ctx context.Context, records []*models.AssetSubDomain, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	for _, record := range records {
+		if record.ID == "" {
+			id, err := c.idGen.NextIDString()
+			if err != nil {
+				return 0, fmt.Errorf("failed to generate ID: %w", err)
+			}
+			record.ID = id
+		}
+	}
+
+	written := 0
+	for start := 0; start < len(records); start += batchSize {
+		end := start + batchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		chunk := records[start:end]
+
+		err := withAudit(ctx, c.db, c.audit, func(exec execer) error {
+			return c.upsertChunk(ctx, exec, chunk)
+		})
+		if err != nil {
+			return written, fmt.Errorf("failed to upsert records %d-%d: %w", start, end, err)
+		}
+		written += len(chunk)
+	}
+
+	return written, nil
+}
+
+// buildUpsertQuery 构建一批记录的多行upsert语句
+func (c *PostgresClient) buildUpsertQuery(records []*models.AssetSubDomain) (string, []interface{}) {
+	rows := make([]string, 0, len(records))
+	args := make([]interface{}, 0, len(records)*len(recordColumns))
+	for _, record := range records {
+		placeholders := make([]string, len(recordColumns))
+		for i := range placeholders {
+			placeholders[i] = "$" + strconv.Itoa(len(args)+i+1)
+		}
+		rows = append(rows, "("+strings.Join(placeholders, ", ")+")")
+		args = append(args, recordValues(record)...)
+	}
+
+	query := "INSERT INTO " + c.table + " (" + strings.Join(recordColumns, ", ") + ") VALUES " +
+		strings.Join(rows, ", ") + " ON CONFLICT (source, domain_id, aliyun_record_id) DO UPDATE SET " + c.upsertAssignments()
+
+	return query, args
+}
+
+// upsertChunk 写入一批记录，开启审计时同时为每条记录写入审计行
+// 主键由数据库生成的新记录无法在多行语句中读回ID，逐条插入
+func (c *PostgresClient) upsertChunk(ctx context.Context, exec execer, records []*models.AssetSubDomain) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		records, err := insertAutoIDRecords(ctx, exec, c, records)
+		if err != nil || len(records) == 0 {
+			return err
+		}
+
+		var before map[string]sql.NullString
+		if c.audit.enabled {
+			if before, err = c.currentValues(ctx, exec, recordIDs(records)); err != nil {
+				return err
+			}
+		}
+
+		query, args := c.buildUpsertQuery(records)
+		if _, err := exec.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+
+		if !c.audit.enabled {
+			return nil
+		}
+		for _, record := range records {
+			action := AuditInsert
+			value, exists := before[record.ID]
+			if exists {
+				action = AuditUpdate
+			}
+			err := c.writeAudit(ctx, exec, record.ID, action, value, auditValue(record.DNSRecord))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// currentValue 查询记录当前的dns_record，记录不存在时返回无效值
+func (c *PostgresClient) currentValue(ctx context.Context, exec execer, localID string) (sql.NullString, error) {
+	var value sql.NullString
+	err := exec.QueryRowContext(ctx, `SELECT dns_record FROM `+c.table+` WHERE id = $1`, localID).Scan(&value)
+	if err == sql.ErrNoRows {
+		return sql.NullString{}, nil
+	}
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to query current record value: %w", err)
+	}
+
+	return value, nil
+}
+
+// currentValues 批量查询已存在记录当前的dns_record，以本地ID为键
+func (c *PostgresClient) currentValues(ctx context.Context, exec execer, ids []string) (map[string]sql.NullString, error) {
+	rows, err := exec.QueryContext(ctx,
+		`SELECT id, dns_record FROM `+c.table+` WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query current record values: %w", err)
+	}
+	defer rows.Close()
+
+	values := make(map[string]sql.NullString)
+	for rows.Next() {
+		var id string
+		var value sql.NullString
+		if err := rows.Scan(&id, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan current record value: %w", err)
+		}
+		values[id] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate current record values: %w", err)
+	}
+
+	return values, nil
+}
+
+// writeAudit 写入一条审计记录
+func (c *PostgresClient) writeAudit(ctx context.Context, exec execer, localID, action string, before, after sql.NullString) error {
+	query := `INSERT INTO ` + c.historyTable() + ` (record_id, action, before_value, after_value, run_id, create_time)
+			  VALUES ($1, $2, $3, $4, $5, NOW())`
+
+	if _, err := exec.ExecContext(ctx, query, localID, action, before, after, c.audit.runID); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+
+	return nil
+}
+
+// SyncDomainTx 在单个事务中执行一个域名的全部变更
+// PostgreSQL事务中任一语句失败后整个事务都不可再用，stopOnError为false时每项变更在保存点中执行，
+// 失败的变更回滚到保存点后继续执行其余变更，以便记录全部错误，最终仍然整体回滚
+func (c *PostgresClient) SyncDomainTx(ctx context.Context, changes *SyncChanges, stopOnError bool) (*SyncResult, error) {
+	return syncChangesTx(ctx, c.db, c, changes, stopOnError, true)
+}
+
+// GetPendingPushRecords 获取需要推送到服务商的本地记录
+func (c *PostgresClient) GetPendingPushRecords(ctx context.Context, domainID, source string) ([]*models.AssetSubDomain, error) {
+	query := `SELECT id, sub_domain, type, dns_record, ttl, priority, line
+			  FROM ` + c.table + `
+			  WHERE domain_id = $1 AND source = $2 AND aliyun_record_id IS NULL`
+	if c.softDelete {
+		query += " AND (status IS NULL OR status <> 'DELETED')"
+	}
+
+	var records []*models.AssetSubDomain
+	err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		rows, err := c.db.QueryContext(ctx, query, domainID, source)
+		if err != nil {
+			return fmt.Errorf("failed to query pending push records: %w", err)
+		}
+		defer rows.Close()
+
+		records, err = scanPendingPushRecords(rows)
+		return err
+	})
+	return records, err
+}
+
+// MarkRecordPushed 推送成功后回写RecordId，并将来源改为同步来源
+func (c *PostgresClient) MarkRecordPushed(ctx context.Context, localID, recordID, source string) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		query := `UPDATE ` + c.table + `
+				  SET aliyun_record_id = $1, source = $2, update_time = NOW()
+				  WHERE id = $3`
+
+		if _, err := c.db.ExecContext(ctx, query, recordID, source, localID); err != nil {
+			return fmt.Errorf("failed to mark record pushed: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetWatermark 获取域名在该来源的同步水位，sync_state中没有时返回0
+func (c *PostgresClient) GetWatermark(ctx context.Context, domainID, source string) (int64, error) {
+	query := `SELECT watermark FROM sync_state WHERE source = $1 AND domain_id = $2`
+
+	var watermark int64
+	err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		return c.db.QueryRowContext(ctx, query, source, domainID).Scan(&watermark)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get watermark: %w", err)
+	}
+
+	return watermark, nil
+}
+
+// SaveWatermark 保存域名在该来源的同步水位
+func (c *PostgresClient) SaveWatermark(ctx context.Context, domainID, source string, watermark int64) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		query := `INSERT INTO sync_state (source, domain_id, watermark, update_time) VALUES ($1, $2, $3, NOW())
+				  ON CONFLICT (source, domain_id) DO UPDATE SET watermark = EXCLUDED.watermark, update_time = EXCLUDED.update_time`
+
+		if _, err := c.db.ExecContext(ctx, query, source, domainID, watermark); err != nil {
+			return fmt.Errorf("failed to save watermark: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetRecentDeletes 获取域名最近runs次同步的删除记录数，sync_metrics中没有该域名时返回空
+func (c *PostgresClient) GetRecentDeletes(ctx context.Context, domainID string, runs int) ([]int, error) {
+	query := `SELECT deleted FROM sync_metrics WHERE domain_id = $1 ORDER BY id DESC LIMIT $2`
+
+	var deletes []int
+	err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		rows, err := c.db.QueryContext(ctx, query, domainID, runs)
+		if err != nil {
+			return fmt.Errorf("failed to query sync metrics: %w", err)
+		}
+		defer rows.Close()
+
+		deletes, err = scanDeletes(rows)
+		return err
+	})
+	return deletes, err
+}
+
+// SaveSyncMetrics 保存域名本次同步的变更数量
+func (c *PostgresClient) SaveSyncMetrics(ctx context.Context, domainID string, added, updated, deleted int) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		query := `INSERT INTO sync_metrics (domain_id, added, updated, deleted, create_time) VALUES ($1, $2, $3, $4, NOW())`
+
+		if _, err := c.db.ExecContext(ctx, query, domainID, added, updated, deleted); err != nil {
+			return fmt.Errorf("failed to save sync metrics: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// SaveResolutionStatus 按检查结果更新resolution_status，不修改update_time
+func (c *PostgresClient) SaveResolutionStatus(ctx context.Context, statuses map[string][]string) error {
+	query := `UPDATE ` + c.table + ` SET resolution_status = $1 WHERE id = ANY($2)`
+	for status, ids := range statuses {
+		err := withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+			_, err := c.db.ExecContext(ctx, query, status, pq.Array(ids))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save resolution status: %w", err)
+		}
+	}
+	return nil
+}
+
+// ExecPostSync 执行同步后的语句
+func (c *PostgresClient) ExecPostSync(ctx context.Context, statements []string, totals PostSyncTotals) error {
+	return withQueryTimeout(ctx, c.queryTimeout, func(ctx context.Context) error {
+		return execPostSync(ctx, c.db, statements, totals)
+	})
+}
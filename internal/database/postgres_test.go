@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"dns-sync/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// newMockPostgres 创建连接到sqlmock的PostgreSQL客户端，测试结束时检查预期的语句都已执行
+func newMockPostgres(t *testing.T) (*PostgresClient, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return &PostgresClient{db: db, table: "asset_sub_domain"}, mock
+}
+
+// TestPostgresWatermark 水位按来源和domain_id读写，冲突目标与主键一致
+func TestPostgresWatermark(t *testing.T) {
+	client, mock := newMockPostgres(t)
+	ctx := context.Background()
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO sync_state (source, domain_id, watermark, update_time) VALUES ($1, $2, $3, NOW())")+
+		".*"+regexp.QuoteMeta("ON CONFLICT (source, domain_id)")).
+		WithArgs("Aliyun-DNS-Sync", "domain-1", int64(1700000000000)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT watermark FROM sync_state WHERE source = $1 AND domain_id = $2")).
+		WithArgs("Cloudflare-DNS-Sync", "domain-1").
+		WillReturnRows(sqlmock.NewRows([]string{"watermark"}))
+
+	if err := client.SaveWatermark(ctx, "domain-1", "Aliyun-DNS-Sync", 1700000000000); err != nil {
+		t.Fatalf("SaveWatermark() error = %v", err)
+	}
+	if got, err := client.GetWatermark(ctx, "domain-1", "Cloudflare-DNS-Sync"); err != nil || got != 0 {
+		t.Errorf("GetWatermark() = %d, %v, want 0 for another source", got, err)
+	}
+}
+
+func TestPostgresBuildUpsertQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows       int
+		softDelete bool
+	}{
+		{name: "single row", rows: 1},
+		{name: "multiple rows", rows: 3},
+		{name: "soft delete restores rows", rows: 2, softDelete: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &PostgresClient{table: "asset_sub_domain", softDelete: tt.softDelete}
+			query, args := client.buildUpsertQuery(testAssets(tt.rows))
+
+			if want := tt.rows * len(recordColumns); len(args) != want {
+				t.Errorf("got %d args, want %d", len(args), want)
+			}
+			// 占位符连续编号，最后一个为$len(args)
+			last := "$" + strconv.Itoa(len(args)) + ")"
+			if !strings.Contains(query, last) || strings.Contains(query, "$"+strconv.Itoa(len(args)+1)) {
+				t.Errorf("placeholders are not numbered $1..$%d", len(args))
+			}
+
+			// 单条和批量写入必须使用同一个冲突目标
+			_, updates, ok := strings.Cut(query, " ON CONFLICT (source, domain_id, aliyun_record_id) DO UPDATE SET ")
+			if !ok {
+				t.Fatalf("query does not conflict on (source, domain_id, aliyun_record_id): %s", query)
+			}
+			for _, column := range []string{"source", "domain_id", "aliyun_record_id", "id"} {
+				if strings.Contains(updates, " "+column+" = ") || strings.HasPrefix(updates, column+" = ") {
+					t.Errorf("update clause overwrites key column %s", column)
+				}
+			}
+			if got := strings.Contains(updates, "deleted_at = NULL"); got != tt.softDelete {
+				t.Errorf("deleted_at restore = %v, want %v", got, tt.softDelete)
+			}
+		})
+	}
+}
+
+// TestPostgresConfiguredTable 语句和审计历史表都使用配置的表名，information_schema按小写的表名查询
+func TestPostgresConfiguredTable(t *testing.T) {
+	client, mock := newMockPostgres(t)
+	client.table = "Tenant_Assets"
+	client.softDelete = true
+	client.audit = auditConfig{enabled: true, runID: "run-1"}
+	ctx := context.Background()
+
+	mock.ExpectQuery(`(?s)FROM information_schema\.tables.*table_name = lower\(\$1\)`).
+		WithArgs("Tenant_Assets").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT dns_record FROM Tenant_Assets WHERE id = $1")).
+		WithArgs("id-1").
+		WillReturnRows(sqlmock.NewRows([]string{"dns_record"}).AddRow("10.0.0.1"))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE Tenant_Assets SET status = 'DELETED'")).
+		WithArgs("id-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO Tenant_Assets_history (record_id")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := client.CheckTableExists(ctx); err != nil {
+		t.Fatalf("CheckTableExists() error = %v", err)
+	}
+	if err := client.deleteRecord(ctx, client.db, "id-1"); err != nil {
+		t.Fatalf("deleteRecord() error = %v", err)
+	}
+
+	query, _ := client.buildUpsertQuery(testAssets(1))
+	if !strings.HasPrefix(query, "INSERT INTO Tenant_Assets (") {
+		t.Errorf("upsert query does not use the configured table: %.60s", query)
+	}
+}
+
+// TestPostgresRecordStatements 单条写入、更新、删除和查询都使用$N占位符，插入按唯一索引upsert并通过RETURNING读回ID
+func TestPostgresRecordStatements(t *testing.T) {
+	client, mock := newMockPostgres(t)
+	client.idGen = &timestampID{}
+	ctx := context.Background()
+	record := testAssets(1)[0]
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO asset_sub_domain (id, ") + `.*` +
+		regexp.QuoteMeta("VALUES ($1, $2, ") + `.*` +
+		regexp.QuoteMeta(") ON CONFLICT (source, domain_id, aliyun_record_id) DO UPDATE SET ") + `.*` +
+		regexp.QuoteMeta(" RETURNING id, (xmax = 0)")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "inserted"}).AddRow("existing-1", false))
+	mock.ExpectExec(`(?s)UPDATE asset_sub_domain\s+SET sub_domain = \$1, .* update_time = \$16\s+WHERE id = \$17`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM asset_sub_domain WHERE id = $1")).
+		WithArgs("existing-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM asset_sub_domain WHERE id = ANY($1)")).
+		WithArgs("{\"id-1\",\"id-2\"}").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(`(?s)SELECT id, sub_domain, .* FROM asset_sub_domain\s+WHERE domain_id = \$1 AND source = \$2 AND aliyun_record_id IS NOT NULL ORDER BY create_time, id`).
+		WithArgs("domain-1", "Aliyun-DNS-Sync").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sub_domain", "type", "dns_record", "aliyun_record_id",
+			"create_time", "update_time", "ttl", "weight", "priority", "line", "content_hash", "status", "rr",
+			"domain_name", "remark", "line_name"}).
+			AddRow("existing-1", "host0.example.com", "A", "10.0.0.1", "1000", now, now, 600, 0, 0, "default",
+				"hash", "ENABLE", "host0", "example.com", "", "默认"))
+
+	if err := client.insertRecord(ctx, client.db, record); err != nil {
+		t.Fatalf("insertRecord() error = %v", err)
+	}
+	// 冲突时RETURNING返回已有行的ID
+	if record.ID != "existing-1" {
+		t.Errorf("record.ID = %q, want existing-1", record.ID)
+	}
+	if err := client.updateRecord(ctx, client.db, record.ID, &models.DNSRecord{DomainName: "example.com", RR: "host0",
+		RecordId: "1000", Type: "A", Value: "10.0.0.1", TTL: 600, Line: "default", Status: "ENABLE"}); err != nil {
+		t.Fatalf("updateRecord() error = %v", err)
+	}
+	if err := client.deleteRecord(ctx, client.db, record.ID); err != nil {
+		t.Fatalf("deleteRecord() error = %v", err)
+	}
+	if err := client.deleteChunk(ctx, client.db, []string{"id-1", "id-2"}); err != nil {
+		t.Fatalf("deleteChunk() error = %v", err)
+	}
+	records, err := client.GetLocalRecords(ctx, "domain-1", "Aliyun-DNS-Sync")
+	if err != nil {
+		t.Fatalf("GetLocalRecords() error = %v", err)
+	}
+	if got := records["1000"]; got == nil || got.ID != "existing-1" || got.TTL != 600 {
+		t.Errorf("GetLocalRecords() = %+v", records)
+	}
+}
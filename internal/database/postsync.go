@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// PostSyncTotals 本轮同步的变更合计，用于替换post_sync_sql中的占位符
+type PostSyncTotals struct {
+	Added   int
+	Updated int
+	Deleted int
+	Pushed  int
+}
+
+// expandPostSyncSQL 将语句中的{added}、{updated}、{deleted}和{pushed}替换为本轮的合计
+// 替换的值都是整数，不会引入SQL注入
+func expandPostSyncSQL(statement string, totals PostSyncTotals) string {
+	return strings.NewReplacer(
+		"{added}", strconv.Itoa(totals.Added),
+		"{updated}", strconv.Itoa(totals.Updated),
+		"{deleted}", strconv.Itoa(totals.Deleted),
+		"{pushed}", strconv.Itoa(totals.Pushed),
+	).Replace(statement)
+}
+
+// execPostSync 在单个事务中依次执行同步后的语句，任一语句失败时整体回滚
+func execPostSync(ctx context.Context, db *sql.DB, statements []string, totals PostSyncTotals) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i, statement := range statements {
+		query := expandPostSyncSQL(statement, totals)
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("post_sync_sql statement %d failed: %w", i+1, err)
+		}
+		slog.Debug("Executed post-sync statement", "index", i+1, "query", query)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestExecPostSync 语句中的占位符替换为本轮合计后在同一事务中依次执行，任一语句失败时回滚并返回出错的序号
+func TestExecPostSync(t *testing.T) {
+	statements := []string{
+		"REFRESH summary SET added = {added}, updated = {updated}",
+		"INSERT INTO sync_log (deleted, pushed, total) VALUES ({deleted}, {pushed}, {added} + {updated})",
+	}
+	want := []string{
+		"REFRESH summary SET added = 5, updated = 2",
+		"INSERT INTO sync_log (deleted, pushed, total) VALUES (3, 1, 5 + 2)",
+	}
+	totals := PostSyncTotals{Added: 5, Updated: 2, Deleted: 3, Pushed: 1}
+
+	tests := []struct {
+		name    string
+		failAt  int
+		wantErr string
+	}{
+		{name: "all statements"},
+		{name: "second statement fails", failAt: 2, wantErr: "post_sync_sql statement 2 failed: relation does not exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mock := newMockMySQL(t)
+			mock.ExpectBegin()
+			for i, query := range want {
+				exec := mock.ExpectExec("^" + regexp.QuoteMeta(query) + "$").WithoutArgs()
+				if i+1 == tt.failAt {
+					exec.WillReturnError(errors.New("relation does not exist"))
+					break
+				}
+				exec.WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			if tt.failAt > 0 {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectCommit()
+			}
+
+			err := client.ExecPostSync(context.Background(), statements, totals)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ExecPostSync() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExecPostSync() error = %v", err)
+			}
+		})
+	}
+}
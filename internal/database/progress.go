@@ -0,0 +1,71 @@
+package database
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Progress 单个域名写入变更的进度，每处理every条记录或距上次输出超过interval时输出一次进度日志
+// 每个域名使用独立的Progress，内部加锁，可以在并发写入时共用；nil表示不输出进度
+type Progress struct {
+	domain   string
+	total    int
+	every    int
+	interval time.Duration
+
+	mu            sync.Mutex
+	processed     int
+	result        SyncResult
+	lastProcessed int
+	lastLog       time.Time
+}
+
+// NewProgress 创建进度记录，total为待处理的变更总数；every和interval都不大于0时返回nil，不输出进度
+func NewProgress(domain string, total, every int, interval time.Duration) *Progress {
+	if every <= 0 && interval <= 0 {
+		return nil
+	}
+	return &Progress{
+		domain:   domain,
+		total:    total,
+		every:    every,
+		interval: interval,
+		lastLog:  time.Now(),
+	}
+}
+
+// Step 记录一批处理完的变更，失败的记录计入已处理数但不计入变更数
+func (p *Progress) Step(added, updated, deleted, failed int) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.processed += added + updated + deleted + failed
+	p.result.Added += added
+	p.result.Updated += updated
+	p.result.Deleted += deleted
+
+	if !p.due(time.Now()) {
+		return
+	}
+	p.lastProcessed = p.processed
+	p.lastLog = time.Now()
+
+	slog.Info("Sync progress", "domain", p.domain, "processed", p.processed, "total", p.total,
+		"added", p.result.Added, "updated", p.result.Updated, "deleted", p.result.Deleted)
+}
+
+// due 判断是否需要输出进度，全部处理完时不再输出，由调用方输出完成日志
+func (p *Progress) due(now time.Time) bool {
+	if p.processed >= p.total {
+		return false
+	}
+	if p.every > 0 && p.processed-p.lastProcessed >= p.every {
+		return true
+	}
+	return p.interval > 0 && now.Sub(p.lastLog) >= p.interval
+}
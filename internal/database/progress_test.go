@@ -0,0 +1,106 @@
+package database
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// captureLogs 将默认日志输出到缓冲区，测试结束时恢复
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf syncBuffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf.buf
+}
+
+// syncBuffer 可并发写入的缓冲区
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// TestProgressEvery 按处理数输出进度的次数，处理完全部变更时不再输出，多个goroutine共用时次数不变
+func TestProgressEvery(t *testing.T) {
+	tests := []struct {
+		name       string
+		total      int
+		every      int
+		batch      int
+		goroutines int
+		want       int
+	}{
+		{name: "one by one", total: 2000, every: 500, batch: 1, goroutines: 1, want: 3},
+		{name: "not a multiple", total: 10, every: 3, batch: 1, goroutines: 1, want: 3},
+		{name: "batches larger than every", total: 20, every: 5, batch: 4, goroutines: 1, want: 2},
+		{name: "fewer than every", total: 4, every: 5, batch: 1, goroutines: 1, want: 0},
+		{name: "concurrent", total: 2000, every: 500, batch: 1, goroutines: 4, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			progress := NewProgress("example.com", tt.total, tt.every, 0)
+
+			var wg sync.WaitGroup
+			steps := tt.total / tt.batch / tt.goroutines
+			for g := 0; g < tt.goroutines; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < steps; i++ {
+						progress.Step(tt.batch, 0, 0, 0)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := strings.Count(logs.String(), "Sync progress"); got != tt.want {
+				t.Errorf("progress logged %d times, want %d:\n%s", got, tt.want, logs)
+			}
+		})
+	}
+}
+
+// TestProgressCounts 进度日志中的处理数包含失败的记录，变更数按类型分别累计
+func TestProgressCounts(t *testing.T) {
+	logs := captureLogs(t)
+	progress := NewProgress("example.com", 2000, 500, 0)
+	progress.Step(300, 100, 50, 50)
+
+	want := "domain=example.com processed=500 total=2000 added=300 updated=100 deleted=50"
+	if !strings.Contains(logs.String(), want) {
+		t.Errorf("progress log = %q, want it to contain %q", logs, want)
+	}
+}
+
+// TestProgressInterval 距上次输出超过interval时输出进度；every和interval都未设置时不记录进度
+func TestProgressInterval(t *testing.T) {
+	logs := captureLogs(t)
+	progress := NewProgress("example.com", 100, 0, 20*time.Millisecond)
+	progress.Step(1, 0, 0, 0)
+	time.Sleep(30 * time.Millisecond)
+	progress.Step(1, 0, 0, 0)
+	progress.Step(1, 0, 0, 0)
+
+	if got := strings.Count(logs.String(), "Sync progress"); got != 1 {
+		t.Errorf("progress logged %d times, want 1", got)
+	}
+
+	if progress := NewProgress("example.com", 100, 0, 0); progress != nil {
+		t.Fatalf("NewProgress() = %+v, want nil", progress)
+	}
+	var disabled *Progress
+	disabled.Step(1, 0, 0, 0)
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"dns-sync/internal/models"
+)
+
+// domainRebuilder 重建域名记录需要的写操作，由各数据库客户端实现
+type domainRebuilder interface {
+	recordWriter
+	clearDomainRecords(ctx context.Context, exec execer, domainID, source string) (int, error)
+}
+
+// rebuildDomainTx 在单个事务中清除域名下指定source的全部记录（包括已软删除的记录）并插入records
+// 任一步骤失败时整体回滚，其它source的记录不受影响，重建过程中其它连接始终能读到完整的旧数据
+func rebuildDomainTx(ctx context.Context, db *sql.DB, rebuilder domainRebuilder, domainID, source string,
+	records []*models.AssetSubDomain) (*SyncResult, error) {
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deleted, err := rebuilder.clearDomainRecords(ctx, tx, domainID, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear domain records: %w", err)
+	}
+
+	for _, record := range records {
+		if err := rebuilder.insertRecord(ctx, tx, record); err != nil {
+			return nil, fmt.Errorf("failed to insert %s: %w", record.SubDomain, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Info("Rebuilt domain records", "domain_id", domainID, "source", source, "deleted", deleted,
+		"inserted", len(records))
+	return &SyncResult{Added: len(records), Deleted: deleted}, nil
+}
+
+// clearRecordsByID 逐条物理删除query查出的记录，purge负责写入审计行
+func clearRecordsByID(ctx context.Context, exec execer, query string, args []interface{},
+	purge func(ctx context.Context, exec execer, localID string) error) (int, error) {
+
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query local records: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan record: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate records: %w", err)
+	}
+
+	for _, id := range ids {
+		if err := purge(ctx, exec, id); err != nil {
+			return 0, fmt.Errorf("failed to delete record %s: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
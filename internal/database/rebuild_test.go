@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeRebuilder 在fakeWriter的基础上按来源清除行，sources为行ID到来源的映射
+type fakeRebuilder struct {
+	fakeWriter
+	sources map[string]string
+}
+
+func (r fakeRebuilder) clearDomainRecords(ctx context.Context, exec execer, domainID, source string) (int, error) {
+	deleted := 0
+	for id, rowSource := range r.sources {
+		if rowSource != source {
+			continue
+		}
+		if _, err := exec.ExecContext(ctx, "DELETE "+id); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// TestRebuildDomainTx 重建在一个事务中替换该来源的行，任一插入失败时整体回滚，其它来源的行不受影响
+func TestRebuildDomainTx(t *testing.T) {
+	tests := []struct {
+		name     string
+		failOn   []string
+		wantErr  string
+		wantRows []string
+	}{
+		{
+			name:     "replaces rows of the source",
+			wantRows: []string{"cf-1", "id-0", "id-1", "id-2"},
+		},
+		{
+			name:     "insert failure rolls back",
+			failOn:   []string{"id-2"},
+			wantErr:  "failed to insert host2.example.com",
+			wantRows: []string{"aliyun-1", "aliyun-2", "cf-1"},
+		},
+		{
+			name:     "clear failure rolls back",
+			failOn:   []string{"aliyun-2"},
+			wantErr:  "failed to clear domain records",
+			wantRows: []string{"aliyun-1", "aliyun-2", "cf-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &fakeTable{
+				rows:   map[string]bool{"aliyun-1": true, "aliyun-2": true, "cf-1": true},
+				failOn: make(map[string]bool),
+			}
+			for _, id := range tt.failOn {
+				table.failOn[id] = true
+			}
+			db := sql.OpenDB(table)
+			defer db.Close()
+
+			rebuilder := fakeRebuilder{sources: map[string]string{
+				"aliyun-1": "Aliyun-DNS-Sync",
+				"aliyun-2": "Aliyun-DNS-Sync",
+				"cf-1":     "Cloudflare-DNS-Sync",
+			}}
+			result, err := rebuildDomainTx(context.Background(), db, rebuilder, "domain-1", "Aliyun-DNS-Sync", testAssets(3))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("rebuildDomainTx() error = %v", err)
+				}
+				if result.Added != 3 || result.Deleted != 2 {
+					t.Errorf("result = %+v, want 3 added, 2 deleted", result)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("rebuildDomainTx() error = %v, want %q", err, tt.wantErr)
+			}
+
+			var rows []string
+			for id := range table.rows {
+				rows = append(rows, id)
+			}
+			sort.Strings(rows)
+			if got, want := strings.Join(rows, ","), strings.Join(tt.wantRows, ","); got != want {
+				t.Errorf("rows after rebuild = %s, want %s", got, want)
+			}
+		})
+	}
+}
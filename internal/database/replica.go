@@ -0,0 +1,18 @@
+package database
+
+import "context"
+
+// primaryKey 上下文中标记读操作必须使用主库的键
+type primaryKey struct{}
+
+// WithPrimary 返回要求后续读操作使用主库的上下文
+// 同一域名刚写入过数据时使用，避免从库复制延迟导致读到旧数据
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// usePrimary 判断上下文是否要求读操作使用主库
+func usePrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
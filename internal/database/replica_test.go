@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// localRecordColumns GetLocalRecords查询返回的列
+var localRecordColumns = []string{"id", "sub_domain", "type", "dns_record", "aliyun_record_id", "create_time",
+	"update_time", "ttl", "weight", "priority", "line", "content_hash", "status", "rr", "domain_name", "remark",
+	"line_name"}
+
+// TestMySQLReadReplica 配置了只读副本时读操作使用副本，写操作和WithPrimary的读操作使用主库，未配置副本时都使用主库
+func TestMySQLReadReplica(t *testing.T) {
+	tests := []struct {
+		name        string
+		replica     bool
+		primary     bool
+		wantReplica bool
+	}{
+		{name: "no replica"},
+		{name: "replica", replica: true, wantReplica: true},
+		{name: "read after write", replica: true, primary: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, primaryMock := newMockMySQL(t)
+			readMock := primaryMock
+			if tt.replica {
+				replica, replicaMock, err := sqlmock.New()
+				if err != nil {
+					t.Fatalf("sqlmock.New() error = %v", err)
+				}
+				t.Cleanup(func() {
+					if err := replicaMock.ExpectationsWereMet(); err != nil {
+						t.Error(err)
+					}
+					replica.Close()
+				})
+				client.replica = replica
+				if tt.wantReplica {
+					readMock = replicaMock
+				}
+			}
+
+			ctx := context.Background()
+			if tt.primary {
+				ctx = WithPrimary(ctx)
+			}
+			// 写操作始终在主库执行，主库和副本的语句分别按顺序匹配
+			primaryMock.ExpectExec(regexp.QuoteMeta("DELETE FROM asset_sub_domain WHERE id = ?")).
+				WithArgs("id-1").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			readMock.ExpectQuery(regexp.QuoteMeta("SELECT id, sub_domain, ")).
+				WithArgs("domain-1", "Aliyun-DNS-Sync").
+				WillReturnRows(sqlmock.NewRows(localRecordColumns))
+			readMock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM asset_sub_domain WHERE domain_id = ? AND source = ?")).
+				WithArgs("domain-1", "Aliyun-DNS-Sync").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+			readMock.ExpectQuery(regexp.QuoteMeta("SELECT watermark FROM sync_state WHERE source = ? AND domain_id = ?")).
+				WithArgs("Aliyun-DNS-Sync", "domain-1").
+				WillReturnRows(sqlmock.NewRows([]string{"watermark"}).AddRow(int64(1700000000000)))
+
+			if err := client.deleteRecord(ctx, client.db, "id-1"); err != nil {
+				t.Fatalf("deleteRecord() error = %v", err)
+			}
+			if _, err := client.GetLocalRecords(ctx, "domain-1", "Aliyun-DNS-Sync"); err != nil {
+				t.Fatalf("GetLocalRecords() error = %v", err)
+			}
+			if count, err := client.GetRecordCount(ctx, "domain-1", "Aliyun-DNS-Sync"); err != nil || count != 3 {
+				t.Fatalf("GetRecordCount() = %d, %v, want 3", count, err)
+			}
+			if watermark, err := client.GetWatermark(ctx, "domain-1", "Aliyun-DNS-Sync"); err != nil ||
+				watermark != 1700000000000 {
+				t.Fatalf("GetWatermark() = %d, %v, want 1700000000000", watermark, err)
+			}
+		})
+	}
+}
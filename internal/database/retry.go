@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// retryBackoff 第一次重试前的等待时间，之后每次翻倍
+const retryBackoff = 200 * time.Millisecond
+
+// MySQL中可以重试的错误码
+const (
+	mysqlErrLockWaitTimeout = 1205
+	mysqlErrDeadlock        = 1213
+)
+
+// withRetry 执行fn，遇到临时性错误时等待后先Ping数据库重新建立连接再重试，最多重试maxRetries次
+// 约束冲突等非临时性错误直接返回
+func withRetry(ctx context.Context, db *sql.DB, maxRetries int, fn func() error) error {
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxRetries || !isTransientMySQLError(err) {
+			return err
+		}
+
+		slog.Warn("Transient database error, retrying", "attempt", attempt+1, "max_retries", maxRetries,
+			"backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (retry aborted: %v)", err, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		// 连接已断开时Ping会从连接池中建立新连接，Ping失败时仍继续重试，由下一次执行返回错误
+		if pingErr := db.PingContext(ctx); pingErr != nil {
+			slog.Warn("Database ping failed before retry", "error", pingErr)
+		}
+	}
+}
+
+// isTransientMySQLError 判断是否为锁等待超时、死锁或连接断开等可重试的错误
+// 超过query_timeout或上下文被取消不重试，否则慢查询会被重复执行
+func isTransientMySQLError(err error) bool {
+	if errors.Is(err, ErrQueryTimeout) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlErrLockWaitTimeout || mysqlErr.Number == mysqlErrDeadlock
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"strings"
+)
+
+// mysqlSchema MySQL建表语句
+//
+//go:embed schema/mysql.sql
+var mysqlSchema string
+
+// postgresSchema PostgreSQL建表语句
+//
+//go:embed schema/postgres.sql
+var postgresSchema string
+
+// initSchema 逐条执行建表语句，语句均为IF NOT EXISTS形式，可重复执行
+// MySQL驱动默认不允许一次执行多条语句，这里按分号拆分
+func initSchema(ctx context.Context, db *sql.DB, schema string) error {
+	for _, statement := range splitStatements(schema) {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to execute schema statement: %w", err)
+		}
+	}
+	return nil
+}
+
+// splitStatements 按行尾分号拆分SQL脚本，忽略注释行和空语句
+func splitStatements(schema string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(schema, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+
+	return statements
+}
@@ -0,0 +1,70 @@
+-- asset_sub_domain、审计历史表及同步状态表，可重复执行
+CREATE TABLE IF NOT EXISTS `asset_sub_domain` (
+  `id` varchar(50) NOT NULL COMMENT 'ID',
+  `sub_domain` varchar(255) DEFAULT NULL COMMENT '子域名',
+  `type` varchar(10) DEFAULT NULL COMMENT 'DNS记录类型',
+  `create_time` datetime DEFAULT NULL COMMENT '创建时间',
+  `update_by` varchar(50) DEFAULT NULL COMMENT '更新人',
+  `create_by` varchar(50) DEFAULT NULL COMMENT '创建人',
+  `update_time` datetime DEFAULT NULL COMMENT '更新时间',
+  `sys_org_code` varchar(50) DEFAULT NULL COMMENT '组织代码',
+  `dns_record` varchar(255) DEFAULT NULL COMMENT 'DNS记录',
+  `name_server` varchar(255) DEFAULT NULL COMMENT '域名服务器',
+  `asset_label` varchar(255) DEFAULT '' COMMENT '资产标签',
+  `asset_manager` varchar(50) DEFAULT NULL COMMENT '资产管理员',
+  `asset_department` varchar(100) DEFAULT NULL COMMENT '资产部门',
+  `level` varchar(20) DEFAULT NULL COMMENT '级别',
+  `domain_id` varchar(50) DEFAULT NULL COMMENT '域名ID',
+  `source` varchar(50) DEFAULT NULL COMMENT '数据来源',
+  `project_id` varchar(50) DEFAULT NULL COMMENT '项目ID',
+  `aliyun_record_id` varchar(50) DEFAULT NULL COMMENT '阿里云记录ID',
+  `ttl` int DEFAULT NULL COMMENT 'TTL',
+  `weight` int DEFAULT NULL COMMENT '权重',
+  `priority` int DEFAULT NULL COMMENT 'MX优先级',
+  `line` varchar(50) DEFAULT NULL COMMENT '解析线路',
+  `line_name` varchar(100) DEFAULT NULL COMMENT '解析线路名称',
+  `content_hash` char(40) DEFAULT NULL COMMENT '记录内容哈希',
+  `status` varchar(20) DEFAULT NULL COMMENT '记录状态',
+  `rr` varchar(255) DEFAULT NULL COMMENT '主机记录',
+  `domain_name` varchar(255) DEFAULT NULL COMMENT '主域名',
+  `remark` varchar(500) DEFAULT NULL COMMENT '记录备注',
+  `raw_record` json DEFAULT NULL COMMENT '服务商原始记录',
+  `resolution_status` varchar(20) DEFAULT NULL COMMENT '解析检查结果',
+  `deleted_at` datetime DEFAULT NULL COMMENT '删除时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_aliyun_record_id` (`source`, `domain_id`, `aliyun_record_id`),
+  KEY `idx_domain_id` (`domain_id`),
+  KEY `idx_project_id` (`project_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='子域名资产表';
+
+CREATE TABLE IF NOT EXISTS `asset_sub_domain_history` (
+  `id` bigint NOT NULL AUTO_INCREMENT COMMENT 'ID',
+  `record_id` varchar(50) NOT NULL COMMENT '子域名资产ID',
+  `action` varchar(10) NOT NULL COMMENT '操作：INSERT/UPDATE/DELETE',
+  `before_value` varchar(255) DEFAULT NULL COMMENT '变更前的DNS记录',
+  `after_value` varchar(255) DEFAULT NULL COMMENT '变更后的DNS记录',
+  `run_id` varchar(50) NOT NULL COMMENT '运行ID',
+  `create_time` datetime NOT NULL COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_record_id` (`record_id`),
+  KEY `idx_run_id` (`run_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='子域名资产变更历史';
+
+CREATE TABLE IF NOT EXISTS `sync_state` (
+  `source` varchar(50) NOT NULL COMMENT '数据来源',
+  `domain_id` varchar(50) NOT NULL COMMENT '域名ID',
+  `watermark` bigint NOT NULL COMMENT '已同步记录的最大UpdateTimestamp（毫秒）',
+  `update_time` datetime NOT NULL COMMENT '更新时间',
+  PRIMARY KEY (`source`, `domain_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='增量同步状态';
+
+CREATE TABLE IF NOT EXISTS `sync_metrics` (
+  `id` bigint NOT NULL AUTO_INCREMENT COMMENT 'ID',
+  `domain_id` varchar(50) NOT NULL COMMENT '域名ID',
+  `added` int NOT NULL COMMENT '新增记录数',
+  `updated` int NOT NULL COMMENT '更新记录数',
+  `deleted` int NOT NULL COMMENT '删除记录数',
+  `create_time` datetime NOT NULL COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_domain_id` (`domain_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='每次同步的变更数量';
@@ -0,0 +1,73 @@
+-- asset_sub_domain、审计历史表及同步状态表，可重复执行
+CREATE TABLE IF NOT EXISTS asset_sub_domain (
+  id varchar(50) PRIMARY KEY,
+  sub_domain varchar(255),
+  type varchar(10),
+  create_time timestamp,
+  update_by varchar(50),
+  create_by varchar(50),
+  update_time timestamp,
+  sys_org_code varchar(50),
+  dns_record varchar(255),
+  name_server varchar(255),
+  asset_label varchar(255) DEFAULT '',
+  asset_manager varchar(50),
+  asset_department varchar(100),
+  level varchar(20),
+  domain_id varchar(50),
+  source varchar(50),
+  project_id varchar(50),
+  aliyun_record_id varchar(50),
+  ttl integer,
+  weight integer,
+  priority integer,
+  line varchar(50),
+  line_name varchar(100),
+  content_hash char(40),
+  status varchar(20),
+  rr varchar(255),
+  domain_name varchar(255),
+  remark varchar(500),
+  raw_record jsonb,
+  resolution_status varchar(20),
+  deleted_at timestamp
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_asset_sub_domain_aliyun_record_id ON asset_sub_domain (source, domain_id, aliyun_record_id);
+
+CREATE INDEX IF NOT EXISTS idx_asset_sub_domain_domain_id ON asset_sub_domain (domain_id);
+
+CREATE INDEX IF NOT EXISTS idx_asset_sub_domain_project_id ON asset_sub_domain (project_id);
+
+CREATE TABLE IF NOT EXISTS asset_sub_domain_history (
+  id bigserial PRIMARY KEY,
+  record_id varchar(50) NOT NULL,
+  action varchar(10) NOT NULL,
+  before_value varchar(255),
+  after_value varchar(255),
+  run_id varchar(50) NOT NULL,
+  create_time timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_asset_sub_domain_history_record_id ON asset_sub_domain_history (record_id);
+
+CREATE INDEX IF NOT EXISTS idx_asset_sub_domain_history_run_id ON asset_sub_domain_history (run_id);
+
+CREATE TABLE IF NOT EXISTS sync_state (
+  source varchar(50) NOT NULL,
+  domain_id varchar(50) NOT NULL,
+  watermark bigint NOT NULL,
+  update_time timestamp NOT NULL,
+  PRIMARY KEY (source, domain_id)
+);
+
+CREATE TABLE IF NOT EXISTS sync_metrics (
+  id bigserial PRIMARY KEY,
+  domain_id varchar(50) NOT NULL,
+  added integer NOT NULL,
+  updated integer NOT NULL,
+  deleted integer NOT NULL,
+  create_time timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_metrics_domain_id ON sync_metrics (domain_id);
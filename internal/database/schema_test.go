@@ -0,0 +1,168 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestInitSchemaTableName --init-db按配置的表名建表，不再创建默认表名的表和索引
+func TestInitSchemaTableName(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver string
+		want   []string
+	}{
+		{name: "mysql", driver: "mysql",
+			want: []string{"CREATE TABLE IF NOT EXISTS `tenant_assets` (", "CREATE TABLE IF NOT EXISTS `tenant_assets_history` ("}},
+		{name: "postgres", driver: "postgres",
+			want: []string{"CREATE TABLE IF NOT EXISTS tenant_assets (", "CREATE TABLE IF NOT EXISTS tenant_assets_history (",
+				"CREATE INDEX IF NOT EXISTS idx_tenant_assets_history_run_id ON tenant_assets_history (run_id)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var statements []string
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(func(_, actual string) error {
+				statements = append(statements, actual)
+				return nil
+			})))
+			if err != nil {
+				t.Fatalf("sqlmock.New() error = %v", err)
+			}
+			defer db.Close()
+
+			schema := mysqlSchema
+			var initSchema func(context.Context) error = (&MySQLClient{db: db, table: "tenant_assets"}).InitSchema
+			if tt.driver == "postgres" {
+				schema = postgresSchema
+				initSchema = (&PostgresClient{db: db, table: "tenant_assets"}).InitSchema
+			}
+			for range splitStatements(schema) {
+				mock.ExpectExec("").WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+
+			if err := initSchema(context.Background()); err != nil {
+				t.Fatalf("InitSchema() error = %v", err)
+			}
+			all := strings.Join(statements, "\n")
+			if strings.Contains(all, "asset_sub_domain") {
+				t.Errorf("schema still references asset_sub_domain:\n%s", all)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(all, want) {
+					t.Errorf("schema does not contain %q", want)
+				}
+			}
+		})
+	}
+}
+
+// schemaColumns 取出建表语句中记录表的列名
+func schemaColumns(t *testing.T, schema string) []string {
+	t.Helper()
+	for _, statement := range splitStatements(schema) {
+		lines := strings.Split(statement, "\n")
+		if !strings.Contains(lines[0], "asset_sub_domain") || strings.Contains(lines[0], "history") {
+			continue
+		}
+		var columns []string
+		for _, line := range lines[1:] {
+			fields := strings.Fields(line)
+			if len(fields) < 2 || fields[0] == "PRIMARY" || fields[0] == "UNIQUE" || fields[0] == "KEY" || fields[0] == ")" {
+				continue
+			}
+			columns = append(columns, strings.Trim(fields[0], "`"))
+		}
+		return columns
+	}
+	t.Fatal("schema has no asset_sub_domain table")
+	return nil
+}
+
+// TestInitSchema 建表语句都可以重复执行，建出的表包含同步依赖的唯一索引和查询索引，建表后的列检查能够通过
+func TestInitSchema(t *testing.T) {
+	tests := []struct {
+		name        string
+		driver      string
+		wantIndexes []string
+		columnQuery string
+	}{
+		{
+			name:   "mysql",
+			driver: "mysql",
+			wantIndexes: []string{"UNIQUE KEY `uk_aliyun_record_id` (`source`, `domain_id`, `aliyun_record_id`)",
+				"KEY `idx_domain_id` (`domain_id`)", "PRIMARY KEY (`source`, `domain_id`)"},
+			columnQuery: `SELECT column_name FROM information_schema.columns`,
+		},
+		{
+			name:   "postgres",
+			driver: "postgres",
+			wantIndexes: []string{
+				"CREATE UNIQUE INDEX IF NOT EXISTS uk_asset_sub_domain_aliyun_record_id ON asset_sub_domain (source, domain_id, aliyun_record_id)",
+				"CREATE INDEX IF NOT EXISTS idx_asset_sub_domain_domain_id ON asset_sub_domain (domain_id)",
+				"PRIMARY KEY (source, domain_id)"},
+			columnQuery: `SELECT column_name FROM information_schema.columns`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New() error = %v", err)
+			}
+			defer db.Close()
+
+			schema := mysqlSchema
+			var initSchema func(context.Context) error = (&MySQLClient{db: db, table: "asset_sub_domain"}).InitSchema
+			var checkColumns func(context.Context) error = (&MySQLClient{db: db, table: "asset_sub_domain",
+				softDelete: true}).CheckColumns
+			if tt.driver == "postgres" {
+				schema = postgresSchema
+				initSchema = (&PostgresClient{db: db, table: "asset_sub_domain"}).InitSchema
+				checkColumns = (&PostgresClient{db: db, table: "asset_sub_domain", softDelete: true}).CheckColumns
+			}
+
+			statements := splitStatements(schema)
+			for _, statement := range statements {
+				if !strings.Contains(statement, " IF NOT EXISTS ") {
+					t.Errorf("statement cannot be run twice: %.60s", statement)
+				}
+			}
+			all := strings.Join(statements, "\n")
+			for _, index := range tt.wantIndexes {
+				if !strings.Contains(all, index) {
+					t.Errorf("schema does not contain %q", index)
+				}
+			}
+
+			// 执行两次建表，再按建出的列检查
+			for run := 0; run < 2; run++ {
+				for _, statement := range statements {
+					mock.ExpectExec(regexp.QuoteMeta(statement)).WillReturnResult(sqlmock.NewResult(0, 0))
+				}
+			}
+			rows := sqlmock.NewRows([]string{"column_name"})
+			for _, column := range schemaColumns(t, schema) {
+				rows.AddRow(column)
+			}
+			mock.ExpectQuery(regexp.QuoteMeta(tt.columnQuery)).WillReturnRows(rows)
+
+			for run := 0; run < 2; run++ {
+				if err := initSchema(context.Background()); err != nil {
+					t.Fatalf("InitSchema() run %d error = %v", run+1, err)
+				}
+			}
+			if err := checkColumns(context.Background()); err != nil {
+				t.Errorf("CheckColumns() after InitSchema error = %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// snowflakeEpoch 起始时间 2024-01-01 00:00:00 UTC（毫秒）
+	snowflakeEpoch = int64(1704067200000)
+
+	workerIDBits = 10
+	sequenceBits = 12
+
+	// MaxWorkerID 允许的最大工作节点ID
+	MaxWorkerID = int64(-1) ^ (int64(-1) << workerIDBits)
+	maxSequence = int64(-1) ^ (int64(-1) << sequenceBits)
+
+	workerIDShift  = sequenceBits
+	timestampShift = sequenceBits + workerIDBits
+)
+
+// Snowflake 雪花算法ID生成器，并发安全
+// ID结构：41位毫秒时间戳 | 10位工作节点ID | 12位序列号
+type Snowflake struct {
+	mu        sync.Mutex
+	workerID  int64
+	lastStamp int64
+	sequence  int64
+}
+
+// NewSnowflake 创建雪花算法ID生成器
+func NewSnowflake(workerID int64) (*Snowflake, error) {
+	if workerID < 0 || workerID > MaxWorkerID {
+		return nil, fmt.Errorf("worker id must be between 0 and %d", MaxWorkerID)
+	}
+	return &Snowflake{workerID: workerID}, nil
+}
+
+// NextID 生成下一个ID
+func (s *Snowflake) NextID() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	if now < s.lastStamp {
+		return 0, fmt.Errorf("clock moved backwards by %dms", s.lastStamp-now)
+	}
+
+	if now == s.lastStamp {
+		s.sequence = (s.sequence + 1) & maxSequence
+		// 同一毫秒内序列号用尽，等待下一毫秒
+		if s.sequence == 0 {
+			for now <= s.lastStamp {
+				now = time.Now().UnixMilli()
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+
+	s.lastStamp = now
+	return (now-snowflakeEpoch)<<timestampShift | s.workerID<<workerIDShift | s.sequence, nil
+}
+
+// NextIDString 生成字符串形式的下一个ID
+func (s *Snowflake) NextIDString() (string, error) {
+	id, err := s.NextID()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), nil
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSnowflakeConcurrentUnique(t *testing.T) {
+	tests := []struct {
+		name       string
+		workerID   int64
+		goroutines int
+		perWorker  int
+	}{
+		{name: "single goroutine", workerID: 1, goroutines: 1, perWorker: 10000},
+		{name: "10k ids across goroutines", workerID: 1, goroutines: 50, perWorker: 200},
+		{name: "max worker id", workerID: MaxWorkerID, goroutines: 10, perWorker: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen, err := NewSnowflake(tt.workerID)
+			if err != nil {
+				t.Fatalf("NewSnowflake() error = %v", err)
+			}
+
+			ids := make([][]int64, tt.goroutines)
+			var wg sync.WaitGroup
+			for g := 0; g < tt.goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < tt.perWorker; i++ {
+						id, err := gen.NextID()
+						if err != nil {
+							t.Errorf("NextID() error = %v", err)
+							return
+						}
+						ids[g] = append(ids[g], id)
+					}
+				}(g)
+			}
+			wg.Wait()
+
+			seen := make(map[int64]bool, tt.goroutines*tt.perWorker)
+			for _, batch := range ids {
+				for _, id := range batch {
+					if seen[id] {
+						t.Fatalf("duplicate id %d", id)
+					}
+					seen[id] = true
+					if got := id >> workerIDShift & MaxWorkerID; got != tt.workerID {
+						t.Fatalf("id %d has worker id %d, want %d", id, got, tt.workerID)
+					}
+				}
+			}
+			if len(seen) != tt.goroutines*tt.perWorker {
+				t.Errorf("got %d ids, want %d", len(seen), tt.goroutines*tt.perWorker)
+			}
+		})
+	}
+}
+
+func TestNewSnowflakeWorkerID(t *testing.T) {
+	tests := []struct {
+		workerID int64
+		wantErr  bool
+	}{
+		{workerID: 0},
+		{workerID: MaxWorkerID},
+		{workerID: -1, wantErr: true},
+		{workerID: MaxWorkerID + 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewSnowflake(tt.workerID); (err != nil) != tt.wantErr {
+			t.Errorf("NewSnowflake(%d) error = %v, wantErr %v", tt.workerID, err, tt.wantErr)
+		}
+	}
+}
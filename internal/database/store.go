@@ -0,0 +1,284 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/models"
+)
+
+// Store 子域名资产存储接口，MySQL和PostgreSQL分别实现
+type Store interface {
+	// Close 关闭数据库连接
+	Close() error
+	// SetSoftDelete 设置是否使用软删除
+	SetSoftDelete(enabled bool)
+	// SetAudit 设置是否记录审计历史
+	SetAudit(enabled bool, runID string)
+	// TestConnection 测试数据库连接
+	TestConnection(ctx context.Context) error
+	// Prewarm 预先建立conns个连接并放回连接池，第一次同步不必等待建立连接
+	Prewarm(ctx context.Context, conns int) error
+	// AcquireRunLock 在主库上获取名为name的运行锁，不等待，已被其它实例持有时返回ErrLockHeld
+	AcquireRunLock(ctx context.Context, name string) (*RunLock, error)
+	// CheckTableExists 检查表是否存在
+	CheckTableExists(ctx context.Context) error
+	// CheckColumns 检查表中是否存在同步会写入的全部列，缺少时返回列出缺失列的错误
+	CheckColumns(ctx context.Context) error
+	// InitSchema 使用内置的建表语句创建缺失的表和索引
+	InitSchema(ctx context.Context) error
+	// GetLocalRecords 获取指定域名下由source同步的有效记录，以阿里云记录ID为键
+	GetLocalRecords(ctx context.Context, domainID, source string) (map[string]*models.AssetSubDomain, error)
+	// GetSoftDeletedRecords 获取指定域名已软删除的记录
+	GetSoftDeletedRecords(ctx context.Context, domainID, source string) (map[string]*models.AssetSubDomain, error)
+	// InsertRecord 插入单条记录
+	InsertRecord(ctx context.Context, record *models.AssetSubDomain) error
+	// UpdateRecord 更新记录
+	UpdateRecord(ctx context.Context, localID string, aliyunRecord *models.DNSRecord) error
+	// DeleteRecord 删除记录
+	DeleteRecord(ctx context.Context, localID string) error
+	// DeleteRecords 分批删除记录，返回删除失败的本地ID及其错误
+	DeleteRecords(ctx context.Context, ids []string, batchSize int) map[string]error
+	// DedupeLocalRecords 删除同一aliyun_record_id的重复行，只保留最早的一行
+	DedupeLocalRecords(ctx context.Context, domainID, source string) (int, error)
+	// BatchUpsert 分批插入或更新记录，返回成功写入的记录数
+	BatchUpsert(ctx context.Context, records []*models.AssetSubDomain, batchSize int) (int, error)
+	// SyncDomainTx 在单个事务中执行一个域名的全部变更
+	SyncDomainTx(ctx context.Context, changes *SyncChanges, stopOnError bool) (*SyncResult, error)
+	// GetPendingPushRecords 获取需要推送到服务商的本地记录
+	GetPendingPushRecords(ctx context.Context, domainID, source string) ([]*models.AssetSubDomain, error)
+	// MarkRecordPushed 推送成功后回写RecordId，并将来源改为source
+	MarkRecordPushed(ctx context.Context, localID, recordID, source string) error
+	// GetWatermark 获取域名在该来源上次同步记录的最大UpdateTimestamp（毫秒），没有记录时返回0
+	// 同一domain_id可能由多个来源同步，水位按来源分别保存
+	GetWatermark(ctx context.Context, domainID, source string) (int64, error)
+	// SaveWatermark 保存域名在该来源本次同步记录的最大UpdateTimestamp
+	SaveWatermark(ctx context.Context, domainID, source string, watermark int64) error
+	// GetRecentDeletes 获取域名最近runs次同步的删除记录数，按时间从新到旧排列
+	GetRecentDeletes(ctx context.Context, domainID string, runs int) ([]int, error)
+	// SaveSyncMetrics 保存域名本次同步的变更数量
+	SaveSyncMetrics(ctx context.Context, domainID string, added, updated, deleted int) error
+	// SaveResolutionStatus 写入记录的解析检查结果，statuses为检查结果到本地ID的映射
+	SaveResolutionStatus(ctx context.Context, statuses map[string][]string) error
+	// RebuildDomainTx 在单个事务中清除域名下source的全部记录并插入records，用于从服务商完整重建
+	RebuildDomainTx(ctx context.Context, domainID, source string, records []*models.AssetSubDomain) (*SyncResult, error)
+	// ExecPostSync 全部域名同步完成后在单个事务中执行配置的语句，占位符替换为本轮的变更合计
+	ExecPostSync(ctx context.Context, statements []string, totals PostSyncTotals) error
+}
+
+// 确保两种客户端都实现了Store接口
+var (
+	_ Store = (*MySQLClient)(nil)
+	_ Store = (*PostgresClient)(nil)
+)
+
+// execer 可执行SQL语句的对象，*sql.DB和*sql.Tx均实现了该接口
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// DefaultBatchSize 批量写入时每条语句的默认行数，每行的列数×500远低于占位符上限65535
+const DefaultBatchSize = 500
+
+// recordColumns 插入记录时写入的列，顺序与recordValues一致
+var recordColumns = []string{
+	"id", "sub_domain", "type", "create_time", "update_by", "create_by", "update_time",
+	"sys_org_code", "dns_record", "name_server", "asset_label", "asset_manager",
+	"asset_department", "level", "domain_id", "source", "project_id", "aliyun_record_id",
+	"ttl", "weight", "priority", "line", "content_hash", "status", "rr", "domain_name", "remark",
+	"line_name", "raw_record",
+}
+
+// autoIDColumns 主键由数据库生成时插入的列，即去掉id的recordColumns，顺序与autoIDValues一致
+var autoIDColumns = recordColumns[1:]
+
+// autoIDValues 按autoIDColumns的顺序返回记录的值
+func autoIDValues(record *models.AssetSubDomain) []interface{} {
+	return recordValues(record)[1:]
+}
+
+// upsertUpdateColumns 记录已存在时由同步覆盖的列，人工维护的资产信息不会被修改
+// source、domain_id和aliyun_record_id组成冲突键，不在其中；RecordId变化的更新由updateRecord完成
+var upsertUpdateColumns = []string{
+	"sub_domain", "type", "dns_record", "ttl", "weight", "priority", "line", "content_hash",
+	"status", "rr", "domain_name", "remark", "line_name", "raw_record", "update_time",
+}
+
+// recordIDs 返回记录的本地ID
+func recordIDs(records []*models.AssetSubDomain) []string {
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	return ids
+}
+
+// deleteRecords 按batchSize将ids分批交给deleteChunk，每批一条语句、一个事务（开启审计时）
+// 批次失败时该批改为逐条调用deleteOne，以便只有真正失败的记录被报告；返回删除失败的ID及其错误
+func deleteRecords(ids []string, batchSize int, deleteChunk func(chunk []string) error,
+	deleteOne func(id string) error) map[string]error {
+
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	failed := make(map[string]error)
+	for start := 0; start < len(ids); start += batchSize {
+		chunk := ids[start:min(start+batchSize, len(ids))]
+		err := deleteChunk(chunk)
+		if err == nil {
+			continue
+		}
+
+		slog.Warn("Batch delete failed, retrying records one by one", "count", len(chunk), "error", err)
+		for _, id := range chunk {
+			if err := deleteOne(id); err != nil {
+				failed[id] = err
+			}
+		}
+	}
+	return failed
+}
+
+// recordValues 按recordColumns的顺序返回记录的值
+func recordValues(record *models.AssetSubDomain) []interface{} {
+	return []interface{}{
+		record.ID,
+		record.SubDomain,
+		record.Type,
+		record.CreateTime,
+		record.UpdateBy,
+		record.CreateBy,
+		record.UpdateTime,
+		record.SysOrgCode,
+		record.DNSRecord,
+		record.NameServer,
+		record.AssetLabel,
+		record.AssetManager,
+		record.AssetDepartment,
+		record.Level,
+		record.DomainID,
+		record.Source,
+		record.ProjectID,
+		record.AliyunRecordID,
+		record.TTL,
+		record.Weight,
+		record.Priority,
+		record.Line,
+		record.ContentHash,
+		record.Status,
+		record.RR,
+		record.DomainName,
+		record.Remark,
+		record.LineName,
+		record.RawRecord,
+	}
+}
+
+// NewStore 按配置的驱动创建存储客户端
+func NewStore(cfg *config.Config) (Store, error) {
+	switch cfg.DB.Driver {
+	case "mysql":
+		return NewMySQLClient(&cfg.MySQL)
+	case "postgres":
+		return NewPostgresClient(&cfg.Postgres)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.DB.Driver)
+	}
+}
+
+// prewarm 并发占用n个连接，全部建立后再放回连接池；超过最大空闲连接数的部分放回时会被关闭
+func prewarm(ctx context.Context, db *sql.DB, n int) error {
+	conns := make([]*sql.Conn, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := db.Conn(ctx)
+			if err == nil {
+				err = conn.PingContext(ctx)
+			}
+			conns[i], errs[i] = conn, err
+		}()
+	}
+	wg.Wait()
+
+	for _, conn := range conns {
+		if conn != nil {
+			conn.Close()
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to open pooled connections: %w", err)
+	}
+	return nil
+}
+
+// requiredColumns 同步会读写的资产表列，软删除模式下还需要deleted_at
+func requiredColumns(softDelete bool) []string {
+	columns := append([]string{}, recordColumns...)
+	if softDelete {
+		columns = append(columns, "deleted_at")
+	}
+	return columns
+}
+
+// checkColumns 查询表现有的列并与required对比，query返回列名，缺少的列按required的顺序列出
+func checkColumns(ctx context.Context, db *sql.DB, table string, required []string, query string, args ...interface{}) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query table columns: %w", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return fmt.Errorf("failed to scan table column: %w", err)
+		}
+		existing[strings.ToLower(column)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate table columns: %w", err)
+	}
+
+	var missing []string
+	for _, column := range required {
+		if !existing[column] {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("table '%s' is missing columns: %s", table, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// scanDeletes 读取sync_metrics查询结果中的删除记录数
+func scanDeletes(rows *sql.Rows) ([]int, error) {
+	var deletes []int
+	for rows.Next() {
+		var deleted int
+		if err := rows.Scan(&deleted); err != nil {
+			return nil, fmt.Errorf("failed to scan sync metrics: %w", err)
+		}
+		deletes = append(deletes, deleted)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sync metrics: %w", err)
+	}
+
+	return deletes, nil
+}
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// testIDs 生成n个本地记录ID
+func testIDs(n int) []string {
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		ids = append(ids, fmt.Sprintf("id-%d", i))
+	}
+	return ids
+}
+
+// TestDeleteRecordsChunks 按batchSize分批删除，最后一批可以不满；未配置batchSize时使用DefaultBatchSize
+func TestDeleteRecordsChunks(t *testing.T) {
+	tests := []struct {
+		name      string
+		ids       int
+		batchSize int
+		want      []int
+	}{
+		{name: "no ids", ids: 0, batchSize: 2},
+		{name: "smaller than batch", ids: 3, batchSize: 10, want: []int{3}},
+		{name: "exactly one batch", ids: 4, batchSize: 4, want: []int{4}},
+		{name: "one over batch", ids: 5, batchSize: 4, want: []int{4, 1}},
+		{name: "exact multiple", ids: 6, batchSize: 2, want: []int{2, 2, 2}},
+		{name: "batch of one", ids: 3, batchSize: 1, want: []int{1, 1, 1}},
+		{name: "default batch size", ids: DefaultBatchSize + 1, batchSize: 0, want: []int{DefaultBatchSize, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := testIDs(tt.ids)
+			var chunks []int
+			var deleted []string
+			failed := deleteRecords(ids, tt.batchSize, func(chunk []string) error {
+				chunks = append(chunks, len(chunk))
+				deleted = append(deleted, chunk...)
+				return nil
+			}, func(id string) error {
+				t.Errorf("deleteOne(%s) called without a failed batch", id)
+				return nil
+			})
+
+			if len(failed) != 0 {
+				t.Errorf("failed = %v, want none", failed)
+			}
+			if fmt.Sprint(chunks) != fmt.Sprint(tt.want) {
+				t.Errorf("chunk sizes = %v, want %v", chunks, tt.want)
+			}
+			if !slices.Equal(deleted, ids) {
+				t.Errorf("deleted %d ids, want all %d in order", len(deleted), len(ids))
+			}
+		})
+	}
+}
+
+// TestDeleteRecordsFallback 批次失败时只有该批改为逐条删除，返回的失败只包含逐条删除仍失败的ID
+func TestDeleteRecordsFallback(t *testing.T) {
+	ids := testIDs(5)
+	locked := map[string]bool{"id-3": true}
+	var singles []string
+
+	failed := deleteRecords(ids, 2, func(chunk []string) error {
+		for _, id := range chunk {
+			if locked[id] {
+				return errors.New("lock wait timeout exceeded")
+			}
+		}
+		return nil
+	}, func(id string) error {
+		singles = append(singles, id)
+		if locked[id] {
+			return errors.New("lock wait timeout exceeded")
+		}
+		return nil
+	})
+
+	if want := []string{"id-2", "id-3"}; !slices.Equal(singles, want) {
+		t.Errorf("single deletes = %v, want %v", singles, want)
+	}
+	if len(failed) != 1 || failed["id-3"] == nil {
+		t.Errorf("failed = %v, want only id-3", failed)
+	}
+}
+
+// TestMySQLDeleteRecords 每批一条DELETE ... WHERE id IN语句，失败的批次改为逐条DELETE
+func TestMySQLDeleteRecords(t *testing.T) {
+	client, mock := newMockMySQL(t)
+	in := func(ids ...string) (string, []driver.Value) {
+		args := make([]driver.Value, 0, len(ids))
+		for _, id := range ids {
+			args = append(args, id)
+		}
+		return "^" + regexp.QuoteMeta("DELETE FROM asset_sub_domain WHERE id IN ("+
+			strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")+")") + "$", args
+	}
+
+	query, args := in("id-0", "id-1")
+	mock.ExpectExec(query).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 2))
+	query, args = in("id-2", "id-3")
+	mock.ExpectExec(query).WithArgs(args...).WillReturnError(errors.New("foreign key constraint fails"))
+	single := "^" + regexp.QuoteMeta("DELETE FROM asset_sub_domain WHERE id = ?") + "$"
+	mock.ExpectExec(single).WithArgs("id-2").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(single).WithArgs("id-3").WillReturnError(errors.New("foreign key constraint fails"))
+	query, args = in("id-4")
+	mock.ExpectExec(query).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	failed := client.DeleteRecords(context.Background(), testIDs(5), 2)
+	if len(failed) != 1 || failed["id-3"] == nil {
+		t.Errorf("DeleteRecords() failed = %v, want only id-3", failed)
+	}
+}
+
+// TestCheckColumns 表缺少写入用到的列时列出全部缺少的列，列名不区分大小写；软删除模式下还需要deleted_at
+func TestCheckColumns(t *testing.T) {
+	without := func(skip ...string) []string {
+		var columns []string
+		for _, column := range recordColumns {
+			if !slices.Contains(skip, column) {
+				columns = append(columns, column)
+			}
+		}
+		return columns
+	}
+	upper := make([]string, 0, len(recordColumns))
+	for _, column := range recordColumns {
+		upper = append(upper, strings.ToUpper(column))
+	}
+
+	tests := []struct {
+		name       string
+		postgres   bool
+		softDelete bool
+		columns    []string
+		wantErr    string
+	}{
+		{name: "all columns", columns: recordColumns},
+		{name: "upper case column names", columns: upper},
+		{name: "missing aliyun_record_id", columns: without("aliyun_record_id"),
+			wantErr: "table 'asset_sub_domain' is missing columns: aliyun_record_id"},
+		{name: "postgres missing aliyun_record_id", postgres: true, columns: without("aliyun_record_id"),
+			wantErr: "table 'asset_sub_domain' is missing columns: aliyun_record_id"},
+		{name: "older schema", columns: without("project_id", "aliyun_record_id", "source"),
+			wantErr: "table 'asset_sub_domain' is missing columns: source, project_id, aliyun_record_id"},
+		{name: "soft delete without deleted_at", softDelete: true, columns: recordColumns,
+			wantErr: "table 'asset_sub_domain' is missing columns: deleted_at"},
+		{name: "soft delete", softDelete: true, columns: append(without(), "deleted_at")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := sqlmock.NewRows([]string{"column_name"})
+			for _, column := range tt.columns {
+				rows.AddRow(column)
+			}
+
+			var checkColumns func(context.Context) error
+			if tt.postgres {
+				client, mock := newMockPostgres(t)
+				client.softDelete = tt.softDelete
+				mock.ExpectQuery(regexp.QuoteMeta("table_schema = current_schema() AND table_name = lower($1)")).
+					WithArgs("asset_sub_domain").WillReturnRows(rows)
+				checkColumns = client.CheckColumns
+			} else {
+				client, mock := newMockMySQL(t)
+				client.softDelete = tt.softDelete
+				mock.ExpectQuery(regexp.QuoteMeta("table_schema = DATABASE() AND table_name = ?")).
+					WithArgs("asset_sub_domain").WillReturnRows(rows)
+				checkColumns = client.CheckColumns
+			}
+
+			err := checkColumns(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("CheckColumns() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckColumns() error = %v", err)
+			}
+		})
+	}
+}
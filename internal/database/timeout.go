@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrQueryTimeout 单次数据库调用超过了mysql.query_timeout或postgres.query_timeout
+var ErrQueryTimeout = errors.New("database query timed out")
+
+// withQueryTimeout 在超时为timeout的上下文中执行一次数据库调用，timeout为0时不限制
+// 超时导致的错误包装为ErrQueryTimeout并带上超时时间，嵌套调用时只包装一次；调用方的上下文被取消时原样返回
+func withQueryTimeout(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	queryCtx, cancel := context.WithTimeoutCause(ctx, timeout, ErrQueryTimeout)
+	defer cancel()
+
+	err := fn(queryCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(queryCtx), ErrQueryTimeout) &&
+		!errors.Is(err, ErrQueryTimeout) {
+		return fmt.Errorf("%w after %s: %w", ErrQueryTimeout, timeout, err)
+	}
+	return err
+}
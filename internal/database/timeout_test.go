@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithQueryTimeout(t *testing.T) {
+	errFailed := errors.New("query failed")
+	// blocking 模拟一直等到上下文结束的慢查询
+	blocking := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name        string
+		timeout     time.Duration
+		cancel      bool
+		fn          func(ctx context.Context) error
+		wantTimeout bool
+		wantErr     error
+	}{
+		{
+			name:        "timeout fires on slow query",
+			timeout:     10 * time.Millisecond,
+			fn:          blocking,
+			wantTimeout: true,
+			wantErr:     context.DeadlineExceeded,
+		},
+		{
+			name:    "fast query keeps its own error",
+			timeout: time.Second,
+			fn:      func(context.Context) error { return errFailed },
+			wantErr: errFailed,
+		},
+		{
+			name:    "zero timeout adds no deadline",
+			timeout: 0,
+			fn: func(ctx context.Context) error {
+				if _, ok := ctx.Deadline(); ok {
+					return errors.New("unexpected deadline")
+				}
+				return nil
+			},
+		},
+		{
+			name:    "cancelled caller is not reported as timeout",
+			timeout: time.Second,
+			cancel:  true,
+			fn:      blocking,
+			wantErr: context.Canceled,
+		},
+		{
+			name:        "nested calls wrap once",
+			timeout:     10 * time.Millisecond,
+			fn:          func(ctx context.Context) error { return withQueryTimeout(ctx, 5*time.Millisecond, blocking) },
+			wantTimeout: true,
+			wantErr:     context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+
+			err := withQueryTimeout(ctx, tt.timeout, tt.fn)
+			if got := errors.Is(err, ErrQueryTimeout); got != tt.wantTimeout {
+				t.Errorf("errors.Is(%v, ErrQueryTimeout) = %v, want %v", err, got, tt.wantTimeout)
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("withQueryTimeout() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("withQueryTimeout() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/go-sql-driver/mysql"
+
+	"dns-sync/internal/config"
+)
+
+// registerMySQLTLS 按tls_mode向mysql驱动注册自定义的TLS配置，不需要自定义配置的模式直接返回
+// 注册名为cfg.TLSConfigName()，与DSN中的tls参数对应
+func registerMySQLTLS(cfg *config.MySQLConfig) error {
+	tlsConfig, err := mysqlTLSConfig(cfg)
+	if err != nil || tlsConfig == nil {
+		return err
+	}
+
+	if err := mysql.RegisterTLSConfig(cfg.TLSConfigName(), tlsConfig); err != nil {
+		return fmt.Errorf("failed to register mysql tls config: %w", err)
+	}
+	return nil
+}
+
+// mysqlTLSConfig 构建TLS配置：require只加密不校验证书，verify-ca校验证书链，verify-full还会校验主机名
+// disable和preferred使用驱动自带的行为，返回nil
+func mysqlTLSConfig(cfg *config.MySQLConfig) (*tls.Config, error) {
+	switch cfg.TLSMode {
+	case "require", "verify-ca", "verify-full":
+	default:
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: cfg.Host,
+	}
+
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mysql ca_cert: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("mysql ca_cert %s contains no valid PEM certificates", cfg.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load mysql client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	switch cfg.TLSMode {
+	case "require":
+		tlsConfig.InsecureSkipVerify = true
+	case "verify-ca":
+		// 只校验证书链不校验主机名，需要跳过默认校验后自行验证
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = verifyCertificateChain(tlsConfig.RootCAs)
+	}
+
+	return tlsConfig, nil
+}
+
+// verifyCertificateChain 使用roots校验服务端证书链，roots为nil时使用系统证书
+func verifyCertificateChain(roots *x509.CertPool) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return fmt.Errorf("mysql server presented no certificate")
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to verify mysql server certificate: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,186 @@
+package database
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"dns-sync/internal/config"
+)
+
+// testCertificate 生成由parent签发的证书，parent为nil时生成自签名的CA
+func testCertificate(t *testing.T, name string, parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{name},
+	}
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// writePEM 将证书写入临时目录并返回路径
+func writePEM(t *testing.T, cert *x509.Certificate) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestRegisterMySQLTLS 每种tls_mode注册的TLS配置和DSN中的tls参数，注册的配置可以被驱动按DSN找到
+func TestRegisterMySQLTLS(t *testing.T) {
+	ca, _ := testCertificate(t, "test-ca", nil, nil)
+	caPath := writePEM(t, ca)
+
+	tests := []struct {
+		mode           string
+		wantRegistered bool
+		wantTLSParam   string
+		wantSkipVerify bool
+		wantVerifyConn bool
+	}{
+		{mode: "disable"},
+		{mode: "preferred", wantTLSParam: "preferred"},
+		{mode: "require", wantRegistered: true, wantTLSParam: config.MySQLTLSConfigName, wantSkipVerify: true},
+		{mode: "verify-ca", wantRegistered: true, wantTLSParam: config.MySQLTLSConfigName, wantSkipVerify: true,
+			wantVerifyConn: true},
+		{mode: "verify-full", wantRegistered: true, wantTLSParam: config.MySQLTLSConfigName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			mysql.DeregisterTLSConfig(config.MySQLTLSConfigName)
+			cfg := &config.MySQLConfig{Host: "db.internal", Port: 3306, Username: "root", Database: "assets",
+				TLSMode: tt.mode, CACert: caPath}
+
+			if err := registerMySQLTLS(cfg); err != nil {
+				t.Fatalf("registerMySQLTLS() error = %v", err)
+			}
+			tlsConfig, err := mysqlTLSConfig(cfg)
+			if err != nil {
+				t.Fatalf("mysqlTLSConfig() error = %v", err)
+			}
+			if (tlsConfig != nil) != tt.wantRegistered {
+				t.Fatalf("mysqlTLSConfig() = %v, want registered %v", tlsConfig, tt.wantRegistered)
+			}
+
+			dsn := cfg.DSN()
+			gotParam := ""
+			if i := strings.Index(dsn, "&tls="); i >= 0 {
+				gotParam = dsn[i+len("&tls="):]
+			}
+			if gotParam != tt.wantTLSParam {
+				t.Errorf("DSN tls param = %q, want %q", gotParam, tt.wantTLSParam)
+			}
+
+			// 驱动按名称查找注册的配置，未注册时解析DSN会失败
+			parsed, err := mysql.ParseDSN(dsn)
+			if err != nil {
+				t.Fatalf("mysql.ParseDSN() error = %v", err)
+			}
+			if !tt.wantRegistered {
+				return
+			}
+			if parsed.TLS == nil || parsed.TLS.ServerName != "db.internal" || parsed.TLS.MinVersion != tls.VersionTLS12 {
+				t.Fatalf("registered TLS config = %+v", parsed.TLS)
+			}
+			if parsed.TLS.InsecureSkipVerify != tt.wantSkipVerify {
+				t.Errorf("InsecureSkipVerify = %v, want %v", parsed.TLS.InsecureSkipVerify, tt.wantSkipVerify)
+			}
+			if (parsed.TLS.VerifyConnection != nil) != tt.wantVerifyConn {
+				t.Errorf("VerifyConnection set = %v, want %v", parsed.TLS.VerifyConnection != nil, tt.wantVerifyConn)
+			}
+			if parsed.TLS.RootCAs == nil {
+				t.Error("RootCAs not loaded from ca_cert")
+			}
+		})
+	}
+	mysql.DeregisterTLSConfig(config.MySQLTLSConfigName)
+}
+
+// TestMySQLTLSConfigErrors 证书文件不存在或内容无效时返回错误
+func TestMySQLTLSConfigErrors(t *testing.T) {
+	invalid := filepath.Join(t.TempDir(), "invalid.pem")
+	if err := os.WriteFile(invalid, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     config.MySQLConfig
+		wantErr string
+	}{
+		{name: "missing ca", cfg: config.MySQLConfig{TLSMode: "verify-ca", CACert: "/nonexistent/ca.pem"},
+			wantErr: "failed to read mysql ca_cert"},
+		{name: "invalid ca", cfg: config.MySQLConfig{TLSMode: "verify-full", CACert: invalid},
+			wantErr: "contains no valid PEM certificates"},
+		{name: "missing client cert", cfg: config.MySQLConfig{TLSMode: "require", ClientCert: "/nonexistent/client.pem",
+			ClientKey: "/nonexistent/client.key"}, wantErr: "failed to load mysql client certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := mysqlTLSConfig(&tt.cfg); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("mysqlTLSConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestVerifyCertificateChain verify-ca只校验证书链：由配置的CA签发的证书通过，与主机名无关；其它CA签发的证书失败
+func TestVerifyCertificateChain(t *testing.T) {
+	ca, caKey := testCertificate(t, "test-ca", nil, nil)
+	other, otherKey := testCertificate(t, "other-ca", nil, nil)
+	leaf, _ := testCertificate(t, "some-other-host", ca, caKey)
+	foreign, _ := testCertificate(t, "db.internal", other, otherKey)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	verify := verifyCertificateChain(roots)
+
+	if err := verify(tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}); err != nil {
+		t.Errorf("certificate signed by the configured CA rejected: %v", err)
+	}
+	if err := verify(tls.ConnectionState{PeerCertificates: []*x509.Certificate{foreign}}); err == nil {
+		t.Error("certificate signed by another CA accepted")
+	}
+	if err := verify(tls.ConnectionState{}); err == nil {
+		t.Error("connection without a certificate accepted")
+	}
+}
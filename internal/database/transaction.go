@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"dns-sync/internal/models"
+)
+
+// RecordUpdate 待更新的记录
+type RecordUpdate struct {
+	LocalRecord  *models.AssetSubDomain
+	AliyunRecord *models.DNSRecord
+	// Restore 是否为恢复已软删除的记录
+	Restore bool
+	// Relink 是否为按名称和类型重新关联到新的阿里云RecordId
+	Relink bool
+}
+
+// SyncChanges 单个域名计算出的变更集合
+type SyncChanges struct {
+	Inserts []*models.AssetSubDomain
+	Updates []RecordUpdate
+	Deletes []*models.AssetSubDomain
+	// Progress 写入时的进度记录，为nil时不输出进度
+	Progress *Progress
+}
+
+// Total 变更总数
+func (c *SyncChanges) Total() int {
+	return len(c.Inserts) + len(c.Updates) + len(c.Deletes)
+}
+
+// SyncResult 变更执行结果
+type SyncResult struct {
+	Added   int
+	Updated int
+	Deleted int
+}
+
+// recordWriter 单条记录的写操作，由各数据库客户端实现，便于共用事务逻辑
+type recordWriter interface {
+	insertRecord(ctx context.Context, exec execer, record *models.AssetSubDomain) error
+	updateRecord(ctx context.Context, exec execer, localID string, aliyunRecord *models.DNSRecord) error
+	deleteRecord(ctx context.Context, exec execer, localID string) error
+}
+
+// insertAutoIDRecords 逐条插入主键由数据库生成的新记录（ID为空），返回其余需要批量写入的记录
+func insertAutoIDRecords(ctx context.Context, exec execer, writer recordWriter,
+	records []*models.AssetSubDomain) ([]*models.AssetSubDomain, error) {
+
+	keyed := make([]*models.AssetSubDomain, 0, len(records))
+	for _, record := range records {
+		if record.ID != "" {
+			keyed = append(keyed, record)
+			continue
+		}
+		if err := writer.insertRecord(ctx, exec, record); err != nil {
+			return nil, err
+		}
+	}
+	return keyed, nil
+}
+
+// SyncDomainTx 在单个事务中执行一个域名的全部变更
+// stopOnError为true时遇到第一个错误立即回滚；为false时继续执行剩余变更以便记录全部错误，
+// 但只要出现过错误仍然整体回滚，保证数据库不会处于部分同步的状态
+func (c *MySQLClient) SyncDomainTx(ctx context.Context, changes *SyncChanges, stopOnError bool) (*SyncResult, error) {
+	return syncChangesTx(ctx, c.db, c, changes, stopOnError, false)
+}
+
+// syncChangesTx 使用writer在db上开启事务执行变更
+// savepoints为true且stopOnError为false时每项变更包在保存点中，失败后回滚到保存点再继续，
+// 用于PostgreSQL这类语句失败后整个事务不可再用的数据库
+func syncChangesTx(ctx context.Context, db *sql.DB, writer recordWriter, changes *SyncChanges,
+	stopOnError, savepoints bool) (*SyncResult, error) {
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &SyncResult{}
+	var firstErr error
+	failed := 0
+
+	// fail 记录错误，返回是否需要立即中止
+	fail := func(err error) bool {
+		failed++
+		if firstErr == nil {
+			firstErr = err
+		}
+		slog.Error("Transaction error", "error", err)
+		return stopOnError
+	}
+
+	// apply 执行一项变更，需要时在保存点中执行
+	apply := func(change func() error) error {
+		if stopOnError || !savepoints {
+			return change()
+		}
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT sync_change"); err != nil {
+			return fmt.Errorf("failed to create savepoint: %w", err)
+		}
+		if err := change(); err != nil {
+			if _, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT sync_change"); rollbackErr != nil {
+				return fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rollbackErr)
+			}
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT sync_change"); err != nil {
+			return fmt.Errorf("failed to release savepoint: %w", err)
+		}
+		return nil
+	}
+
+	for _, record := range changes.Inserts {
+		err := apply(func() error { return writer.insertRecord(ctx, tx, record) })
+		if err != nil {
+			if fail(fmt.Errorf("insert %s: %w", record.SubDomain, err)) {
+				return nil, fmt.Errorf("transaction rolled back: %w", firstErr)
+			}
+			changes.Progress.Step(0, 0, 0, 1)
+			continue
+		}
+		result.Added++
+		changes.Progress.Step(1, 0, 0, 0)
+		slog.Debug("Added new record", "action", "insert", "sub_domain", record.SubDomain,
+			"record_id", *record.AliyunRecordID)
+	}
+
+	for _, update := range changes.Updates {
+		err := apply(func() error { return writer.updateRecord(ctx, tx, update.LocalRecord.ID, update.AliyunRecord) })
+		if err != nil {
+			if fail(fmt.Errorf("update %s: %w", update.LocalRecord.SubDomain, err)) {
+				return nil, fmt.Errorf("transaction rolled back: %w", firstErr)
+			}
+			changes.Progress.Step(0, 0, 0, 1)
+			continue
+		}
+		result.Updated++
+		changes.Progress.Step(0, 1, 0, 0)
+		if update.Relink {
+			slog.Debug("Relinked record", "action", "relink", "sub_domain", update.LocalRecord.SubDomain,
+				"record_id", update.AliyunRecord.RecordId)
+		} else if update.Restore {
+			slog.Debug("Restored record", "action", "restore", "sub_domain", update.LocalRecord.SubDomain,
+				"record_id", update.AliyunRecord.RecordId)
+		} else {
+			slog.Debug("Updated record", "action", "update", "sub_domain", update.LocalRecord.SubDomain,
+				"record_id", update.AliyunRecord.RecordId)
+		}
+	}
+
+	for _, record := range changes.Deletes {
+		err := apply(func() error { return writer.deleteRecord(ctx, tx, record.ID) })
+		if err != nil {
+			if fail(fmt.Errorf("delete %s: %w", record.SubDomain, err)) {
+				return nil, fmt.Errorf("transaction rolled back: %w", firstErr)
+			}
+			changes.Progress.Step(0, 0, 0, 1)
+			continue
+		}
+		result.Deleted++
+		changes.Progress.Step(0, 0, 1, 0)
+		slog.Debug("Deleted record", "action", "delete", "sub_domain", record.SubDomain,
+			"record_id", *record.AliyunRecordID)
+	}
+
+	if firstErr != nil {
+		return nil, fmt.Errorf("transaction rolled back after %d errors: %w", failed, firstErr)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,234 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"dns-sync/internal/models"
+)
+
+// fakeTable 测试用的内存表，只保存已提交的行ID
+// abortOnError为true时模拟PostgreSQL：事务中语句失败后，回滚到保存点之前的语句都会失败
+type fakeTable struct {
+	mu           sync.Mutex
+	rows         map[string]bool
+	failOn       map[string]bool
+	abortOnError bool
+}
+
+// fakeOp 一条待提交的写操作
+type fakeOp struct {
+	verb string
+	id   string
+}
+
+// fakeConn 单个连接的事务状态
+type fakeConn struct {
+	table     *fakeTable
+	inTx      bool
+	aborted   bool
+	pending   []fakeOp
+	savepoint int
+}
+
+func (t *fakeTable) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{table: t}, nil
+}
+
+func (t *fakeTable) Driver() driver.Driver { return nil }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.inTx, c.aborted, c.pending = true, false, nil
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	defer c.reset()
+	if c.aborted {
+		return errors.New("current transaction is aborted")
+	}
+	c.table.mu.Lock()
+	defer c.table.mu.Unlock()
+	for _, op := range c.pending {
+		c.table.apply(op)
+	}
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.reset()
+	return nil
+}
+
+func (c *fakeConn) reset() {
+	c.inTx, c.aborted, c.pending, c.savepoint = false, false, nil, 0
+}
+
+// ExecContext 执行"INSERT <id>"形式的语句和保存点语句
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	switch {
+	case strings.HasPrefix(query, "ROLLBACK TO SAVEPOINT"):
+		c.pending, c.aborted = c.pending[:c.savepoint], false
+		return driver.RowsAffected(0), nil
+	case c.aborted:
+		return nil, errors.New("current transaction is aborted, commands ignored until end of transaction block")
+	case strings.HasPrefix(query, "SAVEPOINT"):
+		c.savepoint = len(c.pending)
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(query, "RELEASE SAVEPOINT"):
+		return driver.RowsAffected(0), nil
+	}
+
+	verb, id, _ := strings.Cut(query, " ")
+	if c.table.failOn[id] {
+		c.aborted = c.inTx && c.table.abortOnError
+		return nil, fmt.Errorf("forced failure for %s", id)
+	}
+	if c.inTx {
+		c.pending = append(c.pending, fakeOp{verb: verb, id: id})
+	} else {
+		c.table.mu.Lock()
+		c.table.apply(fakeOp{verb: verb, id: id})
+		c.table.mu.Unlock()
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (t *fakeTable) apply(op fakeOp) {
+	switch op.verb {
+	case "INSERT", "UPDATE":
+		t.rows[op.id] = true
+	case "DELETE":
+		delete(t.rows, op.id)
+	}
+}
+
+// fakeWriter 将每项变更写成一条语句
+type fakeWriter struct{}
+
+func (fakeWriter) insertRecord(ctx context.Context, exec execer, record *models.AssetSubDomain) error {
+	_, err := exec.ExecContext(ctx, "INSERT "+record.ID)
+	return err
+}
+
+func (fakeWriter) updateRecord(ctx context.Context, exec execer, localID string, _ *models.DNSRecord) error {
+	_, err := exec.ExecContext(ctx, "UPDATE "+localID)
+	return err
+}
+
+func (fakeWriter) deleteRecord(ctx context.Context, exec execer, localID string) error {
+	_, err := exec.ExecContext(ctx, "DELETE "+localID)
+	return err
+}
+
+// testChanges 新增id-0到id-2，更新existing-1，删除existing-2
+func testChanges() *SyncChanges {
+	existing := testAssets(2)
+	existing[0].ID, existing[1].ID = "existing-1", "existing-2"
+	return &SyncChanges{
+		Inserts: testAssets(3),
+		Updates: []RecordUpdate{{LocalRecord: existing[0], AliyunRecord: existing[0].ToDNSRecord("example.com")}},
+		Deletes: []*models.AssetSubDomain{existing[1]},
+	}
+}
+
+func TestSyncChangesTx(t *testing.T) {
+	tests := []struct {
+		name         string
+		failOn       []string
+		abortOnError bool
+		stopOnError  bool
+		savepoints   bool
+		// wantErr 为空表示应当提交成功
+		wantErr  string
+		wantRows []string
+	}{
+		{
+			name:     "commits all changes",
+			wantRows: []string{"existing-1", "id-0", "id-1", "id-2"},
+		},
+		{
+			name:        "stop on error rolls back",
+			failOn:      []string{"id-1"},
+			stopOnError: true,
+			wantErr:     "transaction rolled back: insert host1.example.com",
+			wantRows:    []string{"existing-1", "existing-2"},
+		},
+		{
+			name:     "continue on error reports every failure and rolls back",
+			failOn:   []string{"id-1", "existing-2"},
+			wantErr:  "transaction rolled back after 2 errors",
+			wantRows: []string{"existing-1", "existing-2"},
+		},
+		{
+			name:         "aborted transaction without savepoints fails every later statement",
+			failOn:       []string{"id-0"},
+			abortOnError: true,
+			wantErr:      "transaction rolled back after 5 errors",
+			wantRows:     []string{"existing-1", "existing-2"},
+		},
+		{
+			name:         "savepoints keep failures isolated in an aborted transaction",
+			failOn:       []string{"id-0"},
+			abortOnError: true,
+			savepoints:   true,
+			wantErr:      "transaction rolled back after 1 errors",
+			wantRows:     []string{"existing-1", "existing-2"},
+		},
+		{
+			name:         "savepoints commit when nothing fails",
+			abortOnError: true,
+			savepoints:   true,
+			wantRows:     []string{"existing-1", "id-0", "id-1", "id-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &fakeTable{
+				rows:         map[string]bool{"existing-1": true, "existing-2": true},
+				failOn:       make(map[string]bool),
+				abortOnError: tt.abortOnError,
+			}
+			for _, id := range tt.failOn {
+				table.failOn[id] = true
+			}
+			db := sql.OpenDB(table)
+			defer db.Close()
+
+			result, err := syncChangesTx(context.Background(), db, fakeWriter{}, testChanges(), tt.stopOnError, tt.savepoints)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("syncChangesTx() error = %v", err)
+				}
+				if result.Added != 3 || result.Updated != 1 || result.Deleted != 1 {
+					t.Errorf("result = %+v, want 3 added, 1 updated, 1 deleted", result)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("syncChangesTx() error = %v, want %q", err, tt.wantErr)
+			}
+
+			var rows []string
+			for id := range table.rows {
+				rows = append(rows, id)
+			}
+			sort.Strings(rows)
+			if got, want := strings.Join(rows, ","), strings.Join(tt.wantRows, ","); got != want {
+				t.Errorf("rows after sync = %s, want %s", got, want)
+			}
+		})
+	}
+}
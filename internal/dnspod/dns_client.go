@@ -0,0 +1,318 @@
+package dnspod
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/models"
+	"dns-sync/internal/provider"
+)
+
+// defaultEndpoint DNSPod API地址
+const defaultEndpoint = "https://dnsapi.cn"
+
+// pageSize 每页拉取的记录数，DNSPod单页最多3000条
+const pageSize = 500
+
+// maxPages 单个域名最多拉取的页数，防止分页死循环
+const maxPages = 1000
+
+// DNSPod返回的状态码
+const (
+	codeSuccess   = "1"
+	codeNoDomains = "9"
+	codeNoRecords = "10"
+)
+
+// DNSPod默认线路的ID和名称，转换后统一为default
+const (
+	defaultLineID   = "0"
+	defaultLineName = "默认"
+)
+
+// DNSClient DNSPod DNS客户端，使用API Token认证
+type DNSClient struct {
+	loginToken string
+	endpoint   string
+	httpClient *http.Client
+}
+
+// apiStatus DNSPod API通用状态
+type apiStatus struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// recordListResponse Record.List响应结构
+type recordListResponse struct {
+	Status apiStatus `json:"status"`
+	Info   struct {
+		// 不同版本的接口可能以字符串或数字返回总数
+		RecordTotal json.Number `json:"record_total"`
+	} `json:"info"`
+	Records []dnsRecord `json:"records"`
+}
+
+// domainListResponse Domain.List响应结构
+type domainListResponse struct {
+	Status apiStatus `json:"status"`
+	Info   struct {
+		DomainTotal json.Number `json:"domain_total"`
+	} `json:"info"`
+	Domains []struct {
+		Name     string `json:"name"`
+		Punycode string `json:"punycode"`
+	} `json:"domains"`
+}
+
+// dnsRecord DNSPod DNS记录，数值字段以字符串返回
+type dnsRecord struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Line      string `json:"line"`
+	LineID    string `json:"line_id"`
+	Type      string `json:"type"`
+	TTL       string `json:"ttl"`
+	Value     string `json:"value"`
+	Weight    *int32 `json:"weight"`
+	MX        string `json:"mx"`
+	Enabled   string `json:"enabled"`
+	Status    string `json:"status"`
+	UpdatedOn string `json:"updated_on"`
+}
+
+// NewDNSClient 创建DNSPod DNS客户端
+func NewDNSClient(cfg *config.DNSPodConfig) (*DNSClient, error) {
+	if cfg.TokenID == "" || cfg.Token == "" {
+		return nil, fmt.Errorf("dnspod token id and token are required")
+	}
+
+	endpoint := defaultEndpoint
+	if cfg.Endpoint != "" {
+		endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	}
+
+	return &DNSClient{
+		loginToken: cfg.TokenID + "," + cfg.Token,
+		endpoint:   endpoint,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// makeRequest 发送POST请求，返回响应体；状态码的校验由调用方处理
+func (c *DNSClient) makeRequest(ctx context.Context, action string, params url.Values) ([]byte, error) {
+	form := url.Values{}
+	for k, v := range params {
+		form[k] = v
+	}
+	form.Set("login_token", c.loginToken)
+	form.Set("format", "json")
+	form.Set("lang", "en")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/"+action,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// DNSPod要求请求携带可识别的User-Agent
+	req.Header.Set("User-Agent", "dns-sync/1.0")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response failed: %w", err)
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
+
+// GetDomainRecords 获取域名的DNS记录
+func (c *DNSClient) GetDomainRecords(ctx context.Context, domain string) ([]*models.DNSRecord, error) {
+	slog.Debug("Getting DNS records", "provider", "dnspod", "domain", domain)
+
+	var allRecords []*models.DNSRecord
+	page := 0
+
+	for page < maxPages {
+		params := url.Values{
+			"domain": {domain},
+			"offset": {strconv.Itoa(page * pageSize)},
+			"length": {strconv.Itoa(pageSize)},
+		}
+
+		body, err := c.makeRequest(ctx, "Record.List", params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list dns records for %s: %w", domain, err)
+		}
+
+		var response recordListResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("failed to parse dns records: %w", err)
+		}
+
+		// 域名下没有记录时返回状态码10
+		if response.Status.Code == codeNoRecords {
+			break
+		}
+		if response.Status.Code != codeSuccess {
+			return nil, fmt.Errorf("API request failed: %s: %s", response.Status.Code, response.Status.Message)
+		}
+
+		for _, record := range response.Records {
+			allRecords = append(allRecords, convertRecord(domain, record))
+		}
+		page++
+
+		var stop bool
+		if allRecords, stop, err = provider.ApplyRecordLimit(ctx, domain, allRecords); err != nil || stop {
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		total, _ := response.Info.RecordTotal.Int64()
+		if len(response.Records) < pageSize || int64(len(allRecords)) >= total {
+			break
+		}
+	}
+
+	slog.Info("Retrieved DNS records", "provider", "dnspod", "domain", domain,
+		"count", len(allRecords), "pages", page)
+	return allRecords, nil
+}
+
+// convertRecord 将DNSPod记录转换为通用DNS记录
+// DNSPod的enabled为"1"表示启用，对应ENABLE，否则为DISABLE；默认线路统一为default
+func convertRecord(domain string, record dnsRecord) *models.DNSRecord {
+	status := "DISABLE"
+	if record.Enabled == "1" {
+		status = "ENABLE"
+	}
+
+	line := record.Line
+	if record.LineID == defaultLineID || record.Line == defaultLineName {
+		line = "default"
+	}
+
+	ttl, _ := strconv.Atoi(record.TTL)
+
+	dnsRecord := &models.DNSRecord{
+		DomainName: domain,
+		RR:         record.Name,
+		RecordId:   record.ID,
+		Type:       record.Type,
+		Value:      record.Value,
+		Line:       line,
+		Status:     status,
+		TTL:        int32(ttl),
+	}
+	if record.Type == "MX" {
+		if mx, err := strconv.Atoi(record.MX); err == nil {
+			dnsRecord.Priority = int32(mx)
+		}
+	}
+	// 只有设置了权重的记录才返回weight，视为开启加权轮询
+	if record.Weight != nil {
+		dnsRecord.Weight = *record.Weight
+		dnsRecord.LbaStatus = true
+	}
+	if updatedOn, err := time.ParseInLocation("2006-01-02 15:04:05", record.UpdatedOn, time.Local); err == nil {
+		dnsRecord.UpdateTimestamp = updatedOn.UnixMilli()
+	}
+
+	return dnsRecord
+}
+
+// TestConnection 测试连接
+func (c *DNSClient) TestConnection(ctx context.Context) error {
+	slog.Debug("Testing DNS connection", "provider", "dnspod")
+
+	body, err := c.makeRequest(ctx, "User.Detail", nil)
+	if err != nil {
+		return fmt.Errorf("failed to test dnspod connection: %w", err)
+	}
+
+	var response struct {
+		Status apiStatus `json:"status"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("failed to parse test response: %w", err)
+	}
+	if response.Status.Code != codeSuccess {
+		return fmt.Errorf("failed to test dnspod connection: %s: %s", response.Status.Code, response.Status.Message)
+	}
+
+	slog.Debug("DNS connection test successful", "provider", "dnspod")
+	return nil
+}
+
+// VerifyDomains 分页拉取账号下的全部域名，检查配置的域名是否都存在
+func (c *DNSClient) VerifyDomains(ctx context.Context, domains []string) error {
+	found := make(map[string]bool)
+
+	for page := 0; page < maxPages; page++ {
+		params := url.Values{
+			"offset": {strconv.Itoa(page * pageSize)},
+			"length": {strconv.Itoa(pageSize)},
+		}
+
+		body, err := c.makeRequest(ctx, "Domain.List", params)
+		if err != nil {
+			return fmt.Errorf("failed to list dnspod domains: %w", err)
+		}
+
+		var response domainListResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return fmt.Errorf("failed to parse domains response: %w", err)
+		}
+
+		// 账号下没有域名时返回状态码9
+		if response.Status.Code == codeNoDomains {
+			break
+		}
+		if response.Status.Code != codeSuccess {
+			return fmt.Errorf("API request failed: %s: %s", response.Status.Code, response.Status.Message)
+		}
+
+		for _, domain := range response.Domains {
+			found[models.NormalizeDomain(domain.Name)] = true
+			if domain.Punycode != "" {
+				found[models.NormalizeDomain(domain.Punycode)] = true
+			}
+		}
+
+		total, _ := response.Info.DomainTotal.Int64()
+		if len(response.Domains) < pageSize || int64((page+1)*pageSize) >= total {
+			break
+		}
+	}
+
+	var missing []string
+	for _, domain := range domains {
+		if !found[models.NormalizeDomain(domain)] {
+			missing = append(missing, domain)
+		}
+	}
+
+	return provider.MissingDomainsError(missing)
+}
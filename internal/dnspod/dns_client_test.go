@@ -0,0 +1,160 @@
+package dnspod
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"dns-sync/internal/config"
+)
+
+// newMockRecordList 模拟Record.List接口，按offset和length返回records条A记录，第一条之后附加固定样例记录
+// code不为1时直接返回该状态码
+func newMockRecordList(t *testing.T, records int, code string, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	samples := []map[string]any{
+		{"id": "mx-1", "name": "@", "line": "默认", "line_id": "0", "type": "MX", "ttl": "600",
+			"value": "mail.example.com.", "mx": "10", "enabled": "1"},
+		{"id": "off-1", "name": "old", "line": "电信", "line_id": "10=0", "type": "A", "ttl": "600",
+			"value": "10.1.0.1", "enabled": "0"},
+		{"id": "lb-1", "name": "lb", "line": "默认", "line_id": "0", "type": "A", "ttl": "60",
+			"value": "10.2.0.1", "weight": 20, "enabled": "1", "updated_on": "2024-01-02 03:04:05"},
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/Record.List" || r.PostFormValue("login_token") != "12345,test-token" ||
+			r.PostFormValue("domain") != "example.com" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if code != codeSuccess {
+			fmt.Fprintf(w, `{"status":{"code":%q,"message":"error %s"}}`, code, code)
+			return
+		}
+
+		offset, _ := strconv.Atoi(r.PostFormValue("offset"))
+		length, _ := strconv.Atoi(r.PostFormValue("length"))
+		all := make([]map[string]any, 0, records+len(samples))
+		all = append(all, samples...)
+		for i := len(samples); i < records; i++ {
+			all = append(all, map[string]any{"id": strconv.Itoa(1000 + i), "name": fmt.Sprintf("host%d", i),
+				"line": "默认", "line_id": "0", "type": "A", "ttl": "600",
+				"value": fmt.Sprintf("10.0.%d.%d", i/256, i%256), "enabled": "1"})
+		}
+		page := all[min(offset, len(all)):min(offset+length, len(all))]
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":  map[string]string{"code": codeSuccess},
+			"info":    map[string]string{"record_total": strconv.Itoa(len(all))},
+			"records": page,
+		})
+	}))
+}
+
+func TestGetDomainRecords(t *testing.T) {
+	tests := []struct {
+		name         string
+		records      int
+		code         string
+		wantRecords  int
+		wantRequests int32
+		wantErr      string
+	}{
+		{name: "single page", records: 3, code: codeSuccess, wantRecords: 3, wantRequests: 1},
+		{name: "two pages", records: pageSize + 100, code: codeSuccess, wantRecords: pageSize + 100, wantRequests: 2},
+		{name: "exactly one full page", records: pageSize, code: codeSuccess, wantRecords: pageSize, wantRequests: 1},
+		{name: "no records", code: codeNoRecords, wantRequests: 1},
+		{name: "api error", code: "-1", wantRequests: 1, wantErr: "API request failed: -1: error -1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := newMockRecordList(t, tt.records, tt.code, &requests)
+			defer server.Close()
+
+			client, err := NewDNSClient(&config.DNSPodConfig{TokenID: "12345", Token: "test-token", Endpoint: server.URL})
+			if err != nil {
+				t.Fatalf("NewDNSClient() error = %v", err)
+			}
+
+			records, err := client.GetDomainRecords(context.Background(), "example.com")
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", got, tt.wantRequests)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetDomainRecords() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetDomainRecords() error = %v", err)
+			}
+			if len(records) != tt.wantRecords {
+				t.Fatalf("got %d records, want %d", len(records), tt.wantRecords)
+			}
+
+			seen := make(map[string]bool, len(records))
+			for _, record := range records {
+				if seen[record.RecordId] {
+					t.Errorf("duplicate record %s", record.RecordId)
+				}
+				seen[record.RecordId] = true
+			}
+		})
+	}
+}
+
+// TestConvertRecord 启用状态、默认线路、MX优先级和权重的转换
+func TestConvertRecord(t *testing.T) {
+	var requests atomic.Int32
+	server := newMockRecordList(t, 3, codeSuccess, &requests)
+	defer server.Close()
+
+	client, err := NewDNSClient(&config.DNSPodConfig{TokenID: "12345", Token: "test-token", Endpoint: server.URL})
+	if err != nil {
+		t.Fatalf("NewDNSClient() error = %v", err)
+	}
+	records, err := client.GetDomainRecords(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("GetDomainRecords() error = %v", err)
+	}
+
+	tests := []struct {
+		recordID string
+		line     string
+		status   string
+		ttl      int32
+		priority int32
+		weight   int32
+		lba      bool
+	}{
+		{recordID: "mx-1", line: "default", status: "ENABLE", ttl: 600, priority: 10},
+		{recordID: "off-1", line: "电信", status: "DISABLE", ttl: 600},
+		{recordID: "lb-1", line: "default", status: "ENABLE", ttl: 60, weight: 20, lba: true},
+	}
+	if len(records) != len(tests) {
+		t.Fatalf("got %d records, want %d", len(records), len(tests))
+	}
+	for i, tt := range tests {
+		record := records[i]
+		if record.RecordId != tt.recordID || record.Line != tt.line || record.Status != tt.status ||
+			record.TTL != tt.ttl || record.Priority != tt.priority || record.Weight != tt.weight || record.LbaStatus != tt.lba {
+			t.Errorf("record %d = %+v, want %+v", i, record, tt)
+		}
+		if record.DomainName != "example.com" {
+			t.Errorf("record %s domain = %q", record.RecordId, record.DomainName)
+		}
+	}
+	if records[2].UpdateTimestamp == 0 {
+		t.Error("UpdateTimestamp not parsed from updated_on")
+	}
+}
@@ -0,0 +1,183 @@
+package file
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"dns-sync/internal/models"
+	"dns-sync/internal/provider"
+)
+
+// defaultTTL 快照中未填写ttl时使用的TTL
+const defaultTTL = 600
+
+// DNSClient 从本地快照文件读取记录的服务商，用于在不访问服务商的情况下对比或恢复数据库
+// 每个域名对应一个YAML或JSON文件，每次获取记录时重新读取，快照修改后无需重启
+type DNSClient struct {
+	// paths 规范化的查询域名到快照文件路径的映射
+	paths map[string]string
+}
+
+// snapshot 快照文件结构，JSON是YAML的子集，两种格式使用相同的字段名
+type snapshot struct {
+	Records []snapshotRecord `yaml:"records"`
+}
+
+// snapshotRecord 快照中的一条记录，rr为空时为@，line为空时为default，status为空时为ENABLE，weight不为0时视为开启加权轮询
+type snapshotRecord struct {
+	RR       string `yaml:"rr"`
+	Type     string `yaml:"type"`
+	Value    string `yaml:"value"`
+	TTL      int32  `yaml:"ttl"`
+	Line     string `yaml:"line"`
+	Priority int32  `yaml:"priority"`
+	Weight   int32  `yaml:"weight"`
+	Status   string `yaml:"status"`
+	Remark   string `yaml:"remark"`
+	// RecordID 记录ID，与数据库中的aliyun_record_id对应；为空时由主机记录、类型、线路和记录值计算
+	RecordID string `yaml:"record_id"`
+}
+
+// NewDNSClient 创建快照文件客户端，paths为查询域名到快照文件路径的映射
+func NewDNSClient(paths map[string]string) *DNSClient {
+	normalized := make(map[string]string, len(paths))
+	for domain, path := range paths {
+		normalized[models.NormalizeDomain(domain)] = path
+	}
+	return &DNSClient{paths: normalized}
+}
+
+// GetDomainRecords 读取域名的快照文件并转换为DNS记录
+func (c *DNSClient) GetDomainRecords(ctx context.Context, domain string) ([]*models.DNSRecord, error) {
+	path, ok := c.paths[models.NormalizeDomain(domain)]
+	if !ok {
+		return nil, fmt.Errorf("%w: no snapshot file configured for %s", provider.ErrDomainNotFound, domain)
+	}
+	slog.Debug("Getting DNS records", "provider", "file", "domain", domain, "path", path)
+
+	records, err := loadSnapshot(domain, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// 快照一次读入，上限检查只需要做一次
+	records, _, err = provider.ApplyRecordLimit(ctx, domain, records)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Retrieved DNS records", "provider", "file", "domain", domain, "count", len(records), "path", path)
+	return records, nil
+}
+
+// loadSnapshot 读取并解析快照文件，语法错误带有yaml给出的行号，字段错误带有记录的序号
+func loadSnapshot(domain, path string) ([]*models.DNSRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+	}
+
+	var content snapshot
+	if err := yaml.UnmarshalStrict(data, &content); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+
+	records := make([]*models.DNSRecord, 0, len(content.Records))
+	seen := make(map[string]int, len(content.Records))
+	for i, entry := range content.Records {
+		record, err := convertRecord(domain, entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid record %d in snapshot %s: %w", i+1, path, err)
+		}
+		if first, exists := seen[record.RecordId]; exists {
+			return nil, fmt.Errorf("invalid record %d in snapshot %s: duplicate record_id %s (first used by record %d)",
+				i+1, path, record.RecordId, first)
+		}
+		seen[record.RecordId] = i + 1
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// convertRecord 将快照记录转换为通用DNS记录并填充默认值
+func convertRecord(domain string, entry snapshotRecord) (*models.DNSRecord, error) {
+	if entry.Type == "" || entry.Value == "" {
+		return nil, fmt.Errorf("type and value are required")
+	}
+
+	status := strings.ToUpper(entry.Status)
+	switch status {
+	case "":
+		status = "ENABLE"
+	case "ENABLE", "DISABLE":
+	default:
+		return nil, fmt.Errorf("status must be ENABLE or DISABLE, got %q", entry.Status)
+	}
+
+	record := &models.DNSRecord{
+		DomainName: domain,
+		RR:         entry.RR,
+		Type:       entry.Type,
+		Value:      entry.Value,
+		TTL:        entry.TTL,
+		Line:       entry.Line,
+		Priority:   entry.Priority,
+		Weight:     entry.Weight,
+		LbaStatus:  entry.Weight != 0,
+		Status:     status,
+		Remark:     entry.Remark,
+		RecordId:   entry.RecordID,
+	}
+	if record.TTL == 0 {
+		record.TTL = defaultTTL
+	}
+	if record.Line == "" {
+		record.Line = "default"
+	}
+	if record.RecordId == "" {
+		record.RecordId = recordID(record)
+	}
+
+	return record, nil
+}
+
+// recordID 计算记录的稳定ID，带file-前缀以免与服务商分配的ID冲突
+func recordID(record *models.DNSRecord) string {
+	sum := sha1.Sum([]byte(strings.Join([]string{record.FullDomain(), record.RecordType(), record.Line,
+		record.RecordValue()}, "|")))
+	return "file-" + hex.EncodeToString(sum[:16])
+}
+
+// TestConnection 检查所有快照文件都可以读取
+func (c *DNSClient) TestConnection(ctx context.Context) error {
+	for _, path := range c.paths {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("snapshot file not accessible: %w", err)
+		}
+	}
+	return nil
+}
+
+// VerifyDomains 检查域名是否都配置了存在的快照文件
+func (c *DNSClient) VerifyDomains(ctx context.Context, domains []string) error {
+	var missing []string
+	for _, domain := range domains {
+		path, ok := c.paths[models.NormalizeDomain(domain)]
+		if !ok {
+			missing = append(missing, domain)
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			missing = append(missing, domain)
+		}
+	}
+	return provider.MissingDomainsError(missing)
+}
@@ -0,0 +1,153 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dns-sync/internal/provider"
+)
+
+// writeSnapshot 将content写入临时目录中的快照文件并返回路径
+func writeSnapshot(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+	return path
+}
+
+func TestGetDomainRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+		// check 检查解析出的记录，wantErr不为空时不调用
+		check func(t *testing.T, client *DNSClient)
+	}{
+		{
+			name: "yaml with defaults",
+			file: "example.com.yaml",
+			content: `records:
+  - rr: www
+    type: A
+    value: 10.0.0.1
+  - type: MX
+    value: mail.example.com
+    priority: 10
+    ttl: 3600
+    record_id: "1001"
+  - rr: old
+    type: A
+    value: 10.0.0.2
+    status: disable
+    weight: 5
+`,
+		},
+		{
+			name:    "json",
+			file:    "example.com.json",
+			content: `{"records": [{"rr": "www", "type": "A", "value": "10.0.0.1"}, {"type": "MX", "value": "mail.example.com", "priority": 10, "ttl": 3600, "record_id": "1001"}, {"rr": "old", "type": "A", "value": "10.0.0.2", "status": "DISABLE", "weight": 5}]}`,
+		},
+		{
+			name:    "syntax error has line number",
+			file:    "example.com.yaml",
+			content: "records:\n  - rr: www\n    type: A\n   value: 10.0.0.1\n",
+			wantErr: "line 3",
+		},
+		{
+			name:    "unknown field",
+			file:    "example.com.yaml",
+			content: "records:\n  - rr: www\n    type: A\n    value: 10.0.0.1\n    tll: 600\n",
+			wantErr: "field tll not found",
+		},
+		{
+			name:    "missing value",
+			file:    "example.com.yaml",
+			content: "records:\n  - rr: www\n    type: A\n  - rr: api\n    type: CNAME\n",
+			wantErr: "invalid record 1",
+		},
+		{
+			name:    "invalid status",
+			file:    "example.com.yaml",
+			content: "records:\n  - rr: www\n    type: A\n    value: 10.0.0.1\n    status: paused\n",
+			wantErr: `status must be ENABLE or DISABLE, got "paused"`,
+		},
+		{
+			name:    "duplicate record id",
+			file:    "example.com.yaml",
+			content: "records:\n  - {rr: www, type: A, value: 10.0.0.1}\n  - {rr: www, type: A, value: 10.0.0.1}\n",
+			wantErr: "invalid record 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeSnapshot(t, tt.file, tt.content)
+			client := NewDNSClient(map[string]string{"Example.com.": path})
+
+			records, err := client.GetDomainRecords(context.Background(), "example.com")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetDomainRecords() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetDomainRecords() error = %v", err)
+			}
+			if len(records) != 3 {
+				t.Fatalf("got %d records, want 3", len(records))
+			}
+
+			www, mx, old := records[0], records[1], records[2]
+			if www.TTL != defaultTTL || www.Line != "default" || www.Status != "ENABLE" ||
+				!strings.HasPrefix(www.RecordId, "file-") || www.DomainName != "example.com" {
+				t.Errorf("www record defaults = %+v", www)
+			}
+			if mx.RecordId != "1001" || mx.RR != "" || mx.Priority != 10 || mx.TTL != 3600 {
+				t.Errorf("mx record = %+v", mx)
+			}
+			if old.Status != "DISABLE" || old.Weight != 5 || !old.LbaStatus {
+				t.Errorf("disabled record = %+v", old)
+			}
+		})
+	}
+}
+
+// TestRecordIDStable 没有record_id的记录每次读取得到相同的ID，记录值变化时ID随之变化
+func TestRecordIDStable(t *testing.T) {
+	read := func(value string) string {
+		path := writeSnapshot(t, "example.com.yaml", "records:\n  - {rr: www, type: A, value: "+value+"}\n")
+		records, err := NewDNSClient(map[string]string{"example.com": path}).GetDomainRecords(context.Background(),
+			"example.com")
+		if err != nil {
+			t.Fatalf("GetDomainRecords() error = %v", err)
+		}
+		return records[0].RecordId
+	}
+
+	if read("10.0.0.1") != read("10.0.0.1") {
+		t.Error("record id changed between reads")
+	}
+	if read("10.0.0.1") == read("10.0.0.2") {
+		t.Error("record id did not change with the value")
+	}
+}
+
+func TestUnknownDomain(t *testing.T) {
+	client := NewDNSClient(map[string]string{"example.com": filepath.Join(t.TempDir(), "missing.yaml")})
+
+	if _, err := client.GetDomainRecords(context.Background(), "example.org"); !errors.Is(err, provider.ErrDomainNotFound) {
+		t.Errorf("GetDomainRecords() error = %v, want ErrDomainNotFound", err)
+	}
+	err := client.VerifyDomains(context.Background(), []string{"example.com", "example.org"})
+	if err == nil || !strings.Contains(err.Error(), "example.com, example.org") {
+		t.Errorf("VerifyDomains() error = %v, want both domains missing", err)
+	}
+}
@@ -0,0 +1,167 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check 就绪检查函数，返回错误表示依赖不可用
+type Check func(ctx context.Context) error
+
+// Server 常驻模式下的健康检查HTTP服务
+// /healthz只表示进程存活；/readyz要求最近一次成功同步在staleness之内，且所有依赖检查通过。
+// 其它HTTP端点可以通过Handle注册到同一个地址上
+type Server struct {
+	mux       *http.ServeMux
+	server    *http.Server
+	staleness time.Duration
+	timeout   time.Duration
+
+	mu          sync.Mutex
+	checks      map[string]Check
+	lastSuccess time.Time
+	lastError   string
+}
+
+// readyResponse /readyz的响应内容
+type readyResponse struct {
+	Status      string            `json:"status"`
+	LastSuccess *time.Time        `json:"last_success,omitempty"`
+	LastError   string            `json:"last_error,omitempty"`
+	Checks      map[string]string `json:"checks,omitempty"`
+	Reason      string            `json:"reason,omitempty"`
+}
+
+// NewServer 创建健康检查服务，staleness为最近一次成功同步的最长间隔，timeout为每个依赖检查的超时时间
+func NewServer(addr string, staleness, timeout time.Duration) *Server {
+	s := &Server{
+		mux:       http.NewServeMux(),
+		staleness: staleness,
+		timeout:   timeout,
+		checks:    make(map[string]Check),
+	}
+	s.mux.HandleFunc("/healthz", s.handleHealthz)
+	s.mux.HandleFunc("/readyz", s.handleReadyz)
+	s.server = &http.Server{
+		Addr:              addr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// AddCheck 注册就绪检查
+func (s *Server) AddCheck(name string, check Check) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks[name] = check
+}
+
+// Handle 在同一个地址上注册其它端点
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// RecordSync 记录一轮同步的结果，失败时保留最近一次成功的时间
+func (s *Server) RecordSync(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.lastError = err.Error()
+		return
+	}
+	s.lastSuccess = time.Now()
+	s.lastError = ""
+}
+
+// Start 在后台开始监听，地址无法监听时返回错误
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.server.Addr, err)
+	}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Health server stopped", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// Shutdown 停止服务，等待进行中的请求完成
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// handleHealthz 进程存活即返回200
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReadyz 检查最近一次成功同步的时间和所有依赖，任一不满足时返回503
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	lastSuccess := s.lastSuccess
+	lastError := s.lastError
+	checks := make(map[string]Check, len(s.checks))
+	for name, check := range s.checks {
+		checks[name] = check
+	}
+	s.mu.Unlock()
+
+	response := readyResponse{Status: "ok", LastError: lastError, Checks: make(map[string]string)}
+	ready := true
+
+	if lastSuccess.IsZero() {
+		ready = false
+		response.Reason = "no successful sync yet"
+	} else {
+		response.LastSuccess = &lastSuccess
+		if age := time.Since(lastSuccess); age > s.staleness {
+			ready = false
+			response.Reason = fmt.Sprintf("last successful sync was %s ago, exceeds %s",
+				age.Round(time.Second), s.staleness)
+		}
+	}
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+		err := checks[name](ctx)
+		cancel()
+		if err != nil {
+			ready = false
+			response.Checks[name] = err.Error()
+			continue
+		}
+		response.Checks[name] = "ok"
+	}
+
+	status := http.StatusOK
+	if !ready {
+		response.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Debug("Failed to write readiness response", "error", err)
+	}
+}
@@ -0,0 +1,158 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestReadyz 最近一次成功同步在staleness之内且依赖检查都通过时就绪，否则返回503和原因；/healthz始终返回200
+func TestReadyz(t *testing.T) {
+	const staleness = time.Minute
+
+	tests := []struct {
+		name string
+		// setup 在请求之前设置同步结果和依赖检查
+		setup      func(s *Server)
+		wantStatus int
+		wantReason string
+		wantChecks map[string]string
+		wantError  string
+	}{
+		{
+			name:       "no sync yet",
+			wantStatus: http.StatusServiceUnavailable,
+			wantReason: "no successful sync yet",
+		},
+		{
+			name: "healthy",
+			setup: func(s *Server) {
+				s.AddCheck("database", func(context.Context) error { return nil })
+				s.AddCheck("aliyun", func(context.Context) error { return nil })
+				s.RecordSync(nil)
+			},
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{"database": "ok", "aliyun": "ok"},
+		},
+		{
+			name: "stale",
+			setup: func(s *Server) {
+				s.AddCheck("database", func(context.Context) error { return nil })
+				s.RecordSync(nil)
+				s.lastSuccess = time.Now().Add(-2 * staleness)
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantReason: "last successful sync was 2m0s ago, exceeds 1m0s",
+			wantChecks: map[string]string{"database": "ok"},
+		},
+		{
+			name: "failed sync within window",
+			setup: func(s *Server) {
+				s.RecordSync(nil)
+				s.RecordSync(errors.New("2 domains failed"))
+			},
+			wantStatus: http.StatusOK,
+			wantError:  "2 domains failed",
+		},
+		{
+			name: "dependency down",
+			setup: func(s *Server) {
+				s.AddCheck("database", func(context.Context) error { return errors.New("connection refused") })
+				s.AddCheck("aliyun", func(context.Context) error { return nil })
+				s.RecordSync(nil)
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"database": "connection refused", "aliyun": "ok"},
+		},
+		{
+			name: "dependency check times out",
+			setup: func(s *Server) {
+				s.AddCheck("aliyun", func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				})
+				s.RecordSync(nil)
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"aliyun": context.DeadlineExceeded.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer("127.0.0.1:0", staleness, 50*time.Millisecond)
+			if tt.setup != nil {
+				tt.setup(s)
+			}
+			server := httptest.NewServer(s.mux)
+			defer server.Close()
+
+			resp, err := http.Get(server.URL + "/healthz")
+			if err != nil {
+				t.Fatalf("GET /healthz error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("/healthz status = %d, want 200", resp.StatusCode)
+			}
+
+			resp, err = http.Get(server.URL + "/readyz")
+			if err != nil {
+				t.Fatalf("GET /readyz error = %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("/readyz status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			var got readyResponse
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatalf("invalid /readyz response: %v", err)
+			}
+			wantStatus := "ok"
+			if tt.wantStatus != http.StatusOK {
+				wantStatus = "unavailable"
+			}
+			if got.Status != wantStatus || got.Reason != tt.wantReason || got.LastError != tt.wantError {
+				t.Errorf("/readyz = %+v, want status %s, reason %q, last error %q", got, wantStatus, tt.wantReason,
+					tt.wantError)
+			}
+			if len(got.Checks) != len(tt.wantChecks) {
+				t.Fatalf("checks = %v, want %v", got.Checks, tt.wantChecks)
+			}
+			for name, want := range tt.wantChecks {
+				if got.Checks[name] != want {
+					t.Errorf("check %s = %q, want %q", name, got.Checks[name], want)
+				}
+			}
+		})
+	}
+}
+
+// TestHandle 其它端点与健康检查共用同一个地址
+func TestHandle(t *testing.T) {
+	s := NewServer("127.0.0.1:0", time.Minute, time.Second)
+	s.Handle("/metrics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "dns_sync_runs_total 1\n")
+	}))
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+
+	for path, want := range map[string]string{"/metrics": "dns_sync_runs_total 1", "/healthz": "ok"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), want) {
+			t.Errorf("GET %s = %d %q, want 200 containing %q", path, resp.StatusCode, body, want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// mask 代替敏感值的占位符
+const mask = "****"
+
+// minSecretLength 参与屏蔽的敏感值的最短长度，更短的值会把日志中大量无关的文本替换掉
+const minSecretLength = 4
+
+// replacer 屏蔽敏感值的替换器，未设置敏感值时为nil
+var replacer atomic.Pointer[strings.Replacer]
+
+// output 日志的输出位置，测试中替换以捕获日志
+var output io.Writer = os.Stderr
+
+// Setup 按配置初始化全局结构化日志
+// format支持text和json；level支持debug、info、warn、error，逐条记录的变更日志为debug级别
+// 输出前屏蔽SetSecrets设置的敏感值
+func Setup(format, level string) error {
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(strings.ToUpper(level))); err != nil {
+		return fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: logLevel}
+	w := maskingWriter{w: output}
+
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text", "":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("invalid log format %q", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// SetSecrets 设置需要在日志中屏蔽的敏感值（密码、密钥、令牌），替换为****
+// 同时屏蔽text和json格式转义后的形式；短于minSecretLength的值不屏蔽
+func SetSecrets(secrets []string) {
+	seen := make(map[string]bool)
+	var patterns []string
+	for _, secret := range secrets {
+		if len(secret) < minSecretLength {
+			continue
+		}
+		quoted := strconv.Quote(secret)
+		encoded, _ := json.Marshal(secret)
+		for _, pattern := range []string{secret, quoted[1 : len(quoted)-1], string(encoded[1 : len(encoded)-1])} {
+			if !seen[pattern] {
+				seen[pattern] = true
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+	if len(patterns) == 0 {
+		replacer.Store(nil)
+		return
+	}
+
+	// 较长的值优先匹配，一个敏感值包含另一个时整体替换
+	sort.Slice(patterns, func(i, j int) bool {
+		return len(patterns[i]) > len(patterns[j])
+	})
+	pairs := make([]string, 0, len(patterns)*2)
+	for _, pattern := range patterns {
+		pairs = append(pairs, pattern, mask)
+	}
+	replacer.Store(strings.NewReplacer(pairs...))
+}
+
+// Redact 屏蔽文本中的敏感值，用于日志以外的输出，如同步摘要和JSON报告
+func Redact(s string) string {
+	if r := replacer.Load(); r != nil {
+		return r.Replace(s)
+	}
+	return s
+}
+
+// maskingWriter 写入前屏蔽敏感值，slog的handler每条日志调用一次Write
+type maskingWriter struct {
+	w io.Writer
+}
+
+// Write 屏蔽敏感值后写入，返回值为原始长度，避免调用方误判为短写
+func (m maskingWriter) Write(p []byte) (int, error) {
+	r := replacer.Load()
+	if r == nil {
+		return m.w.Write(p)
+	}
+	if _, err := io.WriteString(m.w, r.Replace(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
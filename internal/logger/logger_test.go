@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// captureOutput 将日志输出替换为缓冲区，测试结束时恢复输出位置和默认logger
+func captureOutput(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous, previousLogger := output, slog.Default()
+	output = &buf
+	t.Cleanup(func() {
+		output = previous
+		slog.SetDefault(previousLogger)
+	})
+	return &buf
+}
+
+// TestSetupLevels --verbose对应debug，输出逐条记录的变更；默认info；--quiet对应warn，只保留警告和错误
+func TestSetupLevels(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     string
+		format    string
+		wantLines int
+	}{
+		{name: "verbose", level: "debug", wantLines: 7},
+		{name: "default", level: "info", wantLines: 4},
+		{name: "quiet", level: "warn", wantLines: 2},
+		{name: "error", level: "error", wantLines: 1},
+		{name: "json upper case level", level: "INFO", format: "json", wantLines: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureOutput(t)
+			if err := Setup(tt.format, tt.level); err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+
+			for _, rr := range []string{"www", "api", "mail"} {
+				slog.Debug("Added record", "sub_domain", rr+".example.com")
+			}
+			slog.Info("Retrieved DNS records", "domain", "example.com", "count", 3)
+			slog.Info("Domain sync completed", "domain", "example.com", "added", 3)
+			slog.Warn("Record list truncated by max_records, skipping deletes", "domain", "example.com")
+			slog.Error("Failed to sync domain", "domain", "example.net", "error", "request failed")
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != tt.wantLines {
+				t.Errorf("got %d lines, want %d:\n%s", len(lines), tt.wantLines, buf.String())
+			}
+		})
+	}
+}
+
+func TestSetupInvalid(t *testing.T) {
+	captureOutput(t)
+	if err := Setup("text", "trace"); err == nil {
+		t.Error("Setup() with invalid level error = nil")
+	}
+	if err := Setup("xml", "info"); err == nil {
+		t.Error("Setup() with invalid format error = nil")
+	}
+}
+
+// TestSetSecrets 设置的敏感值在text和json格式的日志中都不会出现，包括转义后的形式；
+// 一个敏感值包含另一个时整体替换，过短的值不屏蔽，Redact对日志以外的文本做同样的屏蔽
+func TestSetSecrets(t *testing.T) {
+	const password = `p@ss"w\rd`
+	const token = "sts-token"
+	secrets := []string{password, token, token + "-extended", "abc", ""}
+
+	for _, format := range []string{"text", "json"} {
+		t.Run(format, func(t *testing.T) {
+			buf := captureOutput(t)
+			SetSecrets(secrets)
+			defer SetSecrets(nil)
+			if err := Setup(format, "debug"); err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+
+			slog.Info("Database client initialized", "dsn", "sync:"+password+"@tcp(db.internal:3306)/assets")
+			slog.Error("Failed to connect", "error", errors.New("access denied for password "+password))
+			slog.Debug("Using credentials", "token", token+"-extended", "short", "abc")
+
+			out := buf.String()
+			for _, leaked := range []string{password, strconv.Quote(password), `p@ss\"w\\rd`, token} {
+				if strings.Contains(out, leaked) {
+					t.Errorf("log output contains secret %q:\n%s", leaked, out)
+				}
+			}
+			if strings.Contains(out, mask+"-extended") {
+				t.Errorf("longer secret partially masked:\n%s", out)
+			}
+			if strings.Count(out, mask) != 3 || !strings.Contains(out, "abc") {
+				t.Errorf("log output = %s, want 3 masked values and the short value kept", out)
+			}
+		})
+	}
+
+	SetSecrets(secrets)
+	defer SetSecrets(nil)
+	if got := Redact("failed to connect: " + password); got != "failed to connect: ****" {
+		t.Errorf("Redact() = %q", got)
+	}
+	SetSecrets(nil)
+	if got := Redact(password); got != password {
+		t.Errorf("Redact() without secrets = %q, want unchanged", got)
+	}
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxCollapsedValueLength 合并后记录值的最大长度，与dns_record列的varchar(255)一致
+const maxCollapsedValueLength = 255
+
+// CollapseValues 将子域名、类型和线路都相同的多条记录（如轮询的多条A记录）合并为一条
+// 合并后的记录值为各记录值排序后用逗号拼接的列表，其它字段取自代表记录：
+// 优先使用keep返回true的记录（通常是本地已有的记录），否则使用RecordId最小的记录，保证结果稳定。
+// 拼接后超过dns_record列长度的一组不合并，输出警告后仍按多行写入。
+// 返回合并后的记录和被合并掉的记录的RecordId
+func CollapseValues(records []*DNSRecord, keep func(recordID string) bool) ([]*DNSRecord, []string) {
+	groups := make(map[string][]*DNSRecord)
+	var order []string
+	for _, record := range records {
+		key := record.FullDomain() + "|" + record.RecordType() + "|" + record.Line
+		if _, exists := groups[key]; !exists {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], record)
+	}
+
+	collapsed := make([]*DNSRecord, 0, len(groups))
+	var merged []string
+	for _, key := range order {
+		group := groups[key]
+		if len(group) == 1 {
+			collapsed = append(collapsed, group[0])
+			continue
+		}
+
+		values := make([]string, 0, len(group))
+		for _, record := range group {
+			values = append(values, record.RecordValue())
+		}
+		if length := utf8.RuneCountInString(strings.Join(values, ",")); length > maxCollapsedValueLength {
+			slog.Warn("Collapsed record value too long, keeping separate rows", "sub_domain", group[0].FullDomain(),
+				"type", group[0].RecordType(), "line", group[0].Line, "values", len(group), "length", length,
+				"max_length", maxCollapsedValueLength)
+			collapsed = append(collapsed, group...)
+			continue
+		}
+
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].RecordId < group[j].RecordId
+		})
+		representative := group[0]
+		for _, record := range group {
+			if keep(record.RecordId) {
+				representative = record
+				break
+			}
+		}
+
+		combined := *representative
+		combined.Values = values
+		for _, record := range group {
+			// 优先级和权重取最小值，任一记录启用即为启用，任一记录锁定即为锁定
+			combined.Priority = min(combined.Priority, record.Priority)
+			combined.Weight = min(combined.Weight, record.Weight)
+			if record.Status == "ENABLE" {
+				combined.Status = record.Status
+			}
+			combined.Locked = combined.Locked || record.Locked
+			combined.UpdateTimestamp = max(combined.UpdateTimestamp, record.UpdateTimestamp)
+			if record.RecordId != representative.RecordId {
+				merged = append(merged, record.RecordId)
+			}
+		}
+		sort.Strings(combined.Values)
+		collapsed = append(collapsed, &combined)
+	}
+
+	return collapsed, merged
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+// roundRobin 生成同一子域名下n条A记录，RecordId从1开始
+func roundRobin(rr string, n int) []*DNSRecord {
+	records := make([]*DNSRecord, 0, n)
+	for i := 0; i < n; i++ {
+		records = append(records, &DNSRecord{
+			DomainName: "example.com",
+			RR:         rr,
+			RecordId:   fmt.Sprintf("%s-%02d", rr, i+1),
+			Type:       "A",
+			Value:      fmt.Sprintf("10.0.%d.%d", i/250, i%250+1),
+			TTL:        600,
+			Line:       "default",
+			Status:     "ENABLE",
+		})
+	}
+	return records
+}
+
+func TestCollapseValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		records    []*DNSRecord
+		keep       string
+		wantRows   int
+		wantMerged int
+		wantValue  string
+	}{
+		{
+			name:      "single record unchanged",
+			records:   roundRobin("www", 1),
+			wantRows:  1,
+			wantValue: "10.0.0.1",
+		},
+		{
+			name:       "round robin collapsed",
+			records:    roundRobin("www", 3),
+			wantRows:   1,
+			wantMerged: 2,
+			wantValue:  "10.0.0.1,10.0.0.2,10.0.0.3",
+		},
+		{
+			name:       "existing record kept as representative",
+			records:    roundRobin("www", 2),
+			keep:       "www-02",
+			wantRows:   1,
+			wantMerged: 1,
+			wantValue:  "10.0.0.1,10.0.0.2",
+		},
+		{
+			// 30个值拼接后超过255个字符
+			name:     "too long for dns_record keeps separate rows",
+			records:  roundRobin("www", 30),
+			wantRows: 30,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collapsed, merged := CollapseValues(tt.records, func(recordID string) bool { return recordID == tt.keep })
+			if len(collapsed) != tt.wantRows {
+				t.Fatalf("got %d rows, want %d", len(collapsed), tt.wantRows)
+			}
+			if len(merged) != tt.wantMerged {
+				t.Errorf("got %d merged record ids, want %d", len(merged), tt.wantMerged)
+			}
+			for _, record := range collapsed {
+				if value := record.RecordValue(); len(value) > maxCollapsedValueLength {
+					t.Errorf("value of %d characters exceeds dns_record", len(value))
+				}
+			}
+			if tt.wantValue != "" && collapsed[0].RecordValue() != tt.wantValue {
+				t.Errorf("value = %q, want %q", collapsed[0].RecordValue(), tt.wantValue)
+			}
+			if tt.keep != "" && collapsed[0].RecordId != tt.keep {
+				t.Errorf("representative = %s, want %s", collapsed[0].RecordId, tt.keep)
+			}
+			for _, id := range merged {
+				if id == collapsed[0].RecordId {
+					t.Errorf("representative %s listed as merged", id)
+				}
+			}
+		})
+	}
+}
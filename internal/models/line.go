@@ -0,0 +1,30 @@
+package models
+
+// DefaultLineNames 阿里云常用解析线路代码对应的名称，可以通过sync.line_names覆盖或补充
+var DefaultLineNames = map[string]string{
+	"default":  "默认",
+	"telecom":  "中国电信",
+	"unicom":   "中国联通",
+	"mobile":   "中国移动",
+	"edu":      "中国教育网",
+	"drpeng":   "鹏博士",
+	"btvn":     "中国广电",
+	"oversea":  "境外",
+	"internal": "中国地区",
+	"search":   "搜索引擎",
+	"google":   "谷歌",
+	"baidu":    "百度",
+	"biying":   "必应",
+}
+
+// LineName 获取线路代码对应的名称，overrides优先于内置名称
+// 没有对应名称时原样返回代码，known为false
+func LineName(code string, overrides map[string]string) (name string, known bool) {
+	if name, ok := overrides[code]; ok {
+		return name, true
+	}
+	if name, ok := DefaultLineNames[code]; ok {
+		return name, true
+	}
+	return code, false
+}
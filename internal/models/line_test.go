@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestLineName(t *testing.T) {
+	overrides := map[string]string{"telecom": "电信", "cn_region_bj": "北京"}
+
+	tests := []struct {
+		code      string
+		overrides map[string]string
+		want      string
+		wantKnown bool
+	}{
+		{code: "default", want: "默认", wantKnown: true},
+		{code: "telecom", want: "中国电信", wantKnown: true},
+		{code: "telecom", overrides: overrides, want: "电信", wantKnown: true},
+		{code: "cn_region_bj", overrides: overrides, want: "北京", wantKnown: true},
+		{code: "unicom", overrides: overrides, want: "中国联通", wantKnown: true},
+		{code: "cn_region_sh", overrides: overrides, want: "cn_region_sh"},
+		{code: "cn_region_bj", want: "cn_region_bj"},
+	}
+
+	for _, tt := range tests {
+		name, known := LineName(tt.code, tt.overrides)
+		if name != tt.want || known != tt.wantKnown {
+			t.Errorf("LineName(%q, %v) = %q, %v, want %q, %v", tt.code, tt.overrides, name, known, tt.want,
+				tt.wantKnown)
+		}
+	}
+}
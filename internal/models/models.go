@@ -1,9 +1,21 @@
 package models
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// 本地记录的status取值
+const (
+	StatusActive   = "ACTIVE"
+	StatusDisabled = "DISABLED"
+	StatusDeleted  = "DELETED"
+)
+
 // DNSRecord 阿里云DNS记录结构体
 type DNSRecord struct {
 	CreateTimestamp int64  `json:"CreateTimestamp"`
@@ -11,6 +23,7 @@ type DNSRecord struct {
 	LbaStatus       bool   `json:"LbaStatus"`
 	Line            string `json:"Line"`
 	Locked          bool   `json:"Locked"`
+	Priority        int32  `json:"Priority"`
 	RR              string `json:"RR"`
 	RecordId        string `json:"RecordId"`
 	Status          string `json:"Status"`
@@ -19,8 +32,21 @@ type DNSRecord struct {
 	UpdateTimestamp int64  `json:"UpdateTimestamp"`
 	Value           string `json:"Value"`
 	Weight          int32  `json:"Weight"`
+	// Remark 记录备注，服务商未返回时为空
+	Remark          string `json:"Remark"`
+	// LineName 线路代码对应的名称，见LineName函数，由同步流程设置
+	LineName        string `json:"-"`
+	// Values 合并多值记录后的全部记录值，已规范化并排序，为空表示单值记录
+	Values          []string `json:"-"`
+	// IgnoreFields 计算ContentHash时忽略的字段，取值见HashFields
+	IgnoreFields    []string `json:"-"`
+	// RawValue 按sync.transforms改写前服务商返回的记录值，未改写时为空
+	RawValue        string   `json:"-"`
 }
 
+// HashFields ContentHash中除子域名外可以忽略的字段
+var HashFields = []string{"type", "value", "ttl", "priority", "weight", "line", "status", "remark"}
+
 // AssetSubDomain 数据库中的子域名记录
 type AssetSubDomain struct {
 	ID               string     `db:"id"`
@@ -41,33 +67,269 @@ type AssetSubDomain struct {
 	Source           string     `db:"source"`
 	ProjectID        string     `db:"project_id"`
 	AliyunRecordID   *string    `db:"aliyun_record_id"`
+	TTL              int32      `db:"ttl"`
+	Weight           int32      `db:"weight"`
+	Priority         int32      `db:"priority"`
+	Line             string     `db:"line"`
+	// ContentHash 记录内容的哈希，旧数据为空
+	ContentHash      string     `db:"content_hash"`
+	// Status 记录状态：ACTIVE、DISABLED（服务商上已停用）或DELETED（软删除）
+	Status           string     `db:"status"`
+	// RR 主机记录，主域名本身为@，旧数据为空
+	RR               string     `db:"rr"`
+	// DomainName 主域名（区域名），旧数据为空
+	DomainName       string     `db:"domain_name"`
+	// Remark 服务商上的记录备注
+	Remark           string     `db:"remark"`
+	// LineName 线路代码对应的名称，旧数据为空
+	LineName         string     `db:"line_name"`
+	// RawRecord 服务商返回的完整记录（JSON），只写入不读取，旧数据为空
+	RawRecord        *string    `db:"raw_record"`
 }
 
-// ConvertToAssetSubDomain 将阿里云DNS记录转换为数据库记录
-func (d *DNSRecord) ConvertToAssetSubDomain(domainID, projectID string) *AssetSubDomain {
-	now := time.Now()
-	
-	// 组合子域名：如果RR为空或为@，则使用域名本身，否则拼接RR和域名
-	subDomain := d.DomainName
-	if d.RR != "" && d.RR != "@" {
-		subDomain = d.RR + "." + d.DomainName
-	}
+// ConvertToAssetSubDomain 将阿里云DNS记录转换为数据库记录，source为记录来源，每个来源只维护自己的记录
+func (d *DNSRecord) ConvertToAssetSubDomain(domainID, projectID, source string) *AssetSubDomain {
+	// 组合子域名并统一为punycode形式
+	subDomain := d.FullDomain()
 
-	// 将Value作为DNS记录值
-	dnsRecord := d.Value
+	// 将Value作为DNS记录值，MX记录带上优先级
+	dnsRecord := d.RecordValue()
 
 	return &AssetSubDomain{
 		SubDomain:       subDomain,
-		Type:            d.Type,
-		CreateTime:      now,
-		UpdateTime:      now,
+		Type:            d.RecordType(),
+		CreateTime:      d.CreateTime(),
+		UpdateTime:      d.UpdateTime(),
 		AssetLabel:      "",
 		DomainID:        domainID,
-		Source:          "Aliyun-DNS-Sync",
+		Source:          source,
 		ProjectID:       projectID,
 		AliyunRecordID:  &d.RecordId,
 		DNSRecord:       &dnsRecord,
+		TTL:             d.TTL,
+		Weight:          d.RecordWeight(),
+		Priority:        d.RecordPriority(),
+		Line:            d.Line,
+		ContentHash:     d.ContentHash(),
+		Status:          d.AssetStatus(),
+		RR:              d.HostRecord(),
+		DomainName:      d.ZoneName(),
+		Remark:          d.Remark,
+		LineName:        d.LineName,
+		RawRecord:       d.RawJSON(),
+	}
+}
+
+// RawJSON 将服务商返回的完整记录序列化为JSON，写入raw_record列
+// 按结构体字段的固定顺序输出，相同的记录总是得到相同的结果；同步流程设置的字段不包含在内，
+// 记录值被改写过时保存改写前的值
+func (d *DNSRecord) RawJSON() *string {
+	record := *d
+	if record.RawValue != "" {
+		record.Value = record.RawValue
+	}
+	data, err := json.Marshal(&record)
+	if err != nil {
+		return nil
+	}
+	raw := string(data)
+	return &raw
+}
+
+// CreateTime 获取记录在服务商上的创建时间，服务商未返回时使用当前时间
+func (d *DNSRecord) CreateTime() time.Time {
+	return millisToTime(d.CreateTimestamp)
+}
+
+// UpdateTime 获取记录在服务商上的最后修改时间，服务商未返回时使用当前时间
+func (d *DNSRecord) UpdateTime() time.Time {
+	return millisToTime(d.UpdateTimestamp)
+}
+
+// millisToTime 将毫秒时间戳转换为本地时区的时间，为0时返回当前时间
+func millisToTime(millis int64) time.Time {
+	if millis == 0 {
+		return time.Now()
+	}
+	return time.UnixMilli(millis).In(time.Local)
+}
+
+// AssetStatus 获取写入数据库的记录状态，服务商上停用的记录为DISABLED
+func (d *DNSRecord) AssetStatus() string {
+	if d.Status == "ENABLE" {
+		return StatusActive
+	}
+	return StatusDisabled
+}
+
+// ContentHash 计算规范化后的记录内容的sha1，用于判断记录是否需要更新
+// 新增需要比较的字段时只需加入这里；IgnoreFields中的字段以空值参与计算，仅这些字段变化时哈希不变
+// 备注为空时不参与计算，没有备注的记录与加入remark之前的哈希相同
+func (d *DNSRecord) ContentHash() string {
+	fields := map[string]string{
+		"type":     d.RecordType(),
+		"value":    d.RecordValue(),
+		"ttl":      strconv.Itoa(int(d.TTL)),
+		"priority": strconv.Itoa(int(d.RecordPriority())),
+		"weight":   strconv.Itoa(int(d.RecordWeight())),
+		"line":     d.Line,
+		"status":   d.AssetStatus(),
+		"remark":   d.Remark,
+	}
+	for _, name := range d.IgnoreFields {
+		delete(fields, name)
+	}
+
+	parts := []string{d.FullDomain()}
+	for _, name := range HashFields {
+		if name == "remark" && fields[name] == "" {
+			continue
+		}
+		parts = append(parts, fields[name])
+	}
+	content := strings.Join(parts, "|")
+
+	sum := sha1.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordType 获取规范化的记录类型，统一为大写，服务商和手工修改的数据大小写可能不一致
+func (d *DNSRecord) RecordType() string {
+	return strings.ToUpper(strings.TrimSpace(d.Type))
+}
+
+// FullDomain 获取记录的完整域名，见FullDomain函数
+func (d *DNSRecord) FullDomain() string {
+	return FullDomain(d.RR, d.DomainName)
+}
+
+// FullDomain 组合主机记录和主域名：rr为空或为@时为域名本身，否则拼接rr和域名
+// 结果统一为小写的punycode形式，通配符记录保留开头的*.
+// 所有由RR得到子域名的地方都应使用这里，与RelativeRR互为逆运算
+func FullDomain(rr, domainName string) string {
+	if rr == "" || rr == "@" {
+		return NormalizeDomain(domainName)
+	}
+	return NormalizeDomain(rr + "." + domainName)
+}
+
+// RelativeRR 拆分出完整域名name相对主域名domain的主机记录，name与domain相同时为@
+// name不在domain下时原样返回，调用方应保证两者的规范化形式一致
+func RelativeRR(name, domain string) string {
+	if name == domain {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+domain)
+}
+
+// HostRecord 获取规范化的主机记录，RR为空或为@时为@，其它与FullDomain一样统一为小写的punycode形式
+func (d *DNSRecord) HostRecord() string {
+	if d.RR == "" || d.RR == "@" {
+		return "@"
+	}
+	return NormalizeDomain(d.RR)
+}
+
+// ZoneName 获取规范化的主域名
+func (d *DNSRecord) ZoneName() string {
+	return NormalizeDomain(d.DomainName)
+}
+
+// RecordValue 获取写入数据库的记录值，记录值按类型规范化
+// MX记录的优先级单独存放在Priority中，这里拼接为"优先级 值"的形式，SRV记录统一为"优先级 权重 端口 目标"，
+// 见CanonicalMX和CanonicalSRV，以便仅优先级、权重或端口变化时也能被检测到；合并后的多值记录为逗号拼接的值列表
+func (d *DNSRecord) RecordValue() string {
+	if len(d.Values) > 0 {
+		return strings.Join(d.Values, ",")
+	}
+	value := NormalizeValue(d.Type, d.Value)
+	switch d.RecordType() {
+	case "MX":
+		return CanonicalMX(d.Priority, value)
+	case "SRV":
+		return CanonicalSRV(d.Priority, value)
+	}
+	return value
+}
+
+// RecordWeight 获取写入weight列并参与对比的权重，只有开启加权轮询（LbaStatus）的记录才有权重，其它记录按0处理
+func (d *DNSRecord) RecordWeight() int32 {
+	if !d.LbaStatus {
+		return 0
 	}
+	return d.Weight
+}
+
+// RecordPriority 获取写入priority列的优先级：MX和SRV取规范化记录值中的优先级，与dns_record保持一致，
+// 阿里云等服务商的SRV优先级只在记录值中；其它类型和无法解析的记录值使用Priority
+func (d *DNSRecord) RecordPriority() int32 {
+	switch d.RecordType() {
+	case "MX", "SRV":
+		// 合并后的多值记录已取各值的最小优先级
+		if len(d.Values) > 0 {
+			return d.Priority
+		}
+		fields := strings.Fields(d.RecordValue())
+		if len(fields) > 0 {
+			if p, err := strconv.Atoi(fields[0]); err == nil {
+				return int32(p)
+			}
+		}
+	}
+	return d.Priority
+}
+
+// FullDomain 获取本地记录规范化的完整域名
+// 手工录入的主域名记录可能保存为"@.域名"，与服务商一侧一样视为域名本身
+func (a *AssetSubDomain) FullDomain() string {
+	name := NormalizeDomain(a.SubDomain)
+	if name == "@" {
+		return NormalizeDomain(a.DomainName)
+	}
+	return strings.TrimPrefix(name, "@.")
+}
+
+// ToDNSRecord 将本地记录转换为DNS记录，用于推送到服务商
+// domain为主域名，子域名与主域名相同时主机记录为@；MX记录值中的优先级会被拆分出来
+func (a *AssetSubDomain) ToDNSRecord(domain string) *DNSRecord {
+	domain = NormalizeDomain(domain)
+	subDomain := a.FullDomain()
+	if subDomain == "" {
+		subDomain = domain
+	}
+
+	rr := RelativeRR(subDomain, domain)
+
+	value := ""
+	if a.DNSRecord != nil {
+		value = *a.DNSRecord
+	}
+
+	recordType := strings.ToUpper(strings.TrimSpace(a.Type))
+	priority := a.Priority
+	if recordType == "MX" {
+		if fields := strings.Fields(value); len(fields) == 2 {
+			if p, err := strconv.Atoi(fields[0]); err == nil {
+				priority = int32(p)
+				value = fields[1]
+			}
+		}
+	}
+
+	record := &DNSRecord{
+		DomainName: domain,
+		RR:         rr,
+		Type:       recordType,
+		Value:      value,
+		TTL:        a.TTL,
+		Priority:   priority,
+		Line:       a.Line,
+	}
+	if a.AliyunRecordID != nil {
+		record.RecordId = *a.AliyunRecordID
+	}
+
+	return record
 }
 
 // DomainSyncResult 同步结果
@@ -75,5 +337,68 @@ type DomainSyncResult struct {
 	Domain      string `json:"domain"`
 	Success     bool   `json:"success"`
 	RecordCount int    `json:"record_count"`
+	Disabled    int    `json:"disabled"`
+	Added       int    `json:"added"`
+	Updated     int    `json:"updated"`
+	Deleted     int    `json:"deleted"`
+	Pushed      int    `json:"pushed"`
 	Error       string `json:"error,omitempty"`
+	// Skipped 域名在服务商上不存在，未同步
+	Skipped     bool   `json:"skipped,omitempty"`
+	// Degraded 域名同步完成但有记录写入失败，FailedByAction为按操作统计的失败数，Errors为部分失败记录的错误
+	Degraded       bool           `json:"degraded,omitempty"`
+	Failed         int            `json:"failed_records,omitempty"`
+	FailedByAction map[string]int `json:"failed_by_action,omitempty"`
+	Errors         []RecordError  `json:"record_errors,omitempty"`
+	// Warning 同步成功但需要关注的情况，如服务商返回空结果而跳过了删除
+	Warning        string         `json:"warning,omitempty"`
+	// DeleteAnomaly 删除数远超该域名最近几次同步的平均值，DeleteAverage为对比的平均删除数
+	DeleteAnomaly  bool           `json:"delete_anomaly,omitempty"`
+	DeleteAverage  float64        `json:"delete_average,omitempty"`
+}
+
+// RecordError 单条记录写入失败的错误
+type RecordError struct {
+	// Action 失败的操作：delete、push或write_back
+	Action    string `json:"action"`
+	SubDomain string `json:"sub_domain"`
+	// RecordID 服务商的记录ID，未知时为空
+	RecordID  string `json:"record_id,omitempty"`
+	Error     string `json:"error"`
+}
+
+// String 格式化为一行错误信息
+func (e RecordError) String() string {
+	if e.RecordID != "" {
+		return e.Action + " " + e.SubDomain + " [" + e.RecordID + "]: " + e.Error
+	}
+	return e.Action + " " + e.SubDomain + ": " + e.Error
+}
+
+// SyncTotals 同步变更合计
+type SyncTotals struct {
+	Domains   int `json:"domains"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+	Degraded  int `json:"degraded"`
+	// FailedRecords 部分成功的域名中写入失败的记录总数
+	FailedRecords int `json:"failed_records"`
+	// Anomalies 标记为删除异常的域名数
+	Anomalies int `json:"anomalies"`
+	Added     int `json:"added"`
+	Updated   int `json:"updated"`
+	Deleted   int `json:"deleted"`
+	Pushed    int `json:"pushed"`
+}
+
+// SyncReport 一次同步运行的报告，按域名排序
+type SyncReport struct {
+	StartTime time.Time          `json:"start_time"`
+	EndTime   time.Time          `json:"end_time"`
+	DryRun    bool               `json:"dry_run"`
+	Totals    SyncTotals         `json:"totals"`
+	Domains   []DomainSyncResult `json:"domains"`
+	// PostSyncError post_sync_sql执行失败的错误
+	PostSyncError string `json:"post_sync_error,omitempty"`
 }
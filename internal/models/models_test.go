@@ -0,0 +1,266 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestContentHashWeight(t *testing.T) {
+	tests := []struct {
+		name        string
+		lbaStatus   bool
+		before      int32
+		after       int32
+		wantWeight  int32
+		wantChanged bool
+	}{
+		{name: "weight only change with WRR", lbaStatus: true, before: 10, after: 20, wantWeight: 20, wantChanged: true},
+		{name: "unchanged weight with WRR", lbaStatus: true, before: 10, after: 10, wantWeight: 10},
+		{name: "weight ignored without WRR", before: 10, after: 20, wantWeight: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := func(weight int32) *DNSRecord {
+				return &DNSRecord{DomainName: "example.com", RR: "www", Type: "A", Value: "10.0.0.1", TTL: 600,
+					Line: "default", Status: "ENABLE", Weight: weight, LbaStatus: tt.lbaStatus}
+			}
+			before, after := record(tt.before), record(tt.after)
+
+			if changed := before.ContentHash() != after.ContentHash(); changed != tt.wantChanged {
+				t.Errorf("content hash changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if got := after.ConvertToAssetSubDomain("domain-1", "project-1", "Aliyun-DNS-Sync").Weight; got != tt.wantWeight {
+				t.Errorf("stored weight = %d, want %d", got, tt.wantWeight)
+			}
+		})
+	}
+}
+
+// TestContentHash 任一参与对比的字段变化都会改变内容哈希，RecordId等不参与对比的字段变化时哈希不变
+func TestContentHash(t *testing.T) {
+	tests := []struct {
+		name        string
+		change      func(r *DNSRecord)
+		ignore      []string
+		wantChanged bool
+	}{
+		{name: "unchanged", change: func(*DNSRecord) {}},
+		{name: "value only", change: func(r *DNSRecord) { r.Value = "10.0.0.2" }, wantChanged: true},
+		{name: "ttl only", change: func(r *DNSRecord) { r.TTL = 60 }, wantChanged: true},
+		{name: "type only", change: func(r *DNSRecord) { r.Type = "CNAME" }, wantChanged: true},
+		{name: "name only", change: func(r *DNSRecord) { r.RR = "api" }, wantChanged: true},
+		{name: "line only", change: func(r *DNSRecord) { r.Line = "telecom" }, wantChanged: true},
+		{name: "status only", change: func(r *DNSRecord) { r.Status = "DISABLE" }, wantChanged: true},
+		{name: "record id only", change: func(r *DNSRecord) { r.RecordId = "2000" }},
+		{name: "update timestamp only", change: func(r *DNSRecord) { r.UpdateTimestamp = 1700000000000 }},
+		{name: "ignored value", change: func(r *DNSRecord) { r.Value = "10.0.0.2" }, ignore: []string{"value"}},
+		{name: "remark added", change: func(r *DNSRecord) { r.Remark = "owner: web team" }, wantChanged: true},
+		{name: "ignored remark", change: func(r *DNSRecord) { r.Remark = "owner: web team" }, ignore: []string{"remark"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := &DNSRecord{DomainName: "example.com", RR: "www", RecordId: "1000", Type: "A", Value: "10.0.0.1",
+				TTL: 600, Line: "default", Status: "ENABLE", IgnoreFields: tt.ignore}
+			after := *before
+			tt.change(&after)
+
+			if changed := before.ContentHash() != after.ContentHash(); changed != tt.wantChanged {
+				t.Errorf("content hash changed = %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+}
+
+// TestRawJSON raw_record按字段的固定顺序序列化，同一记录多次序列化结果相同，反序列化后与服务商返回的记录一致；
+// 同步流程设置的字段不写入，记录值被改写过时保存改写前的值
+func TestRawJSON(t *testing.T) {
+	const response = `{"Remark":"owner: web team","Weight":5,"Value":"10.0.0.1","UpdateTimestamp":1700000000123,` +
+		`"Type":"A","TTL":600,"Status":"ENABLE","RecordId":"1000","RR":"www","Priority":0,"Locked":true,` +
+		`"Line":"telecom","LbaStatus":true,"DomainName":"example.com","CreateTimestamp":1600000000456}`
+	const want = `{"CreateTimestamp":1600000000456,"DomainName":"example.com","LbaStatus":true,"Line":"telecom",` +
+		`"Locked":true,"Priority":0,"RR":"www","RecordId":"1000","Status":"ENABLE","TTL":600,"Type":"A",` +
+		`"UpdateTimestamp":1700000000123,"Value":"10.0.0.1","Weight":5,"Remark":"owner: web team"}`
+
+	tests := []struct {
+		name   string
+		change func(r *DNSRecord)
+		want   string
+	}{
+		{name: "provider record", change: func(*DNSRecord) {}, want: want},
+		{name: "sync fields excluded", change: func(r *DNSRecord) {
+			r.LineName, r.Values, r.IgnoreFields = "电信", []string{"10.0.0.1", "10.0.0.2"}, []string{"ttl"}
+		}, want: want},
+		{name: "rewritten value", change: func(r *DNSRecord) { r.Value, r.RawValue = "10.1.0.1", "10.0.0.1" },
+			want: want},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var record DNSRecord
+			if err := json.Unmarshal([]byte(response), &record); err != nil {
+				t.Fatal(err)
+			}
+			original := record
+			tt.change(&record)
+
+			raw := record.RawJSON()
+			if raw == nil {
+				t.Fatal("RawJSON() = nil")
+			}
+			if *raw != tt.want {
+				t.Errorf("RawJSON() = %s, want %s", *raw, tt.want)
+			}
+			for i := 0; i < 10; i++ {
+				if again := record.RawJSON(); *again != *raw {
+					t.Fatalf("RawJSON() changed between calls: %s, then %s", *raw, *again)
+				}
+			}
+			if stored := record.ConvertToAssetSubDomain("domain-1", "project-1", "Aliyun-DNS-Sync").RawRecord; stored == nil ||
+				*stored != *raw {
+				t.Errorf("raw_record = %v, want %s", stored, *raw)
+			}
+
+			var decoded DNSRecord
+			if err := json.Unmarshal([]byte(*raw), &decoded); err != nil {
+				t.Fatalf("raw_record is not valid JSON: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, original) {
+				t.Errorf("decoded raw_record = %+v, want %+v", decoded, original)
+			}
+		})
+	}
+}
+
+// TestRecordTimes 服务商的毫秒时间戳转换为本地时区的时间并保留毫秒，未返回时间戳时使用当前时间
+func TestRecordTimes(t *testing.T) {
+	tests := []struct {
+		name       string
+		create     int64
+		update     int64
+		wantCreate time.Time
+		wantUpdate time.Time
+	}{
+		{
+			name:       "both timestamps",
+			create:     1700000000123,
+			update:     1700000300456,
+			wantCreate: time.UnixMilli(1700000000123),
+			wantUpdate: time.UnixMilli(1700000300456),
+		},
+		{name: "zero update timestamp", create: 1700000000123, wantCreate: time.UnixMilli(1700000000123)},
+		{name: "zero timestamps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &DNSRecord{DomainName: "example.com", RR: "www", RecordId: "1000", Type: "A", Value: "10.0.0.1",
+				CreateTimestamp: tt.create, UpdateTimestamp: tt.update}
+			before := time.Now()
+			asset := record.ConvertToAssetSubDomain("domain-1", "project-1", "Aliyun-DNS-Sync")
+			after := time.Now()
+
+			for _, c := range []struct {
+				field string
+				got   time.Time
+				want  time.Time
+			}{
+				{field: "create_time", got: asset.CreateTime, want: tt.wantCreate},
+				{field: "update_time", got: asset.UpdateTime, want: tt.wantUpdate},
+			} {
+				if c.want.IsZero() {
+					if c.got.Before(before) || c.got.After(after) {
+						t.Errorf("%s = %s, want the current time", c.field, c.got)
+					}
+					continue
+				}
+				if !c.got.Equal(c.want) || c.got.Location() != time.Local {
+					t.Errorf("%s = %s, want %s in the local time zone", c.field, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+// TestConvertToAssetSubDomainNames rr和domain_name单独保存规范化的主机记录和主域名，sub_domain为组合后的完整域名，
+// 转换回DNS记录时得到同样的主机记录
+func TestConvertToAssetSubDomainNames(t *testing.T) {
+	tests := []struct {
+		name          string
+		rr            string
+		domain        string
+		wantRR        string
+		wantZone      string
+		wantSubDomain string
+	}{
+		{name: "apex", rr: "@", domain: "example.com", wantRR: "@", wantZone: "example.com",
+			wantSubDomain: "example.com"},
+		{name: "empty rr", rr: "", domain: "example.com", wantRR: "@", wantZone: "example.com",
+			wantSubDomain: "example.com"},
+		{name: "normal", rr: "www", domain: "example.com", wantRR: "www", wantZone: "example.com",
+			wantSubDomain: "www.example.com"},
+		{name: "nested", rr: "api.dev", domain: "example.com", wantRR: "api.dev", wantZone: "example.com",
+			wantSubDomain: "api.dev.example.com"},
+		{name: "wildcard", rr: "*", domain: "example.com", wantRR: "*", wantZone: "example.com",
+			wantSubDomain: "*.example.com"},
+		{name: "nested wildcard", rr: "*.dev", domain: "example.com", wantRR: "*.dev", wantZone: "example.com",
+			wantSubDomain: "*.dev.example.com"},
+		{name: "mixed case", rr: "WWW", domain: "Example.COM", wantRR: "www", wantZone: "example.com",
+			wantSubDomain: "www.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &DNSRecord{DomainName: tt.domain, RR: tt.rr, RecordId: "1000", Type: "A", Value: "10.0.0.1",
+				TTL: 600, Line: "default", Status: "ENABLE"}
+			asset := record.ConvertToAssetSubDomain("domain-1", "project-1", "Aliyun-DNS-Sync")
+
+			if asset.RR != tt.wantRR || asset.DomainName != tt.wantZone || asset.SubDomain != tt.wantSubDomain {
+				t.Errorf("rr, domain_name, sub_domain = %q, %q, %q, want %q, %q, %q", asset.RR, asset.DomainName,
+					asset.SubDomain, tt.wantRR, tt.wantZone, tt.wantSubDomain)
+			}
+			if got := asset.ToDNSRecord(tt.domain).RR; got != tt.wantRR {
+				t.Errorf("ToDNSRecord().RR = %q, want %q", got, tt.wantRR)
+			}
+		})
+	}
+}
+
+// TestFullDomain 主机记录和主域名组合为完整域名，RelativeRR按主域名还原主机记录，两者互为逆运算
+func TestFullDomain(t *testing.T) {
+	tests := []struct {
+		name   string
+		rr     string
+		domain string
+		want   string
+		wantRR string
+	}{
+		{name: "apex", rr: "@", domain: "example.com", want: "example.com", wantRR: "@"},
+		{name: "empty rr", rr: "", domain: "example.com", want: "example.com", wantRR: "@"},
+		{name: "single label", rr: "www", domain: "example.com", want: "www.example.com", wantRR: "www"},
+		{name: "wildcard", rr: "*", domain: "example.com", want: "*.example.com", wantRR: "*"},
+		{name: "nested", rr: "api.dev", domain: "example.com", want: "api.dev.example.com", wantRR: "api.dev"},
+		{name: "nested wildcard", rr: "*.dev", domain: "example.com", want: "*.dev.example.com", wantRR: "*.dev"},
+		{name: "mixed case and trailing dot", rr: "WWW", domain: "Example.COM.", want: "www.example.com",
+			wantRR: "www"},
+		{name: "unicode domain", rr: "www", domain: "例子.com", want: "www.xn--fsqu00a.com", wantRR: "www"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FullDomain(tt.rr, tt.domain); got != tt.want {
+				t.Errorf("FullDomain(%q, %q) = %q, want %q", tt.rr, tt.domain, got, tt.want)
+			}
+			record := &DNSRecord{DomainName: tt.domain, RR: tt.rr}
+			if got := record.FullDomain(); got != tt.want {
+				t.Errorf("DNSRecord.FullDomain() = %q, want %q", got, tt.want)
+			}
+			if got := RelativeRR(tt.want, NormalizeDomain(tt.domain)); got != tt.wantRR {
+				t.Errorf("RelativeRR(%q) = %q, want %q", tt.want, got, tt.wantRR)
+			}
+		})
+	}
+}
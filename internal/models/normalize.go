@@ -0,0 +1,73 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// valueNormalizers 按记录类型规范化记录值的规则，对比和写入数据库前统一执行
+// 未列出的类型只去掉首尾空白；新增类型时在这里添加一行即可
+var valueNormalizers = map[string]func(string) string{
+	"CNAME": normalizeHostname,
+	"NS":    normalizeHostname,
+	"MX":    normalizeHostname,
+	"PTR":   normalizeHostname,
+	"SRV":   normalizeLastField,
+	"AAAA":  strings.ToLower,
+}
+
+// NormalizeValue 按记录类型规范化记录值，避免尾部的点、大小写差异导致每次同步都误判为更新
+func NormalizeValue(recordType, value string) string {
+	value = strings.TrimSpace(value)
+	if normalize, ok := valueNormalizers[strings.ToUpper(recordType)]; ok {
+		return normalize(value)
+	}
+	return value
+}
+
+// normalizeHostname 主机名统一为小写的punycode形式，去掉末尾的点
+func normalizeHostname(value string) string {
+	return NormalizeDomain(value)
+}
+
+// normalizeLastField 只规范化最后一个字段的主机名，如SRV记录的"优先级 权重 端口 目标"
+func normalizeLastField(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return value
+	}
+	fields[len(fields)-1] = normalizeHostname(fields[len(fields)-1])
+	return strings.Join(fields, " ")
+}
+
+// CanonicalMX 组合MX记录的规范形式"优先级 主机名"，value应已规范化
+// 值本身已带优先级时（如快照文件中的"10 mail.example.com"）以值中的优先级为准，不重复拼接
+func CanonicalMX(priority int32, value string) string {
+	if fields := strings.Fields(value); len(fields) == 2 {
+		if p, err := strconv.Atoi(fields[0]); err == nil && p >= 0 {
+			return strconv.Itoa(p) + " " + fields[1]
+		}
+	}
+	return strconv.Itoa(int(priority)) + " " + value
+}
+
+// CanonicalSRV 组合SRV记录的规范形式"优先级 权重 端口 目标"，数字去掉前导零，value应已规范化
+// 阿里云、DNSPod和Route53的值已包含全部四个字段；Cloudflare的值为"权重 端口 目标"，优先级单独返回，这里补在最前面
+// 字段数或数字不符合格式时原样返回
+func CanonicalSRV(priority int32, value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 3 {
+		fields = append([]string{strconv.Itoa(int(priority))}, fields...)
+	}
+	if len(fields) != 4 {
+		return value
+	}
+	for i := 0; i < 3; i++ {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil || n < 0 {
+			return value
+		}
+		fields[i] = strconv.Itoa(n)
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,125 @@
+package models
+
+import "testing"
+
+func TestCanonicalMX(t *testing.T) {
+	tests := []struct {
+		name     string
+		priority int32
+		value    string
+		want     string
+	}{
+		{name: "priority from field", priority: 10, value: "mail.example.com", want: "10 mail.example.com"},
+		{name: "priority already in value", priority: 20, value: "10 mail.example.com", want: "10 mail.example.com"},
+		{name: "leading zero in value", priority: 0, value: "05 mail.example.com", want: "5 mail.example.com"},
+		{name: "zero priority", priority: 0, value: "mail.example.com", want: "0 mail.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanonicalMX(tt.priority, tt.value); got != tt.want {
+				t.Errorf("CanonicalMX(%d, %q) = %q, want %q", tt.priority, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalSRV(t *testing.T) {
+	tests := []struct {
+		name     string
+		priority int32
+		value    string
+		want     string
+	}{
+		{name: "all four fields", priority: 1, value: "1 10 5269 xmpp.example.com", want: "1 10 5269 xmpp.example.com"},
+		{name: "priority returned separately", priority: 1, value: "10 5269 xmpp.example.com", want: "1 10 5269 xmpp.example.com"},
+		{name: "leading zeros", priority: 0, value: "01 010 05269 xmpp.example.com", want: "1 10 5269 xmpp.example.com"},
+		{name: "too few fields", priority: 1, value: "5269 xmpp.example.com", want: "5269 xmpp.example.com"},
+		{name: "non numeric port", priority: 1, value: "1 10 xmpp xmpp.example.com", want: "1 10 xmpp xmpp.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanonicalSRV(tt.priority, tt.value); got != tt.want {
+				t.Errorf("CanonicalSRV(%d, %q) = %q, want %q", tt.priority, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordValueChangeDetection(t *testing.T) {
+	tests := []struct {
+		name        string
+		before      DNSRecord
+		after       DNSRecord
+		wantValue   string
+		wantChanged bool
+	}{
+		{
+			name:      "MX trailing dot and case",
+			before:    DNSRecord{Type: "MX", Value: "mail.example.com", Priority: 10},
+			after:     DNSRecord{Type: "mx", Value: "Mail.Example.com.", Priority: 10},
+			wantValue: "10 mail.example.com",
+		},
+		{
+			name:        "MX priority only",
+			before:      DNSRecord{Type: "MX", Value: "mail.example.com", Priority: 10},
+			after:       DNSRecord{Type: "MX", Value: "mail.example.com", Priority: 20},
+			wantValue:   "20 mail.example.com",
+			wantChanged: true,
+		},
+		{
+			name:      "SRV priority returned separately",
+			before:    DNSRecord{Type: "SRV", Value: "1 10 5269 xmpp.example.com"},
+			after:     DNSRecord{Type: "SRV", Value: "10 5269 XMPP.example.com.", Priority: 1},
+			wantValue: "1 10 5269 xmpp.example.com",
+		},
+		{
+			name:        "SRV weight only",
+			before:      DNSRecord{Type: "SRV", Value: "1 10 5269 xmpp.example.com"},
+			after:       DNSRecord{Type: "SRV", Value: "1 20 5269 xmpp.example.com"},
+			wantValue:   "1 20 5269 xmpp.example.com",
+			wantChanged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, r := range []*DNSRecord{&tt.before, &tt.after} {
+				r.DomainName, r.RR, r.TTL, r.Line, r.Status = "example.com", "_xmpp._tcp", 600, "default", "ENABLE"
+			}
+
+			if got := tt.after.RecordValue(); got != tt.wantValue {
+				t.Errorf("RecordValue() = %q, want %q", got, tt.wantValue)
+			}
+			if changed := tt.before.ContentHash() != tt.after.ContentHash(); changed != tt.wantChanged {
+				t.Errorf("content hash changed = %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestNormalizeValue(t *testing.T) {
+	tests := []struct {
+		recordType string
+		value      string
+		want       string
+	}{
+		{recordType: "CNAME", value: "target.example.com.", want: "target.example.com"},
+		{recordType: "CNAME", value: "Target.Example.COM", want: "target.example.com"},
+		{recordType: "cname", value: " Target.Example.com. ", want: "target.example.com"},
+		{recordType: "NS", value: "NS1.Example.net.", want: "ns1.example.net"},
+		{recordType: "MX", value: "Mail.Example.com.", want: "mail.example.com"},
+		{recordType: "SRV", value: "1 10 5269 XMPP.Example.com.", want: "1 10 5269 xmpp.example.com"},
+		{recordType: "A", value: " 10.0.0.1\n", want: "10.0.0.1"},
+		{recordType: "AAAA", value: "2001:DB8::1", want: "2001:db8::1"},
+		// TXT区分大小写，只去掉首尾空白
+		{recordType: "TXT", value: " v=spf1 Include:Example.com ", want: "v=spf1 Include:Example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeValue(tt.recordType, tt.value); got != tt.want {
+			t.Errorf("NormalizeValue(%q, %q) = %q, want %q", tt.recordType, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// labelSeparators IDNA中与.等价的标签分隔符：全角句号、表意文字句号和半角表意文字句号
+var labelSeparators = strings.NewReplacer("．", ".", "。", ".", "｡", ".")
+
+// NormalizeDomain 将域名统一为小写的punycode（IDNA ASCII）形式，去掉末尾的点
+// 通配符*等ASCII标签保持不变，只有含非ASCII字符的标签按IDNA映射后编码，如全角的ｅｘａｍｐｌｅ映射为example
+func NormalizeDomain(name string) string {
+	name = labelSeparators.Replace(name)
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if isASCII(label) {
+			continue
+		}
+		labels[i] = labelToASCII(label)
+	}
+
+	return strings.Join(labels, ".")
+}
+
+// labelToASCII 按IDNA查询规则转换单个标签；不符合规则的标签直接按punycode编码，保证相同写法得到相同结果
+func labelToASCII(label string) string {
+	if ascii, err := idna.Lookup.ToASCII(label); err == nil {
+		return ascii
+	}
+	ascii, _ := idna.Punycode.ToASCII(label)
+	return ascii
+}
+
+// isASCII 判断字符串是否只包含ASCII字符
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
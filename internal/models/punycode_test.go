@@ -0,0 +1,32 @@
+package models
+
+import "testing"
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "ascii lowercased", input: "WWW.Example.COM", want: "www.example.com"},
+		{name: "trailing dot", input: "example.com.", want: "example.com"},
+		{name: "wildcard kept", input: "*.Example.com", want: "*.example.com"},
+		{name: "service labels kept", input: "_sip._tcp.example.com", want: "_sip._tcp.example.com"},
+		{name: "chinese label", input: "www.例子.com", want: "www.xn--fsqu00a.com"},
+		{name: "wildcard with idn", input: "*.例子.com", want: "*.xn--fsqu00a.com"},
+		{name: "full-width letters", input: "ｅｘａｍｐｌｅ.com", want: "example.com"},
+		{name: "full-width uppercase letters", input: "ＷＷＷ.example.com", want: "www.example.com"},
+		{name: "ideographic full stop", input: "中文。com", want: "xn--fiq228c.com"},
+		{name: "full-width full stop", input: "www．例子．com．", want: "www.xn--fsqu00a.com"},
+		{name: "already punycode", input: "www.XN--FSQU00A.com", want: "www.xn--fsqu00a.com"},
+		{name: "german sharp s", input: "straße.de", want: "xn--strae-oqa.de"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeDomain(tt.input); got != tt.want {
+				t.Errorf("NormalizeDomain(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package models
+
+import "strings"
+
+// Transformer 改写服务商记录，在拉取之后、对比和写入之前执行
+// 不修改传入的记录：值需要变化时返回副本，否则原样返回
+type Transformer func(record *DNSRecord) *DNSRecord
+
+// Chain 按顺序组合多个Transformer，前一个的结果作为后一个的输入；没有Transformer时原样返回记录
+func Chain(transformers ...Transformer) Transformer {
+	return func(record *DNSRecord) *DNSRecord {
+		for _, transform := range transformers {
+			record = transform(record)
+		}
+		return record
+	}
+}
+
+// LowercaseValue 记录值转为小写
+func LowercaseValue() Transformer {
+	return valueTransformer(strings.ToLower)
+}
+
+// StripTrailingDot 去掉记录值末尾的点，如CNAME目标的target.example.com.
+func StripTrailingDot() Transformer {
+	return valueTransformer(func(value string) string {
+		return strings.TrimSuffix(value, ".")
+	})
+}
+
+// SuffixStrip 去掉记录值的后缀，不区分大小写，值末尾的点保留；值等于后缀本身时不改写
+// 如suffix为.internal时，app.svc.internal改写为app.svc，SRV记录的值只有最后的目标主机名会带有后缀
+func SuffixStrip(suffix string) Transformer {
+	suffix = strings.ToLower(strings.TrimSuffix(suffix, "."))
+	return valueTransformer(func(value string) string {
+		name := strings.TrimSuffix(value, ".")
+		if len(name) <= len(suffix) || !strings.HasSuffix(strings.ToLower(name), suffix) {
+			return value
+		}
+		return name[:len(name)-len(suffix)] + value[len(name):]
+	})
+}
+
+// valueTransformer 用rewrite改写记录值，值变化时返回副本并在RawValue中保留服务商返回的原值
+func valueTransformer(rewrite func(string) string) Transformer {
+	return func(record *DNSRecord) *DNSRecord {
+		value := rewrite(record.Value)
+		if value == record.Value {
+			return record
+		}
+		transformed := *record
+		if transformed.RawValue == "" {
+			transformed.RawValue = record.Value
+		}
+		transformed.Value = value
+		return &transformed
+	}
+}
@@ -0,0 +1,80 @@
+package models
+
+import "testing"
+
+// TestTransformers 单个改写规则只改写记录值，值变化时返回副本并保留服务商返回的原值
+func TestTransformers(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform Transformer
+		value     string
+		want      string
+	}{
+		{name: "lowercase", transform: LowercaseValue(), value: "Target.Example.COM", want: "target.example.com"},
+		{name: "strip trailing dot", transform: StripTrailingDot(), value: "target.example.com.", want: "target.example.com"},
+		{name: "strip trailing dot unchanged", transform: StripTrailingDot(), value: "10.0.0.1", want: "10.0.0.1"},
+		{name: "suffix strip", transform: SuffixStrip(".internal"), value: "app.svc.internal", want: "app.svc"},
+		{name: "suffix strip keeps dot", transform: SuffixStrip(".internal."), value: "app.svc.INTERNAL.",
+			want: "app.svc."},
+		{name: "suffix strip srv target", transform: SuffixStrip(".internal"), value: "1 10 5269 xmpp.internal",
+			want: "1 10 5269 xmpp"},
+		{name: "suffix strip label only", transform: SuffixStrip(".internal"), value: "app.myinternal",
+			want: "app.myinternal"},
+		{name: "suffix strip whole value", transform: SuffixStrip(".internal"), value: ".internal", want: ".internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &DNSRecord{RR: "app", Type: "CNAME", Value: tt.value}
+			got := tt.transform(record)
+			if got.Value != tt.want {
+				t.Errorf("Value = %q, want %q", got.Value, tt.want)
+			}
+			if record.Value != tt.value {
+				t.Errorf("input record modified to %q", record.Value)
+			}
+			if tt.want == tt.value {
+				if got != record || got.RawValue != "" {
+					t.Errorf("unchanged value returned a copy with RawValue %q", got.RawValue)
+				}
+			} else if got == record || got.RawValue != tt.value {
+				t.Errorf("RawValue = %q, want %q in a copy", got.RawValue, tt.value)
+			}
+		})
+	}
+}
+
+// TestChain 多个改写规则按顺序执行，后一个规则看到前一个的结果，RawValue始终为服务商返回的原值
+func TestChain(t *testing.T) {
+	tests := []struct {
+		name  string
+		chain Transformer
+		value string
+		want  string
+	}{
+		{name: "empty chain", chain: Chain(), value: "App.svc.internal.", want: "App.svc.internal."},
+		{name: "strip dot then suffix", chain: Chain(StripTrailingDot(), SuffixStrip(".internal")),
+			value: "App.svc.internal.", want: "App.svc"},
+		{name: "lowercase then suffix", chain: Chain(LowercaseValue(), SuffixStrip(".internal")),
+			value: "App.svc.INTERNAL", want: "app.svc"},
+		// 先去掉后缀时末尾的点保留，再去掉点
+		{name: "suffix then strip dot", chain: Chain(SuffixStrip(".internal"), StripTrailingDot()),
+			value: "app.svc.internal.", want: "app.svc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &DNSRecord{RR: "app", Type: "CNAME", Value: tt.value}
+			got := tt.chain(record)
+			if got.Value != tt.want {
+				t.Errorf("Value = %q, want %q", got.Value, tt.want)
+			}
+			if record.Value != tt.value || record.RawValue != "" {
+				t.Errorf("input record modified to %q/%q", record.Value, record.RawValue)
+			}
+			if tt.want != tt.value && got.RawValue != tt.value {
+				t.Errorf("RawValue = %q, want %q", got.RawValue, tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+import "strings"
+
+// InZone 判断记录的完整域名是否为zone本身或zone下的子域名，zone应为规范化的域名
+func (d *DNSRecord) InZone(zone string) bool {
+	name := d.FullDomain()
+	return name == zone || strings.HasSuffix(name, "."+zone)
+}
+
+// IsSystemRecord 判断记录是否为zone本身的NS或SOA记录，这些记录由服务商维护区域时生成，不是资产
+// name和zone应为规范化的域名，recordType不区分大小写
+func IsSystemRecord(name, recordType, zone string) bool {
+	if name != zone {
+		return false
+	}
+	switch strings.ToUpper(recordType) {
+	case "NS", "SOA":
+		return true
+	}
+	return false
+}
+
+// Rebase 返回以zone为主域名的记录副本，主机记录改为相对zone的形式，完整域名不变
+// 用于在服务商的注册域名下查询托管的子区域，调用前应先用InZone确认记录属于zone
+func (d *DNSRecord) Rebase(zone string) *DNSRecord {
+	rebased := *d
+	rebased.DomainName = zone
+	rebased.RR = RelativeRR(d.FullDomain(), zone)
+	return &rebased
+}
@@ -0,0 +1,69 @@
+package models
+
+import "testing"
+
+// TestRebase 注册域名下查询到的记录按子区域过滤并改写主机记录，完整域名保持不变
+func TestRebase(t *testing.T) {
+	tests := []struct {
+		name       string
+		rr         string
+		zone       string
+		wantInZone bool
+		wantRR     string
+	}{
+		{name: "subzone apex", rr: "api", zone: "api.example.com", wantInZone: true, wantRR: "@"},
+		{name: "host in subzone", rr: "www.api", zone: "api.example.com", wantInZone: true, wantRR: "www"},
+		{name: "nested host", rr: "a.b.api", zone: "api.example.com", wantInZone: true, wantRR: "a.b"},
+		{name: "wildcard in subzone", rr: "*.api", zone: "api.example.com", wantInZone: true, wantRR: "*"},
+		{name: "mixed case", rr: "WWW.Api", zone: "api.example.com", wantInZone: true, wantRR: "www"},
+		{name: "sibling host", rr: "www", zone: "api.example.com"},
+		{name: "registered apex", rr: "@", zone: "api.example.com"},
+		{name: "label suffix only", rr: "myapi", zone: "api.example.com"},
+		{name: "zone is apex", rr: "www", zone: "example.com", wantInZone: true, wantRR: "www"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &DNSRecord{DomainName: "example.com", RR: tt.rr, Type: "A", Value: "10.0.0.1"}
+			if got := record.InZone(tt.zone); got != tt.wantInZone {
+				t.Fatalf("InZone(%q) = %v, want %v", tt.zone, got, tt.wantInZone)
+			}
+			if !tt.wantInZone {
+				return
+			}
+
+			rebased := record.Rebase(tt.zone)
+			if rebased.DomainName != tt.zone || rebased.RR != tt.wantRR {
+				t.Errorf("Rebase() = %s/%s, want %s/%s", rebased.DomainName, rebased.RR, tt.zone, tt.wantRR)
+			}
+			if rebased.FullDomain() != record.FullDomain() {
+				t.Errorf("rebased FullDomain() = %s, want %s", rebased.FullDomain(), record.FullDomain())
+			}
+			if record.DomainName != "example.com" || record.RR != tt.rr {
+				t.Errorf("Rebase() modified the original record: %s/%s", record.DomainName, record.RR)
+			}
+		})
+	}
+}
+
+// TestIsSystemRecord 只有域名本身的NS和SOA记录是系统记录，子域名的NS委派和其它类型都不是
+func TestIsSystemRecord(t *testing.T) {
+	tests := []struct {
+		name       string
+		recordType string
+		want       bool
+	}{
+		{name: "example.com", recordType: "NS", want: true},
+		{name: "example.com", recordType: "soa", want: true},
+		{name: "example.com", recordType: "MX"},
+		{name: "example.com", recordType: "TXT"},
+		{name: "dev.example.com", recordType: "NS"},
+		{name: "www.example.com", recordType: "SOA"},
+	}
+
+	for _, tt := range tests {
+		if got := IsSystemRecord(tt.name, tt.recordType, "example.com"); got != tt.want {
+			t.Errorf("IsSystemRecord(%q, %q) = %v, want %v", tt.name, tt.recordType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/logger"
+	"dns-sync/internal/models"
+)
+
+// Webhook 同步完成后把摘要POST到配置的地址
+type Webhook struct {
+	url    string
+	format string
+	always bool
+	client *http.Client
+}
+
+// payload json格式的请求体
+type payload struct {
+	Status        string            `json:"status"`
+	ExitCode      int               `json:"exit_code"`
+	StartTime     time.Time         `json:"start_time"`
+	EndTime       time.Time         `json:"end_time"`
+	DryRun        bool              `json:"dry_run"`
+	Totals        models.SyncTotals `json:"totals"`
+	Failures      []failure         `json:"failures,omitempty"`
+	PostSyncError string            `json:"post_sync_error,omitempty"`
+}
+
+// failure 同步失败或部分记录写入失败的域名
+type failure struct {
+	Domain        string `json:"domain"`
+	Error         string `json:"error,omitempty"`
+	FailedRecords int    `json:"failed_records,omitempty"`
+}
+
+// slackMessage Slack incoming webhook的消息体
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// NewWebhook 按配置创建webhook通知，未配置webhook_url时返回nil
+func NewWebhook(cfg config.NotifyConfig) *Webhook {
+	if cfg.WebhookURL == "" {
+		return nil
+	}
+	return &Webhook{
+		url:    cfg.WebhookURL,
+		format: cfg.Format,
+		always: cfg.On == "always",
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Notify 发送本次同步的摘要，exitCode为0且on不为always时不发送
+// 错误信息中的敏感值在发送前屏蔽；返回的错误只用于记录日志，不影响同步结果
+func (w *Webhook) Notify(ctx context.Context, report *models.SyncReport, exitCode int) error {
+	if w == nil || (exitCode == 0 && !w.always) {
+		return nil
+	}
+
+	var body any = buildPayload(report, exitCode)
+	if w.format == "slack" {
+		body = slackMessage{Text: slackText(report, exitCode)}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	data = []byte(logger.Redact(string(data)))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to build notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to send notification: webhook returned status %d", resp.StatusCode)
+	}
+
+	slog.Debug("Sent sync notification", "format", w.format, "exit_code", exitCode)
+	return nil
+}
+
+// buildPayload 组装json格式的请求体，只列出失败和部分失败的域名
+func buildPayload(report *models.SyncReport, exitCode int) payload {
+	p := payload{
+		Status:        "succeeded",
+		ExitCode:      exitCode,
+		StartTime:     report.StartTime,
+		EndTime:       report.EndTime,
+		DryRun:        report.DryRun,
+		Totals:        report.Totals,
+		PostSyncError: report.PostSyncError,
+	}
+	if exitCode != 0 {
+		p.Status = "failed"
+	}
+	for _, domain := range report.Domains {
+		if domain.Error != "" || domain.Degraded {
+			p.Failures = append(p.Failures, failure{Domain: domain.Domain, Error: domain.Error,
+				FailedRecords: domain.Failed})
+		}
+	}
+	return p
+}
+
+// slackText 组装Slack消息：第一行为结果和合计，之后每个失败的域名一行
+func slackText(report *models.SyncReport, exitCode int) string {
+	p := buildPayload(report, exitCode)
+
+	var b strings.Builder
+	if exitCode != 0 {
+		fmt.Fprintf(&b, ":x: dns-sync failed (exit code %d)", exitCode)
+	} else {
+		b.WriteString(":white_check_mark: dns-sync succeeded")
+	}
+	if p.DryRun {
+		b.WriteString(" [dry-run]")
+	}
+	fmt.Fprintf(&b, "\nDomains: %d succeeded, %d failed, %d degraded, %d skipped | Records: +%d ~%d -%d",
+		p.Totals.Succeeded, p.Totals.Failed, p.Totals.Degraded, p.Totals.Skipped,
+		p.Totals.Added, p.Totals.Updated, p.Totals.Deleted)
+	for _, f := range p.Failures {
+		if f.Error != "" {
+			fmt.Fprintf(&b, "\n• %s: %s", f.Domain, f.Error)
+		} else {
+			fmt.Fprintf(&b, "\n• %s: %d records failed", f.Domain, f.FailedRecords)
+		}
+	}
+	if p.PostSyncError != "" {
+		fmt.Fprintf(&b, "\n• post_sync_sql: %s", p.PostSyncError)
+	}
+	return b.String()
+}
@@ -0,0 +1,191 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/models"
+)
+
+// testReport 一个域名成功、一个失败、一个部分记录写入失败的同步报告
+func testReport() *models.SyncReport {
+	return &models.SyncReport{
+		StartTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC),
+		Totals:    models.SyncTotals{Domains: 3, Succeeded: 2, Failed: 1, Degraded: 1, Added: 5, Updated: 1, Deleted: 2},
+		Domains: []models.DomainSyncResult{
+			{Domain: "example.com", Success: true, Added: 5},
+			{Domain: "example.net", Error: "failed to get DNS records: request failed"},
+			{Domain: "example.org", Success: true, Degraded: true, Failed: 3},
+		},
+	}
+}
+
+// receiver 记录收到的请求体
+type receiver struct {
+	mu     sync.Mutex
+	bodies []string
+	status int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	r.bodies = append(r.bodies, string(body))
+	r.mu.Unlock()
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(r.status)
+}
+
+func TestWebhookNotify(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		on       string
+		exitCode int
+		status   int
+		// check 为nil表示不应发送通知
+		check   func(t *testing.T, body string)
+		wantErr string
+	}{
+		{
+			name:     "success suppressed by default",
+			exitCode: 0,
+		},
+		{
+			name:     "success sent when always",
+			on:       "always",
+			exitCode: 0,
+			check: func(t *testing.T, body string) {
+				var p payload
+				if err := json.Unmarshal([]byte(body), &p); err != nil {
+					t.Fatalf("invalid payload: %v", err)
+				}
+				if p.Status != "succeeded" || p.ExitCode != 0 || p.Totals.Added != 5 {
+					t.Errorf("payload = %+v", p)
+				}
+			},
+		},
+		{
+			name:     "failure json",
+			exitCode: 2,
+			check: func(t *testing.T, body string) {
+				var p payload
+				if err := json.Unmarshal([]byte(body), &p); err != nil {
+					t.Fatalf("invalid payload: %v", err)
+				}
+				if p.Status != "failed" || p.ExitCode != 2 || p.Totals.Failed != 1 {
+					t.Errorf("payload = %+v", p)
+				}
+				want := []failure{
+					{Domain: "example.net", Error: "failed to get DNS records: request failed"},
+					{Domain: "example.org", FailedRecords: 3},
+				}
+				if len(p.Failures) != len(want) {
+					t.Fatalf("failures = %+v, want %+v", p.Failures, want)
+				}
+				for i := range want {
+					if p.Failures[i] != want[i] {
+						t.Errorf("failures[%d] = %+v, want %+v", i, p.Failures[i], want[i])
+					}
+				}
+			},
+		},
+		{
+			name:     "failure slack",
+			format:   "slack",
+			exitCode: 2,
+			check: func(t *testing.T, body string) {
+				var m slackMessage
+				if err := json.Unmarshal([]byte(body), &m); err != nil {
+					t.Fatalf("invalid slack message: %v", err)
+				}
+				for _, want := range []string{
+					":x: dns-sync failed (exit code 2)",
+					"Domains: 2 succeeded, 1 failed, 1 degraded, 0 skipped | Records: +5 ~1 -2",
+					"• example.net: failed to get DNS records: request failed",
+					"• example.org: 3 records failed",
+				} {
+					if !strings.Contains(m.Text, want) {
+						t.Errorf("slack text %q does not contain %q", m.Text, want)
+					}
+				}
+			},
+		},
+		{
+			name:     "receiver error",
+			exitCode: 1,
+			status:   http.StatusInternalServerError,
+			check:    func(*testing.T, string) {},
+			wantErr:  "webhook returned status 500",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recv := &receiver{status: tt.status}
+			if recv.status == 0 {
+				recv.status = http.StatusOK
+			}
+			server := httptest.NewServer(recv)
+			defer server.Close()
+
+			webhook := NewWebhook(config.NotifyConfig{WebhookURL: server.URL, Format: tt.format, On: tt.on,
+				Timeout: time.Second})
+			err := webhook.Notify(context.Background(), testReport(), tt.exitCode)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Notify() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+
+			if tt.check == nil {
+				if len(recv.bodies) != 0 {
+					t.Fatalf("got %d notifications, want none", len(recv.bodies))
+				}
+				return
+			}
+			if len(recv.bodies) != 1 {
+				t.Fatalf("got %d notifications, want 1", len(recv.bodies))
+			}
+			tt.check(t, recv.bodies[0])
+		})
+	}
+}
+
+func TestWebhookTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	webhook := NewWebhook(config.NotifyConfig{WebhookURL: server.URL, Timeout: 50 * time.Millisecond})
+	if err := webhook.Notify(context.Background(), testReport(), 1); err == nil {
+		t.Fatal("Notify() error = nil, want timeout")
+	}
+}
+
+func TestNilWebhook(t *testing.T) {
+	if webhook := NewWebhook(config.NotifyConfig{}); webhook != nil {
+		t.Fatalf("NewWebhook() = %v, want nil without webhook_url", webhook)
+	}
+	var webhook *Webhook
+	if err := webhook.Notify(context.Background(), testReport(), 1); err != nil {
+		t.Errorf("Notify() on nil webhook error = %v", err)
+	}
+}
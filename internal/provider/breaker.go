@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"dns-sync/internal/models"
+)
+
+// ErrCircuitOpen 服务商连续失败次数达到阈值，本轮不再调用该服务商
+var ErrCircuitOpen = errors.New("provider circuit open")
+
+// CircuitBreaker 按服务商统计拉取记录连续失败次数的熔断器，达到阈值后本轮剩余的调用直接返回ErrCircuitOpen
+// 任一次成功的调用会清零计数；每轮同步开始时调用Reset重新放行
+type CircuitBreaker struct {
+	// threshold 连续失败多少次后熔断，不大于0时不熔断
+	threshold int
+
+	mu       sync.Mutex
+	failures map[string]int
+	// open 已熔断的服务商及触发熔断的最后一个错误
+	open map[string]error
+}
+
+// NewCircuitBreaker 创建熔断器，threshold不大于0时只透传调用
+func NewCircuitBreaker(threshold int) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		failures:  make(map[string]int),
+		open:      make(map[string]error),
+	}
+}
+
+// Reset 清除所有服务商的失败计数和熔断状态
+func (b *CircuitBreaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = make(map[string]int)
+	b.open = make(map[string]error)
+}
+
+// allow 服务商已熔断时返回包装了ErrCircuitOpen的错误
+func (b *CircuitBreaker) allow(key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if lastErr, ok := b.open[key]; ok {
+		return fmt.Errorf("%w: %s failed %d times in a row, last error: %v", ErrCircuitOpen, key, b.threshold, lastErr)
+	}
+	return nil
+}
+
+// record 记录一次调用的结果；域名不存在、记录数超限和取消不代表服务商故障，不计入失败
+func (b *CircuitBreaker) record(key string, err error) {
+	if b.threshold <= 0 {
+		return
+	}
+	if err != nil && (errors.Is(err, ErrDomainNotFound) || errors.Is(err, ErrTooManyRecords) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen)) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.failures[key] = 0
+		return
+	}
+	if _, open := b.open[key]; open {
+		return
+	}
+	b.failures[key]++
+	if b.failures[key] >= b.threshold {
+		b.open[key] = err
+		slog.Error("Provider keeps failing, skipping it for the rest of this run", "provider", key,
+			"consecutive_failures", b.failures[key], "error", err)
+	}
+}
+
+// Wrap 返回经过熔断器的服务商客户端，支持推送的客户端包装后仍实现RecordPusher
+func (b *CircuitBreaker) Wrap(key string, p DNSProvider) DNSProvider {
+	guarded := &breakerProvider{DNSProvider: p, key: key, breaker: b}
+	if pusher, ok := p.(RecordPusher); ok {
+		return &breakerPusher{breakerProvider: guarded, pusher: pusher}
+	}
+	return guarded
+}
+
+// breakerProvider 熔断器包装的服务商客户端，连接测试和域名检查不经过熔断器
+type breakerProvider struct {
+	DNSProvider
+	key     string
+	breaker *CircuitBreaker
+}
+
+// GetDomainRecords 服务商未熔断时获取域名记录
+func (p *breakerProvider) GetDomainRecords(ctx context.Context, domain string) ([]*models.DNSRecord, error) {
+	if err := p.breaker.allow(p.key); err != nil {
+		return nil, err
+	}
+	records, err := p.DNSProvider.GetDomainRecords(ctx, domain)
+	p.breaker.record(p.key, err)
+	return records, err
+}
+
+// breakerPusher 熔断器包装的支持推送的服务商客户端
+// 推送失败多为单条记录的参数错误，不计入连续失败，只在已熔断时拒绝调用
+type breakerPusher struct {
+	*breakerProvider
+	pusher RecordPusher
+}
+
+// AddDomainRecord 服务商未熔断时新增记录
+func (p *breakerPusher) AddDomainRecord(ctx context.Context, record *models.DNSRecord) (string, error) {
+	if err := p.breaker.allow(p.key); err != nil {
+		return "", err
+	}
+	return p.pusher.AddDomainRecord(ctx, record)
+}
+
+// UpdateDomainRecord 服务商未熔断时更新记录
+func (p *breakerPusher) UpdateDomainRecord(ctx context.Context, record *models.DNSRecord) error {
+	if err := p.breaker.allow(p.key); err != nil {
+		return err
+	}
+	return p.pusher.UpdateDomainRecord(ctx, record)
+}
+
+// DeleteDomainRecord 服务商未熔断时删除记录
+func (p *breakerPusher) DeleteDomainRecord(ctx context.Context, recordID string) error {
+	if err := p.breaker.allow(p.key); err != nil {
+		return err
+	}
+	return p.pusher.DeleteDomainRecord(ctx, recordID)
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"dns-sync/internal/models"
+)
+
+// countingProvider 记录GetDomainRecords的调用次数，按顺序返回errs中的错误，用完后返回nil
+type countingProvider struct {
+	calls int
+	errs  []error
+}
+
+func (p *countingProvider) GetDomainRecords(ctx context.Context, domain string) ([]*models.DNSRecord, error) {
+	p.calls++
+	if len(p.errs) == 0 {
+		return nil, nil
+	}
+	err := p.errs[0]
+	p.errs = p.errs[1:]
+	return nil, err
+}
+
+func (p *countingProvider) TestConnection(ctx context.Context) error { return nil }
+
+func (p *countingProvider) VerifyDomains(ctx context.Context, domains []string) error { return nil }
+
+// repeat 返回n个相同的错误
+func repeat(err error, n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+// TestCircuitBreaker 连续失败达到阈值后不再调用服务商，直接返回ErrCircuitOpen；成功的调用清零计数，
+// 域名不存在和记录数超限不计入失败
+func TestCircuitBreaker(t *testing.T) {
+	outage := errors.New("request failed: connection refused")
+	notFound := fmt.Errorf("%w: example.com", ErrDomainNotFound)
+
+	tests := []struct {
+		name      string
+		threshold int
+		errs      []error
+		requests  int
+		wantCalls int
+		wantOpen  int
+	}{
+		{name: "opens at threshold", threshold: 3, errs: repeat(outage, 10), requests: 10, wantCalls: 3, wantOpen: 7},
+		{
+			name:      "success resets count",
+			threshold: 3,
+			errs:      []error{outage, outage, nil, outage, outage, nil},
+			requests:  6,
+			wantCalls: 6,
+		},
+		{name: "domain not found not counted", threshold: 2, errs: repeat(notFound, 5), requests: 5, wantCalls: 5},
+		{
+			name:      "too many records not counted",
+			threshold: 2,
+			errs:      repeat(fmt.Errorf("%w: example.com has more than 10 records", ErrTooManyRecords), 4),
+			requests:  4,
+			wantCalls: 4,
+		},
+		{name: "disabled", threshold: -1, errs: repeat(outage, 10), requests: 10, wantCalls: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &countingProvider{errs: tt.errs}
+			guarded := NewCircuitBreaker(tt.threshold).Wrap("aliyun", inner)
+
+			open := 0
+			for i := 0; i < tt.requests; i++ {
+				if _, err := guarded.GetDomainRecords(context.Background(), "example.com"); errors.Is(err, ErrCircuitOpen) {
+					open++
+				}
+			}
+			if inner.calls != tt.wantCalls {
+				t.Errorf("provider called %d times, want %d", inner.calls, tt.wantCalls)
+			}
+			if open != tt.wantOpen {
+				t.Errorf("got %d ErrCircuitOpen, want %d", open, tt.wantOpen)
+			}
+		})
+	}
+}
+
+// TestCircuitBreakerKeys 每个服务商单独计数，一个服务商熔断不影响其它服务商；Reset后重新放行
+func TestCircuitBreakerKeys(t *testing.T) {
+	outage := errors.New("request failed: connection refused")
+	breaker := NewCircuitBreaker(2)
+	failing := &countingProvider{errs: repeat(outage, 10)}
+	healthy := &countingProvider{}
+	guardedFailing := breaker.Wrap("aliyun", failing)
+	guardedHealthy := breaker.Wrap("aliyun:other", healthy)
+
+	for i := 0; i < 5; i++ {
+		guardedFailing.GetDomainRecords(context.Background(), "example.com")
+		if _, err := guardedHealthy.GetDomainRecords(context.Background(), "example.net"); err != nil {
+			t.Fatalf("healthy provider error = %v", err)
+		}
+	}
+	if failing.calls != 2 || healthy.calls != 5 {
+		t.Errorf("calls = %d failing, %d healthy, want 2 and 5", failing.calls, healthy.calls)
+	}
+
+	_, err := guardedFailing.GetDomainRecords(context.Background(), "example.com")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error = %v, want ErrCircuitOpen", err)
+	}
+	if want := "aliyun failed 2 times in a row, last error: " + outage.Error(); !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %v, want it to contain %q", err, want)
+	}
+
+	breaker.Reset()
+	guardedFailing.GetDomainRecords(context.Background(), "example.com")
+	if failing.calls != 3 {
+		t.Errorf("calls after Reset = %d, want 3", failing.calls)
+	}
+}
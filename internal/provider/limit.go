@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"dns-sync/internal/models"
+)
+
+// ErrTooManyRecords 域名的记录数超出max_records
+var ErrTooManyRecords = errors.New("too many records")
+
+// RecordLimit 拉取单个域名记录数的上限，通过WithRecordLimit传给GetDomainRecords
+type RecordLimit struct {
+	// Max 最多拉取的记录数，0表示不限制
+	Max int
+	// Truncate 超出时截断到Max条并停止分页；为false时返回ErrTooManyRecords
+	Truncate bool
+	// Truncated 本次拉取是否因超出上限被截断，由服务商客户端设置
+	Truncated bool
+}
+
+// limitKey 上下文中RecordLimit的键
+type limitKey struct{}
+
+// WithRecordLimit 返回携带记录数上限的上下文，每次拉取应使用新的RecordLimit
+func WithRecordLimit(ctx context.Context, limit *RecordLimit) context.Context {
+	return context.WithValue(ctx, limitKey{}, limit)
+}
+
+// ApplyRecordLimit 在每页记录追加到records之后调用，检查是否超出上下文中的记录数上限
+// 返回截断后的记录和是否应停止分页；未设置上限或未超出时原样返回
+func ApplyRecordLimit(ctx context.Context, domain string, records []*models.DNSRecord) ([]*models.DNSRecord, bool, error) {
+	limit, _ := ctx.Value(limitKey{}).(*RecordLimit)
+	if limit == nil || limit.Max <= 0 || len(records) <= limit.Max {
+		return records, false, nil
+	}
+
+	if !limit.Truncate {
+		return nil, true, fmt.Errorf("%w: %s has more than %d records", ErrTooManyRecords, domain, limit.Max)
+	}
+
+	slog.Warn("Domain exceeds max_records, truncating", "domain", domain, "max_records", limit.Max)
+	limit.Truncated = true
+	return records[:limit.Max], true, nil
+}
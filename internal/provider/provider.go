@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"dns-sync/internal/models"
+)
+
+// 支持的DNS服务商名称
+const (
+	Aliyun     = "aliyun"
+	Cloudflare = "cloudflare"
+	DNSPod     = "dnspod"
+	Route53    = "route53"
+	// File 从本地快照文件读取记录，不访问任何服务商
+	File       = "file"
+)
+
+// 服务商API返回的通用错误类型，各服务商的错误通过errors.Is与之匹配
+var (
+	// ErrDomainNotFound 域名不存在或不属于当前账号
+	ErrDomainNotFound = errors.New("domain not found on provider")
+	// ErrAuthFailed 凭证无效、签名错误或权限不足
+	ErrAuthFailed = errors.New("provider rejected credentials")
+)
+
+// DNSProvider DNS服务商接口，各服务商需要将记录转换为models.DNSRecord
+type DNSProvider interface {
+	// GetDomainRecords 获取域名的全部DNS记录
+	GetDomainRecords(ctx context.Context, domain string) ([]*models.DNSRecord, error)
+	// TestConnection 测试服务商API连接
+	TestConnection(ctx context.Context) error
+	// VerifyDomains 检查域名是否都存在于账号中，返回的错误会列出所有找不到的域名
+	VerifyDomains(ctx context.Context, domains []string) error
+}
+
+// MissingDomainsError 生成列出找不到的域名的错误，没有缺失时返回nil
+func MissingDomainsError(missing []string) error {
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("domains not found on account: %s", strings.Join(missing, ", "))
+}
+
+// RecordPusher 支持写入记录的DNS服务商，用于将本地记录推送到服务商
+type RecordPusher interface {
+	// AddDomainRecord 新增记录，返回服务商分配的记录ID
+	AddDomainRecord(ctx context.Context, record *models.DNSRecord) (string, error)
+	// UpdateDomainRecord 按record.RecordId更新记录
+	UpdateDomainRecord(ctx context.Context, record *models.DNSRecord) error
+	// DeleteDomainRecord 删除记录
+	DeleteDomainRecord(ctx context.Context, recordID string) error
+}
@@ -0,0 +1,403 @@
+package route53
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/models"
+	"dns-sync/internal/provider"
+)
+
+// defaultEndpoint Route53 API地址，Route53为全局服务
+const defaultEndpoint = "https://route53.amazonaws.com"
+
+// apiVersion Route53的API版本
+const apiVersion = "2013-04-01"
+
+// pageSize 每页拉取的记录集数，Route53单页最多300条
+const pageSize = 300
+
+// maxPages 单个域名最多拉取的页数，防止分页死循环
+const maxPages = 1000
+
+// DNSClient AWS Route53 DNS客户端
+type DNSClient struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	region          string
+	endpoint        string
+	httpClient      *http.Client
+
+	// zoneIDs 域名到托管区域ID的缓存，并发同步的域名共享
+	mu      sync.Mutex
+	zoneIDs map[string]string
+}
+
+// hostedZone 托管区域
+type hostedZone struct {
+	ID     string `xml:"Id"`
+	Name   string `xml:"Name"`
+	Config struct {
+		PrivateZone bool `xml:"PrivateZone"`
+	} `xml:"Config"`
+}
+
+// listHostedZonesByNameResponse ListHostedZonesByName响应结构
+type listHostedZonesByNameResponse struct {
+	HostedZones []hostedZone `xml:"HostedZones>HostedZone"`
+}
+
+// resourceRecordSet 记录集，同名同类型的多个记录值归为一组
+type resourceRecordSet struct {
+	Name            string   `xml:"Name"`
+	Type            string   `xml:"Type"`
+	SetIdentifier   string   `xml:"SetIdentifier"`
+	Weight          *int32   `xml:"Weight"`
+	TTL             *int32   `xml:"TTL"`
+	ResourceRecords []string `xml:"ResourceRecords>ResourceRecord>Value"`
+	AliasTarget     *struct {
+		HostedZoneID string `xml:"HostedZoneId"`
+		DNSName      string `xml:"DNSName"`
+	} `xml:"AliasTarget"`
+}
+
+// listResourceRecordSetsResponse ListResourceRecordSets响应结构
+type listResourceRecordSetsResponse struct {
+	ResourceRecordSets   []resourceRecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+	IsTruncated          bool                `xml:"IsTruncated"`
+	NextRecordName       string              `xml:"NextRecordName"`
+	NextRecordType       string              `xml:"NextRecordType"`
+	NextRecordIdentifier string              `xml:"NextRecordIdentifier"`
+}
+
+// errorResponse Route53错误响应结构
+type errorResponse struct {
+	Error struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Error"`
+}
+
+// NewDNSClient 创建Route53 DNS客户端
+func NewDNSClient(cfg *config.AWSConfig) (*DNSClient, error) {
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("aws access key id and secret access key are required")
+	}
+
+	// Region在加载配置时已默认为us-east-1
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	endpoint := defaultEndpoint
+	if cfg.Endpoint != "" {
+		endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	}
+
+	return &DNSClient{
+		accessKeyID:     cfg.AccessKeyID,
+		secretAccessKey: cfg.SecretAccessKey,
+		sessionToken:    cfg.SessionToken,
+		region:          region,
+		endpoint:        endpoint,
+		httpClient:      &http.Client{Timeout: 30 * time.Second},
+		zoneIDs:         make(map[string]string),
+	}, nil
+}
+
+// makeRequest 发送签名的GET请求，返回响应体
+func (c *DNSClient) makeRequest(ctx context.Context, path string, query url.Values) ([]byte, error) {
+	u, err := url.Parse(c.endpoint + "/" + apiVersion + path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	u.RawQuery = canonicalQueryString(query)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	c.signRequest(req, time.Now())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response failed: %w", err)
+	}
+
+	if resp.StatusCode != 200 {
+		var apiErr errorResponse
+		if xml.Unmarshal(body, &apiErr) == nil && apiErr.Error.Code != "" {
+			return nil, fmt.Errorf("API request failed with status %d: %s: %s", resp.StatusCode,
+				apiErr.Error.Code, apiErr.Error.Message)
+		}
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
+
+// getZoneID 根据域名查询托管区域ID，存在同名的公有和私有区域时使用公有区域
+// 域名不存在时返回空字符串
+func (c *DNSClient) getZoneID(ctx context.Context, domain string) (string, error) {
+	domain = models.NormalizeDomain(domain)
+
+	c.mu.Lock()
+	zoneID, ok := c.zoneIDs[domain]
+	c.mu.Unlock()
+	if ok {
+		return zoneID, nil
+	}
+
+	body, err := c.makeRequest(ctx, "/hostedzonesbyname", url.Values{
+		"dnsname":  {domain},
+		"maxitems": {"10"},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up hosted zone for %s: %w", domain, err)
+	}
+
+	var response listHostedZonesByNameResponse
+	if err := xml.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to parse hosted zones: %w", err)
+	}
+
+	// 结果按名称排序，从dnsname开始返回，需要过滤出名称完全相同的区域
+	for _, zone := range response.HostedZones {
+		if models.NormalizeDomain(unescapeName(zone.Name)) != domain {
+			continue
+		}
+		if zoneID == "" || !zone.Config.PrivateZone {
+			zoneID = strings.TrimPrefix(zone.ID, "/hostedzone/")
+		}
+		if !zone.Config.PrivateZone {
+			break
+		}
+	}
+
+	if zoneID != "" {
+		c.mu.Lock()
+		c.zoneIDs[domain] = zoneID
+		c.mu.Unlock()
+	}
+
+	return zoneID, nil
+}
+
+// GetDomainRecords 获取域名的DNS记录，记录集中的每个值拆分为一条记录
+func (c *DNSClient) GetDomainRecords(ctx context.Context, domain string) ([]*models.DNSRecord, error) {
+	slog.Debug("Getting DNS records", "provider", "route53", "domain", domain)
+
+	zoneID, err := c.getZoneID(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	if zoneID == "" {
+		return nil, fmt.Errorf("hosted zone %s not found", domain)
+	}
+
+	var allRecords []*models.DNSRecord
+	query := url.Values{"maxitems": {strconv.Itoa(pageSize)}}
+	page := 0
+
+	for {
+		// 防止IsTruncated异常导致死循环
+		if page >= maxPages {
+			return nil, fmt.Errorf("pagination for %s exceeded %d pages, aborting to avoid infinite loop", domain, maxPages)
+		}
+
+		body, err := c.makeRequest(ctx, "/hostedzone/"+zoneID+"/rrset", query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list record sets for %s: %w", domain, err)
+		}
+		page++
+
+		var response listResourceRecordSetsResponse
+		if err := xml.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("failed to parse record sets: %w", err)
+		}
+
+		for _, recordSet := range response.ResourceRecordSets {
+			allRecords = append(allRecords, convertRecordSet(domain, recordSet)...)
+		}
+
+		var stop bool
+		if allRecords, stop, err = provider.ApplyRecordLimit(ctx, domain, allRecords); err != nil || stop {
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		if !response.IsTruncated {
+			break
+		}
+
+		// 下一页从NextRecordName/NextRecordType/NextRecordIdentifier开始
+		query = url.Values{
+			"maxitems": {strconv.Itoa(pageSize)},
+			"name":     {response.NextRecordName},
+			"type":     {response.NextRecordType},
+		}
+		if response.NextRecordIdentifier != "" {
+			query.Set("identifier", response.NextRecordIdentifier)
+		}
+	}
+
+	slog.Info("Retrieved DNS records", "provider", "route53", "domain", domain,
+		"count", len(allRecords), "pages", page)
+	return allRecords, nil
+}
+
+// convertRecordSet 将Route53记录集拆分为通用DNS记录
+// Route53没有单条记录的ID，RecordId由名称、类型、SetIdentifier和记录值计算得出，值变化时视为删除后新增；
+// 别名记录没有TTL和记录值，记录值为别名目标的域名，TTL为0；Route53没有停用状态和解析线路
+func convertRecordSet(domain string, recordSet resourceRecordSet) []*models.DNSRecord {
+	domain = models.NormalizeDomain(domain)
+	name := models.NormalizeDomain(unescapeName(recordSet.Name))
+
+	rr := models.RelativeRR(name, domain)
+
+	var ttl, weight int32
+	if recordSet.TTL != nil {
+		ttl = *recordSet.TTL
+	}
+	// 加权路由的记录集合才带Weight，视为开启加权轮询
+	if recordSet.Weight != nil {
+		weight = *recordSet.Weight
+	}
+
+	values := recordSet.ResourceRecords
+	if recordSet.AliasTarget != nil {
+		values = []string{recordSet.AliasTarget.DNSName}
+	}
+
+	records := make([]*models.DNSRecord, 0, len(values))
+	for _, value := range values {
+		record := &models.DNSRecord{
+			DomainName: domain,
+			RR:         rr,
+			RecordId:   recordID(name, recordSet.Type, recordSet.SetIdentifier, value),
+			Type:       recordSet.Type,
+			Value:      value,
+			Line:       "default",
+			Status:     "ENABLE",
+			TTL:        ttl,
+			Weight:     weight,
+			LbaStatus:  recordSet.Weight != nil,
+		}
+
+		switch recordSet.Type {
+		case "MX":
+			// MX记录值为"优先级 主机名"
+			if fields := strings.Fields(value); len(fields) == 2 {
+				if priority, err := strconv.Atoi(fields[0]); err == nil {
+					record.Priority = int32(priority)
+					record.Value = fields[1]
+				}
+			}
+		case "TXT":
+			record.Value = unquoteTXT(value)
+		}
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+// recordID 计算记录的稳定ID，带r53-前缀以免与其它服务商的ID冲突
+func recordID(name, recordType, setIdentifier, value string) string {
+	sum := sha1.Sum([]byte(strings.Join([]string{name, recordType, setIdentifier, value}, "|")))
+	return "r53-" + hex.EncodeToString(sum[:16])
+}
+
+// unescapeName 还原Route53返回的域名中的八进制转义，如通配符*会返回为\052
+func unescapeName(name string) string {
+	if !strings.Contains(name, `\`) {
+		return name
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) {
+			if code, err := strconv.ParseUint(name[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// unquoteTXT 去掉TXT记录值的引号，分段的"a" "b"会拼接为ab
+func unquoteTXT(value string) string {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, `"`) {
+		return value
+	}
+
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(value); i++ {
+		switch ch := value[i]; {
+		case ch == '\\' && quoted && i+1 < len(value):
+			i++
+			b.WriteByte(value[i])
+		case ch == '"':
+			quoted = !quoted
+		case quoted:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
+
+// TestConnection 测试连接
+func (c *DNSClient) TestConnection(ctx context.Context) error {
+	slog.Debug("Testing DNS connection", "provider", "route53")
+
+	if _, err := c.makeRequest(ctx, "/hostedzonecount", nil); err != nil {
+		return fmt.Errorf("failed to test route53 connection: %w", err)
+	}
+
+	slog.Debug("DNS connection test successful", "provider", "route53")
+	return nil
+}
+
+// VerifyDomains 逐个查询托管区域，检查配置的域名是否都存在
+func (c *DNSClient) VerifyDomains(ctx context.Context, domains []string) error {
+	var missing []string
+	for _, domain := range domains {
+		zoneID, err := c.getZoneID(ctx, domain)
+		if err != nil {
+			return err
+		}
+		if zoneID == "" {
+			missing = append(missing, domain)
+		}
+	}
+
+	return provider.MissingDomainsError(missing)
+}
@@ -0,0 +1,206 @@
+package route53
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"dns-sync/internal/config"
+)
+
+const hostedZonesXML = `<?xml version="1.0" encoding="UTF-8"?>
+<ListHostedZonesByNameResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+  <HostedZones>
+    <HostedZone><Id>/hostedzone/ZPRIVATE</Id><Name>example.com.</Name><Config><PrivateZone>true</PrivateZone></Config></HostedZone>
+    <HostedZone><Id>/hostedzone/ZPUBLIC</Id><Name>example.com.</Name><Config><PrivateZone>false</PrivateZone></Config></HostedZone>
+    <HostedZone><Id>/hostedzone/ZOTHER</Id><Name>example.net.</Name></HostedZone>
+  </HostedZones>
+</ListHostedZonesByNameResponse>`
+
+const firstPageXML = `<?xml version="1.0" encoding="UTF-8"?>
+<ListResourceRecordSetsResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+  <ResourceRecordSets>
+    <ResourceRecordSet><Name>www.example.com.</Name><Type>A</Type><TTL>300</TTL>
+      <ResourceRecords><ResourceRecord><Value>10.0.0.1</Value></ResourceRecord><ResourceRecord><Value>10.0.0.2</Value></ResourceRecord></ResourceRecords>
+    </ResourceRecordSet>
+    <ResourceRecordSet><Name>api.example.com.</Name><Type>CNAME</Type><TTL>60</TTL>
+      <ResourceRecords><ResourceRecord><Value>lb.example.net</Value></ResourceRecord></ResourceRecords>
+    </ResourceRecordSet>
+    <ResourceRecordSet><Name>\052.example.com.</Name><Type>A</Type><TTL>300</TTL>
+      <ResourceRecords><ResourceRecord><Value>10.0.0.3</Value></ResourceRecord></ResourceRecords>
+    </ResourceRecordSet>
+  </ResourceRecordSets>
+  <IsTruncated>true</IsTruncated>
+  <NextRecordName>example.com.</NextRecordName>
+  <NextRecordType>A</NextRecordType>
+</ListResourceRecordSetsResponse>`
+
+const secondPageXML = `<?xml version="1.0" encoding="UTF-8"?>
+<ListResourceRecordSetsResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+  <ResourceRecordSets>
+    <ResourceRecordSet><Name>example.com.</Name><Type>A</Type>
+      <AliasTarget><HostedZoneId>Z2FDTNDATAQYW2</HostedZoneId><DNSName>d123.cloudfront.net.</DNSName></AliasTarget>
+    </ResourceRecordSet>
+    <ResourceRecordSet><Name>example.com.</Name><Type>MX</Type><TTL>3600</TTL>
+      <ResourceRecords><ResourceRecord><Value>10 mail.example.com.</Value></ResourceRecord></ResourceRecords>
+    </ResourceRecordSet>
+    <ResourceRecordSet><Name>example.com.</Name><Type>TXT</Type><TTL>3600</TTL>
+      <ResourceRecords><ResourceRecord><Value>"v=spf1 include:example.net" " -all"</Value></ResourceRecord></ResourceRecords>
+    </ResourceRecordSet>
+    <ResourceRecordSet><Name>lb.example.com.</Name><Type>A</Type><SetIdentifier>blue</SetIdentifier><Weight>10</Weight><TTL>60</TTL>
+      <ResourceRecords><ResourceRecord><Value>10.0.1.1</Value></ResourceRecord></ResourceRecords>
+    </ResourceRecordSet>
+  </ResourceRecordSets>
+  <IsTruncated>false</IsTruncated>
+</ListResourceRecordSetsResponse>`
+
+// newMockRoute53 模拟ListHostedZonesByName和分两页的ListResourceRecordSets，未签名的请求返回403
+func newMockRoute53(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-id/") ||
+			r.Header.Get("X-Amz-Date") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<ErrorResponse><Error><Code>SignatureDoesNotMatch</Code><Message>bad signature</Message></Error></ErrorResponse>`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/xml")
+		switch r.URL.Path {
+		case "/2013-04-01/hostedzonesbyname":
+			fmt.Fprint(w, hostedZonesXML)
+		case "/2013-04-01/hostedzone/ZPUBLIC/rrset":
+			if r.URL.Query().Get("name") == "" {
+				fmt.Fprint(w, firstPageXML)
+				return
+			}
+			if r.URL.Query().Get("name") != "example.com." || r.URL.Query().Get("type") != "A" {
+				http.Error(w, "unexpected start record", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, secondPageXML)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func newTestClient(t *testing.T, endpoint string) *DNSClient {
+	t.Helper()
+	client, err := NewDNSClient(&config.AWSConfig{AccessKeyID: "test-id", SecretAccessKey: "test-secret",
+		Endpoint: endpoint})
+	if err != nil {
+		t.Fatalf("NewDNSClient() error = %v", err)
+	}
+	return client
+}
+
+func TestGetDomainRecords(t *testing.T) {
+	var requests atomic.Int32
+	server := newMockRoute53(t, &requests)
+	defer server.Close()
+
+	records, err := newTestClient(t, server.URL).GetDomainRecords(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("GetDomainRecords() error = %v", err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("got %d requests, want 3", got)
+	}
+
+	tests := []struct {
+		rr       string
+		typ      string
+		value    string
+		ttl      int32
+		priority int32
+		weight   int32
+	}{
+		{rr: "www", typ: "A", value: "10.0.0.1", ttl: 300},
+		{rr: "www", typ: "A", value: "10.0.0.2", ttl: 300},
+		{rr: "api", typ: "CNAME", value: "lb.example.net", ttl: 60},
+		{rr: "*", typ: "A", value: "10.0.0.3", ttl: 300},
+		// 别名记录没有TTL，记录值为别名目标
+		{rr: "@", typ: "A", value: "d123.cloudfront.net."},
+		{rr: "@", typ: "MX", value: "mail.example.com.", ttl: 3600, priority: 10},
+		{rr: "@", typ: "TXT", value: "v=spf1 include:example.net -all", ttl: 3600},
+		{rr: "lb", typ: "A", value: "10.0.1.1", ttl: 60, weight: 10},
+	}
+	if len(records) != len(tests) {
+		t.Fatalf("got %d records, want %d", len(records), len(tests))
+	}
+
+	seen := make(map[string]bool, len(records))
+	for i, tt := range tests {
+		record := records[i]
+		if record.RR != tt.rr || record.Type != tt.typ || record.Value != tt.value || record.TTL != tt.ttl ||
+			record.Priority != tt.priority || record.Weight != tt.weight || record.LbaStatus != (tt.weight > 0) {
+			t.Errorf("record %d = %+v, want %+v", i, record, tt)
+		}
+		if record.DomainName != "example.com" || record.Line != "default" || record.Status != "ENABLE" {
+			t.Errorf("record %d: domain %q, line %q, status %q", i, record.DomainName, record.Line, record.Status)
+		}
+		if !strings.HasPrefix(record.RecordId, "r53-") || seen[record.RecordId] {
+			t.Errorf("record %d has invalid or duplicate id %q", i, record.RecordId)
+		}
+		seen[record.RecordId] = true
+	}
+}
+
+// TestRecordIDStable 记录ID只由名称、类型、SetIdentifier和记录值决定，重复拉取得到相同的ID
+func TestRecordIDStable(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  [4]string
+		equal bool
+	}{
+		{name: "same record", a: [4]string{"www.example.com", "A", "", "10.0.0.1"},
+			b: [4]string{"www.example.com", "A", "", "10.0.0.1"}, equal: true},
+		{name: "different value", a: [4]string{"www.example.com", "A", "", "10.0.0.1"},
+			b: [4]string{"www.example.com", "A", "", "10.0.0.2"}},
+		{name: "different set identifier", a: [4]string{"lb.example.com", "A", "blue", "10.0.0.1"},
+			b: [4]string{"lb.example.com", "A", "green", "10.0.0.1"}},
+	}
+
+	for _, tt := range tests {
+		a := recordID(tt.a[0], tt.a[1], tt.a[2], tt.a[3])
+		b := recordID(tt.b[0], tt.b[1], tt.b[2], tt.b[3])
+		if (a == b) != tt.equal {
+			t.Errorf("%s: recordID equal = %v, want %v", tt.name, a == b, tt.equal)
+		}
+	}
+}
+
+func TestVerifyDomains(t *testing.T) {
+	var requests atomic.Int32
+	server := newMockRoute53(t, &requests)
+	defer server.Close()
+
+	err := newTestClient(t, server.URL).VerifyDomains(context.Background(), []string{"example.com", "example.org"})
+	if err == nil || !strings.Contains(err.Error(), "domains not found on account: example.org") {
+		t.Fatalf("VerifyDomains() error = %v, want example.org missing", err)
+	}
+}
+
+func TestUnquoteTXT(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: `"v=spf1 -all"`, want: "v=spf1 -all"},
+		{value: `"part one" "part two"`, want: "part onepart two"},
+		{value: `"escaped \" quote"`, want: `escaped " quote`},
+		{value: "unquoted", want: "unquoted"},
+	}
+
+	for _, tt := range tests {
+		if got := unquoteTXT(tt.value); got != tt.want {
+			t.Errorf("unquoteTXT(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
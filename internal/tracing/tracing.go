@@ -0,0 +1,291 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dns-sync/internal/logger"
+)
+
+// maxBufferedSpans 两次导出之间最多缓存的span数，超出后丢弃新的span
+const maxBufferedSpans = 10000
+
+// exportTimeout 每次导出请求的超时时间
+const exportTimeout = 10 * time.Second
+
+// OTLP中的span类型和状态码
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+	statusCodeError  = 2
+)
+
+// current 当前的导出器，未调用Setup时为nil，此时Start返回的span为nil，所有操作都是空操作
+var current atomic.Pointer[exporter]
+
+// exporter 在内存中缓存结束的span，Flush时以OTLP/HTTP JSON格式发送到collector
+type exporter struct {
+	endpoint string
+	service  string
+	client   *http.Client
+
+	mu      sync.Mutex
+	spans   []*Span
+	dropped int
+}
+
+// Span 一段被追踪的操作，nil表示未开启追踪
+type Span struct {
+	exporter *exporter
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+	end      time.Time
+
+	mu     sync.Mutex
+	attrs  []attribute
+	errMsg string
+}
+
+// attribute span上的一个属性
+type attribute struct {
+	key   string
+	value any
+}
+
+// spanKey 在context中保存当前span的键
+type spanKey struct{}
+
+// Setup 开启追踪，endpoint为OTLP/HTTP collector地址，未带路径时追加/v1/traces；endpoint为空时不开启
+func Setup(endpoint, service string) error {
+	if endpoint == "" {
+		current.Store(nil)
+		return nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid otel endpoint %q, expected an http or https URL", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+
+	current.Store(&exporter{
+		endpoint: u.String(),
+		service:  service,
+		client:   &http.Client{Timeout: exportTimeout},
+	})
+	return nil
+}
+
+// Start 创建子span，ctx中没有span时创建新的trace；args为属性的键值对，格式与slog相同
+// 未开启追踪时返回原ctx和nil
+func Start(ctx context.Context, name string, args ...any) (context.Context, *Span) {
+	return start(ctx, name, spanKindInternal, args)
+}
+
+// StartClient 创建调用外部服务的span，collector据此区分服务商和数据库请求
+func StartClient(ctx context.Context, name string, args ...any) (context.Context, *Span) {
+	return start(ctx, name, spanKindClient, args)
+}
+
+// start 创建span，kind为OTLP的span类型
+func start(ctx context.Context, name string, kind int, args []any) (context.Context, *Span) {
+	exp := current.Load()
+	if exp == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		exporter: exp,
+		name:     name,
+		kind:     kind,
+		start:    time.Now(),
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		_, _ = rand.Read(span.traceID[:])
+	}
+	_, _ = rand.Read(span.spanID[:])
+	span.SetAttributes(args...)
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetAttributes 设置属性，args为键值对，同名属性以最后一次为准
+func (s *Span) SetAttributes(args ...any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i+1 < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok {
+			key = fmt.Sprint(args[i])
+		}
+		s.attrs = append(s.attrs, attribute{key: key, value: args[i+1]})
+	}
+}
+
+// RecordError 将span标记为失败，err为nil时忽略；错误信息中的敏感值已屏蔽
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errMsg = logger.Redact(err.Error())
+}
+
+// End 结束span并放入导出缓存
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	exp := s.exporter
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+	if len(exp.spans) >= maxBufferedSpans {
+		exp.dropped++
+		return
+	}
+	exp.spans = append(exp.spans, s)
+}
+
+// Flush 发送缓存的全部span，未开启追踪或没有span时直接返回
+// 发送失败时丢弃这批span，追踪数据不影响同步结果
+func Flush(ctx context.Context) error {
+	exp := current.Load()
+	if exp == nil {
+		return nil
+	}
+
+	exp.mu.Lock()
+	spans, dropped := exp.spans, exp.dropped
+	exp.spans, exp.dropped = nil, 0
+	exp.mu.Unlock()
+
+	if dropped > 0 {
+		slog.Warn("Dropped spans, export buffer full", "dropped", dropped, "limit", maxBufferedSpans)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(exp.request(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, exp.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := exp.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to export spans: collector returned status %d", resp.StatusCode)
+	}
+
+	slog.Debug("Exported spans", "count", len(spans), "endpoint", exp.endpoint)
+	return nil
+}
+
+// request 按OTLP/HTTP JSON格式组装导出请求
+func (e *exporter) request(spans []*Span) map[string]any {
+	encoded := make([]map[string]any, 0, len(spans))
+	for _, span := range spans {
+		encoded = append(encoded, span.encode())
+	}
+
+	return map[string]any{
+		"resourceSpans": []map[string]any{{
+			"resource": map[string]any{
+				"attributes": []map[string]any{encodeAttribute("service.name", e.service)},
+			},
+			"scopeSpans": []map[string]any{{
+				"scope": map[string]any{"name": "dns-sync"},
+				"spans": encoded,
+			}},
+		}},
+	}
+}
+
+// encode 将span转换为OTLP JSON对象，traceId和spanId按规范使用十六进制字符串
+func (s *Span) encode() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attrs := make([]map[string]any, 0, len(s.attrs))
+	for _, attr := range s.attrs {
+		attrs = append(attrs, encodeAttribute(attr.key, attr.value))
+	}
+
+	span := map[string]any{
+		"traceId":           hex.EncodeToString(s.traceID[:]),
+		"spanId":            hex.EncodeToString(s.spanID[:]),
+		"name":              s.name,
+		"kind":              s.kind,
+		"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+		"attributes":        attrs,
+	}
+	if s.parentID != [8]byte{} {
+		span["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+	}
+	if s.errMsg != "" {
+		span["status"] = map[string]any{"code": statusCodeError, "message": s.errMsg}
+	}
+	return span
+}
+
+// encodeAttribute 将属性值转换为OTLP的AnyValue，整数按规范编码为字符串
+func encodeAttribute(key string, value any) map[string]any {
+	var encoded map[string]any
+	switch v := value.(type) {
+	case string:
+		encoded = map[string]any{"stringValue": v}
+	case bool:
+		encoded = map[string]any{"boolValue": v}
+	case int:
+		encoded = map[string]any{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int32:
+		encoded = map[string]any{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int64:
+		encoded = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		encoded = map[string]any{"doubleValue": v}
+	default:
+		encoded = map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+	return map[string]any{"key": key, "value": encoded}
+}
@@ -0,0 +1,185 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// exportedSpan collector收到的OTLP JSON中的一个span
+type exportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+	Attributes   []struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	} `json:"attributes"`
+	Status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+// attr 获取属性值，未设置时为nil
+func (s exportedSpan) attr(key string) any {
+	for _, attr := range s.Attributes {
+		if attr.Key == key {
+			for _, value := range attr.Value {
+				return value
+			}
+		}
+	}
+	return nil
+}
+
+// collector 模拟OTLP/HTTP collector，按名称保存收到的span
+type collector struct {
+	mu       sync.Mutex
+	paths    []string
+	services []string
+	spans    map[string]exportedSpan
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []struct {
+					Value struct {
+						StringValue string `json:"stringValue"`
+					} `json:"value"`
+				} `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []exportedSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paths = append(c.paths, r.URL.Path)
+	for _, resource := range request.ResourceSpans {
+		for _, attr := range resource.Resource.Attributes {
+			c.services = append(c.services, attr.Value.StringValue)
+		}
+		for _, scope := range resource.ScopeSpans {
+			for _, span := range scope.Spans {
+				c.spans[span.Name] = span
+			}
+		}
+	}
+}
+
+// TestSpanHierarchy 子span继承trace并指向父span，属性、类型和错误状态按OTLP格式导出到collector
+func TestSpanHierarchy(t *testing.T) {
+	recv := &collector{spans: make(map[string]exportedSpan)}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+	if err := Setup(server.URL, "dns-sync"); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	defer Setup("", "")
+
+	ctx, run := Start(context.Background(), "sync.run", "domains", 2, "dry_run", false)
+	domainCtx, domain := Start(ctx, "sync.domain", "domain", "example.com")
+	_, request := StartClient(domainCtx, "aliyun.request", "action", "DescribeDomainRecords")
+	request.RecordError(errors.New("request failed"))
+	request.End()
+	domain.SetAttributes("records", int64(250), "added", 3)
+	domain.End()
+	_, other := Start(context.Background(), "other.run")
+	other.End()
+	run.End()
+
+	if err := Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if len(recv.paths) != 1 || recv.paths[0] != "/v1/traces" || recv.services[0] != "dns-sync" {
+		t.Fatalf("exports = %v for %v, want one to /v1/traces for dns-sync", recv.paths, recv.services)
+	}
+
+	tests := []struct {
+		name   string
+		parent string
+		kind   int
+		attrs  map[string]any
+		status string
+	}{
+		{name: "sync.run", kind: spanKindInternal, attrs: map[string]any{"domains": "2", "dry_run": false}},
+		{name: "sync.domain", parent: "sync.run", kind: spanKindInternal,
+			attrs: map[string]any{"domain": "example.com", "records": "250", "added": "3"}},
+		{name: "aliyun.request", parent: "sync.domain", kind: spanKindClient,
+			attrs: map[string]any{"action": "DescribeDomainRecords"}, status: "request failed"},
+		{name: "other.run", kind: spanKindInternal},
+	}
+	for _, tt := range tests {
+		span, ok := recv.spans[tt.name]
+		if !ok {
+			t.Errorf("span %s not exported", tt.name)
+			continue
+		}
+		if span.Kind != tt.kind {
+			t.Errorf("%s kind = %d, want %d", tt.name, span.Kind, tt.kind)
+		}
+		if tt.parent == "" {
+			if span.ParentSpanID != "" {
+				t.Errorf("%s parent = %s, want root span", tt.name, span.ParentSpanID)
+			}
+		} else if parent := recv.spans[tt.parent]; span.ParentSpanID != parent.SpanID || span.TraceID != parent.TraceID {
+			t.Errorf("%s parent = %s in trace %s, want %s in trace %s", tt.name, span.ParentSpanID, span.TraceID,
+				parent.SpanID, parent.TraceID)
+		}
+		for key, want := range tt.attrs {
+			if got := span.attr(key); got != want {
+				t.Errorf("%s attribute %s = %v, want %v", tt.name, key, got, want)
+			}
+		}
+		if span.Status.Message != tt.status || (tt.status != "") != (span.Status.Code == statusCodeError) {
+			t.Errorf("%s status = %+v, want %q", tt.name, span.Status, tt.status)
+		}
+	}
+	if recv.spans["other.run"].TraceID == recv.spans["sync.run"].TraceID {
+		t.Error("unrelated root spans share a trace")
+	}
+
+	// 已导出的span不会再次发送
+	if err := Flush(context.Background()); err != nil || len(recv.paths) != 1 {
+		t.Errorf("second Flush() error = %v after %d exports, want no export", err, len(recv.paths))
+	}
+}
+
+// TestDisabled 未配置endpoint时Start返回nil span，所有操作都是空操作；endpoint无效时Setup返回错误
+func TestDisabled(t *testing.T) {
+	if err := Setup("", "dns-sync"); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	ctx, span := Start(context.Background(), "sync.run", "domains", 1)
+	if span != nil || ctx != context.Background() {
+		t.Fatalf("Start() span = %v, want nil without endpoint", span)
+	}
+	span.SetAttributes("added", 1)
+	span.RecordError(errors.New("failed"))
+	span.End()
+	if err := Flush(ctx); err != nil {
+		t.Errorf("Flush() error = %v", err)
+	}
+
+	for _, endpoint := range []string{"collector:4318", "ftp://collector:4318", "http://"} {
+		if err := Setup(endpoint, "dns-sync"); err == nil {
+			t.Errorf("Setup(%q) error = nil, want invalid endpoint", endpoint)
+		}
+	}
+	Setup("", "")
+}
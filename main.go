@@ -1,142 +1,1064 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
+	"net"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"sort"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"dns-sync/internal/aliyun"
+	"dns-sync/internal/cloudflare"
 	"dns-sync/internal/config"
 	"dns-sync/internal/database"
+	"dns-sync/internal/dnspod"
+	"dns-sync/internal/file"
+	"dns-sync/internal/health"
+	"dns-sync/internal/logger"
 	"dns-sync/internal/models"
+	"dns-sync/internal/notify"
+	"dns-sync/internal/provider"
+	"dns-sync/internal/route53"
+	"dns-sync/internal/tracing"
 )
 
 // SyncStats 同步统计信息
 type SyncStats struct {
-	Domain  string
-	Added   int
-	Updated int
-	Deleted int
-	Error   string
+	Domain      string
+	RecordCount int
+	Disabled    int
+	Added       int
+	Updated     int
+	Deleted     int
+	Pushed      int
+	Error       string
+	// Watermark 本次拉取到的记录的最大UpdateTimestamp，增量模式下同步成功后保存
+	Watermark int64
+	// ThresholdExceeded 是否因删除数量超出阈值而放弃同步
+	ThresholdExceeded bool
+	// Skipped 域名在配置中被停用或在服务商上不存在，跳过同步，SkipReason为跳过原因
+	Skipped    bool
+	SkipReason string
+	// Failed 写入失败的记录数，大于0时该域名为部分成功
+	Failed int
+	// FailedByAction 按操作统计的写入失败数
+	FailedByAction map[string]int
+	// FailedSamples 部分失败记录的错误，最多maxFailedSamples条
+	FailedSamples []models.RecordError
+	// Warning 同步成功但需要关注的情况，如服务商返回空结果而跳过了删除
+	Warning string
+	// DeleteAnomaly 删除数远超最近几次同步的平均值，DeleteAverage为对比的平均删除数
+	DeleteAnomaly bool
+	DeleteAverage float64
+}
+
+// maxFailedSamples 每个域名保留的失败记录错误信息条数
+const maxFailedSamples = 5
+
+// addFailure 记录一条写入失败的记录，action为失败的操作，recordID为服务商的记录ID，未知时为空
+func (s *SyncStats) addFailure(action, subDomain, recordID string, err error) {
+	s.Failed++
+	if s.FailedByAction == nil {
+		s.FailedByAction = make(map[string]int)
+	}
+	s.FailedByAction[action]++
+	if len(s.FailedSamples) < maxFailedSamples {
+		s.FailedSamples = append(s.FailedSamples, models.RecordError{
+			Action:    action,
+			SubDomain: subDomain,
+			RecordID:  recordID,
+			Error:     err.Error(),
+		})
+	}
+}
+
+// Degraded 域名整体同步完成，但有记录写入失败
+func (s *SyncStats) Degraded() bool {
+	return s.Error == "" && s.Failed > 0
 }
 
 func main() {
-	// 设置日志格式
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	os.Exit(run())
+}
+
+// run 执行程序主流程并返回退出码，退出前会执行所有defer
+func run() int {
+	// diff子命令只计算并输出变更，verify子命令输出数据库与服务商的差异报告，
+	// export子命令只把服务商记录导出为CSV，不访问数据库；validate子命令只检查配置文件，不建立任何连接；
+	// prune子命令只删除服务商上已不存在的本地记录；参数解析与同步相同
+	diffMode := len(os.Args) > 1 && os.Args[1] == "diff"
+	verifyMode := len(os.Args) > 1 && os.Args[1] == "verify"
+	exportMode := len(os.Args) > 1 && os.Args[1] == "export"
+	validateMode := len(os.Args) > 1 && os.Args[1] == "validate"
+	pruneMode := len(os.Args) > 1 && os.Args[1] == "prune"
+	args := os.Args[1:]
+	if diffMode || verifyMode || exportMode || validateMode || pruneMode {
+		args = os.Args[2:]
+	}
+
+	// 解析命令行参数
+	configFlag := flag.String("config", "", "path to the config file (or set DNS_SYNC_CONFIG)")
+	dryRun := flag.Bool("dry-run", false, "report changes without writing to MySQL (or set DRY_RUN=1)")
+	timeout := flag.Duration("timeout", 0, "timeout for each sync run, e.g. 10m (overrides sync.timeout in config)")
+	reportPath := flag.String("report", "", "write a JSON sync report to this path")
+	interval := flag.Duration("interval", 0, "run continuously, syncing every interval, e.g. 5m (overrides sync.interval in config)")
+	initDB := flag.Bool("init-db", false, "create missing tables and indexes from the embedded schema before syncing")
+	dedupe := flag.Bool("dedupe", false, "remove duplicate rows sharing an aliyun_record_id before syncing, keeping the oldest")
+	maxDrift := flag.Int("max-drift", 0, "verify: exit non-zero when the total number of discrepancies exceeds this")
+	sampleSize := flag.Int("sample", 5, "verify: number of sample records listed per discrepancy category")
+	since := flag.Bool("since", false, "only compare records updated after the stored watermark (or set sync.since in config)")
+	output := flag.String("output", "-", "export: write the CSV to this path, - for stdout")
+	verbose := flag.Bool("verbose", false, "log every record change and debug details (overrides log_level in config)")
+	quiet := flag.Bool("quiet", false, "log only warnings and errors, the summary is still printed (overrides log_level in config)")
+	rebuild := flag.Bool("rebuild", false, "delete and reinsert all synced rows of each domain from the provider, one transaction per domain")
+	yes := flag.Bool("yes", false, "rebuild: skip the confirmation prompt")
+	var onlyDomains stringList
+	flag.Var(&onlyDomains, "domain", "sync only this domain from the config (repeatable)")
+	flag.CommandLine.Parse(args)
+	if os.Getenv("DRY_RUN") == "1" || diffMode || verifyMode {
+		*dryRun = true
+	}
+	if *verbose && *quiet {
+		return fatal("Invalid flags", fmt.Errorf("--verbose and --quiet are mutually exclusive"))
+	}
+	if *rebuild && (diffMode || verifyMode || exportMode || validateMode || pruneMode || *interval > 0) {
+		return fatal("Invalid flags", fmt.Errorf("--rebuild cannot be combined with subcommands or --interval"))
+	}
+	// 命令行指定的日志级别在加载配置前就生效，--quiet时不输出启动阶段的info日志
+	logLevel := ""
+	if *verbose {
+		logLevel = "debug"
+	} else if *quiet {
+		logLevel = "warn"
+	}
+	if logLevel != "" {
+		logger.Setup("text", logLevel)
+	}
 
-	log.Println("Starting DNS incremental sync application...")
+	if validateMode {
+		configPath, err := config.ResolveConfigPath(*configFlag)
+		if err != nil {
+			return fatal("Failed to find config file", err)
+		}
+		return runValidate(os.Stdout, configPath)
+	}
+
+	// 每个进程生成一个运行ID，写入审计记录以区分不同运行
+	runID := fmt.Sprintf("%s-%d", time.Now().Format("20060102T150405"), os.Getpid())
+	slog.Info("Starting DNS incremental sync application", "run_id", runID)
 
 	// 加载配置文件
-	configPath := filepath.Join("config", "config.yaml")
-	cfg, err := config.LoadConfig(configPath)
+	configPath, err := config.ResolveConfigPath(*configFlag)
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		return fatal("Failed to find config file", err)
 	}
-	log.Println("Configuration loaded successfully")
-
-	// 初始化阿里云DNS客户端
-	dnsClient, err := aliyun.NewDNSClient(&cfg.Aliyun)
+	loadConfig := config.LoadConfig
+	if exportMode {
+		loadConfig = config.LoadProviderConfig
+	}
+	cfg, err := loadConfig(configPath)
 	if err != nil {
-		log.Fatalf("Failed to create DNS client: %v", err)
+		return fatal("Failed to load config", err)
+	}
+	// 此后的日志、摘要和报告中不出现配置的密码、密钥和令牌
+	logger.SetSecrets(cfg.Secrets())
+	cfg.Sync.DryRun = *dryRun
+	cfg.Sync.Since = cfg.Sync.Since || *since
+	cfg.Sync.PruneOnly = pruneMode
+	if pruneMode && cfg.Sync.Direction == "push" {
+		return fatal("Invalid config for prune", fmt.Errorf("prune requires sync_direction pull or both"))
 	}
-	log.Println("Aliyun DNS client initialized")
 
-	// 测试阿里云连接
-	if err := dnsClient.TestConnection(); err != nil {
-		log.Fatalf("Failed to test Aliyun connection: %v", err)
+	// 只同步命令行指定的域名
+	if len(onlyDomains) > 0 {
+		cfg.Domains, err = filterDomains(cfg.Domains, onlyDomains)
+		if err != nil {
+			return fatal("Invalid --domain flag", err)
+		}
 	}
-	log.Println("Aliyun connection test passed")
 
-	// 初始化MySQL客户端
-	mysqlClient, err := database.NewMySQLClient(&cfg.MySQL)
+	// 按配置设置日志格式和级别，命令行的--verbose/--quiet优先
+	if logLevel != "" {
+		cfg.LogLevel = logLevel
+	}
+	if err := logger.Setup(cfg.LogFormat, cfg.LogLevel); err != nil {
+		return fatal("Failed to set up logger", err)
+	}
+	slog.Info("Configuration loaded successfully", "path", configPath, "domains", len(cfg.Domains))
+	if err := tracing.Setup(cfg.OtelEndpoint, "dns-sync"); err != nil {
+		return fatal("Failed to set up tracing", err)
+	}
+	if cfg.OtelEndpoint != "" {
+		slog.Info("Tracing enabled", "otel_endpoint", config.RedactURL(cfg.OtelEndpoint))
+	}
+	if cfg.Sync.DryRun {
+		slog.Info("[DRY-RUN] Dry-run mode enabled, no changes will be written to MySQL")
+	}
+	if cfg.Sync.PruneOnly {
+		slog.Info("Prune mode enabled, only deleting records removed from the provider")
+	}
+
+	// 收到SIGINT/SIGTERM时取消同步；常驻模式下只停止调度，正在进行的同步会执行完
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 设置每次同步的超时时间，命令行参数优先
+	syncTimeout := cfg.Sync.Timeout
+	if *timeout > 0 {
+		syncTimeout = *timeout
+	}
+	if syncTimeout > 0 {
+		slog.Info("Sync timeout set", "timeout", syncTimeout.String())
+	}
+
+	// 设置常驻模式的同步间隔，命令行参数优先
+	syncInterval := cfg.Sync.Interval
+	if *interval > 0 {
+		syncInterval = *interval
+	}
+
+	// 初始化配置中用到的DNS服务商客户端
+	providers, err := newProviders(cfg)
 	if err != nil {
-		log.Fatalf("Failed to create MySQL client: %v", err)
+		return fatal("Failed to initialize DNS providers", err)
 	}
-	defer mysqlClient.Close()
-	log.Println("MySQL client initialized")
 
-	// 测试数据库连接
-	if err := mysqlClient.TestConnection(); err != nil {
-		log.Fatalf("Failed to test MySQL connection: %v", err)
+	// 服务商和数据库的检查并发执行，全部失败项一起报告；export不访问数据库
+	checks := providerChecks(cfg, providers)
+	var store database.Store
+	if !exportMode {
+		checks = append(checks, databaseCheck(cfg, runID, *initDB, &store))
+	}
+	err = runStartupChecks(ctx, checks)
+	if store != nil {
+		defer store.Close()
+	}
+	if err != nil {
+		return fatal("Startup checks failed", err)
+	}
+
+	// 服务商连续失败时本轮跳过其余域名，避免每个域名都重试并刷屏
+	breaker := provider.NewCircuitBreaker(cfg.Sync.CircuitBreakerThreshold)
+	for key, dnsClient := range providers {
+		providers[key] = breaker.Wrap(key, dnsClient)
+	}
+
+	if exportMode {
+		return runExport(ctx, cfg, providers, syncTimeout, *output)
+	}
+
+	// 写入数据库的运行之间互斥；dry-run、diff和verify只读，不获取锁
+	var runLock *database.RunLock
+	if cfg.Sync.RunLock && !cfg.Sync.DryRun {
+		var code int
+		if runLock, code = lockRun(ctx, cfg, store); code != exitOK {
+			return code
+		}
+		defer func() {
+			releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := runLock.Release(releaseCtx); err != nil {
+				slog.Warn("Failed to release run lock, it is released when the connection closes", "error", err)
+			}
+		}()
+	}
+
+	// 按需清理重复的本地记录
+	if *dedupe {
+		// 清理掉的行可能还未复制到只读副本，本次运行的读操作都使用主库
+		if dedupeDomains(ctx, cfg, store) > 0 {
+			ctx = database.WithPrimary(ctx)
+		}
+	}
+
+	if diffMode {
+		if runDiff(ctx, cfg, providers, store, syncTimeout) > 0 {
+			return exitDomainFailed
+		}
+		return exitOK
 	}
-	log.Println("MySQL connection test passed")
 
-	// 检查数据库表是否存在
-	if err := mysqlClient.CheckTableExists(); err != nil {
-		log.Fatalf("Database table check failed: %v", err)
+	if verifyMode {
+		return runVerify(ctx, cfg, providers, store, syncTimeout, *maxDrift, *sampleSize)
 	}
-	log.Println("Database table exists")
+
+	if *rebuild {
+		if !cfg.Sync.DryRun && !*yes && !confirmRebuild(os.Stdin, enabledDomains(cfg.Domains)) {
+			slog.Warn("Rebuild aborted, not confirmed")
+			return exitSetupError
+		}
+		return runRebuild(ctx, cfg, providers, store, syncTimeout)
+	}
+
+	if syncInterval > 0 {
+		slog.Info("Running in daemon mode", "interval", syncInterval.String())
+		healthServer, err := startHealthServer(cfg, providers, store, syncInterval)
+		if err != nil {
+			return fatal("Failed to start health server", err)
+		}
+
+		// 同步使用不随信号取消的context，收到信号后本轮同步完成再退出
+		runCtx := context.WithoutCancel(ctx)
+		runLoop(ctx, syncInterval, func() {
+			breaker.Reset()
+			// 持有锁的连接断开后锁已被释放，重新获取失败时跳过本轮，避免与其它实例同时写入
+			if runLock != nil {
+				if err := runLock.Refresh(runCtx); err != nil {
+					slog.Error("Lost run lock, skipping this sync run", "lock", cfg.RunLockName(), "error", err)
+					if healthServer != nil {
+						healthServer.RecordSync(err)
+					}
+					return
+				}
+			}
+			code := runSync(runCtx, cfg, providers, store, syncTimeout, *reportPath)
+			if healthServer != nil {
+				var syncErr error
+				if code != exitOK {
+					syncErr = fmt.Errorf("sync finished with exit code %d", code)
+				}
+				healthServer.RecordSync(syncErr)
+			}
+		})
+
+		if healthServer != nil {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := healthServer.Shutdown(shutdownCtx); err != nil {
+				slog.Warn("Failed to stop health server", "error", err)
+			}
+			cancel()
+		}
+		slog.Info("DNS incremental sync application stopped")
+		return exitOK
+	}
+
+	// 执行一次增量同步，有失败的同步时按失败类型返回退出码
+	code := runSync(ctx, cfg, providers, store, syncTimeout, *reportPath)
+
+	slog.Info("DNS incremental sync application completed", "exit_code", code)
+
+	return code
+}
+
+// dedupeDomains 清理每个域名下aliyun_record_id重复的本地记录，dry-run模式下跳过，返回清理的行数
+func dedupeDomains(ctx context.Context, cfg *config.Config, store database.Store) int {
+	if cfg.Sync.DryRun {
+		slog.Info("[DRY-RUN] Skipping dedupe of local records")
+		return 0
+	}
+
+	total := 0
+	for _, domainMapping := range cfg.Domains {
+		if !domainMapping.IsEnabled() {
+			continue
+		}
+		removed, err := store.DedupeLocalRecords(ctx, domainMapping.DomainID, domainMapping.Source)
+		if err != nil {
+			slog.Error("Failed to dedupe local records", "domain", domainMapping.Domain, "error", err)
+			continue
+		}
+		if removed > 0 {
+			slog.Info("Removed duplicate local records", "domain", domainMapping.Domain, "count", removed)
+		}
+		total += removed
+	}
+	return total
+}
+
+// runSync 执行一轮全部域名的同步，输出报告和摘要，返回按失败类型确定的退出码
+func runSync(ctx context.Context, cfg *config.Config, providers map[string]provider.DNSProvider,
+	store database.Store, syncTimeout time.Duration, reportPath string) int {
+
+	startTime := time.Now()
+	if syncTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, syncTimeout)
+		defer cancel()
+	}
+
+	// 每轮同步为一个trace，结束后立即导出，常驻模式下不会积压到退出时
+	ctx, span := tracing.Start(ctx, "sync.run", "domains", len(cfg.Domains), "dry_run", cfg.Sync.DryRun)
+	defer func() {
+		span.End()
+		if err := tracing.Flush(ctx); err != nil {
+			slog.Warn("Failed to export traces", "error", err)
+		}
+	}()
 
 	// 执行增量同步
-	var syncStats []*SyncStats
+	syncStats := syncDomains(ctx, cfg, providers, store)
+	detectDeleteAnomalies(ctx, cfg, store, syncStats)
+	checkResolution(ctx, cfg, store, syncStats, net.DefaultResolver)
+
 	totalAdded := 0
 	totalUpdated := 0
 	totalDeleted := 0
+	for _, stats := range syncStats {
+		totalAdded += stats.Added
+		totalUpdated += stats.Updated
+		totalDeleted += stats.Deleted
+	}
 
-	for _, domainMapping := range cfg.Domains {
-		log.Printf("Processing domain: %s (project_id: %s, domain_id: %s)",
-			domainMapping.Domain, domainMapping.ProjectID, domainMapping.DomainID)
+	report := buildSyncReport(syncStats, startTime, time.Now(), cfg.Sync.DryRun)
+	span.SetAttributes("added", totalAdded, "updated", totalUpdated, "deleted", totalDeleted,
+		"failed_domains", report.Totals.Failed)
 
-		stats := &SyncStats{
-			Domain: domainMapping.Domain,
+	// 全部域名完成后执行post_sync_sql
+	postSyncErr := runPostSync(ctx, cfg, store, report.Totals)
+	if postSyncErr != nil {
+		report.PostSyncError = postSyncErr.Error()
+	}
+
+	// 输出JSON报告
+	if reportPath != "" {
+		if err := writeSyncReport(reportPath, report); err != nil {
+			slog.Error("Failed to write sync report", "path", reportPath, "error", err)
+		} else {
+			slog.Info("Sync report written", "path", reportPath)
 		}
+	}
 
-		// 执行单个域名的增量同步
-		added, updated, deleted, err := incrementalSyncDomain(dnsClient, mysqlClient, domainMapping)
+	// 打印同步结果摘要
+	printIncrementalSyncSummary(syncStats, totalAdded, totalUpdated, totalDeleted, cfg.Sync.DryRun)
+
+	code := syncExitCode(syncStats)
+	if postSyncErr != nil && cfg.Sync.PostSyncSQLFatal && code == exitOK {
+		code = exitPostSyncFailed
+	}
+
+	// 同步超时或被取消时同样需要通知，使用不会被取消的上下文，超时由notify.timeout控制
+	if err := notify.NewWebhook(cfg.Notify).Notify(context.WithoutCancel(ctx), report, code); err != nil {
+		slog.Warn("Failed to send sync notification", "error", err)
+	}
+	return code
+}
+
+// runPostSync 执行配置的post_sync_sql，未配置或dry-run模式下跳过；失败时记录错误并返回
+func runPostSync(ctx context.Context, cfg *config.Config, store database.Store, totals models.SyncTotals) error {
+	if len(cfg.Sync.PostSyncSQL) == 0 {
+		return nil
+	}
+	if cfg.Sync.DryRun {
+		slog.Info("[DRY-RUN] Skipping post_sync_sql", "statements", len(cfg.Sync.PostSyncSQL))
+		return nil
+	}
+
+	err := store.ExecPostSync(ctx, cfg.Sync.PostSyncSQL, database.PostSyncTotals{
+		Added:   totals.Added,
+		Updated: totals.Updated,
+		Deleted: totals.Deleted,
+		Pushed:  totals.Pushed,
+	})
+	if err != nil {
+		slog.Error("Post-sync SQL failed", "fatal", cfg.Sync.PostSyncSQLFatal, "error", err)
+		return err
+	}
+	slog.Info("Post-sync SQL executed", "statements", len(cfg.Sync.PostSyncSQL))
+	return nil
+}
+
+// startHealthServer 按配置启动健康检查服务，未配置监听地址时返回nil
+// 就绪检查复用数据库和各服务商客户端的TestConnection
+func startHealthServer(cfg *config.Config, providers map[string]provider.DNSProvider, store database.Store,
+	interval time.Duration) (*health.Server, error) {
+
+	if cfg.Health.Listen == "" {
+		return nil, nil
+	}
+
+	staleness := cfg.Health.Staleness
+	if staleness == 0 {
+		staleness = 3 * interval
+	}
+
+	server := health.NewServer(cfg.Health.Listen, staleness, cfg.Health.CheckTimeout)
+	server.AddCheck("database", store.TestConnection)
+	for name, dnsClient := range providers {
+		server.AddCheck(name, dnsClient.TestConnection)
+	}
+
+	if err := server.Start(); err != nil {
+		return nil, err
+	}
+	slog.Info("Health server listening", "address", cfg.Health.Listen, "staleness", staleness.String())
+
+	return server, nil
+}
+
+// newTimer 创建runLoop两轮之间等待用的定时器，测试中替换为立即触发的定时器
+var newTimer = time.NewTimer
+
+// runLoop 按间隔重复执行同步，每轮之间加入最多为间隔10%的随机抖动，避免多个副本同时请求
+func runLoop(ctx context.Context, interval time.Duration, run func()) {
+	for {
+		run()
+		// 同步过程中收到退出信号时不再安排下一轮
+		if ctx.Err() != nil {
+			slog.Info("Shutdown signal received, stopping daemon")
+			return
+		}
+
+		wait := interval + time.Duration(rand.Int64N(int64(interval)/10+1))
+		slog.Info("Next sync scheduled", "in", wait.Round(time.Second).String())
+
+		timer := newTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			slog.Info("Shutdown signal received, stopping daemon")
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// stringList 可重复指定的字符串命令行参数
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// filterDomains 按名称筛选域名映射，名称不在配置中时返回错误
+func filterDomains(domains []config.DomainMapping, names []string) ([]config.DomainMapping, error) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[models.NormalizeDomain(name)] = true
+	}
+
+	var filtered []config.DomainMapping
+	for _, domainMapping := range domains {
+		name := models.NormalizeDomain(domainMapping.Domain)
+		if wanted[name] {
+			filtered = append(filtered, domainMapping)
+			delete(wanted, name)
+		}
+	}
+
+	if len(wanted) > 0 {
+		var unknown []string
+		for name := range wanted {
+			unknown = append(unknown, name)
+		}
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("domains not found in config: %s", strings.Join(unknown, ", "))
+	}
+
+	return filtered, nil
+}
+
+// enabledDomains 返回启用的域名映射，按域名排序，用于不经过syncDomain的子命令
+func enabledDomains(domains []config.DomainMapping) []config.DomainMapping {
+	enabled := make([]config.DomainMapping, 0, len(domains))
+	for _, domainMapping := range domains {
+		if !domainMapping.IsEnabled() {
+			slog.Info("Domain disabled in config, skipping", "domain", domainMapping.Domain)
+			continue
+		}
+		enabled = append(enabled, domainMapping)
+	}
+
+	sort.SliceStable(enabled, func(i, j int) bool {
+		return enabled[i].Domain < enabled[j].Domain
+	})
+	return enabled
+}
+
+// lockRun 获取运行锁，返回非exitOK的退出码时调用方应直接退出：锁已被另一个实例持有时为exitLocked
+func lockRun(ctx context.Context, cfg *config.Config, store database.Store) (*database.RunLock, int) {
+	lockName := cfg.RunLockName()
+	runLock, err := store.AcquireRunLock(ctx, lockName)
+	if errors.Is(err, database.ErrLockHeld) {
+		slog.Error("Another dns-sync instance is already running, exiting", "lock", lockName)
+		return nil, exitLocked
+	}
+	if err != nil {
+		return nil, fatal("Failed to acquire run lock", err)
+	}
+	slog.Info("Run lock acquired", "lock", lockName)
+	return runLock, exitOK
+}
+
+// fatal 记录启动阶段的错误日志，返回配置或连接错误的退出码
+func fatal(msg string, err error) int {
+	slog.Error(msg, "error", err)
+	return exitSetupError
+}
+
+// syncDomains 使用工作池并发同步所有域名，结果按域名排序
+func syncDomains(ctx context.Context, cfg *config.Config, providers map[string]provider.DNSProvider,
+	store database.Store) []*SyncStats {
+
+	syncStats := make([]*SyncStats, len(cfg.Domains))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	slog.Info("Syncing domains", "count", len(cfg.Domains), "workers", cfg.Sync.Concurrency)
+	for w := 0; w < cfg.Sync.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// 每个任务只写入自己下标的位置，无需加锁
+			for i := range jobs {
+				syncStats[i] = syncDomain(ctx, cfg, providers, store, cfg.Domains[i])
+			}
+		}()
+	}
+
+	for i := range cfg.Domains {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.SliceStable(syncStats, func(i, j int) bool {
+		return syncStats[i].Domain < syncStats[j].Domain
+	})
+
+	return syncStats
+}
+
+// syncDomain 同步单个域名并生成统计信息
+func syncDomain(ctx context.Context, cfg *config.Config, providers map[string]provider.DNSProvider,
+	store database.Store, domainMapping config.DomainMapping) *SyncStats {
+
+	stats := &SyncStats{
+		Domain: domainMapping.Domain,
+	}
+	ctx, span := tracing.Start(ctx, "sync.domain", "domain", domainMapping.Domain,
+		"provider", domainMapping.ProviderKey())
+	defer endDomainSpan(span, stats)
+
+	// 停用的域名保留配置但不同步，不计为失败
+	if !domainMapping.IsEnabled() {
+		slog.Info("Domain disabled in config, skipping", "domain", domainMapping.Domain)
+		stats.Skipped = true
+		stats.SkipReason = "disabled in config"
+		return stats
+	}
+
+	// 同步已被取消，剩余域名标记为失败
+	if err := ctx.Err(); err != nil {
+		slog.Warn("Sync cancelled, skipping domain", "domain", domainMapping.Domain, "error", err)
+		stats.Error = fmt.Sprintf("sync cancelled: %v", err)
+		return stats
+	}
+
+	slog.Info("Processing domain", "domain", domainMapping.Domain, "provider", domainMapping.Provider,
+		"project_id", domainMapping.ProjectID, "domain_id", domainMapping.DomainID)
+
+	dnsClient := providers[domainMapping.ProviderKey()]
+	direction := cfg.Sync.Direction
+
+	// 先推送本地记录，随后的拉取即可按RecordId匹配到刚推送的记录；prune只删除，不推送
+	if (direction == "push" || direction == "both") && !cfg.Sync.PruneOnly {
+		err := pushDomain(ctx, dnsClient, store, domainMapping, cfg.Sync, stats)
+		if errors.Is(err, provider.ErrCircuitOpen) {
+			skipProviderOutage(stats, domainMapping, err)
+			return stats
+		}
 		if err != nil {
 			stats.Error = err.Error()
-			log.Printf("Error syncing domain %s: %v", domainMapping.Domain, err)
-		} else {
-			stats.Added = added
-			stats.Updated = updated
-			stats.Deleted = deleted
-			totalAdded += added
-			totalUpdated += updated
-			totalDeleted += deleted
-			log.Printf("Domain %s sync completed: +%d ~%d -%d", 
-				domainMapping.Domain, added, updated, deleted)
+			slog.Error("Error pushing domain", "domain", domainMapping.Domain, "error", err)
+			return stats
 		}
+		// 推送回写的记录可能还未复制到只读副本，该域名随后的拉取改为从主库读取
+		if stats.Pushed > 0 && !cfg.Sync.DryRun {
+			ctx = database.WithPrimary(ctx)
+		}
+	}
 
-		syncStats = append(syncStats, stats)
+	// 执行单个域名的增量同步
+	if direction == "pull" || direction == "both" {
+		err := incrementalSyncDomain(ctx, dnsClient, store, domainMapping, cfg.Sync, stats)
+		if errors.Is(err, provider.ErrDomainNotFound) {
+			// 域名已从服务商账号中移除，跳过而不是按全部删除处理
+			stats.Skipped = true
+			stats.SkipReason = "domain not found on provider"
+			slog.Warn("Domain does not exist on provider, skipping", "domain", domainMapping.Domain,
+				"provider", domainMapping.Provider, "error", err)
+			return stats
+		}
+		if errors.Is(err, provider.ErrCircuitOpen) {
+			skipProviderOutage(stats, domainMapping, err)
+			return stats
+		}
+		if errors.Is(err, provider.ErrAuthFailed) {
+			stats.Error = err.Error()
+			slog.Error("Provider rejected credentials, check access key and permissions", "domain", domainMapping.Domain,
+				"provider", domainMapping.Provider, "error", err)
+			return stats
+		}
+		if err != nil {
+			stats.Error = err.Error()
+			stats.ThresholdExceeded = errors.Is(err, errDeleteThreshold)
+			slog.Error("Error syncing domain", "domain", domainMapping.Domain, "error", err)
+			return stats
+		}
 	}
 
-	// 打印同步结果摘要
-	printIncrementalSyncSummary(syncStats, totalAdded, totalUpdated, totalDeleted)
+	if stats.Degraded() {
+		slog.Warn("Domain sync completed with failed records", "domain", domainMapping.Domain, "added", stats.Added,
+			"updated", stats.Updated, "deleted", stats.Deleted, "pushed", stats.Pushed, "failed", stats.Failed)
+		return stats
+	}
+	slog.Info("Domain sync completed", "domain", domainMapping.Domain, "added", stats.Added,
+		"updated", stats.Updated, "deleted", stats.Deleted, "pushed", stats.Pushed)
+
+	return stats
+}
 
-	log.Println("DNS incremental sync application completed")
+// endDomainSpan 在域名的span上记录同步结果并结束span
+func endDomainSpan(span *tracing.Span, stats *SyncStats) {
+	span.SetAttributes("records", stats.RecordCount, "added", stats.Added, "updated", stats.Updated,
+		"deleted", stats.Deleted, "pushed", stats.Pushed, "failed", stats.Failed, "skipped", stats.Skipped)
+	if stats.Error != "" {
+		span.RecordError(errors.New(stats.Error))
+	}
+	span.End()
+}
+
+// skipProviderOutage 服务商已熔断时跳过域名，熔断时已输出过错误，这里只记录info日志
+func skipProviderOutage(stats *SyncStats, domainMapping config.DomainMapping, err error) {
+	stats.Skipped = true
+	stats.SkipReason = "provider outage"
+	slog.Info("Skipping domain, provider circuit is open", "domain", domainMapping.Domain,
+		"provider", domainMapping.ProviderKey(), "error", err)
+}
+
+// newProviders 按配置创建用到的DNS服务商客户端，连接测试见providerChecks
+func newProviders(cfg *config.Config) (map[string]provider.DNSProvider, error) {
+	providers := make(map[string]provider.DNSProvider)
+
+	// 每个阿里云账号创建一个客户端，同一账号的域名共享签名凭证和限流器
+	for _, name := range cfg.AliyunAccountsInUse() {
+		account, _ := cfg.AliyunAccount(name)
+		dnsClient, err := aliyun.NewDNSClient(account)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Aliyun DNS client for account %s: %w", name, err)
+		}
+		key := config.DomainMapping{Provider: provider.Aliyun, Account: name}.ProviderKey()
+		providers[key] = dnsClient
+		slog.Info("DNS client initialized", "provider", key)
+	}
+
+	if cfg.UsesProvider(provider.Cloudflare) {
+		dnsClient, err := cloudflare.NewDNSClient(&cfg.Cloudflare)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Cloudflare DNS client: %w", err)
+		}
+		providers[provider.Cloudflare] = dnsClient
+		slog.Info("DNS client initialized", "provider", provider.Cloudflare)
+	}
+
+	if cfg.UsesProvider(provider.Route53) {
+		dnsClient, err := route53.NewDNSClient(&cfg.AWS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Route53 DNS client: %w", err)
+		}
+		providers[provider.Route53] = dnsClient
+		slog.Info("DNS client initialized", "provider", provider.Route53)
+	}
+
+	if cfg.UsesProvider(provider.DNSPod) {
+		dnsClient, err := dnspod.NewDNSClient(&cfg.DNSPod)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create DNSPod DNS client: %w", err)
+		}
+		providers[provider.DNSPod] = dnsClient
+		slog.Info("DNS client initialized", "provider", provider.DNSPod)
+	}
+
+	if cfg.UsesProvider(provider.File) {
+		paths := make(map[string]string)
+		for _, domainMapping := range cfg.Domains {
+			if domainMapping.Provider == provider.File {
+				paths[domainMapping.QueryDomain()] = domainMapping.Path
+			}
+		}
+		providers[provider.File] = file.NewDNSClient(paths)
+		slog.Info("DNS client initialized", "provider", provider.File)
+	}
+
+	return providers, nil
+}
+
+// pushDomain 将本地指定来源且未关联RecordId的记录推送到服务商，并回写RecordId
+func pushDomain(ctx context.Context, dnsClient provider.DNSProvider, store database.Store,
+	domainMapping config.DomainMapping, syncCfg config.SyncConfig, stats *SyncStats) error {
+
+	pusher, ok := dnsClient.(provider.RecordPusher)
+	if !ok {
+		return fmt.Errorf("provider %s does not support pushing records", domainMapping.Provider)
+	}
+
+	records, err := store.GetPendingPushRecords(ctx, domainMapping.DomainID, syncCfg.PushSource)
+	if err != nil {
+		return fmt.Errorf("failed to get pending push records: %w", err)
+	}
+
+	slog.Info("Found local records to push", "domain", domainMapping.Domain, "count", len(records))
+
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("push cancelled: %w", err)
+		}
+
+		dnsRecord := record.ToDNSRecord(domainMapping.QueryDomain())
+		if syncCfg.DryRun {
+			stats.Pushed++
+			slog.Info("[DRY-RUN] Would push record", "action", "push", "sub_domain", record.SubDomain,
+				"type", record.Type)
+			continue
+		}
+
+		recordID, err := pusher.AddDomainRecord(ctx, dnsRecord)
+		if errors.Is(err, provider.ErrCircuitOpen) {
+			return err
+		}
+		if err != nil {
+			slog.Error("Failed to push record", "action", "push", "sub_domain", record.SubDomain, "error", err)
+			stats.addFailure("push", record.SubDomain, "", err)
+			continue
+		}
+
+		// 回写失败时记录会在下次拉取时作为新记录插入，这里只记录错误
+		if err := store.MarkRecordPushed(ctx, record.ID, recordID, domainMapping.Source); err != nil {
+			slog.Error("Failed to write back pushed record id", "action", "push", "sub_domain", record.SubDomain,
+				"record_id", recordID, "error", err)
+			stats.addFailure("write_back", record.SubDomain, recordID, err)
+			continue
+		}
+
+		stats.Pushed++
+		slog.Debug("Pushed record", "action", "push", "sub_domain", record.SubDomain, "record_id", recordID)
+	}
+
+	return nil
 }
 
 // incrementalSyncDomain 执行单个域名的增量同步
-func incrementalSyncDomain(dnsClient *aliyun.DNSClient, mysqlClient *database.MySQLClient, 
-	domainMapping config.DomainMapping) (int, int, int, error) {
+// 同步结果写入stats；syncCfg.DryRun为true时只统计和打印变更，不写入数据库
+func incrementalSyncDomain(ctx context.Context, dnsClient provider.DNSProvider, store database.Store, 
+	domainMapping config.DomainMapping, syncCfg config.SyncConfig, stats *SyncStats) error {
 	
-	// 1. 获取阿里云当前所有DNS记录
-	dnsRecords, err := dnsClient.GetDomainRecords(domainMapping.Domain)
+	changes, localCount, err := computeSyncChanges(ctx, dnsClient, store, domainMapping, syncCfg, stats)
+	if err != nil {
+		return err
+	}
+	splitPrune(changes, syncCfg, domainMapping.Domain)
+
+	// 删除数量超出阈值时放弃该域名，防止配置错误导致误删
+	if err := checkDeleteThreshold(changes.Deletes, localCount, syncCfg); err != nil {
+		for _, record := range changes.Deletes {
+			slog.Warn("Refused to delete record", "domain", domainMapping.Domain, "action", "delete",
+				"sub_domain", record.SubDomain, "record_id", *record.AliyunRecordID)
+		}
+		return err
+	}
+
+	// 执行变更
+	changes.Progress = database.NewProgress(domainMapping.Domain, changes.Total(),
+		syncCfg.ProgressEvery, syncCfg.ProgressInterval)
+	if syncCfg.DryRun {
+		logDryRunChanges(changes)
+		stats.Added, stats.Updated, stats.Deleted = len(changes.Inserts), len(changes.Updates), len(changes.Deletes)
+		return nil
+	}
+
+	if err := applyDomainChanges(ctx, store, changes, domainMapping, syncCfg, stats); err != nil {
+		return err
+	}
+
+	// 新增和更新全部写入后才推进水位，失败时下次仍会重新对比这些记录；prune没有写入新增和更新，不推进水位
+	if syncCfg.Since && stats.Watermark > 0 && !syncCfg.PruneOnly {
+		if err := store.SaveWatermark(ctx, domainMapping.DomainID, domainMapping.Source, stats.Watermark); err != nil {
+			return fmt.Errorf("failed to save sync watermark: %w", err)
+		}
+	}
+	return nil
+}
+
+// splitPrune 按模式拆分变更：prune子命令只保留删除；defer_deletes开启时常规同步去掉删除，留给prune执行
+// 恢复和重新关联属于更新，prune不执行，对应的本地行也不在删除列表中
+func splitPrune(changes *database.SyncChanges, syncCfg config.SyncConfig, domain string) {
+	if syncCfg.PruneOnly {
+		if len(changes.Inserts) > 0 || len(changes.Updates) > 0 {
+			slog.Debug("Prune mode, skipping inserts and updates", "domain", domain,
+				"skipped_inserts", len(changes.Inserts), "skipped_updates", len(changes.Updates))
+		}
+		changes.Inserts, changes.Updates = nil, nil
+		return
+	}
+	if syncCfg.DeferDeletes && len(changes.Deletes) > 0 {
+		slog.Info("Deferred deletes to prune", "domain", domain, "deferred_deletes", len(changes.Deletes))
+		changes.Deletes = nil
+	}
+}
+
+// applyDomainChanges 按配置在一个事务中或分批写入变更，写入结果记入stats
+func applyDomainChanges(ctx context.Context, store database.Store, changes *database.SyncChanges,
+	domainMapping config.DomainMapping, syncCfg config.SyncConfig, stats *SyncStats) (err error) {
+
+	ctx, span := tracing.Start(ctx, "sync.apply", "inserts", len(changes.Inserts), "updates", len(changes.Updates),
+		"deletes", len(changes.Deletes), "transaction", syncCfg.Transaction)
+	defer func() {
+		span.SetAttributes("added", stats.Added, "updated", stats.Updated, "deleted", stats.Deleted)
+		span.RecordError(err)
+		span.End()
+	}()
+
+	if syncCfg.Transaction {
+		result, err := store.SyncDomainTx(ctx, changes, syncCfg.StopOnError)
+		if err != nil {
+			return err
+		}
+		stats.Added, stats.Updated, stats.Deleted = result.Added, result.Updated, result.Deleted
+		return nil
+	}
+
+	stats.Added, stats.Updated, stats.Deleted, err = applySyncChanges(ctx, store, changes, domainMapping, syncCfg.BatchSize, stats)
+	return err
+}
+
+// fetchDomainRecords 获取域名在服务商上的全部记录，返回的truncated表示记录数超出max_records被截断
+// 配置了zone_apex时查询注册的主域名，只保留子区域下的记录，并将主机记录改为相对子区域的形式
+func fetchDomainRecords(ctx context.Context, dnsClient provider.DNSProvider,
+	domainMapping config.DomainMapping) ([]*models.DNSRecord, bool, error) {
+
+	limit := &provider.RecordLimit{
+		Max:      domainMapping.MaxRecords,
+		Truncate: domainMapping.MaxRecordsAction == "truncate",
+	}
+	records, err := dnsClient.GetDomainRecords(provider.WithRecordLimit(ctx, limit), domainMapping.QueryDomain())
+	if err != nil || domainMapping.ZoneApex == "" {
+		return records, limit.Truncated, err
+	}
+
+	zone := models.NormalizeDomain(domainMapping.Domain)
+	var inZone []*models.DNSRecord
+	for _, record := range records {
+		if record.InZone(zone) {
+			inZone = append(inZone, record.Rebase(zone))
+		}
+	}
+	slog.Debug("Filtered records to subzone", "domain", domainMapping.Domain, "zone_apex", domainMapping.ZoneApex,
+		"total", len(records), "in_zone", len(inZone))
+	return inZone, limit.Truncated, nil
+}
+
+// computeSyncChanges 拉取服务商记录和本地记录并计算变更集合，不写入数据库
+// 返回变更集合和参与对比的本地记录数；记录数和停用数写入stats，sync和diff共用
+func computeSyncChanges(ctx context.Context, dnsClient provider.DNSProvider, store database.Store,
+	domainMapping config.DomainMapping, syncCfg config.SyncConfig, stats *SyncStats) (*database.SyncChanges, int, error) {
+
+	// 1. 获取服务商当前所有DNS记录
+	dnsRecords, truncated, err := fetchDomainRecords(ctx, dnsClient, domainMapping)
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("failed to get DNS records: %w", err)
+		return nil, 0, fmt.Errorf("failed to get DNS records: %w", err)
 	}
 
-	// 2. 过滤只处理A和CNAME记录，且状态为ENABLE
+	// 2. 过滤只处理配置的记录类型、解析线路和子域名；停用的记录保留并标记为DISABLED，不按删除处理
+	// 保留的记录按sync.transforms改写记录值，之后的对比和写入都使用改写后的值
 	var validRecords []*models.DNSRecord
+	presentIDs := make(map[string]bool, len(dnsRecords))
+	transform := recordTransformer(syncCfg.Transforms)
 	for _, record := range dnsRecords {
-		if (record.Type == "A" || record.Type == "CNAME") && record.Status == "ENABLE" {
+		presentIDs[record.RecordId] = true
+		if domainMapping.AcceptsType(record.Type) && domainMapping.AcceptsLine(record.Line) &&
+			domainMapping.AcceptsName(record.FullDomain()) &&
+			!isSystemRecord(syncCfg, domainMapping, record.FullDomain(), record.Type) {
+			record = transform(record)
+			// 忽略的字段不计入内容哈希，仅这些字段变化时不会触发更新
+			record.IgnoreFields = syncCfg.IgnoreFields
+			labelLine(record, syncCfg.LineNames)
 			validRecords = append(validRecords, record)
+			if record.Status != "ENABLE" {
+				stats.Disabled++
+			}
 		}
 	}
 
-	log.Printf("Found %d valid DNS records (A/CNAME, ENABLED) for domain: %s", 
-		len(validRecords), domainMapping.Domain)
+	slog.Info("Found valid DNS records", "domain", domainMapping.Domain, "count", len(validRecords),
+		"disabled", stats.Disabled,
+		"record_types", strings.Join(domainMapping.RecordTypes, "/"), "lines", strings.Join(domainMapping.Lines, "/"))
+	stats.RecordCount = len(validRecords)
 
 	// 3. 获取数据库中该域名的所有记录
-	localRecords, err := mysqlClient.GetLocalRecords(domainMapping.DomainID)
+	localCtx, localSpan := tracing.StartClient(ctx, "db.get_local_records", "domain_id", domainMapping.DomainID,
+		"source", domainMapping.Source)
+	localRecords, err := store.GetLocalRecords(localCtx, domainMapping.DomainID, domainMapping.Source)
+	localSpan.SetAttributes("records", len(localRecords))
+	localSpan.RecordError(err)
+	localSpan.End()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get local records: %w", err)
+	}
+
+	// 其它线路、被include/exclude过滤掉的和域名本身的NS/SOA本地记录不参与对比，避免被当作已删除；
+	// 旧数据没有线路信息，仍参与对比
+	for recordID, record := range localRecords {
+		if record.Line != "" && !domainMapping.AcceptsLine(record.Line) {
+			delete(localRecords, recordID)
+			continue
+		}
+		if !domainMapping.AcceptsName(record.FullDomain()) ||
+			isSystemRecord(syncCfg, domainMapping, record.FullDomain(), record.Type) {
+			delete(localRecords, recordID)
+		}
+	}
+
+	slog.Info("Found local records", "domain", domainMapping.Domain, "count", len(localRecords))
+
+	// 软删除模式下获取已删除的记录，阿里云上重新出现时恢复而不是重复插入
+	deletedRecords, err := store.GetSoftDeletedRecords(ctx, domainMapping.DomainID, domainMapping.Source)
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("failed to get local records: %w", err)
+		return nil, 0, fmt.Errorf("failed to get soft-deleted records: %w", err)
 	}
 
-	log.Printf("Found %d local records for domain: %s", len(localRecords), domainMapping.Domain)
+	// 多值记录合并为一行，优先保留已有本地行的RecordId；被合并的RecordId不再视为存在，已有的本地行会被删除
+	if syncCfg.CollapseValues {
+		var merged []string
+		validRecords, merged = models.CollapseValues(validRecords, func(recordID string) bool {
+			return localRecords[recordID] != nil || deletedRecords[recordID] != nil
+		})
+		for _, recordID := range merged {
+			delete(presentIDs, recordID)
+		}
+	}
+
+	// 增量模式下跳过本地已有且在水位之后没有修改过的记录；删除仍按服务商的完整结果判断
+	if syncCfg.Since {
+		watermark, err := store.GetWatermark(ctx, domainMapping.DomainID, domainMapping.Source)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get sync watermark: %w", err)
+		}
+		// 截断的结果中没有的记录可能早于新的水位，不推进水位，下次仍从原水位对比
+		if !truncated {
+			stats.Watermark = max(watermark, maxUpdateTimestamp(validRecords))
+		}
+		if watermark > 0 {
+			total := len(validRecords)
+			validRecords = recordsSince(validRecords, watermark, localRecords)
+			slog.Info("Skipped records unchanged since watermark", "domain", domainMapping.Domain,
+				"watermark", watermark, "skipped", total-len(validRecords), "count", len(validRecords))
+		}
+	}
 
 	// 4. 构建阿里云记录映射表
 	aliyunRecords := make(map[string]*models.DNSRecord)
@@ -144,97 +1066,535 @@ func incrementalSyncDomain(dnsClient *aliyun.DNSClient, mysqlClient *database.My
 		aliyunRecords[record.RecordId] = record
 	}
 
-	// 5. 执行三向对比同步
-	added := 0
-	updated := 0
-	deleted := 0
+	// 5. 三向对比，计算变更集合
+	changes := buildSyncChanges(aliyunRecords, presentIDs, localRecords, deletedRecords, domainMapping)
+	if syncCfg.MatchBy == "name_type" {
+		reconcileByNameType(changes, aliyunRecords)
+	}
+	if syncCfg.LockedRecords != "sync" {
+		skipLockedUpdates(changes, syncCfg.LockedRecords, domainMapping.Domain)
+	}
+	// 截断的结果不完整，本地有而结果中没有的记录不一定已被删除
+	if truncated && len(changes.Deletes) > 0 {
+		slog.Warn("Record list truncated by max_records, skipping deletes", "domain", domainMapping.Domain,
+			"skipped_deletes", len(changes.Deletes))
+		changes.Deletes = nil
+	}
+	// 服务商返回空结果而本地有记录时，更可能是服务商的临时故障或域名配置错误，不删除本地记录
+	if len(dnsRecords) == 0 && len(changes.Deletes) > 0 && !syncCfg.AllowEmpty {
+		slog.Warn("Provider returned no records for a domain with local records, skipping deletes",
+			"domain", domainMapping.Domain, "local_records", len(localRecords), "skipped_deletes", len(changes.Deletes))
+		stats.Warning = fmt.Sprintf("provider returned no records, skipped deleting %d local records",
+			len(changes.Deletes))
+		changes.Deletes = nil
+	}
+
+	return changes, len(localRecords), nil
+}
+
+// maxUpdateTimestamp 获取记录的最大UpdateTimestamp
+func maxUpdateTimestamp(records []*models.DNSRecord) int64 {
+	var watermark int64
+	for _, record := range records {
+		watermark = max(watermark, record.UpdateTimestamp)
+	}
+	return watermark
+}
+
+// recordsSince 筛选需要对比的记录：水位之后修改过的记录、本地没有的记录，以及服务商未返回修改时间的记录
+func recordsSince(records []*models.DNSRecord, watermark int64,
+	localRecords map[string]*models.AssetSubDomain) []*models.DNSRecord {
+
+	var result []*models.DNSRecord
+	for _, record := range records {
+		if record.UpdateTimestamp == 0 || record.UpdateTimestamp > watermark || localRecords[record.RecordId] == nil {
+			result = append(result, record)
+		}
+	}
+	return result
+}
+
+// skipLockedUpdates 按locked_records策略移除对服务商上已锁定记录的更新
+// 恢复和重新关联的记录仍然保留，保证锁定记录在本地可见；被删除的记录已不在服务商上，不受影响
+func skipLockedUpdates(changes *database.SyncChanges, policy, domain string) {
+	var updates []database.RecordUpdate
+	for _, update := range changes.Updates {
+		if !update.AliyunRecord.Locked || update.Restore || update.Relink {
+			updates = append(updates, update)
+			continue
+		}
+
+		if policy == "readonly" {
+			slog.Warn("Locked record differs from local record, not updating", "domain", domain, "action", "update",
+				"sub_domain", update.AliyunRecord.FullDomain(), "record_id", update.AliyunRecord.RecordId)
+		} else {
+			slog.Debug("Skipped update of locked record", "domain", domain, "action", "update",
+				"sub_domain", update.AliyunRecord.FullDomain(), "record_id", update.AliyunRecord.RecordId)
+		}
+	}
+	changes.Updates = updates
+}
+
+// logDryRunChanges 在dry-run模式下逐条打印将要执行的变更
+func logDryRunChanges(changes *database.SyncChanges) {
+	for _, record := range changes.Inserts {
+		slog.Info("[DRY-RUN] Would add new record", "action", "insert", "sub_domain", record.SubDomain,
+			"record_id", *record.AliyunRecordID)
+	}
+	for _, update := range changes.Updates {
+		if update.Relink {
+			slog.Info("[DRY-RUN] Would relink record", "action", "relink", "sub_domain", update.LocalRecord.SubDomain,
+				"old_record_id", *update.LocalRecord.AliyunRecordID, "record_id", update.AliyunRecord.RecordId)
+			continue
+		}
+		if update.Restore {
+			slog.Info("[DRY-RUN] Would restore record", "action", "restore",
+				"sub_domain", update.AliyunRecord.FullDomain(), "record_id", update.AliyunRecord.RecordId)
+			continue
+		}
+		slog.Info("[DRY-RUN] Would update record", "action", "update", "from", update.LocalRecord.SubDomain,
+			"to", update.AliyunRecord.FullDomain(), "record_id", update.AliyunRecord.RecordId)
+	}
+	for _, record := range changes.Deletes {
+		slog.Info("[DRY-RUN] Would delete record", "action", "delete", "sub_domain", record.SubDomain,
+			"record_id", *record.AliyunRecordID)
+	}
+}
+
+// checkDeleteThreshold 检查待删除记录数是否超过配置的绝对数量或百分比
+// 待删除记录数不超过max_delete_percent_min时不检查百分比，否则本地只有几条记录的域名删除一条就会被拒绝
+func checkDeleteThreshold(deletes []*models.AssetSubDomain, localCount int, syncCfg config.SyncConfig) error {
+	if len(deletes) == 0 {
+		return nil
+	}
+
+	if syncCfg.MaxDeleteCount > 0 && len(deletes) > syncCfg.MaxDeleteCount {
+		return fmt.Errorf("%w: refusing to delete %d records: exceeds max_delete_count %d",
+			errDeleteThreshold, len(deletes), syncCfg.MaxDeleteCount)
+	}
+
+	if len(deletes) <= syncCfg.MaxDeletePercentMin {
+		return nil
+	}
+
+	percent := float64(len(deletes)) / float64(localCount) * 100
+	if percent > syncCfg.MaxDeletePercent {
+		return fmt.Errorf("%w: refusing to delete %d of %d local records (%.1f%%): exceeds max_delete_percent %.1f%%",
+			errDeleteThreshold, len(deletes), localCount, percent, syncCfg.MaxDeletePercent)
+	}
+
+	return nil
+}
+
+// warnedLines 已经警告过的未知线路代码，每个代码只警告一次
+var warnedLines sync.Map
+
+// isSystemRecord 未开启include_system_records时，判断记录是否为域名本身的NS或SOA记录
+// 配置了zone_apex时为子区域本身的记录，即父区域中的委派记录
+func isSystemRecord(syncCfg config.SyncConfig, domainMapping config.DomainMapping, name, recordType string) bool {
+	return !syncCfg.IncludeSystemRecords &&
+		models.IsSystemRecord(name, recordType, models.NormalizeDomain(domainMapping.Domain))
+}
+
+// recordTransformer 按配置顺序组合记录值改写规则，规则名称在加载配置时已校验
+func recordTransformer(transforms []config.TransformConfig) models.Transformer {
+	chain := make([]models.Transformer, 0, len(transforms))
+	for _, transform := range transforms {
+		switch transform.Name {
+		case "lowercase_value":
+			chain = append(chain, models.LowercaseValue())
+		case "strip_trailing_dot":
+			chain = append(chain, models.StripTrailingDot())
+		case "suffix_strip":
+			chain = append(chain, models.SuffixStrip(transform.Arg))
+		}
+	}
+	return models.Chain(chain...)
+}
+
+// labelLine 按内置名称和sync.line_names设置记录的线路名称，没有名称的线路代码原样使用
+func labelLine(record *models.DNSRecord, lineNames map[string]string) {
+	if record.Line == "" {
+		return
+	}
+
+	name, known := models.LineName(record.Line, lineNames)
+	if !known {
+		if _, warned := warnedLines.LoadOrStore(record.Line, true); !warned {
+			slog.Warn("Unknown line code, storing it as the line name", "line", record.Line)
+		}
+	}
+	record.LineName = name
+}
+
+// buildSyncChanges 对比阿里云记录与本地记录，计算需要新增、更新、删除的记录
+// deletedRecords中的记录如果在阿里云重新出现，则恢复该记录；
+// presentIDs为服务商完整结果中的RecordId，被类型或线路过滤掉的记录不会当作已删除
+func buildSyncChanges(aliyunRecords map[string]*models.DNSRecord, presentIDs map[string]bool,
+	localRecords, deletedRecords map[string]*models.AssetSubDomain, domainMapping config.DomainMapping) *database.SyncChanges {
+
+	changes := &database.SyncChanges{}
 
 	// 处理新增和更新
 	for recordId, aliyunRecord := range aliyunRecords {
 		if localRecord, exists := localRecords[recordId]; exists {
 			// 记录存在，检查是否需要更新
 			if database.NeedUpdate(aliyunRecord, localRecord) {
-				err := mysqlClient.UpdateRecord(localRecord.ID, aliyunRecord)
-				if err != nil {
-					log.Printf("Failed to update record %s: %v", recordId, err)
-				} else {
-					updated++
-					log.Printf("Updated record: %s -> %s", localRecord.SubDomain, 
-						getFullDomain(aliyunRecord))
-				}
+				changes.Updates = append(changes.Updates, database.RecordUpdate{
+					LocalRecord:  localRecord,
+					AliyunRecord: aliyunRecord,
+				})
 			}
+		} else if deletedRecord, exists := deletedRecords[recordId]; exists {
+			// 已软删除的记录重新出现，恢复并更新
+			changes.Updates = append(changes.Updates, database.RecordUpdate{
+				LocalRecord:  deletedRecord,
+				AliyunRecord: aliyunRecord,
+				Restore:      true,
+			})
 		} else {
 			// 新记录，插入数据库
-			newRecord := aliyunRecord.ConvertToAssetSubDomain(
-				domainMapping.DomainID, 
-				domainMapping.ProjectID,
-			)
-			err := mysqlClient.InsertRecord(newRecord)
-			if err != nil {
-				log.Printf("Failed to insert record %s: %v", recordId, err)
-			} else {
-				added++
-				log.Printf("Added new record: %s", newRecord.SubDomain)
-			}
+			changes.Inserts = append(changes.Inserts, newAssetRecord(aliyunRecord, domainMapping))
 		}
 	}
 
-	// 处理删除
+	// 处理删除：只有RecordId在服务商的完整结果中确实不存在时才删除
 	for recordId, localRecord := range localRecords {
-		if _, exists := aliyunRecords[recordId]; !exists {
-			// 阿里云已删除，数据库也删除
-			err := mysqlClient.DeleteRecord(localRecord.ID)
-			if err != nil {
-				log.Printf("Failed to delete record %s: %v", recordId, err)
-			} else {
-				deleted++
-				log.Printf("Deleted record: %s", localRecord.SubDomain)
+		if _, exists := aliyunRecords[recordId]; !exists && !presentIDs[recordId] {
+			changes.Deletes = append(changes.Deletes, localRecord)
+		}
+	}
+
+	return changes
+}
+
+// newAssetRecord 转换需要新增的记录，并写入配置的资产字段
+// 更新时不经过这里，upsert和updateRecord也不会更新这些列，人工修改过的值不会被覆盖
+func newAssetRecord(aliyunRecord *models.DNSRecord, domainMapping config.DomainMapping) *models.AssetSubDomain {
+	record := aliyunRecord.ConvertToAssetSubDomain(domainMapping.DomainID, domainMapping.ProjectID, domainMapping.Source)
+
+	fields := domainMapping.AssetDefaults
+	record.AssetLabel = fields.AssetLabel
+	record.CreateBy = optionalString(fields.CreateBy)
+	record.UpdateBy = optionalString(fields.CreateBy)
+	record.SysOrgCode = optionalString(fields.SysOrgCode)
+	record.AssetDepartment = optionalString(fields.AssetDepartment)
+	record.Level = optionalString(fields.Level)
+
+	return record
+}
+
+// optionalString 空字符串转换为NULL
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// reconcileByNameType 将待删除与待新增的记录按(子域名, 类型)配对
+// 阿里云控制台删除后重建的记录会分配新的RecordId，配对成功后改为更新原有行的aliyun_record_id，
+// 保留人工维护的资产信息；同名同类型有多条时优先匹配记录值相同的一条
+func reconcileByNameType(changes *database.SyncChanges, aliyunRecords map[string]*models.DNSRecord) {
+	if len(changes.Deletes) == 0 || len(changes.Inserts) == 0 {
+		return
+	}
+
+	candidates := make(map[string][]*models.AssetSubDomain)
+	for _, record := range changes.Deletes {
+		// 旧数据的子域名可能未转换为punycode
+		key := record.FullDomain() + "|" + strings.ToUpper(record.Type)
+		candidates[key] = append(candidates[key], record)
+	}
+
+	// 按RecordId排序，保证配对结果稳定
+	sort.Slice(changes.Inserts, func(i, j int) bool {
+		return *changes.Inserts[i].AliyunRecordID < *changes.Inserts[j].AliyunRecordID
+	})
+
+	matched := make(map[string]bool)
+	var inserts []*models.AssetSubDomain
+	for _, record := range changes.Inserts {
+		key := record.SubDomain + "|" + strings.ToUpper(record.Type)
+		pool := candidates[key]
+		if len(pool) == 0 {
+			inserts = append(inserts, record)
+			continue
+		}
+
+		pick := 0
+		for i, candidate := range pool {
+			if candidate.DNSRecord != nil && record.DNSRecord != nil && *candidate.DNSRecord == *record.DNSRecord {
+				pick = i
+				break
+			}
+		}
+		localRecord := pool[pick]
+		candidates[key] = append(pool[:pick], pool[pick+1:]...)
+		matched[localRecord.ID] = true
+
+		changes.Updates = append(changes.Updates, database.RecordUpdate{
+			LocalRecord:  localRecord,
+			AliyunRecord: aliyunRecords[*record.AliyunRecordID],
+			Relink:       true,
+		})
+	}
+	changes.Inserts = inserts
+
+	var deletes []*models.AssetSubDomain
+	for _, record := range changes.Deletes {
+		if !matched[record.ID] {
+			deletes = append(deletes, record)
+		}
+	}
+	changes.Deletes = deletes
+}
+
+// applySyncChanges 批量写入新增和更新的记录，再分批执行删除，单条删除失败不影响其它记录，失败记录写入stats
+func applySyncChanges(ctx context.Context, store database.Store, changes *database.SyncChanges,
+	domainMapping config.DomainMapping, batchSize int, stats *SyncStats) (int, int, int, error) {
+
+	added := 0
+	updated := 0
+	deleted := 0
+
+	// 新增在前、更新在后，便于按写入数拆分统计
+	// 批量upsert按(source, domain_id, aliyun_record_id)冲突更新，不会改写aliyun_record_id，
+	// 重新关联等RecordId变化的更新在批量写入后逐条执行
+	upserts := make([]*models.AssetSubDomain, 0, len(changes.Inserts)+len(changes.Updates))
+	upserts = append(upserts, changes.Inserts...)
+	var relinks []database.RecordUpdate
+	for _, update := range changes.Updates {
+		if local := update.LocalRecord.AliyunRecordID; local == nil || *local != update.AliyunRecord.RecordId {
+			relinks = append(relinks, update)
+			continue
+		}
+		record := update.AliyunRecord.ConvertToAssetSubDomain(domainMapping.DomainID, domainMapping.ProjectID, domainMapping.Source)
+		record.ID = update.LocalRecord.ID
+		upserts = append(upserts, record)
+	}
+
+	// 按批次调用BatchUpsert，每批写完后更新进度
+	if batchSize <= 0 {
+		batchSize = database.DefaultBatchSize
+	}
+	for start := 0; start < len(upserts); start += batchSize {
+		end := min(start+batchSize, len(upserts))
+		written, err := store.BatchUpsert(ctx, upserts[start:end], batchSize)
+		batchAdded := max(0, min(start+written, len(changes.Inserts))-start)
+		batchUpdated := written - batchAdded
+		added += batchAdded
+		updated += batchUpdated
+		if err != nil {
+			return added, updated, deleted, fmt.Errorf("failed to upsert records: %w", err)
+		}
+		changes.Progress.Step(batchAdded, batchUpdated, 0, 0)
+	}
+	for _, update := range relinks {
+		if err := store.UpdateRecord(ctx, update.LocalRecord.ID, update.AliyunRecord); err != nil {
+			return added, updated, deleted, fmt.Errorf("failed to relink record %s: %w", update.LocalRecord.SubDomain, err)
+		}
+		updated++
+		changes.Progress.Step(0, 1, 0, 0)
+	}
+	if len(upserts)+len(relinks) > 0 {
+		slog.Debug("Upserted records", "action", "upsert", "domain", domainMapping.Domain,
+			"added", added, "updated", updated)
+	}
+
+	// 删除按批次合并为IN语句，批次失败时由DeleteRecords逐条重试，只报告真正失败的记录
+	for start := 0; start < len(changes.Deletes); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return added, updated, deleted, fmt.Errorf("sync cancelled: %w", err)
+		}
+
+		chunk := changes.Deletes[start:min(start+batchSize, len(changes.Deletes))]
+		ids := make([]string, 0, len(chunk))
+		for _, record := range chunk {
+			ids = append(ids, record.ID)
+		}
+		failed := store.DeleteRecords(ctx, ids, batchSize)
+
+		for _, record := range chunk {
+			if err, ok := failed[record.ID]; ok {
+				slog.Error("Failed to delete record", "action", "delete", "sub_domain", record.SubDomain,
+					"record_id", *record.AliyunRecordID, "error", err)
+				stats.addFailure("delete", record.SubDomain, *record.AliyunRecordID, err)
+				continue
 			}
+			deleted++
+			slog.Debug("Deleted record", "action", "delete", "sub_domain", record.SubDomain,
+				"record_id", *record.AliyunRecordID)
 		}
+		changes.Progress.Step(0, 0, len(chunk)-len(failed), len(failed))
 	}
 
 	return added, updated, deleted, nil
 }
 
-// getFullDomain 获取完整域名
-func getFullDomain(record *models.DNSRecord) string {
-	if record.RR == "" || record.RR == "@" {
-		return record.DomainName
+// formatFailures 按操作名排序输出失败数，如"delete 2, push 1"
+func formatFailures(byAction map[string]int) string {
+	actions := make([]string, 0, len(byAction))
+	for action := range byAction {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+
+	parts := make([]string, 0, len(actions))
+	for _, action := range actions {
+		parts = append(parts, fmt.Sprintf("%s %d", action, byAction[action]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// buildSyncReport 根据同步统计生成报告
+func buildSyncReport(stats []*SyncStats, startTime, endTime time.Time, dryRun bool) *models.SyncReport {
+	report := &models.SyncReport{
+		StartTime: startTime,
+		EndTime:   endTime,
+		DryRun:    dryRun,
+		Domains:   make([]models.DomainSyncResult, 0, len(stats)),
+	}
+
+	for _, stat := range stats {
+		report.Domains = append(report.Domains, models.DomainSyncResult{
+			Domain:         stat.Domain,
+			Success:        stat.Error == "" && stat.Failed == 0,
+			RecordCount:    stat.RecordCount,
+			Disabled:       stat.Disabled,
+			Added:          stat.Added,
+			Updated:        stat.Updated,
+			Deleted:        stat.Deleted,
+			Pushed:         stat.Pushed,
+			Error:          stat.Error,
+			Skipped:        stat.Skipped,
+			Degraded:       stat.Degraded(),
+			Failed:         stat.Failed,
+			FailedByAction: stat.FailedByAction,
+			Errors:         stat.FailedSamples,
+			Warning:        stat.Warning,
+			DeleteAnomaly:  stat.DeleteAnomaly,
+			DeleteAverage:  stat.DeleteAverage,
+		})
+
+		report.Totals.Domains++
+		if stat.Error != "" {
+			report.Totals.Failed++
+			continue
+		}
+		if stat.Skipped {
+			report.Totals.Skipped++
+			continue
+		}
+		if stat.Degraded() {
+			report.Totals.Degraded++
+		} else {
+			report.Totals.Succeeded++
+		}
+		report.Totals.FailedRecords += stat.Failed
+		if stat.DeleteAnomaly {
+			report.Totals.Anomalies++
+		}
+		report.Totals.Added += stat.Added
+		report.Totals.Updated += stat.Updated
+		report.Totals.Deleted += stat.Deleted
+		report.Totals.Pushed += stat.Pushed
+	}
+
+	return report
+}
+
+// writeSyncReport 将同步报告以JSON格式写入文件
+func writeSyncReport(path string, report *models.SyncReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
 	}
-	return record.RR + "." + record.DomainName
+	data = []byte(logger.Redact(string(data)))
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return nil
 }
 
-// printIncrementalSyncSummary 打印增量同步结果摘要
-func printIncrementalSyncSummary(stats []*SyncStats, totalAdded, totalUpdated, totalDeleted int) {
+// printIncrementalSyncSummary 打印增量同步结果摘要，退出码由syncExitCode根据同一份统计单独计算
+func printIncrementalSyncSummary(stats []*SyncStats, totalAdded, totalUpdated, totalDeleted int, dryRun bool) {
 	fmt.Println("\n" + strings.Repeat("=", 70))
-	fmt.Println("DNS INCREMENTAL SYNC SUMMARY")
+	if dryRun {
+		fmt.Println("DNS INCREMENTAL SYNC SUMMARY [DRY-RUN]")
+	} else {
+		fmt.Println("DNS INCREMENTAL SYNC SUMMARY")
+	}
 	fmt.Println(strings.Repeat("=", 70))
 
 	successCount := 0
 	failureCount := 0
+	skippedCount := 0
+	degradedCount := 0
+	anomalyCount := 0
+	totalPushed := 0
 
 	for _, stat := range stats {
+		totalPushed += stat.Pushed
 		if stat.Error != "" {
 			fmt.Printf("%-20s ✗ FAILED\n", stat.Domain)
-			fmt.Printf("  Error: %s\n", stat.Error)
+			fmt.Printf("  Error: %s\n", logger.Redact(stat.Error))
 			failureCount++
+		} else if stat.Degraded() {
+			fmt.Printf("%-20s ! DEGRADED (+%d ~%d -%d, %d failed: %s)\n",
+				stat.Domain, stat.Added, stat.Updated, stat.Deleted, stat.Failed, formatFailures(stat.FailedByAction))
+			for _, sample := range stat.FailedSamples {
+				fmt.Printf("  Error: %s\n", logger.Redact(sample.String()))
+			}
+			if stat.Failed > len(stat.FailedSamples) {
+				fmt.Printf("  ... and %d more\n", stat.Failed-len(stat.FailedSamples))
+			}
+			degradedCount++
+		} else if stat.Skipped {
+			fmt.Printf("%-20s - SKIPPED (%s)\n", stat.Domain, stat.SkipReason)
+			skippedCount++
 		} else {
 			fmt.Printf("%-20s ✓ SUCCESS (+%d ~%d -%d)\n", 
 				stat.Domain, stat.Added, stat.Updated, stat.Deleted)
+			if stat.Pushed > 0 {
+				fmt.Printf("  Pushed to provider: %d\n", stat.Pushed)
+			}
+			if stat.Disabled > 0 {
+				fmt.Printf("  Disabled on provider: %d\n", stat.Disabled)
+			}
 			successCount++
 		}
+		if stat.Warning != "" {
+			fmt.Printf("  Warning: %s\n", stat.Warning)
+		}
+		if stat.DeleteAnomaly {
+			fmt.Printf("  Deletion anomaly: %d deleted, recent average %.1f\n", stat.Deleted, stat.DeleteAverage)
+			anomalyCount++
+		}
 	}
 
 	fmt.Println(strings.Repeat("-", 70))
 	fmt.Printf("Total domains processed: %d\n", len(stats))
 	fmt.Printf("Successful: %d\n", successCount)
 	fmt.Printf("Failed: %d\n", failureCount)
+	if degradedCount > 0 {
+		fmt.Printf("Degraded: %d\n", degradedCount)
+	}
+	if skippedCount > 0 {
+		fmt.Printf("Skipped: %d\n", skippedCount)
+	}
+	if anomalyCount > 0 {
+		fmt.Printf("Deletion anomalies: %d\n", anomalyCount)
+	}
 	fmt.Printf("Total changes: +%d ~%d -%d\n", totalAdded, totalUpdated, totalDeleted)
+	if totalPushed > 0 {
+		fmt.Printf("Total pushed to provider: %d\n", totalPushed)
+	}
 	fmt.Printf("Sync time: %s\n", time.Now().Format("2006-01-02 15:04:05"))
-	fmt.Println(strings.Repeat("=", 70))
-
-	// 如果有失败的同步，退出时返回错误代码
-	if failureCount > 0 {
-		os.Exit(1)
+	if dryRun {
+		fmt.Println("Dry run: no changes were written to MySQL")
 	}
+	fmt.Println(strings.Repeat("=", 70))
 }
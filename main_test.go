@@ -0,0 +1,2402 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"dns-sync/internal/aliyun"
+	"dns-sync/internal/config"
+	"dns-sync/internal/database"
+	"dns-sync/internal/file"
+	"dns-sync/internal/models"
+	"dns-sync/internal/provider"
+	"dns-sync/internal/tracing"
+)
+
+// fakeProvider 返回固定记录的服务商，每次调用返回记录的副本，同步过程对记录的修改不会影响下一次调用
+type fakeProvider struct {
+	records []*models.DNSRecord
+	err     error
+	calls   int
+	// domain 最近一次查询的域名
+	domain string
+}
+
+func (p *fakeProvider) GetDomainRecords(ctx context.Context, domain string) ([]*models.DNSRecord, error) {
+	p.calls++
+	p.domain = domain
+	if p.err != nil {
+		return nil, p.err
+	}
+	records := make([]*models.DNSRecord, 0, len(p.records))
+	for _, record := range p.records {
+		clone := *record
+		records = append(records, &clone)
+	}
+	return records, nil
+}
+
+func (p *fakeProvider) TestConnection(ctx context.Context) error { return nil }
+
+func (p *fakeProvider) VerifyDomains(ctx context.Context, domains []string) error { return nil }
+
+// memStore 内存中的Store，rows以本地ID为键保存全部来源和域名的行，同步用不到的方法未实现，调用时panic
+type memStore struct {
+	database.Store
+
+	mu     sync.Mutex
+	rows   map[string]*models.AssetSubDomain
+	nextID int
+	// inserts、updates、deletes 写入的行数
+	inserts, updates, deletes int
+	// rebuildErr 不为nil时RebuildDomainTx在写入任何行之前返回该错误，模拟事务回滚
+	rebuildErr error
+	// lockErr AcquireRunLock返回的错误
+	lockErr error
+	// softDelete 为true时删除只将行标记为DELETED，与开启soft_delete的数据库一致
+	softDelete bool
+	// watermarks 按来源和domain_id保存的增量水位
+	watermarks map[string]int64
+	// deleteErrs 按aliyun_record_id指定DeleteRecords中删除失败的记录
+	deleteErrs map[string]error
+	// postSyncTotals ExecPostSync收到的合计，postSyncErr为其返回的错误
+	postSyncTotals []database.PostSyncTotals
+	postSyncErr    error
+	// metrics 按domain_id保存的每次同步的删除数，从旧到新，对应sync_metrics
+	metrics map[string][]int
+	// resolution SaveResolutionStatus写入的结果，以本地ID为键
+	resolution map[string]string
+}
+
+func newMemStore(rows ...*models.AssetSubDomain) *memStore {
+	s := &memStore{rows: make(map[string]*models.AssetSubDomain)}
+	for _, row := range rows {
+		s.put(row)
+	}
+	return s
+}
+
+// put 保存行的副本，没有ID时分配一个
+func (s *memStore) put(row *models.AssetSubDomain) {
+	if row.ID == "" {
+		s.nextID++
+		row.ID = fmt.Sprintf("auto-%d", s.nextID)
+	}
+	clone := *row
+	s.rows[row.ID] = &clone
+}
+
+// find 按(source, domain_id, aliyun_record_id)查找行，与真实表的唯一索引一致
+func (s *memStore) find(source, domainID, recordID string) *models.AssetSubDomain {
+	for _, row := range s.rows {
+		if row.Source == source && row.DomainID == domainID && row.AliyunRecordID != nil && *row.AliyunRecordID == recordID {
+			return row
+		}
+	}
+	return nil
+}
+
+// recordIDs 返回某来源和域名下全部行的aliyun_record_id，按字典序排列
+func (s *memStore) recordIDs(domainID, source string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	for _, row := range s.rows {
+		if row.DomainID == domainID && row.Source == source && row.AliyunRecordID != nil {
+			ids = append(ids, *row.AliyunRecordID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (s *memStore) GetLocalRecords(ctx context.Context, domainID, source string) (map[string]*models.AssetSubDomain, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := make(map[string]*models.AssetSubDomain)
+	for _, row := range s.rows {
+		if row.DomainID == domainID && row.Source == source && row.AliyunRecordID != nil && row.Status != "DELETED" {
+			clone := *row
+			records[*row.AliyunRecordID] = &clone
+		}
+	}
+	return records, nil
+}
+
+func (s *memStore) GetSoftDeletedRecords(ctx context.Context, domainID, source string) (map[string]*models.AssetSubDomain, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := make(map[string]*models.AssetSubDomain)
+	for _, row := range s.rows {
+		if row.DomainID == domainID && row.Source == source && row.AliyunRecordID != nil && row.Status == "DELETED" {
+			clone := *row
+			records[*row.AliyunRecordID] = &clone
+		}
+	}
+	return records, nil
+}
+
+func (s *memStore) GetPendingPushRecords(ctx context.Context, domainID, source string) ([]*models.AssetSubDomain, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var records []*models.AssetSubDomain
+	for _, row := range s.rows {
+		if row.DomainID == domainID && row.Source == source && row.AliyunRecordID == nil {
+			clone := *row
+			records = append(records, &clone)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records, nil
+}
+
+func (s *memStore) MarkRecordPushed(ctx context.Context, localID, recordID, source string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	row, ok := s.rows[localID]
+	if !ok {
+		return fmt.Errorf("record %s not found", localID)
+	}
+	row.AliyunRecordID, row.Source = &recordID, source
+	return nil
+}
+
+func (s *memStore) GetWatermark(ctx context.Context, domainID, source string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.watermarks[source+"/"+domainID], nil
+}
+
+func (s *memStore) SaveWatermark(ctx context.Context, domainID, source string, watermark int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.watermarks == nil {
+		s.watermarks = make(map[string]int64)
+	}
+	s.watermarks[source+"/"+domainID] = watermark
+	return nil
+}
+
+func (s *memStore) ExecPostSync(ctx context.Context, statements []string, totals database.PostSyncTotals) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.postSyncTotals = append(s.postSyncTotals, totals)
+	return s.postSyncErr
+}
+
+func (s *memStore) SaveResolutionStatus(ctx context.Context, statuses map[string][]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.resolution == nil {
+		s.resolution = make(map[string]string)
+	}
+	for status, ids := range statuses {
+		for _, id := range ids {
+			s.resolution[id] = status
+		}
+	}
+	return nil
+}
+
+// GetRecentDeletes 与数据库实现一致，从新到旧返回最近runs次的删除数
+func (s *memStore) GetRecentDeletes(ctx context.Context, domainID string, runs int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := s.metrics[domainID]
+	var recent []int
+	for i := len(history) - 1; i >= 0 && len(recent) < runs; i-- {
+		recent = append(recent, history[i])
+	}
+	return recent, nil
+}
+
+func (s *memStore) SaveSyncMetrics(ctx context.Context, domainID string, added, updated, deleted int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.metrics == nil {
+		s.metrics = make(map[string][]int)
+	}
+	s.metrics[domainID] = append(s.metrics[domainID], deleted)
+	return nil
+}
+
+func (s *memStore) AcquireRunLock(ctx context.Context, name string) (*database.RunLock, error) {
+	return nil, s.lockErr
+}
+
+// upsert 与批量upsert相同：冲突键已存在时只覆盖同步维护的列，保留ID和人工维护的资产信息
+func (s *memStore) upsert(record *models.AssetSubDomain) {
+	existing := record
+	if record.AliyunRecordID != nil {
+		if row := s.find(record.Source, record.DomainID, *record.AliyunRecordID); row != nil {
+			existing = row
+		}
+	}
+	if existing == record {
+		s.inserts++
+		s.put(record)
+		return
+	}
+	s.updates++
+	existing.SubDomain, existing.Type, existing.DNSRecord, existing.TTL = record.SubDomain, record.Type, record.DNSRecord, record.TTL
+	existing.Line, existing.ContentHash, existing.Status = record.Line, record.ContentHash, record.Status
+}
+
+// remove 删除一行，软删除模式下只标记为DELETED
+func (s *memStore) remove(localID string) {
+	s.deletes++
+	if row, ok := s.rows[localID]; ok && s.softDelete {
+		row.Status = "DELETED"
+		return
+	}
+	delete(s.rows, localID)
+}
+
+// update 与UpdateRecord相同，可以改写aliyun_record_id
+func (s *memStore) update(localID string, aliyunRecord *models.DNSRecord) error {
+	row, ok := s.rows[localID]
+	if !ok {
+		return fmt.Errorf("record %s not found", localID)
+	}
+	s.updates++
+	record := aliyunRecord.ConvertToAssetSubDomain(row.DomainID, row.ProjectID, row.Source)
+	row.AliyunRecordID, row.SubDomain, row.Type, row.DNSRecord = record.AliyunRecordID, record.SubDomain, record.Type, record.DNSRecord
+	row.TTL, row.Line, row.ContentHash, row.Status = record.TTL, record.Line, record.ContentHash, record.Status
+	return nil
+}
+
+func (s *memStore) BatchUpsert(ctx context.Context, records []*models.AssetSubDomain, batchSize int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, record := range records {
+		s.upsert(record)
+	}
+	return len(records), nil
+}
+
+func (s *memStore) UpdateRecord(ctx context.Context, localID string, aliyunRecord *models.DNSRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.update(localID, aliyunRecord)
+}
+
+func (s *memStore) DeleteRecords(ctx context.Context, ids []string, batchSize int) map[string]error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failed := make(map[string]error)
+	for _, id := range ids {
+		if row := s.rows[id]; row != nil && row.AliyunRecordID != nil && s.deleteErrs[*row.AliyunRecordID] != nil {
+			failed[id] = s.deleteErrs[*row.AliyunRecordID]
+			continue
+		}
+		s.remove(id)
+	}
+	return failed
+}
+
+func (s *memStore) SyncDomainTx(ctx context.Context, changes *database.SyncChanges, stopOnError bool) (*database.SyncResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, record := range changes.Inserts {
+		s.upsert(record)
+	}
+	for _, update := range changes.Updates {
+		if err := s.update(update.LocalRecord.ID, update.AliyunRecord); err != nil {
+			return nil, err
+		}
+	}
+	for _, record := range changes.Deletes {
+		s.remove(record.ID)
+	}
+	return &database.SyncResult{Added: len(changes.Inserts), Updated: len(changes.Updates), Deleted: len(changes.Deletes)}, nil
+}
+
+func (s *memStore) RebuildDomainTx(ctx context.Context, domainID, source string,
+	records []*models.AssetSubDomain) (*database.SyncResult, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rebuildErr != nil {
+		return nil, s.rebuildErr
+	}
+	result := &database.SyncResult{}
+	for id, row := range s.rows {
+		if row.DomainID == domainID && row.Source == source {
+			delete(s.rows, id)
+			result.Deleted++
+		}
+	}
+	for _, record := range records {
+		s.put(record)
+		result.Added++
+	}
+	return result, nil
+}
+
+// testDomain 同步A和CNAME记录、来源为Aliyun-DNS-Sync的测试域名
+func testDomain() config.DomainMapping {
+	return config.DomainMapping{
+		Domain:      "example.com",
+		DomainID:    "domain-1",
+		ProjectID:   "project-1",
+		Provider:    "aliyun",
+		Source:      "Aliyun-DNS-Sync",
+		RecordTypes: []string{"A", "CNAME"},
+		Lines:       []string{"default"},
+	}
+}
+
+// testSyncConfig 与默认配置相同的同步配置
+func testSyncConfig() config.SyncConfig {
+	return config.SyncConfig{
+		MaxDeletePercent:    100,
+		MaxDeletePercentMin: 5,
+		MatchBy:             "record_id",
+		LockedRecords:       "sync",
+	}
+}
+
+// testRecord 生成服务商上的一条记录
+func testRecord(recordID, rr, recordType, value string) *models.DNSRecord {
+	return &models.DNSRecord{
+		DomainName: "example.com",
+		RecordId:   recordID,
+		RR:         rr,
+		Type:       recordType,
+		Value:      value,
+		TTL:        600,
+		Line:       "default",
+		Status:     "ENABLE",
+	}
+}
+
+// testRow 生成已同步到本地的一行，与同步写入的行一样带有线路名称
+func testRow(domainMapping config.DomainMapping, record *models.DNSRecord) *models.AssetSubDomain {
+	clone := *record
+	labelLine(&clone, nil)
+	return clone.ConvertToAssetSubDomain(domainMapping.DomainID, domainMapping.ProjectID, domainMapping.Source)
+}
+
+// testRecords 生成n条A记录，RecordId从1000开始
+func testRecords(n int) []*models.DNSRecord {
+	records := make([]*models.DNSRecord, 0, n)
+	for i := 0; i < n; i++ {
+		records = append(records, testRecord(fmt.Sprintf("%d", 1000+i), fmt.Sprintf("host%d", i), "A",
+			fmt.Sprintf("10.0.0.%d", i)))
+	}
+	return records
+}
+
+// syncedStore 保存records同步到本地后的行
+func syncedStore(domainMapping config.DomainMapping, records []*models.DNSRecord) *memStore {
+	store := newMemStore()
+	for _, record := range records {
+		store.put(testRow(domainMapping, record))
+	}
+	return store
+}
+
+// testDeletes 生成n条待删除的本地记录
+func testDeletes(n int) []*models.AssetSubDomain {
+	deletes := make([]*models.AssetSubDomain, 0, n)
+	for i := 0; i < n; i++ {
+		recordID := fmt.Sprintf("%d", 1000+i)
+		deletes = append(deletes, &models.AssetSubDomain{ID: fmt.Sprintf("id-%d", i), AliyunRecordID: &recordID})
+	}
+	return deletes
+}
+
+func TestCheckDeleteThreshold(t *testing.T) {
+	defaults := config.SyncConfig{MaxDeletePercent: 20, MaxDeletePercentMin: 5}
+
+	tests := []struct {
+		name       string
+		deletes    int
+		localCount int
+		syncCfg    config.SyncConfig
+		wantErr    bool
+	}{
+		{name: "no deletes", deletes: 0, localCount: 10, syncCfg: defaults},
+		{name: "one of four under minimum count", deletes: 1, localCount: 4, syncCfg: defaults},
+		{name: "all of five under minimum count", deletes: 5, localCount: 5, syncCfg: defaults},
+		{name: "under percent", deletes: 10, localCount: 100, syncCfg: defaults},
+		{name: "exactly at percent", deletes: 20, localCount: 100, syncCfg: defaults},
+		{name: "over percent", deletes: 21, localCount: 100, syncCfg: defaults, wantErr: true},
+		{name: "over minimum count and percent", deletes: 6, localCount: 10, syncCfg: defaults, wantErr: true},
+		{name: "negative minimum always checks percent", deletes: 1, localCount: 4,
+			syncCfg: config.SyncConfig{MaxDeletePercent: 20, MaxDeletePercentMin: -1}, wantErr: true},
+		{name: "max count applies under minimum count", deletes: 3, localCount: 100,
+			syncCfg: config.SyncConfig{MaxDeleteCount: 2, MaxDeletePercent: 20, MaxDeletePercentMin: 5}, wantErr: true},
+		{name: "at max count", deletes: 2, localCount: 100,
+			syncCfg: config.SyncConfig{MaxDeleteCount: 2, MaxDeletePercent: 20, MaxDeletePercentMin: 5}},
+		{name: "percent disabled", deletes: 100, localCount: 100,
+			syncCfg: config.SyncConfig{MaxDeletePercent: 100, MaxDeletePercentMin: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDeleteThreshold(testDeletes(tt.deletes), tt.localCount, tt.syncCfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkDeleteThreshold() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errDeleteThreshold) {
+				t.Errorf("checkDeleteThreshold() error = %v, want errDeleteThreshold", err)
+			}
+		})
+	}
+}
+
+// TestIncrementalSyncDeleteThreshold 删除数在阈值内时照常删除，超出时放弃该域名且不写入任何变更
+func TestIncrementalSyncDeleteThreshold(t *testing.T) {
+	tests := []struct {
+		name        string
+		local       int
+		remote      int
+		transaction bool
+		wantErr     bool
+		wantRows    int
+	}{
+		{name: "under threshold proceeds", local: 10, remote: 9, wantRows: 9},
+		{name: "under threshold proceeds in transaction", local: 10, remote: 8, transaction: true, wantRows: 8},
+		{name: "over threshold aborts", local: 10, remote: 4, wantErr: true, wantRows: 10},
+		{name: "over threshold aborts in transaction", local: 10, remote: 4, transaction: true, wantErr: true, wantRows: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domainMapping := testDomain()
+			records := testRecords(tt.local)
+			store := syncedStore(domainMapping, records)
+			dnsClient := &fakeProvider{records: records[:tt.remote]}
+
+			syncCfg := testSyncConfig()
+			syncCfg.MaxDeletePercent = 20
+			syncCfg.Transaction = tt.transaction
+			stats := &SyncStats{Domain: domainMapping.Domain}
+
+			err := incrementalSyncDomain(context.Background(), dnsClient, store, domainMapping, syncCfg, stats)
+			if tt.wantErr {
+				if !errors.Is(err, errDeleteThreshold) {
+					t.Fatalf("incrementalSyncDomain() error = %v, want errDeleteThreshold", err)
+				}
+				if store.deletes != 0 {
+					t.Errorf("deleted %d rows after aborting", store.deletes)
+				}
+			} else if err != nil {
+				t.Fatalf("incrementalSyncDomain() error = %v", err)
+			}
+			if got := len(store.rows); got != tt.wantRows {
+				t.Errorf("got %d rows, want %d", got, tt.wantRows)
+			}
+			if !tt.wantErr && stats.Deleted != tt.local-tt.remote {
+				t.Errorf("stats.Deleted = %d, want %d", stats.Deleted, tt.local-tt.remote)
+			}
+		})
+	}
+}
+
+// TestIncrementalSyncRecordIDSwap 服务商重建记录分配了新的RecordId：name_type下更新原有行并保留资产信息，
+// record_id下删除原有行再插入新行
+func TestIncrementalSyncRecordIDSwap(t *testing.T) {
+	tests := []struct {
+		name        string
+		matchBy     string
+		transaction bool
+		// wantKept 本地ID保留下来并关联到新RecordId的行，为空表示原有行被删除
+		wantKept map[string]string
+	}{
+		{name: "name_type relinks", matchBy: "name_type", wantKept: map[string]string{"id-www-1": "2001", "id-www-2": "2000"}},
+		{name: "name_type relinks in transaction", matchBy: "name_type", transaction: true,
+			wantKept: map[string]string{"id-www-1": "2001", "id-www-2": "2000"}},
+		{name: "record_id recreates", matchBy: "record_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domainMapping := testDomain()
+			store := newMemStore()
+			for id, record := range map[string]*models.DNSRecord{
+				"id-www-1": testRecord("1000", "www", "A", "10.0.0.1"),
+				"id-www-2": testRecord("1001", "www", "A", "10.0.0.2"),
+				"id-api":   testRecord("1002", "api", "CNAME", "lb.example.net"),
+			} {
+				row := testRow(domainMapping, record)
+				row.ID, row.AssetLabel = id, "managed"
+				store.put(row)
+			}
+			// 同名同类型的两条记录都分配了新的RecordId，按记录值配对
+			dnsClient := &fakeProvider{records: []*models.DNSRecord{
+				testRecord("2000", "www", "A", "10.0.0.2"),
+				testRecord("2001", "www", "A", "10.0.0.1"),
+				testRecord("1002", "api", "CNAME", "lb.example.net"),
+			}}
+
+			syncCfg := testSyncConfig()
+			syncCfg.MatchBy = tt.matchBy
+			syncCfg.Transaction = tt.transaction
+			stats := &SyncStats{Domain: domainMapping.Domain}
+			if err := incrementalSyncDomain(context.Background(), dnsClient, store, domainMapping, syncCfg, stats); err != nil {
+				t.Fatalf("incrementalSyncDomain() error = %v", err)
+			}
+
+			if got, want := store.recordIDs(domainMapping.DomainID, domainMapping.Source), []string{"1002", "2000", "2001"}; fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Fatalf("record ids = %v, want %v", got, want)
+			}
+			if tt.wantKept == nil {
+				if stats.Added != 2 || stats.Deleted != 2 {
+					t.Errorf("added %d, deleted %d, want 2 and 2", stats.Added, stats.Deleted)
+				}
+				return
+			}
+			if stats.Added != 0 || stats.Updated != 2 || stats.Deleted != 0 {
+				t.Errorf("added %d, updated %d, deleted %d, want 0, 2, 0", stats.Added, stats.Updated, stats.Deleted)
+			}
+			for id, recordID := range tt.wantKept {
+				row := store.rows[id]
+				if row == nil {
+					t.Errorf("row %s was deleted", id)
+					continue
+				}
+				if *row.AliyunRecordID != recordID || row.AssetLabel != "managed" {
+					t.Errorf("row %s: record id %s, asset label %q, want %s and managed", id, *row.AliyunRecordID,
+						row.AssetLabel, recordID)
+				}
+			}
+		})
+	}
+}
+
+// TestIncrementalSyncNameFilters include/exclude过滤掉的记录既不写入也不删除，本地已有的行保持原样
+func TestIncrementalSyncNameFilters(t *testing.T) {
+	local := []*models.DNSRecord{
+		testRecord("1000", "www", "A", "10.0.0.1"),
+		testRecord("1001", "api.v1", "A", "10.0.0.2"),
+		testRecord("1002", "api.v2", "A", "10.0.0.3"),
+		testRecord("1003", "internal-1", "A", "10.0.0.4"),
+		// 服务商上已删除
+		testRecord("1004", "internal-2", "A", "10.0.0.5"),
+	}
+	remote := []*models.DNSRecord{
+		testRecord("1000", "www", "A", "10.0.1.1"),
+		testRecord("1001", "api.v1", "A", "10.0.0.2"),
+		testRecord("1002", "api.v2", "A", "10.0.1.3"),
+		testRecord("1003", "internal-1", "A", "10.0.1.4"),
+		testRecord("1005", "api.v3", "A", "10.0.0.6"),
+		testRecord("1006", "internal-3", "A", "10.0.0.7"),
+	}
+
+	tests := []struct {
+		name        string
+		include     []string
+		exclude     []string
+		wantIDs     []string
+		wantAdded   int
+		wantUpdated int
+		wantDeleted int
+		// unchanged 被过滤掉、值应保持本地原值的RecordId
+		unchanged []string
+	}{
+		{
+			name:        "include only",
+			include:     []string{"api.*.example.com", "www.example.com"},
+			wantIDs:     []string{"1000", "1001", "1002", "1003", "1004", "1005"},
+			wantAdded:   1,
+			wantUpdated: 2,
+			unchanged:   []string{"1003", "1004"},
+		},
+		{
+			name:        "exclude only",
+			exclude:     []string{"api.v2.example.com"},
+			wantIDs:     []string{"1000", "1001", "1002", "1003", "1005", "1006"},
+			wantAdded:   2,
+			wantUpdated: 2,
+			wantDeleted: 1,
+			unchanged:   []string{"1002"},
+		},
+		{
+			name:        "include and exclude",
+			include:     []string{"api.*.example.com", "www.example.com"},
+			exclude:     []string{"api.v2.example.com"},
+			wantIDs:     []string{"1000", "1001", "1002", "1003", "1004", "1005"},
+			wantAdded:   1,
+			wantUpdated: 1,
+			unchanged:   []string{"1002", "1003", "1004"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domainMapping := testDomain()
+			domainMapping.Include, domainMapping.Exclude = tt.include, tt.exclude
+			store := syncedStore(domainMapping, local)
+			before, _ := store.GetLocalRecords(context.Background(), domainMapping.DomainID, domainMapping.Source)
+
+			stats := &SyncStats{Domain: domainMapping.Domain}
+			err := incrementalSyncDomain(context.Background(), &fakeProvider{records: remote}, store, domainMapping,
+				testSyncConfig(), stats)
+			if err != nil {
+				t.Fatalf("incrementalSyncDomain() error = %v", err)
+			}
+
+			if got := store.recordIDs(domainMapping.DomainID, domainMapping.Source); fmt.Sprint(got) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("record ids = %v, want %v", got, tt.wantIDs)
+			}
+			if stats.Added != tt.wantAdded || stats.Updated != tt.wantUpdated || stats.Deleted != tt.wantDeleted {
+				t.Errorf("added %d, updated %d, deleted %d, want %d, %d, %d", stats.Added, stats.Updated, stats.Deleted,
+					tt.wantAdded, tt.wantUpdated, tt.wantDeleted)
+			}
+			after, _ := store.GetLocalRecords(context.Background(), domainMapping.DomainID, domainMapping.Source)
+			for _, recordID := range tt.unchanged {
+				if after[recordID] == nil || *after[recordID].DNSRecord != *before[recordID].DNSRecord {
+					t.Errorf("filtered record %s was modified or deleted", recordID)
+				}
+			}
+		})
+	}
+}
+
+// TestIncrementalSyncKeepsOtherSources 同一domain_id下其它来源的行不参与对比，阿里云同步不会删除或覆盖Cloudflare同步的行
+func TestIncrementalSyncKeepsOtherSources(t *testing.T) {
+	for _, transaction := range []bool{false, true} {
+		t.Run(fmt.Sprintf("transaction=%v", transaction), func(t *testing.T) {
+			aliyun := testDomain()
+			cloudflare := testDomain()
+			cloudflare.Provider, cloudflare.Source = "cloudflare", "Cloudflare-DNS-Sync"
+
+			store := syncedStore(aliyun, []*models.DNSRecord{
+				testRecord("1000", "www", "A", "10.0.0.1"),
+				testRecord("1001", "api", "A", "10.0.0.2"),
+			})
+			// Cloudflare的记录ID与阿里云新增的记录ID相同，验证冲突键包含来源
+			for _, record := range []*models.DNSRecord{
+				testRecord("cf-1", "cdn", "CNAME", "cdn.example.net"),
+				testRecord("1002", "blog", "A", "10.0.1.1"),
+			} {
+				store.put(testRow(cloudflare, record))
+			}
+
+			syncCfg := testSyncConfig()
+			syncCfg.Transaction = transaction
+			stats := &SyncStats{Domain: aliyun.Domain}
+			dnsClient := &fakeProvider{records: []*models.DNSRecord{
+				testRecord("1000", "www", "A", "10.0.0.1"),
+				testRecord("1002", "mail", "A", "10.0.0.3"),
+			}}
+			if err := incrementalSyncDomain(context.Background(), dnsClient, store, aliyun, syncCfg, stats); err != nil {
+				t.Fatalf("incrementalSyncDomain() error = %v", err)
+			}
+
+			if got, want := store.recordIDs(aliyun.DomainID, aliyun.Source), []string{"1000", "1002"}; fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("aliyun record ids = %v, want %v", got, want)
+			}
+			cloudflareRows, _ := store.GetLocalRecords(context.Background(), cloudflare.DomainID, cloudflare.Source)
+			if len(cloudflareRows) != 2 {
+				t.Fatalf("got %d cloudflare rows, want 2", len(cloudflareRows))
+			}
+			if row := cloudflareRows["1002"]; row == nil || row.SubDomain != "blog.example.com" {
+				t.Errorf("cloudflare row 1002 = %+v, want blog.example.com", row)
+			}
+		})
+	}
+}
+
+// TestIncrementalSyncEmptyResult 服务商返回空结果时不删除本地记录并给出警告，allow_empty开启后照常删除
+func TestIncrementalSyncEmptyResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		local       int
+		remote      int
+		allowEmpty  bool
+		wantRows    int
+		wantWarning bool
+	}{
+		{name: "empty result skips deletes", local: 3, remote: 0, wantRows: 3, wantWarning: true},
+		{name: "allow_empty deletes", local: 3, remote: 0, allowEmpty: true, wantRows: 0},
+		{name: "non-empty result deletes", local: 3, remote: 1, wantRows: 1},
+		{name: "empty domain", local: 0, remote: 0, wantRows: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domainMapping := testDomain()
+			records := testRecords(tt.local)
+			store := syncedStore(domainMapping, records)
+
+			syncCfg := testSyncConfig()
+			syncCfg.AllowEmpty = tt.allowEmpty
+			stats := &SyncStats{Domain: domainMapping.Domain}
+			err := incrementalSyncDomain(context.Background(), &fakeProvider{records: records[:tt.remote]}, store,
+				domainMapping, syncCfg, stats)
+			if err != nil {
+				t.Fatalf("incrementalSyncDomain() error = %v", err)
+			}
+
+			if got := len(store.rows); got != tt.wantRows {
+				t.Errorf("got %d rows, want %d", got, tt.wantRows)
+			}
+			if got := stats.Warning != ""; got != tt.wantWarning {
+				t.Errorf("stats.Warning = %q, want warning = %v", stats.Warning, tt.wantWarning)
+			}
+		})
+	}
+}
+
+// TestIncrementalSyncPrune prune只删除服务商上已不存在的记录，不新增也不更新，删除阈值同样生效；
+// defer_deletes开启后常规同步不删除
+func TestIncrementalSyncPrune(t *testing.T) {
+	local := testRecords(10)
+	remote := append([]*models.DNSRecord{}, local[:9]...)
+	remote[0] = testRecord(local[0].RecordId, local[0].RR, "A", "10.0.1.1")
+	remote = append(remote, testRecord("2000", "new", "A", "10.0.2.1"))
+
+	tests := []struct {
+		name           string
+		pruneOnly      bool
+		deferDeletes   bool
+		remote         []*models.DNSRecord
+		maxDeleteCount int
+		wantErr        bool
+		wantInserts    int
+		wantUpdates    int
+		wantDeletes    int
+	}{
+		{name: "prune only deletes", pruneOnly: true, remote: remote, wantDeletes: 1},
+		{name: "prune applies the threshold", pruneOnly: true, remote: remote[:5], maxDeleteCount: 3, wantErr: true},
+		{name: "deferred deletes", deferDeletes: true, remote: remote, wantInserts: 1, wantUpdates: 1},
+		{name: "regular sync", remote: remote, wantInserts: 1, wantUpdates: 1, wantDeletes: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domainMapping := testDomain()
+			store := syncedStore(domainMapping, local)
+
+			syncCfg := testSyncConfig()
+			syncCfg.PruneOnly, syncCfg.DeferDeletes = tt.pruneOnly, tt.deferDeletes
+			syncCfg.MaxDeleteCount = tt.maxDeleteCount
+			stats := &SyncStats{Domain: domainMapping.Domain}
+			err := incrementalSyncDomain(context.Background(), &fakeProvider{records: tt.remote}, store, domainMapping,
+				syncCfg, stats)
+			if tt.wantErr {
+				if !errors.Is(err, errDeleteThreshold) {
+					t.Fatalf("incrementalSyncDomain() error = %v, want errDeleteThreshold", err)
+				}
+			} else if err != nil {
+				t.Fatalf("incrementalSyncDomain() error = %v", err)
+			}
+
+			if store.inserts != tt.wantInserts || store.updates != tt.wantUpdates || store.deletes != tt.wantDeletes {
+				t.Errorf("wrote %d inserts, %d updates, %d deletes, want %d, %d, %d", store.inserts, store.updates,
+					store.deletes, tt.wantInserts, tt.wantUpdates, tt.wantDeletes)
+			}
+		})
+	}
+}
+
+// TestLockRun 运行锁被另一个实例持有时第二次运行以exitLocked退出，不开始同步
+func TestLockRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		lockErr  error
+		wantCode int
+	}{
+		{name: "acquired", wantCode: exitOK},
+		{name: "held by another instance", lockErr: database.ErrLockHeld, wantCode: exitLocked},
+		{name: "database error", lockErr: errors.New("connection refused"), wantCode: exitSetupError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemStore()
+			store.lockErr = tt.lockErr
+			cfg := &config.Config{Sync: config.SyncConfig{RunLock: true, LockName: "dns-sync:test"}}
+			if _, code := lockRun(context.Background(), cfg, store); code != tt.wantCode {
+				t.Errorf("lockRun() code = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+// TestComputeSyncChangesFromSnapshot 快照文件与服务商走相同的对比流程，得到预期的新增、更新和删除
+func TestComputeSyncChangesFromSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "example.com.yaml")
+	snapshot := `records:
+  - {record_id: "1000", rr: www, type: A, value: 10.0.0.1}
+  - {record_id: "1001", rr: api, type: A, value: 10.0.1.2}
+  - {rr: mail, type: A, value: 10.0.0.9}
+`
+	if err := os.WriteFile(path, []byte(snapshot), 0o600); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+
+	domainMapping := testDomain()
+	domainMapping.Provider, domainMapping.Source = "file", "File-DNS-Sync"
+	store := syncedStore(domainMapping, []*models.DNSRecord{
+		testRecord("1000", "www", "A", "10.0.0.1"),
+		testRecord("1001", "api", "A", "10.0.0.2"),
+		testRecord("1002", "old", "A", "10.0.0.3"),
+	})
+
+	stats := &SyncStats{Domain: domainMapping.Domain}
+	changes, localCount, err := computeSyncChanges(context.Background(), file.NewDNSClient(map[string]string{
+		"example.com": path,
+	}), store, domainMapping, testSyncConfig(), stats)
+	if err != nil {
+		t.Fatalf("computeSyncChanges() error = %v", err)
+	}
+
+	if localCount != 3 || stats.RecordCount != 3 {
+		t.Errorf("local count %d, record count %d, want 3 and 3", localCount, stats.RecordCount)
+	}
+	if len(changes.Inserts) != 1 || changes.Inserts[0].SubDomain != "mail.example.com" {
+		t.Errorf("inserts = %+v, want mail.example.com", changes.Inserts)
+	}
+	if len(changes.Updates) != 1 || changes.Updates[0].AliyunRecord.RecordId != "1001" ||
+		changes.Updates[0].AliyunRecord.Value != "10.0.1.2" {
+		t.Errorf("updates = %+v, want 1001 to 10.0.1.2", changes.Updates)
+	}
+	if len(changes.Deletes) != 1 || *changes.Deletes[0].AliyunRecordID != "1002" {
+		t.Errorf("deletes = %+v, want 1002", changes.Deletes)
+	}
+}
+
+// cancellingProvider 第一次拉取记录时取消同步，模拟运行中收到退出信号
+type cancellingProvider struct {
+	fakeProvider
+	cancel context.CancelFunc
+}
+
+func (p *cancellingProvider) GetDomainRecords(ctx context.Context, domain string) ([]*models.DNSRecord, error) {
+	p.calls++
+	p.cancel()
+	return nil, ctx.Err()
+}
+
+// TestSyncDomainsCancelled 同步被取消后正在同步的域名返回错误，剩余域名不再请求服务商，也不写入数据库
+func TestSyncDomainsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := &config.Config{Sync: testSyncConfig()}
+	cfg.Sync.Concurrency = 1
+	cfg.Sync.Direction = "pull"
+	for _, name := range []string{"example.com", "example.net", "example.org"} {
+		domainMapping := testDomain()
+		domainMapping.Domain, domainMapping.DomainID = name, "id-"+name
+		cfg.Domains = append(cfg.Domains, domainMapping)
+	}
+	dnsClient := &cancellingProvider{cancel: cancel}
+	store := newMemStore()
+
+	stats := syncDomains(ctx, cfg, map[string]provider.DNSProvider{testDomain().ProviderKey(): dnsClient}, store)
+	if dnsClient.calls != 1 {
+		t.Errorf("provider called %d times after cancel, want 1", dnsClient.calls)
+	}
+	if len(stats) != len(cfg.Domains) {
+		t.Fatalf("got %d results, want %d", len(stats), len(cfg.Domains))
+	}
+	for _, s := range stats {
+		if s.Error == "" {
+			t.Errorf("domain %s succeeded after cancel", s.Domain)
+		}
+	}
+	if store.inserts+store.updates+store.deletes != 0 {
+		t.Errorf("store written after cancel: %d inserts, %d updates, %d deletes", store.inserts, store.updates, store.deletes)
+	}
+	if got := syncExitCode(stats); got == exitOK {
+		t.Errorf("syncExitCode() = %d for a cancelled sync, want failure", got)
+	}
+}
+
+// TestIncrementalSyncSoftDeleteCycle 软删除模式下服务商删除记录后只标记本地行，记录重新出现时恢复原有的行而不是重复插入
+func TestIncrementalSyncSoftDeleteCycle(t *testing.T) {
+	for _, transaction := range []bool{false, true} {
+		t.Run(fmt.Sprintf("transaction=%v", transaction), func(t *testing.T) {
+			domainMapping := testDomain()
+			records := testRecords(3)
+			store := syncedStore(domainMapping, records)
+			store.softDelete = true
+			localID := store.find(domainMapping.Source, domainMapping.DomainID, "1001").ID
+
+			syncCfg := testSyncConfig()
+			syncCfg.Transaction = transaction
+
+			// remote 每一轮服务商上的记录，wantStatus 每一轮之后1001对应行的状态
+			rounds := []struct {
+				remote     []*models.DNSRecord
+				wantStatus string
+				wantStats  [3]int
+			}{
+				{remote: []*models.DNSRecord{records[0], records[2]}, wantStatus: "DELETED", wantStats: [3]int{0, 0, 1}},
+				{remote: records, wantStatus: "ACTIVE", wantStats: [3]int{0, 1, 0}},
+				{remote: []*models.DNSRecord{records[0], records[2]}, wantStatus: "DELETED", wantStats: [3]int{0, 0, 1}},
+				{remote: records, wantStatus: "ACTIVE", wantStats: [3]int{0, 1, 0}},
+			}
+			for i, round := range rounds {
+				stats := &SyncStats{Domain: domainMapping.Domain}
+				err := incrementalSyncDomain(context.Background(), &fakeProvider{records: round.remote}, store,
+					domainMapping, syncCfg, stats)
+				if err != nil {
+					t.Fatalf("round %d: incrementalSyncDomain() error = %v", i, err)
+				}
+				if got := [3]int{stats.Added, stats.Updated, stats.Deleted}; got != round.wantStats {
+					t.Errorf("round %d: added/updated/deleted = %v, want %v", i, got, round.wantStats)
+				}
+				row := store.rows[localID]
+				if row == nil {
+					t.Fatalf("round %d: row %s was removed", i, localID)
+				}
+				if row.Status != round.wantStatus {
+					t.Errorf("round %d: status = %q, want %q", i, row.Status, round.wantStatus)
+				}
+			}
+			if store.inserts != 0 || len(store.rows) != 3 {
+				t.Errorf("got %d inserts and %d rows, want 0 and 3", store.inserts, len(store.rows))
+			}
+		})
+	}
+}
+
+// zoneProvider 按查询的域名返回不同记录的服务商，只读，可以被多个worker同时调用
+type zoneProvider struct {
+	fakeProvider
+	zones map[string]int
+	fail  map[string]bool
+}
+
+func (p *zoneProvider) GetDomainRecords(ctx context.Context, domain string) ([]*models.DNSRecord, error) {
+	if p.fail[domain] {
+		return nil, errors.New("request failed")
+	}
+	records := testRecords(p.zones[domain])
+	for _, record := range records {
+		record.DomainName = domain
+	}
+	return records, nil
+}
+
+// TestSyncDomainsConcurrent 多个worker并发同步时每个域名的统计互不干扰，结果按域名排序，汇总与逐个相加一致
+func TestSyncDomainsConcurrent(t *testing.T) {
+	cfg := &config.Config{Sync: testSyncConfig()}
+	cfg.Sync.Concurrency = 4
+	cfg.Sync.Direction = "pull"
+	dnsClient := &zoneProvider{zones: map[string]int{}, fail: map[string]bool{}}
+	wantAdded := 0
+	for i := 20; i > 0; i-- {
+		domainMapping := testDomain()
+		domainMapping.Domain = fmt.Sprintf("example%02d.com", i)
+		domainMapping.DomainID = fmt.Sprintf("domain-%d", i)
+		cfg.Domains = append(cfg.Domains, domainMapping)
+		if i%5 == 0 {
+			dnsClient.fail[domainMapping.Domain] = true
+			continue
+		}
+		dnsClient.zones[domainMapping.Domain] = i
+		wantAdded += i
+	}
+	store := newMemStore()
+
+	providers := map[string]provider.DNSProvider{testDomain().ProviderKey(): dnsClient}
+	stats := syncDomains(context.Background(), cfg, providers, store)
+	if len(stats) != len(cfg.Domains) {
+		t.Fatalf("got %d results, want %d", len(stats), len(cfg.Domains))
+	}
+	for i, s := range stats {
+		if want := fmt.Sprintf("example%02d.com", i+1); s.Domain != want {
+			t.Fatalf("stats[%d].Domain = %s, want %s", i, s.Domain, want)
+		}
+		if dnsClient.fail[s.Domain] {
+			if s.Error == "" {
+				t.Errorf("domain %s: error is empty", s.Domain)
+			}
+			continue
+		}
+		if s.Error != "" || s.Added != i+1 || s.RecordCount != i+1 {
+			t.Errorf("domain %s: added = %d, records = %d, error = %q, want %d", s.Domain, s.Added, s.RecordCount, s.Error, i+1)
+		}
+	}
+
+	totals := buildSyncReport(stats, time.Now(), time.Now(), false).Totals
+	if totals.Domains != 20 || totals.Succeeded != 16 || totals.Failed != 4 || totals.Added != wantAdded {
+		t.Errorf("totals = %+v, want 16 succeeded, 4 failed, %d added", totals, wantAdded)
+	}
+	if store.inserts != wantAdded {
+		t.Errorf("store got %d inserts, want %d", store.inserts, wantAdded)
+	}
+}
+
+// TestWriteSyncReport 同步后写出的报告符合约定的JSON结构，字段名和类型供下游脚本解析
+func TestWriteSyncReport(t *testing.T) {
+	cfg := &config.Config{Sync: testSyncConfig()}
+	cfg.Sync.Concurrency = 2
+	cfg.Sync.Direction = "pull"
+	dnsClient := &zoneProvider{zones: map[string]int{"example.com": 3}, fail: map[string]bool{"example.net": true}}
+	for _, name := range []string{"example.com", "example.net"} {
+		domainMapping := testDomain()
+		domainMapping.Domain, domainMapping.DomainID = name, "id-"+name
+		cfg.Domains = append(cfg.Domains, domainMapping)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	providers := map[string]provider.DNSProvider{testDomain().ProviderKey(): dnsClient}
+	stats := syncDomains(context.Background(), cfg, providers, newMemStore())
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := writeSyncReport(path, buildSyncReport(stats, start, start.Add(time.Minute), false)); err != nil {
+		t.Fatalf("writeSyncReport() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report map[string]any
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+
+	// requireFields 检查对象包含指定类型的字段，kind为JSON解码后的Go类型名
+	requireFields := func(t *testing.T, name string, object map[string]any, fields map[string]string) {
+		t.Helper()
+		for field, kind := range fields {
+			value, ok := object[field]
+			if !ok {
+				t.Errorf("%s: missing field %q", name, field)
+				continue
+			}
+			if got := fmt.Sprintf("%T", value); got != kind {
+				t.Errorf("%s.%s is %s, want %s", name, field, got, kind)
+			}
+		}
+	}
+
+	requireFields(t, "report", report, map[string]string{"start_time": "string", "end_time": "string",
+		"dry_run": "bool", "totals": "map[string]interface {}", "domains": "[]interface {}"})
+	if report["start_time"] != "2024-01-01T00:00:00Z" {
+		t.Errorf("start_time = %v, want RFC 3339", report["start_time"])
+	}
+	totals, _ := report["totals"].(map[string]any)
+	requireFields(t, "totals", totals, map[string]string{"domains": "float64", "succeeded": "float64",
+		"failed": "float64", "skipped": "float64", "degraded": "float64", "added": "float64", "updated": "float64",
+		"deleted": "float64", "pushed": "float64"})
+	if totals["domains"] != 2.0 || totals["succeeded"] != 1.0 || totals["failed"] != 1.0 || totals["added"] != 3.0 {
+		t.Errorf("totals = %v", totals)
+	}
+
+	domains, _ := report["domains"].([]any)
+	if len(domains) != 2 {
+		t.Fatalf("got %d domains, want 2", len(domains))
+	}
+	for _, domain := range domains {
+		result, _ := domain.(map[string]any)
+		requireFields(t, "domain", result, map[string]string{"domain": "string", "success": "bool",
+			"record_count": "float64", "added": "float64", "updated": "float64", "deleted": "float64"})
+	}
+	if failed, _ := domains[1].(map[string]any); failed["domain"] != "example.net" || failed["error"] == nil {
+		t.Errorf("failed domain = %v, want example.net with an error", failed)
+	}
+}
+
+// pushProvider 支持写入记录的服务商，新增的记录会出现在之后拉取的结果中，RR为fail的记录写入失败
+type pushProvider struct {
+	fakeProvider
+	added []*models.DNSRecord
+}
+
+func (p *pushProvider) AddDomainRecord(ctx context.Context, record *models.DNSRecord) (string, error) {
+	if record.RR == "fail" {
+		return "", errors.New("InvalidRR.Format")
+	}
+	clone := *record
+	clone.RecordId = fmt.Sprintf("%d", 9000+len(p.added))
+	clone.Status = "ENABLE"
+	p.added = append(p.added, &clone)
+	p.records = append(p.records, &clone)
+	return clone.RecordId, nil
+}
+
+func (p *pushProvider) UpdateDomainRecord(ctx context.Context, record *models.DNSRecord) error {
+	return nil
+}
+
+func (p *pushProvider) DeleteDomainRecord(ctx context.Context, recordID string) error { return nil }
+
+// TestSyncDomainPush 双向同步先推送本地新增的记录并回写RecordId，随后的拉取匹配到回写的行，不会重复插入；
+// 推送失败的记录保持待推送状态，域名标记为部分失败
+func TestSyncDomainPush(t *testing.T) {
+	domainMapping := testDomain()
+	records := testRecords(2)
+	store := syncedStore(domainMapping, records)
+	for _, rr := range []string{"new", "fail"} {
+		subDomain, value := rr+".example.com", "10.0.1.1"
+		store.put(&models.AssetSubDomain{ID: "local-" + rr, DomainID: domainMapping.DomainID, SubDomain: subDomain,
+			Type: "A", DNSRecord: &value, TTL: 600, Line: "default", Source: "Manual-Push"})
+	}
+	dnsClient := &pushProvider{fakeProvider: fakeProvider{records: records}}
+
+	cfg := &config.Config{Sync: testSyncConfig()}
+	cfg.Sync.Direction = "both"
+	cfg.Sync.PushSource = "Manual-Push"
+	providers := map[string]provider.DNSProvider{domainMapping.ProviderKey(): dnsClient}
+	stats := syncDomain(context.Background(), cfg, providers, store, domainMapping)
+
+	if stats.Error != "" || stats.Pushed != 1 || stats.Failed != 1 || !stats.Degraded() {
+		t.Errorf("stats = %+v, want 1 pushed and 1 failed", stats)
+	}
+	if len(dnsClient.added) != 1 || dnsClient.added[0].RR != "new" || dnsClient.added[0].DomainName != "example.com" {
+		t.Fatalf("added records = %+v, want new.example.com", dnsClient.added)
+	}
+	pushed := store.rows["local-new"]
+	if pushed.AliyunRecordID == nil || *pushed.AliyunRecordID != "9000" || pushed.Source != domainMapping.Source {
+		t.Errorf("pushed row has record id %v and source %s, want 9000 and %s", pushed.AliyunRecordID, pushed.Source,
+			domainMapping.Source)
+	}
+	if stats.Added != 0 || store.inserts != 0 {
+		t.Errorf("pull inserted %d rows after push, want 0", store.inserts)
+	}
+	if failed := store.rows["local-fail"]; failed.AliyunRecordID != nil || failed.Source != "Manual-Push" {
+		t.Errorf("failed row = %+v, want it still pending", failed)
+	}
+	if got := store.recordIDs(domainMapping.DomainID, domainMapping.Source); len(got) != 3 {
+		t.Errorf("synced record ids = %v, want 3", got)
+	}
+}
+
+// TestRunLoop 使用立即触发的定时器模拟时钟，循环执行指定轮数后在取消时退出，每轮之间等待间隔加最多10%的抖动
+func TestRunLoop(t *testing.T) {
+	var waits []time.Duration
+	newTimer = func(d time.Duration) *time.Timer {
+		waits = append(waits, d)
+		return time.NewTimer(0)
+	}
+	t.Cleanup(func() { newTimer = time.NewTimer })
+
+	const rounds = 5
+	interval := time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runLoop(ctx, interval, func() {
+			runs++
+			if runs == rounds {
+				cancel()
+			}
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runLoop did not stop after cancel")
+	}
+
+	if runs != rounds {
+		t.Errorf("got %d runs, want %d", runs, rounds)
+	}
+	// 最后一轮中已取消，不再安排下一轮
+	if len(waits) != rounds-1 {
+		t.Fatalf("got %d waits, want %d", len(waits), rounds-1)
+	}
+	var elapsed time.Duration
+	for i, wait := range waits {
+		if wait < interval || wait > interval+interval/10 {
+			t.Errorf("wait %d = %s, want between %s and %s", i, wait, interval, interval+interval/10)
+		}
+		elapsed += wait
+	}
+	if limit := rounds * (interval + interval/10); elapsed > limit {
+		t.Errorf("%d rounds took %s of fake time, want at most %s", rounds, elapsed, limit)
+	}
+}
+
+// TestIncrementalSyncLines 同一主机记录在三条线路上各有一条记录，只同步配置的线路，其它线路的本地行不参与对比也不会被删除
+func TestIncrementalSyncLines(t *testing.T) {
+	lineRecord := func(recordID, line, value string) *models.DNSRecord {
+		record := testRecord(recordID, "www", "A", value)
+		record.Line = line
+		return record
+	}
+	remote := []*models.DNSRecord{
+		lineRecord("1000", "default", "10.0.0.1"),
+		lineRecord("1001", "telecom", "10.0.0.2"),
+		lineRecord("1002", "unicom", "10.0.0.3"),
+	}
+
+	tests := []struct {
+		name      string
+		lines     []string
+		wantAdded []string
+	}{
+		{name: "default only", lines: []string{"default"}, wantAdded: []string{"1000"}},
+		{name: "two lines", lines: []string{"default", "telecom"}, wantAdded: []string{"1000", "1001"}},
+		{name: "all lines", lines: []string{"default", "telecom", "unicom"}, wantAdded: []string{"1000", "1001", "1002"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domainMapping := testDomain()
+			domainMapping.Lines = tt.lines
+			// 之前按其它线路同步过的行，服务商上已删除
+			store := newMemStore(testRow(domainMapping, lineRecord("900", "mobile", "10.0.0.9")))
+			stats := &SyncStats{Domain: domainMapping.Domain}
+
+			err := incrementalSyncDomain(context.Background(), &fakeProvider{records: remote}, store, domainMapping,
+				testSyncConfig(), stats)
+			if err != nil {
+				t.Fatalf("incrementalSyncDomain() error = %v", err)
+			}
+			if stats.Added != len(tt.wantAdded) || stats.Deleted != 0 || stats.RecordCount != len(tt.wantAdded) {
+				t.Errorf("added = %d, deleted = %d, records = %d, want %d added", stats.Added, stats.Deleted,
+					stats.RecordCount, len(tt.wantAdded))
+			}
+			want := append([]string{"900"}, tt.wantAdded...)
+			sort.Strings(want)
+			if got := store.recordIDs(domainMapping.DomainID, domainMapping.Source); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("record ids = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestIncrementalSyncDisabledRecords 停用的记录保留并标记为DISABLED，不按删除处理，重新启用后恢复为ACTIVE
+func TestIncrementalSyncDisabledRecords(t *testing.T) {
+	domainMapping := testDomain()
+	records := testRecords(2)
+	store := syncedStore(domainMapping, records)
+	localID := store.find(domainMapping.Source, domainMapping.DomainID, "1000").ID
+
+	rounds := []struct {
+		status       string
+		wantStatus   string
+		wantUpdated  int
+		wantDisabled int
+	}{
+		{status: "ENABLE", wantStatus: models.StatusActive},
+		{status: "DISABLE", wantStatus: models.StatusDisabled, wantUpdated: 1, wantDisabled: 1},
+		{status: "DISABLE", wantStatus: models.StatusDisabled, wantDisabled: 1},
+		{status: "ENABLE", wantStatus: models.StatusActive, wantUpdated: 1},
+	}
+	for i, round := range rounds {
+		records[0].Status = round.status
+		stats := &SyncStats{Domain: domainMapping.Domain}
+		err := incrementalSyncDomain(context.Background(), &fakeProvider{records: records}, store, domainMapping,
+			testSyncConfig(), stats)
+		if err != nil {
+			t.Fatalf("round %d: incrementalSyncDomain() error = %v", i, err)
+		}
+		if stats.Updated != round.wantUpdated || stats.Disabled != round.wantDisabled || stats.Added+stats.Deleted != 0 {
+			t.Errorf("round %d: stats = %+v, want %d updated and %d disabled", i, stats, round.wantUpdated,
+				round.wantDisabled)
+		}
+		if got := store.rows[localID].Status; got != round.wantStatus {
+			t.Errorf("round %d: status = %q, want %q", i, got, round.wantStatus)
+		}
+	}
+}
+
+func TestFilterDomains(t *testing.T) {
+	var domains []config.DomainMapping
+	for _, name := range []string{"example.com", "example.net", "例子.com"} {
+		domainMapping := testDomain()
+		domainMapping.Domain = name
+		domains = append(domains, domainMapping)
+	}
+
+	tests := []struct {
+		name    string
+		names   []string
+		want    []string
+		wantErr string
+	}{
+		{name: "single domain", names: []string{"example.net"}, want: []string{"example.net"}},
+		{name: "keeps config order", names: []string{"例子.com", "example.com"}, want: []string{"example.com", "例子.com"}},
+		{name: "normalized name", names: []string{"Example.NET."}, want: []string{"example.net"}},
+		{name: "punycode name", names: []string{"xn--fsqu00a.com"}, want: []string{"例子.com"}},
+		{name: "repeated name", names: []string{"example.com", "example.com"}, want: []string{"example.com"}},
+		{name: "unknown domain", names: []string{"example.com", "example.org", "example.io"},
+			wantErr: "domains not found in config: example.io, example.org"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, err := filterDomains(domains, tt.names)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("filterDomains() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("filterDomains() error = %v", err)
+			}
+			var got []string
+			for _, domainMapping := range filtered {
+				got = append(got, domainMapping.Domain)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("filterDomains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNewProvidersAccounts 每个用到的阿里云账号创建一个客户端，同一账号的域名共享客户端，不同账号互不共享
+func TestNewProvidersAccounts(t *testing.T) {
+	data := `aliyun:
+  access_key_id: "default-id"
+  access_key_secret: "default-secret"
+aliyun_accounts:
+  prod:
+    access_key_id: "prod-id"
+    access_key_secret: "prod-secret"
+  unused:
+    access_key_id: "unused-id"
+    access_key_secret: "unused-secret"
+domains:
+  - domain: "example.com"
+    domain_id: "domain-1"
+    project_id: "project-1"
+  - domain: "example.net"
+    domain_id: "domain-2"
+    project_id: "project-1"
+    account: "default"
+  - domain: "example.org"
+    domain_id: "domain-3"
+    project_id: "project-1"
+    account: "prod"
+  - domain: "example.io"
+    domain_id: "domain-4"
+    project_id: "project-1"
+    account: "prod"
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadProviderConfig(path)
+	if err != nil {
+		t.Fatalf("LoadProviderConfig() error = %v", err)
+	}
+
+	providers, err := newProviders(cfg)
+	if err != nil {
+		t.Fatalf("newProviders() error = %v", err)
+	}
+	if len(providers) != 2 {
+		t.Fatalf("newProviders() created %d clients, want one per account in use", len(providers))
+	}
+
+	clients := make(map[string]provider.DNSProvider)
+	for _, domainMapping := range cfg.Domains {
+		p := providers[domainMapping.ProviderKey()]
+		if p == nil {
+			t.Fatalf("no provider for %s (%s)", domainMapping.Domain, domainMapping.ProviderKey())
+		}
+		clients[domainMapping.Domain] = p
+	}
+	if clients["example.com"] != clients["example.net"] || clients["example.org"] != clients["example.io"] {
+		t.Error("domains on the same account use different clients")
+	}
+	if clients["example.com"] == clients["example.org"] {
+		t.Error("domains on different accounts share a client")
+	}
+}
+
+// TestNewProvidersDomainCredentials 凭证不同的域名各用一个客户端，凭证相同的域名共用客户端
+func TestNewProvidersDomainCredentials(t *testing.T) {
+	data := `domains:
+  - domain: "example.com"
+    domain_id: "domain-1"
+    project_id: "project-1"
+    access_key_id: "sub-a-id"
+    access_key_secret: "sub-a-secret"
+  - domain: "example.net"
+    domain_id: "domain-2"
+    project_id: "project-1"
+    access_key_id: "sub-b-id"
+    access_key_secret: "sub-b-secret"
+    region: "cn-shanghai"
+  - domain: "example.org"
+    domain_id: "domain-3"
+    project_id: "project-1"
+    access_key_id: "sub-a-id"
+    access_key_secret: "sub-a-secret"
+`
+	t.Setenv("ALIBABA_CLOUD_ACCESS_KEY_ID", "")
+	t.Setenv("ALIBABA_CLOUD_ACCESS_KEY_SECRET", "")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadProviderConfig(path)
+	if err != nil {
+		t.Fatalf("LoadProviderConfig() error = %v", err)
+	}
+
+	providers, err := newProviders(cfg)
+	if err != nil {
+		t.Fatalf("newProviders() error = %v", err)
+	}
+	if len(providers) != 2 {
+		t.Fatalf("newProviders() created %d clients, want one per credential set", len(providers))
+	}
+
+	clients := make(map[string]provider.DNSProvider)
+	for _, domainMapping := range cfg.Domains {
+		p := providers[domainMapping.ProviderKey()]
+		if p == nil {
+			t.Fatalf("no provider for %s (%s)", domainMapping.Domain, domainMapping.ProviderKey())
+		}
+		clients[domainMapping.Domain] = p
+	}
+	if clients["example.com"] != clients["example.org"] {
+		t.Error("domains with the same credentials use different clients")
+	}
+	if clients["example.com"] == clients["example.net"] {
+		t.Error("domains with different credentials share a client")
+	}
+}
+
+// TestSyncExitCode 各域名同步结果组合对应的退出码，其它失败优先于阈值保护，阈值保护优先于部分失败
+func TestSyncExitCode(t *testing.T) {
+	ok := &SyncStats{Domain: "ok.example.com", Added: 1}
+	skipped := &SyncStats{Domain: "skipped.example.com", Skipped: true, SkipReason: "disabled"}
+	failed := &SyncStats{Domain: "failed.example.com", Error: "failed to get DNS records: InvalidAccessKeyId"}
+	threshold := &SyncStats{Domain: "threshold.example.com", Error: "delete threshold exceeded",
+		ThresholdExceeded: true}
+	degraded := &SyncStats{Domain: "degraded.example.com", Added: 2, Failed: 1}
+
+	tests := []struct {
+		name  string
+		stats []*SyncStats
+		want  int
+	}{
+		{name: "no domains", want: exitOK},
+		{name: "all succeeded", stats: []*SyncStats{ok, skipped}, want: exitOK},
+		{name: "domain failed", stats: []*SyncStats{ok, failed}, want: exitDomainFailed},
+		{name: "delete threshold only", stats: []*SyncStats{ok, threshold}, want: exitDeleteThreshold},
+		{name: "failure beats threshold", stats: []*SyncStats{threshold, failed}, want: exitDomainFailed},
+		{name: "threshold beats degraded", stats: []*SyncStats{degraded, threshold}, want: exitDeleteThreshold},
+		{name: "degraded", stats: []*SyncStats{ok, degraded}, want: exitDegraded},
+		{name: "failure beats degraded", stats: []*SyncStats{degraded, failed}, want: exitDomainFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := syncExitCode(tt.stats); got != tt.want {
+				t.Errorf("syncExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSyncExitCodeScenarios 服务商请求失败和删除数超出阈值经过完整的同步流程后得到对应的退出码
+func TestSyncExitCodeScenarios(t *testing.T) {
+	tests := []struct {
+		name string
+		// remote 为nil时服务商返回错误
+		remote []*models.DNSRecord
+		local  int
+		want   int
+	}{
+		{name: "synced", remote: testRecords(3), local: 3, want: exitOK},
+		{name: "provider auth failure", local: 3, want: exitDomainFailed},
+		{name: "delete threshold", remote: testRecords(1), local: 5, want: exitDeleteThreshold},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domainMapping := testDomain()
+			cfg := &config.Config{Sync: testSyncConfig(), Domains: []config.DomainMapping{domainMapping}}
+			cfg.Sync.Concurrency, cfg.Sync.Direction, cfg.Sync.MaxDeleteCount = 1, "pull", 2
+
+			dnsClient := &fakeProvider{records: tt.remote}
+			if tt.remote == nil {
+				dnsClient.err = errors.New("InvalidAccessKeyId.NotFound: Specified access key is not found")
+			}
+			providers := map[string]provider.DNSProvider{domainMapping.ProviderKey(): dnsClient}
+			store := syncedStore(domainMapping, testRecords(tt.local))
+
+			stats := syncDomains(context.Background(), cfg, providers, store)
+			if got := syncExitCode(stats); got != tt.want {
+				t.Errorf("syncExitCode() = %d, want %d (stats %+v)", got, tt.want, stats[0])
+			}
+		})
+	}
+}
+
+// TestIncrementalSyncLockedRecords 服务商上已锁定且与本地不同的记录：sync策略照常更新，skip和readonly不更新，
+// readonly同时输出警告；新出现的锁定记录在每种策略下都会插入
+func TestIncrementalSyncLockedRecords(t *testing.T) {
+	tests := []struct {
+		policy      string
+		wantValue   string
+		wantUpdated int
+		wantWarning bool
+	}{
+		{policy: "sync", wantValue: "10.0.1.0", wantUpdated: 1},
+		{policy: "skip", wantValue: "10.0.0.0"},
+		{policy: "readonly", wantValue: "10.0.0.0", wantWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			var logs bytes.Buffer
+			defer slog.SetDefault(slog.Default())
+			slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+			domainMapping := testDomain()
+			store := syncedStore(domainMapping, testRecords(1))
+			changed := testRecord("1000", "host0", "A", "10.0.1.0")
+			added := testRecord("1001", "host1", "A", "10.0.0.1")
+			changed.Locked, added.Locked = true, true
+			dnsClient := &fakeProvider{records: []*models.DNSRecord{changed, added}}
+
+			syncCfg := testSyncConfig()
+			syncCfg.LockedRecords = tt.policy
+			stats := &SyncStats{Domain: domainMapping.Domain}
+			if err := incrementalSyncDomain(context.Background(), dnsClient, store, domainMapping, syncCfg,
+				stats); err != nil {
+				t.Fatalf("incrementalSyncDomain() error = %v", err)
+			}
+
+			row := store.find(domainMapping.Source, domainMapping.DomainID, "1000")
+			if row == nil || row.DNSRecord == nil || *row.DNSRecord != tt.wantValue {
+				t.Errorf("locked record row = %+v, want value %s", row, tt.wantValue)
+			}
+			if stats.Updated != tt.wantUpdated {
+				t.Errorf("stats.Updated = %d, want %d", stats.Updated, tt.wantUpdated)
+			}
+			if stats.Added != 1 || store.find(domainMapping.Source, domainMapping.DomainID, "1001") == nil {
+				t.Errorf("new locked record not inserted, stats.Added = %d", stats.Added)
+			}
+			warned := strings.Contains(logs.String(), "Locked record differs from local record")
+			if warned != tt.wantWarning {
+				t.Errorf("warning logged = %v, want %v", warned, tt.wantWarning)
+			}
+		})
+	}
+}
+
+// TestIncrementalSyncAssetDefaults 新增的记录写入域名配置的资产字段；记录更新时只改同步的列，人工修改过的资产字段保留
+func TestIncrementalSyncAssetDefaults(t *testing.T) {
+	domainMapping := testDomain()
+	domainMapping.AssetDefaults = config.AssetFields{AssetLabel: "dns", CreateBy: "dns-sync", SysOrgCode: "A01",
+		AssetDepartment: "ops", Level: "2"}
+	record := testRecord("1000", "www", "A", "10.0.0.1")
+	dnsClient := &fakeProvider{records: []*models.DNSRecord{record}}
+	store := newMemStore()
+	syncCfg := testSyncConfig()
+
+	if err := incrementalSyncDomain(context.Background(), dnsClient, store, domainMapping, syncCfg,
+		&SyncStats{Domain: domainMapping.Domain}); err != nil {
+		t.Fatalf("first incrementalSyncDomain() error = %v", err)
+	}
+	row := store.find(domainMapping.Source, domainMapping.DomainID, "1000")
+	if row == nil {
+		t.Fatal("record not inserted")
+	}
+	got := []*string{&row.AssetLabel, row.CreateBy, row.UpdateBy, row.SysOrgCode, row.AssetDepartment, row.Level}
+	want := []string{"dns", "dns-sync", "dns-sync", "A01", "ops", "2"}
+	for i := range want {
+		if got[i] == nil || *got[i] != want[i] {
+			t.Errorf("inserted asset field %d = %v, want %s", i, got[i], want[i])
+		}
+	}
+
+	// 人工修改资产字段后，服务商上的记录值变化
+	edited := func(value string) *string { return &value }
+	row.AssetLabel, row.CreateBy, row.SysOrgCode = "edited", edited("alice"), edited("B02")
+	row.AssetDepartment, row.Level = edited("security"), edited("1")
+	record.Value = "10.0.0.2"
+
+	stats := &SyncStats{Domain: domainMapping.Domain}
+	if err := incrementalSyncDomain(context.Background(), dnsClient, store, domainMapping, syncCfg,
+		stats); err != nil {
+		t.Fatalf("second incrementalSyncDomain() error = %v", err)
+	}
+	if stats.Updated != 1 || *row.DNSRecord != "10.0.0.2" {
+		t.Fatalf("record not updated: stats.Updated = %d, value %s", stats.Updated, *row.DNSRecord)
+	}
+	got = []*string{&row.AssetLabel, row.CreateBy, row.SysOrgCode, row.AssetDepartment, row.Level}
+	want = []string{"edited", "alice", "B02", "security", "1"}
+	for i := range want {
+		if got[i] == nil || *got[i] != want[i] {
+			t.Errorf("asset field %d after update = %v, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+// TestSyncDomainProviderErrors 服务商上不存在的域名跳过且不删除本地记录，鉴权失败和其它错误使该域名失败
+func TestSyncDomainProviderErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        string
+		wantSkipped bool
+		wantCode    int
+	}{
+		{name: "domain does not exist", code: "InvalidDomainName.NoExist", wantSkipped: true, wantCode: exitOK},
+		{name: "signature invalid", code: "SignatureDoesNotMatch", wantCode: exitDomainFailed},
+		{name: "other error", code: "InternalError", wantCode: exitDomainFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domainMapping := testDomain()
+			cfg := &config.Config{Sync: testSyncConfig(), Domains: []config.DomainMapping{domainMapping}}
+			cfg.Sync.Direction = "pull"
+			dnsClient := &fakeProvider{err: &aliyun.APIError{StatusCode: 400, Code: tt.code, RequestId: "req-1"}}
+			providers := map[string]provider.DNSProvider{domainMapping.ProviderKey(): dnsClient}
+			store := syncedStore(domainMapping, testRecords(3))
+
+			stats := syncDomain(context.Background(), cfg, providers, store, domainMapping)
+			if stats.Skipped != tt.wantSkipped {
+				t.Errorf("stats.Skipped = %v, want %v", stats.Skipped, tt.wantSkipped)
+			}
+			if tt.wantSkipped == (stats.Error != "") {
+				t.Errorf("stats.Error = %q", stats.Error)
+			}
+			if got := syncExitCode([]*SyncStats{stats}); got != tt.wantCode {
+				t.Errorf("syncExitCode() = %d, want %d", got, tt.wantCode)
+			}
+			if len(store.rows) != 3 || store.deletes != 0 {
+				t.Errorf("local rows changed: %d rows, %d deletes", len(store.rows), store.deletes)
+			}
+		})
+	}
+}
+
+// TestIncrementalSyncSince 首次同步后保存最大的UpdateTimestamp，之后只对比水位之后修改过的记录：
+// 未修改的记录即使本地行被改动也跳过，修改过的记录照常更新，服务商上删除的记录仍按完整结果删除
+func TestIncrementalSyncSince(t *testing.T) {
+	domainMapping := testDomain()
+	records := testRecords(5)
+	for i, record := range records {
+		record.UpdateTimestamp = int64(1000 + i)
+	}
+	dnsClient := &fakeProvider{records: records}
+	store := newMemStore()
+	syncCfg := testSyncConfig()
+	syncCfg.Since = true
+
+	stats := &SyncStats{Domain: domainMapping.Domain}
+	if err := incrementalSyncDomain(context.Background(), dnsClient, store, domainMapping, syncCfg, stats); err != nil {
+		t.Fatalf("first incrementalSyncDomain() error = %v", err)
+	}
+	watermark, _ := store.GetWatermark(context.Background(), domainMapping.DomainID, domainMapping.Source)
+	if stats.Added != 5 || watermark != 1004 {
+		t.Fatalf("first sync added %d records, watermark %d, want 5 and 1004", stats.Added, watermark)
+	}
+
+	// 本地行被改动但服务商记录未变化；1001在水位之后被修改，1004被删除
+	stale := "10.9.9.0"
+	row := store.find(domainMapping.Source, domainMapping.DomainID, "1000")
+	row.DNSRecord, row.ContentHash = &stale, "edited"
+	records[1].Value, records[1].UpdateTimestamp = "10.0.1.1", 2000
+	dnsClient.records = records[:4]
+
+	stats = &SyncStats{Domain: domainMapping.Domain}
+	if err := incrementalSyncDomain(context.Background(), dnsClient, store, domainMapping, syncCfg, stats); err != nil {
+		t.Fatalf("second incrementalSyncDomain() error = %v", err)
+	}
+	if stats.Added != 0 || stats.Updated != 1 || stats.Deleted != 1 {
+		t.Errorf("second sync = +%d ~%d -%d, want +0 ~1 -1", stats.Added, stats.Updated, stats.Deleted)
+	}
+	if got := *store.find(domainMapping.Source, domainMapping.DomainID, "1000").DNSRecord; got != stale {
+		t.Errorf("record unchanged since the watermark was compared, value = %s", got)
+	}
+	if got := *store.find(domainMapping.Source, domainMapping.DomainID, "1001").DNSRecord; got != "10.0.1.1" {
+		t.Errorf("record modified after the watermark value = %s, want 10.0.1.1", got)
+	}
+	watermark, _ = store.GetWatermark(context.Background(), domainMapping.DomainID, domainMapping.Source)
+	if watermark != 2000 {
+		t.Errorf("watermark = %d, want 2000", watermark)
+	}
+
+	// 关闭增量模式后完整对比，本地被改动的行恢复为服务商的值
+	syncCfg.Since = false
+	stats = &SyncStats{Domain: domainMapping.Domain}
+	if err := incrementalSyncDomain(context.Background(), dnsClient, store, domainMapping, syncCfg, stats); err != nil {
+		t.Fatalf("full incrementalSyncDomain() error = %v", err)
+	}
+	if stats.Updated != 1 {
+		t.Errorf("full sync updated %d records, want 1", stats.Updated)
+	}
+}
+
+func TestRecordsSince(t *testing.T) {
+	local := map[string]*models.AssetSubDomain{"1000": {}, "1001": {}, "1002": {}}
+	records := []*models.DNSRecord{
+		{RecordId: "1000", UpdateTimestamp: 100},
+		{RecordId: "1001", UpdateTimestamp: 200},
+		{RecordId: "1002"},
+		{RecordId: "1003", UpdateTimestamp: 50},
+		{RecordId: "1004", UpdateTimestamp: 150},
+	}
+
+	var got []string
+	for _, record := range recordsSince(records, 150, local) {
+		got = append(got, record.RecordId)
+	}
+	// 只跳过1000：1003和1004未超过水位但本地不存在，1002没有修改时间
+	want := []string{"1001", "1002", "1003", "1004"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("recordsSince() = %v, want %v", got, want)
+	}
+}
+
+// TestSyncDomainDegraded 部分记录写入失败时域名标记为部分成功：失败数和按操作的计数计入统计，错误样例有上限，
+// 报告中该域名不算成功，退出码为exitDegraded
+func TestSyncDomainDegraded(t *testing.T) {
+	domainMapping := testDomain()
+	cfg := &config.Config{Sync: testSyncConfig(), Domains: []config.DomainMapping{domainMapping}}
+	cfg.Sync.Direction = "pull"
+	records := testRecords(10)
+	store := syncedStore(domainMapping, records)
+	store.deleteErrs = make(map[string]error)
+	for _, record := range records[2:9] {
+		store.deleteErrs[record.RecordId] = errors.New("lock wait timeout exceeded")
+	}
+	providers := map[string]provider.DNSProvider{domainMapping.ProviderKey(): &fakeProvider{records: records[:2]}}
+
+	stats := syncDomain(context.Background(), cfg, providers, store, domainMapping)
+	if stats.Error != "" || !stats.Degraded() {
+		t.Fatalf("stats = %+v, want degraded without error", stats)
+	}
+	if stats.Deleted != 1 || stats.Failed != 7 || stats.FailedByAction["delete"] != 7 {
+		t.Errorf("deleted %d, failed %d (%v), want 1 and 7 deletes", stats.Deleted, stats.Failed,
+			stats.FailedByAction)
+	}
+	if len(stats.FailedSamples) != maxFailedSamples {
+		t.Errorf("kept %d failure samples, want %d", len(stats.FailedSamples), maxFailedSamples)
+	}
+	for _, sample := range stats.FailedSamples {
+		if sample.Action != "delete" || sample.Error != "lock wait timeout exceeded" || sample.RecordID == "" {
+			t.Errorf("failure sample = %+v", sample)
+		}
+	}
+
+	report := buildSyncReport([]*SyncStats{stats}, time.Now(), time.Now(), false)
+	if result := report.Domains[0]; result.Success || !result.Degraded || result.Failed != 7 {
+		t.Errorf("report domain = %+v, want degraded with 7 failures", result)
+	}
+	if report.Totals.Degraded != 1 || report.Totals.Failed != 0 {
+		t.Errorf("report totals = %+v, want 1 degraded and no failed domains", report.Totals)
+	}
+	if got := syncExitCode([]*SyncStats{stats}); got != exitDegraded {
+		t.Errorf("syncExitCode() = %d, want %d", got, exitDegraded)
+	}
+}
+
+// TestSyncDomainsDisabled 停用的域名不调用服务商、不写本地表，摘要中计为跳过而不是失败
+func TestSyncDomainsDisabled(t *testing.T) {
+	enabled := testDomain()
+	disabled := testDomain()
+	disabled.Domain, disabled.DomainID, disabled.Account = "example.net", "domain-2", "disabled"
+	off := false
+	disabled.Enabled = &off
+
+	cfg := &config.Config{Sync: testSyncConfig(), Domains: []config.DomainMapping{enabled, disabled}}
+	cfg.Sync.Concurrency, cfg.Sync.Direction = 1, "pull"
+
+	// 停用域名的服务商返回错误，一旦被调用同步就会失败
+	disabledClient := &fakeProvider{records: testRecords(2), err: errors.New("request failed")}
+	providers := map[string]provider.DNSProvider{
+		enabled.ProviderKey():  &fakeProvider{records: testRecords(2)},
+		disabled.ProviderKey(): disabledClient,
+	}
+	store := newMemStore()
+
+	stats := syncDomains(context.Background(), cfg, providers, store)
+	if len(stats) != 2 {
+		t.Fatalf("got %d stats, want 2", len(stats))
+	}
+	if stats[0].Skipped || stats[0].Error != "" || stats[0].Added != 2 {
+		t.Errorf("enabled domain stats = %+v, want 2 added", stats[0])
+	}
+	got := stats[1]
+	if !got.Skipped || got.Error != "" || got.Added != 0 {
+		t.Errorf("disabled domain stats = %+v, want skipped without error", got)
+	}
+	for i := 0; i < 2; i++ {
+		if store.find(disabled.Source, disabled.DomainID, fmt.Sprintf("%d", 1000+i)) != nil {
+			t.Errorf("record %d of disabled domain was stored", 1000+i)
+		}
+	}
+	if code := syncExitCode(stats); code != exitOK {
+		t.Errorf("syncExitCode() = %d, want %d", code, exitOK)
+	}
+	if report := buildSyncReport(stats, time.Time{}, time.Time{}, false); report.Totals.Skipped != 1 ||
+		report.Totals.Failed != 0 {
+		t.Errorf("report totals = %+v, want 1 skipped and no failures", report.Totals)
+	}
+}
+
+// TestFetchDomainRecordsSubzone 配置zone_apex时向服务商查询注册的主域名，只保留子区域下的记录，
+// 主机记录相对子区域保存，不会拼出www.api.example.com这样错误的名字
+func TestFetchDomainRecordsSubzone(t *testing.T) {
+	domainMapping := testDomain()
+	domainMapping.Domain, domainMapping.ZoneApex = "api.example.com", "example.com"
+
+	dnsClient := &fakeProvider{records: []*models.DNSRecord{
+		testRecord("1000", "@", "A", "10.0.0.1"),
+		testRecord("1001", "www", "A", "10.0.0.2"),
+		testRecord("1002", "api", "A", "10.0.0.3"),
+		testRecord("1003", "www.api", "A", "10.0.0.4"),
+		testRecord("1004", "*.api", "CNAME", "lb.example.net"),
+	}}
+
+	records, _, err := fetchDomainRecords(context.Background(), dnsClient, domainMapping)
+	if err != nil {
+		t.Fatalf("fetchDomainRecords() error = %v", err)
+	}
+	if dnsClient.domain != "example.com" {
+		t.Errorf("queried domain = %s, want example.com", dnsClient.domain)
+	}
+
+	want := map[string]string{"1002": "@", "1003": "www", "1004": "*"}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(records), len(want))
+	}
+	for _, record := range records {
+		if record.DomainName != "api.example.com" || record.RR != want[record.RecordId] {
+			t.Errorf("record %s = %s/%s, want api.example.com/%s", record.RecordId, record.DomainName, record.RR,
+				want[record.RecordId])
+		}
+		if wantName := strings.TrimPrefix(want[record.RecordId]+".api.example.com", "@."); record.FullDomain() != wantName {
+			t.Errorf("record %s FullDomain() = %s, want %s", record.RecordId, record.FullDomain(), wantName)
+		}
+	}
+}
+
+// TestIncrementalSyncIgnoreFields 只有ignore_fields中的字段变化时不更新本地记录，其它字段变化时照常更新
+func TestIncrementalSyncIgnoreFields(t *testing.T) {
+	domainMapping := testDomain()
+	domainMapping.Lines = []string{"default", "telecom"}
+	syncCfg := testSyncConfig()
+	syncCfg.IgnoreFields = []string{"ttl", "line"}
+
+	record := testRecord("1000", "www", "A", "10.0.0.1")
+	store := newMemStore()
+	rounds := []struct {
+		name        string
+		change      func(r *models.DNSRecord)
+		wantAdded   int
+		wantUpdated int
+	}{
+		{name: "initial sync", change: func(*models.DNSRecord) {}, wantAdded: 1},
+		{name: "ttl only", change: func(r *models.DNSRecord) { r.TTL = 60 }},
+		{name: "line only", change: func(r *models.DNSRecord) { r.Line = "telecom" }},
+		{name: "value", change: func(r *models.DNSRecord) { r.Value = "10.0.0.2" }, wantUpdated: 1},
+		{name: "status", change: func(r *models.DNSRecord) { r.Status = "DISABLE" }, wantUpdated: 1},
+		{name: "ttl back", change: func(r *models.DNSRecord) { r.TTL = 600 }},
+	}
+	for _, round := range rounds {
+		round.change(record)
+		stats := &SyncStats{Domain: domainMapping.Domain}
+		err := incrementalSyncDomain(context.Background(), &fakeProvider{records: []*models.DNSRecord{record}}, store,
+			domainMapping, syncCfg, stats)
+		if err != nil {
+			t.Fatalf("%s: incrementalSyncDomain() error = %v", round.name, err)
+		}
+		if stats.Added != round.wantAdded || stats.Updated != round.wantUpdated || stats.Deleted != 0 {
+			t.Errorf("%s: added = %d, updated = %d, deleted = %d, want %d added and %d updated", round.name,
+				stats.Added, stats.Updated, stats.Deleted, round.wantAdded, round.wantUpdated)
+		}
+	}
+	if local := store.find(domainMapping.Source, domainMapping.DomainID, "1000"); local == nil ||
+		local.DNSRecord == nil || *local.DNSRecord != "10.0.0.2" {
+		t.Errorf("local record = %+v, want value 10.0.0.2", local)
+	}
+}
+
+// TestIncrementalSyncTypeCase 记录类型大小写在多次同步之间变化时只在首次新增，之后不产生更新，存储的类型统一为大写
+func TestIncrementalSyncTypeCase(t *testing.T) {
+	domainMapping := testDomain()
+	domainMapping.RecordTypes = []string{"a", "Cname"}
+	syncCfg := testSyncConfig()
+
+	store := newMemStore()
+	rounds := []struct {
+		name      string
+		types     []string
+		wantAdded int
+	}{
+		{name: "initial sync", types: []string{"cname", "A", "txt"}, wantAdded: 2},
+		{name: "upper case", types: []string{"CNAME", "A", "TXT"}},
+		{name: "mixed case", types: []string{"CName", "a", "Txt"}},
+	}
+	for _, round := range rounds {
+		records := []*models.DNSRecord{
+			testRecord("1000", "www", round.types[0], "target.example.com"),
+			testRecord("1001", "api", round.types[1], "10.0.0.1"),
+			testRecord("1002", "@", round.types[2], "v=spf1 -all"),
+		}
+		stats := &SyncStats{Domain: domainMapping.Domain}
+		err := incrementalSyncDomain(context.Background(), &fakeProvider{records: records}, store, domainMapping,
+			syncCfg, stats)
+		if err != nil {
+			t.Fatalf("%s: incrementalSyncDomain() error = %v", round.name, err)
+		}
+		if stats.Added != round.wantAdded || stats.Updated != 0 || stats.Deleted != 0 {
+			t.Errorf("%s: added = %d, updated = %d, deleted = %d, want %d added", round.name,
+				stats.Added, stats.Updated, stats.Deleted, round.wantAdded)
+		}
+	}
+
+	for recordID, want := range map[string]string{"1000": "CNAME", "1001": "A"} {
+		if local := store.find(domainMapping.Source, domainMapping.DomainID, recordID); local == nil ||
+			local.Type != want {
+			t.Errorf("local record %s = %+v, want type %s", recordID, local, want)
+		}
+	}
+	if local := store.find(domainMapping.Source, domainMapping.DomainID, "1002"); local != nil {
+		t.Errorf("TXT record stored = %+v, want filtered", local)
+	}
+}
+
+// TestIncrementalSyncApex 主域名记录的主机记录为空或为@视为相同，本地以任一形式保存时都不产生更新；
+// 旧数据保存为"@.域名"的行更新一次改写为域名本身，之后保持不变
+func TestIncrementalSyncApex(t *testing.T) {
+	domainMapping := testDomain()
+	syncCfg := testSyncConfig()
+
+	emptyRR := testRecord("1000", "", "A", "10.0.0.1")
+	atRR := testRecord("1001", "@", "A", "10.0.0.2")
+	legacy := testRecord("1002", "@", "A", "10.0.0.3")
+	legacyRow := testRow(domainMapping, legacy)
+	legacyRow.SubDomain = "@." + domainMapping.Domain
+	store := newMemStore(testRow(domainMapping, emptyRR), testRow(domainMapping, atRR), legacyRow)
+
+	rounds := []struct {
+		name        string
+		rr          [3]string
+		wantUpdated int
+	}{
+		{name: "same form", rr: [3]string{"", "@", "@"}, wantUpdated: 1},
+		{name: "swapped form", rr: [3]string{"@", "", ""}},
+		{name: "swapped back", rr: [3]string{"", "@", "@"}},
+	}
+	for _, round := range rounds {
+		emptyRR.RR, atRR.RR, legacy.RR = round.rr[0], round.rr[1], round.rr[2]
+		stats := &SyncStats{Domain: domainMapping.Domain}
+		err := incrementalSyncDomain(context.Background(),
+			&fakeProvider{records: []*models.DNSRecord{emptyRR, atRR, legacy}}, store, domainMapping, syncCfg, stats)
+		if err != nil {
+			t.Fatalf("%s: incrementalSyncDomain() error = %v", round.name, err)
+		}
+		if stats.Added != 0 || stats.Updated != round.wantUpdated || stats.Deleted != 0 {
+			t.Errorf("%s: added = %d, updated = %d, deleted = %d, want %d updated", round.name,
+				stats.Added, stats.Updated, stats.Deleted, round.wantUpdated)
+		}
+	}
+
+	for _, recordID := range []string{"1000", "1001", "1002"} {
+		if local := store.find(domainMapping.Source, domainMapping.DomainID, recordID); local == nil ||
+			local.SubDomain != domainMapping.Domain {
+			t.Errorf("local record %s = %+v, want sub_domain %s", recordID, local, domainMapping.Domain)
+		}
+	}
+}
+
+// TestIncrementalSyncSystemRecords 域名本身的NS和SOA记录默认既不导入也不删除，子域名的NS委派和MX、TXT照常同步；
+// 开启include_system_records时同步全部记录
+func TestIncrementalSyncSystemRecords(t *testing.T) {
+	zone := []*models.DNSRecord{
+		testRecord("1000", "@", "NS", "ns1.alidns.com"),
+		testRecord("1001", "@", "NS", "ns2.alidns.com"),
+		testRecord("1002", "@", "SOA", "ns1.alidns.com. hostmaster.example.com. 1 3600 1200 86400 600"),
+		testRecord("1003", "dev", "NS", "ns1.dev-dns.net"),
+		testRecord("1004", "@", "MX", "mail.example.com"),
+		testRecord("1005", "@", "TXT", "v=spf1 -all"),
+		testRecord("1006", "www", "A", "10.0.0.1"),
+	}
+
+	tests := []struct {
+		name        string
+		include     bool
+		want        []string
+		wantAdded   int
+		wantDeleted int
+	}{
+		{name: "default", want: []string{"1003", "1004", "1005", "1006", "900"}, wantAdded: 4},
+		{name: "include system records", include: true,
+			want: []string{"1000", "1001", "1002", "1003", "1004", "1005", "1006"}, wantAdded: 7, wantDeleted: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domainMapping := testDomain()
+			domainMapping.RecordTypes = []string{"A", "CNAME", "NS", "SOA", "MX", "TXT"}
+			syncCfg := testSyncConfig()
+			syncCfg.IncludeSystemRecords = tt.include
+
+			// 本地已有一条服务商上不存在的主域名NS记录，默认不参与对比，不会被删除
+			store := syncedStore(domainMapping, []*models.DNSRecord{testRecord("900", "@", "NS", "ns9.example.net")})
+			stats := &SyncStats{Domain: domainMapping.Domain}
+			err := incrementalSyncDomain(context.Background(), &fakeProvider{records: zone}, store, domainMapping,
+				syncCfg, stats)
+			if err != nil {
+				t.Fatalf("incrementalSyncDomain() error = %v", err)
+			}
+
+			got := store.recordIDs(domainMapping.DomainID, domainMapping.Source)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("record ids = %v, want %v", got, tt.want)
+			}
+			if stats.Added != tt.wantAdded || stats.Deleted != tt.wantDeleted {
+				t.Errorf("added = %d, deleted = %d, want %d added and %d deleted", stats.Added, stats.Deleted,
+					tt.wantAdded, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+// TestIncrementalSyncTransforms 配置的改写规则按顺序作用于拉取的记录，对比和写入都使用改写后的值，
+// 改写后与本地相同的记录不更新，raw_record保存服务商返回的原值
+func TestIncrementalSyncTransforms(t *testing.T) {
+	domainMapping := testDomain()
+	syncCfg := testSyncConfig()
+	syncCfg.Transforms = []config.TransformConfig{{Name: "strip_trailing_dot"}, {Name: "suffix_strip", Arg: ".internal"}}
+
+	unchanged := testRecord("1000", "app", "CNAME", "app.svc")
+	changed := testRecord("1001", "api", "CNAME", "api.svc")
+	store := syncedStore(domainMapping, []*models.DNSRecord{unchanged, changed})
+
+	remote := []*models.DNSRecord{
+		testRecord("1000", "app", "CNAME", "app.svc.internal."),
+		testRecord("1001", "api", "CNAME", "api-v2.svc.internal."),
+		testRecord("1002", "www", "CNAME", "lb.example.net."),
+	}
+	stats := &SyncStats{Domain: domainMapping.Domain}
+	err := incrementalSyncDomain(context.Background(), &fakeProvider{records: remote}, store, domainMapping, syncCfg,
+		stats)
+	if err != nil {
+		t.Fatalf("incrementalSyncDomain() error = %v", err)
+	}
+	if stats.Added != 1 || stats.Updated != 1 || stats.Deleted != 0 {
+		t.Errorf("added = %d, updated = %d, deleted = %d, want 1 added and 1 updated", stats.Added, stats.Updated,
+			stats.Deleted)
+	}
+
+	want := map[string]string{"1000": "app.svc", "1001": "api-v2.svc", "1002": "lb.example.net"}
+	for recordID, value := range want {
+		local := store.find(domainMapping.Source, domainMapping.DomainID, recordID)
+		if local == nil || local.DNSRecord == nil || *local.DNSRecord != value {
+			t.Errorf("local record %s = %+v, want value %s", recordID, local, value)
+		}
+	}
+	if local := store.find(domainMapping.Source, domainMapping.DomainID, "1002"); local == nil ||
+		local.RawRecord == nil || !strings.Contains(*local.RawRecord, `"Value":"lb.example.net."`) {
+		t.Errorf("raw_record = %v, want the provider value", local)
+	}
+	if remote[0].Value != "app.svc.internal." {
+		t.Errorf("provider record modified to %q", remote[0].Value)
+	}
+}
+
+// TestIncrementalSyncMixedResult 部分记录删除失败时incrementalSyncDomain不返回错误，成功的新增、更新和删除照常计数，
+// 失败的记录按操作计数并保留错误样例，报告中的域名结果为部分成功
+func TestIncrementalSyncMixedResult(t *testing.T) {
+	domainMapping := testDomain()
+	records := testRecords(5)
+	store := syncedStore(domainMapping, records)
+	store.deleteErrs = map[string]error{"1003": errors.New("lock wait timeout exceeded")}
+
+	// 1000不变，1001的值变化，1002和1003在服务商上已删除，1010为新记录
+	changed := *records[1]
+	changed.Value = "10.0.1.1"
+	remote := []*models.DNSRecord{records[0], &changed, records[4], testRecord("1010", "new", "A", "10.0.0.10")}
+
+	stats := &SyncStats{Domain: domainMapping.Domain}
+	err := incrementalSyncDomain(context.Background(), &fakeProvider{records: remote}, store, domainMapping,
+		testSyncConfig(), stats)
+	if err != nil {
+		t.Fatalf("incrementalSyncDomain() error = %v", err)
+	}
+	if stats.Added != 1 || stats.Updated != 1 || stats.Deleted != 1 || stats.Failed != 1 {
+		t.Errorf("added = %d, updated = %d, deleted = %d, failed = %d, want 1 each", stats.Added, stats.Updated,
+			stats.Deleted, stats.Failed)
+	}
+	if fmt.Sprint(stats.FailedByAction) != "map[delete:1]" {
+		t.Errorf("FailedByAction = %v, want map[delete:1]", stats.FailedByAction)
+	}
+	want := models.RecordError{Action: "delete", SubDomain: "host3.example.com", RecordID: "1003",
+		Error: "lock wait timeout exceeded"}
+	if len(stats.FailedSamples) != 1 || stats.FailedSamples[0] != want {
+		t.Errorf("FailedSamples = %+v, want [%+v]", stats.FailedSamples, want)
+	}
+	if !stats.Degraded() {
+		t.Error("Degraded() = false, want true")
+	}
+	if store.find(domainMapping.Source, domainMapping.DomainID, "1002") != nil ||
+		store.find(domainMapping.Source, domainMapping.DomainID, "1003") == nil {
+		t.Errorf("record ids = %v, want 1002 deleted and 1003 kept", store.recordIDs(domainMapping.DomainID,
+			domainMapping.Source))
+	}
+
+	result := buildSyncReport([]*SyncStats{stats}, time.Now(), time.Now(), false).Domains[0]
+	if result.Success || !result.Degraded || result.Added != 1 || result.Updated != 1 || result.Deleted != 1 ||
+		result.Failed != 1 || result.FailedByAction["delete"] != 1 || len(result.Errors) != 1 ||
+		result.Errors[0] != want {
+		t.Errorf("report domain = %+v, want degraded with the failed delete", result)
+	}
+}
+
+// TestRunSyncPostSync 全部域名完成后以本轮合计执行一次post_sync_sql；失败时写入报告，
+// 只有配置了post_sync_sql_fatal时才改变退出码，dry-run模式下不执行
+func TestRunSyncPostSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		fatal    bool
+		dryRun   bool
+		wantRuns int
+		wantCode int
+	}{
+		{name: "executed", wantRuns: 1, wantCode: exitOK},
+		{name: "failure not fatal", err: errors.New("post_sync_sql statement 1 failed"), wantRuns: 1, wantCode: exitOK},
+		{name: "failure fatal", err: errors.New("post_sync_sql statement 1 failed"), fatal: true, wantRuns: 1,
+			wantCode: exitPostSyncFailed},
+		{name: "dry run", dryRun: true, wantCode: exitOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domainMapping := testDomain()
+			cfg := &config.Config{Sync: testSyncConfig(), Domains: []config.DomainMapping{domainMapping}}
+			cfg.Sync.Concurrency, cfg.Sync.Direction, cfg.Sync.DryRun = 1, "pull", tt.dryRun
+			cfg.Sync.PostSyncSQL = []string{"REFRESH MATERIALIZED VIEW dns_summary"}
+			cfg.Sync.PostSyncSQLFatal = tt.fatal
+
+			// 本地3条记录：1000不变，1001的值变化，1002已在服务商上删除，另有2条新记录
+			records := testRecords(5)
+			store := syncedStore(domainMapping, records[:3])
+			store.postSyncErr = tt.err
+			changed := *records[1]
+			changed.Value = "10.0.1.1"
+			remote := []*models.DNSRecord{records[0], &changed, records[3], records[4]}
+			providers := map[string]provider.DNSProvider{domainMapping.ProviderKey(): &fakeProvider{records: remote}}
+
+			reportPath := filepath.Join(t.TempDir(), "report.json")
+			if code := runSync(context.Background(), cfg, providers, store, 0, reportPath); code != tt.wantCode {
+				t.Errorf("runSync() = %d, want %d", code, tt.wantCode)
+			}
+			if len(store.postSyncTotals) != tt.wantRuns {
+				t.Fatalf("ExecPostSync called %d times, want %d", len(store.postSyncTotals), tt.wantRuns)
+			}
+			if tt.wantRuns > 0 {
+				if want := (database.PostSyncTotals{Added: 2, Updated: 1, Deleted: 1}); store.postSyncTotals[0] != want {
+					t.Errorf("post-sync totals = %+v, want %+v", store.postSyncTotals[0], want)
+				}
+			}
+
+			data, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var report models.SyncReport
+			if err := json.Unmarshal(data, &report); err != nil {
+				t.Fatal(err)
+			}
+			want := ""
+			if tt.err != nil {
+				want = tt.err.Error()
+			}
+			if report.PostSyncError != want {
+				t.Errorf("report post_sync_error = %q, want %q", report.PostSyncError, want)
+			}
+		})
+	}
+}
+
+// TestSyncDomainsCircuitBreaker 同一服务商连续失败达到阈值后，剩余域名不再调用服务商，标记为因服务商故障跳过
+func TestSyncDomainsCircuitBreaker(t *testing.T) {
+	cfg := &config.Config{Sync: testSyncConfig()}
+	cfg.Sync.Concurrency, cfg.Sync.Direction = 1, "pull"
+	for i := 0; i < 6; i++ {
+		domainMapping := testDomain()
+		domainMapping.Domain, domainMapping.DomainID = fmt.Sprintf("example%d.com", i), fmt.Sprintf("domain-%d", i)
+		cfg.Domains = append(cfg.Domains, domainMapping)
+	}
+
+	dnsClient := &fakeProvider{err: errors.New("request failed: connection refused")}
+	key := cfg.Domains[0].ProviderKey()
+	providers := map[string]provider.DNSProvider{key: provider.NewCircuitBreaker(2).Wrap(key, dnsClient)}
+
+	stats := syncDomains(context.Background(), cfg, providers, newMemStore())
+	if dnsClient.calls != 2 {
+		t.Errorf("provider called %d times, want 2", dnsClient.calls)
+	}
+	failed, skipped := 0, 0
+	for _, stat := range stats {
+		switch {
+		case stat.Error != "":
+			failed++
+		case stat.Skipped && stat.SkipReason == "provider outage":
+			skipped++
+		default:
+			t.Errorf("domain %s stats = %+v, want failed or skipped", stat.Domain, stat)
+		}
+	}
+	if failed != 2 || skipped != 4 {
+		t.Errorf("got %d failed and %d skipped domains, want 2 and 4", failed, skipped)
+	}
+	if code := syncExitCode(stats); code != exitDomainFailed {
+		t.Errorf("syncExitCode() = %d, want %d", code, exitDomainFailed)
+	}
+}
+
+// TestLabelLine 线路名称按sync.line_names优先、内置名称其次设置，Line保留原始代码；
+// 未知代码原样作为名称并只警告一次
+func TestLabelLine(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	warnedLines.Delete("test_unknown_line")
+
+	lineNames := map[string]string{"telecom": "电信", "cn_region_bj": "北京"}
+	tests := []struct {
+		line     string
+		wantName string
+	}{
+		{line: "default", wantName: "默认"},
+		{line: "unicom", wantName: "中国联通"},
+		{line: "telecom", wantName: "电信"},
+		{line: "cn_region_bj", wantName: "北京"},
+		{line: "test_unknown_line", wantName: "test_unknown_line"},
+		{line: "test_unknown_line", wantName: "test_unknown_line"},
+		{line: "", wantName: ""},
+	}
+
+	for _, tt := range tests {
+		record := testRecord("1000", "www", "A", "10.0.0.1")
+		record.Line = tt.line
+		labelLine(record, lineNames)
+		if record.Line != tt.line || record.LineName != tt.wantName {
+			t.Errorf("line %q: Line, LineName = %q, %q, want %q, %q", tt.line, record.Line, record.LineName, tt.line,
+				tt.wantName)
+		}
+
+		asset := record.ConvertToAssetSubDomain("domain-1", "project-1", "Aliyun-DNS-Sync")
+		if asset.Line != tt.line || asset.LineName != tt.wantName {
+			t.Errorf("line %q: stored line, line_name = %q, %q, want %q, %q", tt.line, asset.Line, asset.LineName,
+				tt.line, tt.wantName)
+		}
+	}
+
+	if got := strings.Count(logs.String(), "Unknown line code"); got != 1 {
+		t.Errorf("got %d unknown line warnings, want 1:\n%s", got, logs.String())
+	}
+	if !strings.Contains(logs.String(), "line=test_unknown_line") {
+		t.Errorf("warning does not name the line code:\n%s", logs.String())
+	}
+}
+
+// TestRunSyncTracing 一轮同步导出一个trace：sync.run下每个域名一个sync.domain，
+// 域名下读取本地记录和应用变更各一个span，并带有记录数属性
+func TestRunSyncTracing(t *testing.T) {
+	type span struct {
+		SpanID       string `json:"spanId"`
+		ParentSpanID string `json:"parentSpanId"`
+		Name         string `json:"name"`
+		Attributes   []struct {
+			Key   string `json:"key"`
+			Value struct {
+				IntValue    string `json:"intValue"`
+				StringValue string `json:"stringValue"`
+			} `json:"value"`
+		} `json:"attributes"`
+	}
+	var mu sync.Mutex
+	var spans []span
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []span `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, resource := range request.ResourceSpans {
+			for _, scope := range resource.ScopeSpans {
+				spans = append(spans, scope.Spans...)
+			}
+		}
+	}))
+	defer server.Close()
+	if err := tracing.Setup(server.URL, "dns-sync"); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	defer tracing.Setup("", "")
+
+	domainMapping := testDomain()
+	cfg := &config.Config{Sync: testSyncConfig(), Domains: []config.DomainMapping{domainMapping}}
+	cfg.Sync.Concurrency, cfg.Sync.Direction = 1, "pull"
+	records := testRecords(4)
+	store := syncedStore(domainMapping, records[:1])
+	providers := map[string]provider.DNSProvider{domainMapping.ProviderKey(): &fakeProvider{records: records}}
+	if code := runSync(context.Background(), cfg, providers, store, 0, ""); code != exitOK {
+		t.Fatalf("runSync() = %d, want %d", code, exitOK)
+	}
+
+	byName := make(map[string]span)
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+	tests := []struct {
+		name   string
+		parent string
+		attrs  map[string]string
+	}{
+		{name: "sync.run", attrs: map[string]string{"domains": "1"}},
+		{name: "sync.domain", parent: "sync.run", attrs: map[string]string{"domain": "example.com", "records": "4",
+			"added": "3"}},
+		{name: "db.get_local_records", parent: "sync.domain", attrs: map[string]string{"records": "1"}},
+		{name: "sync.apply", parent: "sync.domain", attrs: map[string]string{"inserts": "3", "added": "3"}},
+	}
+	for _, tt := range tests {
+		s, ok := byName[tt.name]
+		if !ok {
+			t.Errorf("span %s not exported, got %d spans", tt.name, len(spans))
+			continue
+		}
+		if parent := byName[tt.parent]; s.ParentSpanID != parent.SpanID {
+			t.Errorf("%s parent = %q, want %s %q", tt.name, s.ParentSpanID, tt.parent, parent.SpanID)
+		}
+		for key, want := range tt.attrs {
+			got := ""
+			for _, attr := range s.Attributes {
+				if attr.Key == key {
+					got = attr.Value.IntValue + attr.Value.StringValue
+				}
+			}
+			if got != want {
+				t.Errorf("%s attribute %s = %q, want %q", tt.name, key, got, want)
+			}
+		}
+	}
+}
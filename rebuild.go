@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/database"
+	"dns-sync/internal/logger"
+	"dns-sync/internal/models"
+	"dns-sync/internal/provider"
+)
+
+// runRebuild 按服务商的记录完整重建每个域名的本地记录：在单个事务中删除该域名source的全部行后重新插入
+// 人工维护的资产字段会被重置为asset_defaults；有域名失败时返回exitDomainFailed
+func runRebuild(ctx context.Context, cfg *config.Config, providers map[string]provider.DNSProvider,
+	store database.Store, syncTimeout time.Duration) int {
+
+	if syncTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, syncTimeout)
+		defer cancel()
+	}
+
+	domains := enabledDomains(cfg.Domains)
+	totalDeleted, totalInserted, failures := 0, 0, 0
+
+	for _, domainMapping := range domains {
+		deleted, inserted, err := rebuildDomain(ctx, providers[domainMapping.ProviderKey()], store, domainMapping,
+			cfg.Sync)
+		if err != nil {
+			failures++
+			slog.Error("Error rebuilding domain", "domain", domainMapping.Domain, "error", err)
+			fmt.Fprintf(os.Stdout, "%s: error: %s\n", domainMapping.Domain, logger.Redact(err.Error()))
+			continue
+		}
+
+		if cfg.Sync.DryRun {
+			fmt.Fprintf(os.Stdout, "%s: would replace %d rows with %d records\n", domainMapping.Domain, deleted,
+				inserted)
+		} else {
+			fmt.Fprintf(os.Stdout, "%s: replaced %d rows with %d records\n", domainMapping.Domain, deleted, inserted)
+		}
+		totalDeleted += deleted
+		totalInserted += inserted
+	}
+
+	fmt.Fprintf(os.Stdout, "%d domains, %d rows removed, %d records inserted", len(domains), totalDeleted,
+		totalInserted)
+	if failures > 0 {
+		fmt.Fprintf(os.Stdout, ", %d failed", failures)
+	}
+	fmt.Fprintln(os.Stdout)
+
+	if failures > 0 {
+		return exitDomainFailed
+	}
+	return exitOK
+}
+
+// rebuildDomain 重建单个域名，返回删除的行数和插入的记录数；dry-run模式下只统计不写入
+// 过滤规则和collapse_values与同步相同；服务商结果不完整或为空时不重建，避免清空本地记录
+func rebuildDomain(ctx context.Context, dnsClient provider.DNSProvider, store database.Store,
+	domainMapping config.DomainMapping, syncCfg config.SyncConfig) (int, int, error) {
+
+	dnsRecords, truncated, err := fetchDomainRecords(ctx, dnsClient, domainMapping)
+	if errors.Is(err, provider.ErrDomainNotFound) {
+		return 0, 0, fmt.Errorf("domain does not exist on provider, refusing to rebuild")
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get DNS records: %w", err)
+	}
+	if truncated {
+		return 0, 0, fmt.Errorf("records exceed max_records, refusing to rebuild from a truncated result")
+	}
+
+	var validRecords []*models.DNSRecord
+	transform := recordTransformer(syncCfg.Transforms)
+	for _, record := range dnsRecords {
+		if domainMapping.AcceptsType(record.Type) && domainMapping.AcceptsLine(record.Line) &&
+			domainMapping.AcceptsName(record.FullDomain()) &&
+			!isSystemRecord(syncCfg, domainMapping, record.FullDomain(), record.Type) {
+			record = transform(record)
+			record.IgnoreFields = syncCfg.IgnoreFields
+			labelLine(record, syncCfg.LineNames)
+			validRecords = append(validRecords, record)
+		}
+	}
+	if len(validRecords) == 0 {
+		return 0, 0, fmt.Errorf("provider returned no matching records, refusing to rebuild")
+	}
+	if syncCfg.CollapseValues {
+		validRecords, _ = models.CollapseValues(validRecords, func(string) bool { return false })
+	}
+
+	records := make([]*models.AssetSubDomain, 0, len(validRecords))
+	for _, record := range validRecords {
+		records = append(records, newAssetRecord(record, domainMapping))
+	}
+
+	if syncCfg.DryRun {
+		localRecords, err := store.GetLocalRecords(ctx, domainMapping.DomainID, domainMapping.Source)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get local records: %w", err)
+		}
+		return len(localRecords), len(records), nil
+	}
+
+	result, err := store.RebuildDomainTx(ctx, domainMapping.DomainID, domainMapping.Source, records)
+	if err != nil {
+		return 0, 0, err
+	}
+	return result.Deleted, result.Added, nil
+}
+
+// confirmRebuild 在标准错误输出提示并从in读取确认，输入y或yes时返回true
+func confirmRebuild(in io.Reader, domains []config.DomainMapping) bool {
+	names := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		names = append(names, domain.Domain)
+	}
+	fmt.Fprintf(os.Stderr, "This deletes and reinserts all synced rows for %d domains (%s).\n"+
+		"Manually maintained asset fields on those rows will be lost. Continue? [y/N]: ",
+		len(domains), strings.Join(names, ", "))
+
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/models"
+)
+
+// TestRebuildDomain 重建替换该来源的全部行并重置资产信息，其它来源的行保留；写入失败或服务商结果为空时本地行不变
+func TestRebuildDomain(t *testing.T) {
+	remote := []*models.DNSRecord{
+		testRecord("2000", "www", "A", "10.0.1.1"),
+		testRecord("2001", "api", "CNAME", "lb.example.net"),
+		testRecord("2002", "mail", "A", "10.0.1.2"),
+	}
+
+	tests := []struct {
+		name       string
+		remote     []*models.DNSRecord
+		rebuildErr error
+		dryRun     bool
+		wantErr    string
+		// wantIDs 重建后阿里云来源的RecordId
+		wantIDs      []string
+		wantDeleted  int
+		wantInserted int
+	}{
+		{name: "replaces rows", remote: remote, wantIDs: []string{"2000", "2001", "2002"}, wantDeleted: 2, wantInserted: 3},
+		{name: "dry run", remote: remote, dryRun: true, wantIDs: []string{"1000", "1001"}, wantDeleted: 2, wantInserted: 3},
+		{name: "failed transaction keeps rows", remote: remote, rebuildErr: errors.New("failed to insert www.example.com"),
+			wantErr: "failed to insert www.example.com", wantIDs: []string{"1000", "1001"}},
+		{name: "empty result refused", wantErr: "provider returned no matching records", wantIDs: []string{"1000", "1001"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aliyun := testDomain()
+			cloudflare := testDomain()
+			cloudflare.Provider, cloudflare.Source = "cloudflare", "Cloudflare-DNS-Sync"
+
+			store := newMemStore()
+			for _, record := range testRecords(2) {
+				row := testRow(aliyun, record)
+				row.AssetLabel = "managed"
+				store.put(row)
+			}
+			store.put(testRow(cloudflare, testRecord("cf-1", "cdn", "CNAME", "cdn.example.net")))
+			store.rebuildErr = tt.rebuildErr
+
+			syncCfg := testSyncConfig()
+			syncCfg.DryRun = tt.dryRun
+			deleted, inserted, err := rebuildDomain(context.Background(), &fakeProvider{records: tt.remote}, store,
+				aliyun, syncCfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("rebuildDomain() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("rebuildDomain() error = %v", err)
+			}
+			if deleted != tt.wantDeleted || inserted != tt.wantInserted {
+				t.Errorf("rebuildDomain() = %d deleted, %d inserted, want %d, %d", deleted, inserted,
+					tt.wantDeleted, tt.wantInserted)
+			}
+
+			if got := store.recordIDs(aliyun.DomainID, aliyun.Source); fmt.Sprint(got) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("aliyun record ids = %v, want %v", got, tt.wantIDs)
+			}
+			if got := store.recordIDs(cloudflare.DomainID, cloudflare.Source); fmt.Sprint(got) != "[cf-1]" {
+				t.Errorf("cloudflare record ids = %v, want [cf-1]", got)
+			}
+			rows, _ := store.GetLocalRecords(context.Background(), aliyun.DomainID, aliyun.Source)
+			for recordID, row := range rows {
+				if wantLabel := row.AssetLabel == "managed"; wantLabel != strings.HasPrefix(recordID, "1") {
+					t.Errorf("record %s asset label = %q", recordID, row.AssetLabel)
+				}
+			}
+		})
+	}
+}
+
+func TestConfirmRebuild(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{input: "y\n", want: true},
+		{input: "YES\n", want: true},
+		{input: " yes \n", want: true},
+		{input: "n\n"},
+		{input: "\n"},
+		{input: ""},
+		{input: "yep\n"},
+	}
+
+	for _, tt := range tests {
+		if got := confirmRebuild(strings.NewReader(tt.input), []config.DomainMapping{testDomain()}); got != tt.want {
+			t.Errorf("confirmRebuild(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/database"
+	"dns-sync/internal/models"
+	"dns-sync/internal/tracing"
+)
+
+// 解析检查写入resolution_status的结果
+const (
+	// resolutionMatch 解析结果与本地记录值一致
+	resolutionMatch = "match"
+	// resolutionMismatch 能够解析但结果与本地记录值不同，通常是记录已过期
+	resolutionMismatch = "mismatch"
+	// resolutionUnresolved 主机名不存在（NXDOMAIN），记录可能指向已下线的资源
+	resolutionUnresolved = "unresolved"
+)
+
+// resolver 解析检查使用的DNS查询，*net.Resolver满足该接口
+type resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+}
+
+// checkResolution 解析同步成功的域名下A和CNAME记录的主机名，与本地记录值对比后写入resolution_status
+// 未开启resolve_check或dry-run模式下跳过；查询超时等无法判断的情况不修改原来的结果
+// 检查失败只记录警告，不影响同步结果
+func checkResolution(ctx context.Context, cfg *config.Config, store database.Store, stats []*SyncStats, r resolver) {
+	if !cfg.Sync.ResolveCheck || cfg.Sync.DryRun {
+		return
+	}
+
+	ctx, span := tracing.Start(ctx, "sync.resolve_check")
+	defer span.End()
+
+	mappings := make(map[string]config.DomainMapping, len(cfg.Domains))
+	for _, domainMapping := range cfg.Domains {
+		mappings[models.NormalizeDomain(domainMapping.Domain)] = domainMapping
+	}
+
+	var records []*models.AssetSubDomain
+	for _, stat := range stats {
+		if stat.Error != "" || stat.Skipped {
+			continue
+		}
+		domainMapping := mappings[models.NormalizeDomain(stat.Domain)]
+		localRecords, err := store.GetLocalRecords(ctx, domainMapping.DomainID, domainMapping.Source)
+		if err != nil {
+			slog.Warn("Failed to load records for resolution check", "domain", stat.Domain, "error", err)
+			continue
+		}
+		for _, record := range localRecords {
+			if resolvable(record) {
+				records = append(records, record)
+			}
+		}
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	results := make([]string, len(records))
+	sem := make(chan struct{}, cfg.Sync.ResolveConcurrency)
+	var wg sync.WaitGroup
+	for i, record := range records {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			lookupCtx, cancel := context.WithTimeout(ctx, cfg.Sync.ResolveTimeout)
+			defer cancel()
+			results[i] = resolveRecord(lookupCtx, r, record)
+		}()
+	}
+	wg.Wait()
+
+	statuses := make(map[string][]string)
+	for i, record := range records {
+		status := results[i]
+		if status == "" {
+			continue
+		}
+		if status != resolutionMatch {
+			slog.Warn("Record does not resolve to stored value", "sub_domain", record.SubDomain,
+				"type", record.Type, "value", *record.DNSRecord, "status", status)
+		}
+		statuses[status] = append(statuses[status], record.ID)
+	}
+
+	span.SetAttributes("records", len(records), "mismatched", len(statuses[resolutionMismatch]),
+		"unresolved", len(statuses[resolutionUnresolved]))
+	slog.Info("Resolution check completed", "records", len(records), "matched", len(statuses[resolutionMatch]),
+		"mismatched", len(statuses[resolutionMismatch]), "unresolved", len(statuses[resolutionUnresolved]))
+
+	if err := store.SaveResolutionStatus(ctx, statuses); err != nil {
+		slog.Warn("Failed to save resolution status", "error", err)
+	}
+}
+
+// resolvable 只检查有记录值的有效A和CNAME记录，泛解析记录没有可以查询的主机名
+func resolvable(record *models.AssetSubDomain) bool {
+	if record.Type != "A" && record.Type != "CNAME" {
+		return false
+	}
+	if record.Status == models.StatusDisabled || record.Status == models.StatusDeleted {
+		return false
+	}
+	if record.DNSRecord == nil || *record.DNSRecord == "" {
+		return false
+	}
+	return !strings.HasPrefix(record.FullDomain(), "*")
+}
+
+// resolveRecord 查询记录的主机名并返回检查结果，无法判断时返回空字符串
+// A记录的每个值都出现在解析结果中为一致；CNAME记录的目标与主机名解析到同一个规范名称为一致
+func resolveRecord(ctx context.Context, r resolver, record *models.AssetSubDomain) string {
+	host := record.FullDomain()
+
+	if record.Type == "A" {
+		addrs, err := r.LookupHost(ctx, host)
+		if err != nil {
+			return lookupFailure(host, err)
+		}
+		resolved := make(map[string]bool, len(addrs))
+		for _, addr := range addrs {
+			resolved[addr] = true
+		}
+		for _, value := range strings.Split(*record.DNSRecord, ",") {
+			if !resolved[strings.TrimSpace(value)] {
+				return resolutionMismatch
+			}
+		}
+		return resolutionMatch
+	}
+
+	cname, err := r.LookupCNAME(ctx, host)
+	if err != nil {
+		return lookupFailure(host, err)
+	}
+	target := models.NormalizeDomain(*record.DNSRecord)
+	if models.NormalizeDomain(cname) == target {
+		return resolutionMatch
+	}
+	// LookupCNAME返回跟随整条CNAME链后的名称，目标本身也是CNAME时比较两者的最终名称
+	targetCNAME, err := r.LookupCNAME(ctx, target)
+	if err != nil {
+		return lookupFailure(target, err)
+	}
+	if models.NormalizeDomain(targetCNAME) == models.NormalizeDomain(cname) {
+		return resolutionMatch
+	}
+	return resolutionMismatch
+}
+
+// lookupFailure 名称不存在时返回unresolved，其它错误（超时、服务器失败）无法判断，返回空字符串
+func lookupFailure(host string, err error) string {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return resolutionUnresolved
+	}
+	slog.Debug("Resolution check lookup failed", "host", host, "error", err)
+	return ""
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/models"
+)
+
+// stubResolver 按主机名返回固定结果的resolver，未列出的主机名返回NXDOMAIN
+type stubResolver struct {
+	hosts  map[string][]string
+	cnames map[string]string
+	// errs 按主机名返回的其它查询错误
+	errs map[string]error
+}
+
+func (r *stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if err := r.errs[host]; err != nil {
+		return nil, err
+	}
+	if addrs, ok := r.hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func (r *stubResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	if err := r.errs[host]; err != nil {
+		return "", err
+	}
+	if cname, ok := r.cnames[host]; ok {
+		return cname, nil
+	}
+	return "", &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// TestCheckResolution A记录的值都在解析结果中、CNAME解析到同一个规范名称时为match，否则为mismatch；
+// 名称不存在为unresolved，查询超时不写入结果；其它类型、泛解析和停用的记录不检查
+func TestCheckResolution(t *testing.T) {
+	domainMapping := testDomain()
+	disabled := testRecord("1009", "old", "A", "10.0.0.9")
+	disabled.Status = "DISABLE"
+	records := []*models.DNSRecord{
+		testRecord("1000", "www", "A", "10.0.0.1"),
+		testRecord("1001", "api", "A", "10.0.0.2"),
+		testRecord("1002", "gone", "A", "10.0.0.3"),
+		testRecord("1003", "slow", "A", "10.0.0.4"),
+		testRecord("1004", "cdn", "CNAME", "cdn.example.net"),
+		testRecord("1005", "shop", "CNAME", "shop.saas.example.net"),
+		testRecord("1006", "blog", "CNAME", "old-blog.example.net"),
+		testRecord("1007", "*", "A", "10.0.0.5"),
+		testRecord("1008", "@", "TXT", "v=spf1 -all"),
+		disabled,
+	}
+	r := &stubResolver{
+		hosts: map[string][]string{
+			"www.example.com": {"10.0.0.1", "10.0.0.11"},
+			"api.example.com": {"10.0.9.9"},
+		},
+		cnames: map[string]string{
+			"cdn.example.com": "cdn.example.net.",
+			// shop的目标本身是CNAME，两者都解析到同一个最终名称
+			"shop.example.com":      "edge.saas.example.net.",
+			"shop.saas.example.net": "edge.saas.example.net.",
+			"blog.example.com":      "new-blog.example.net.",
+			"old-blog.example.net":  "old-blog.example.net.",
+		},
+		errs: map[string]error{"slow.example.com": &net.DNSError{Err: "i/o timeout", Name: "slow.example.com",
+			IsTimeout: true}},
+	}
+
+	tests := []struct {
+		name   string
+		check  bool
+		dryRun bool
+		failed bool
+		want   map[string]string
+	}{
+		{
+			name:  "enabled",
+			check: true,
+			want: map[string]string{"1000": resolutionMatch, "1001": resolutionMismatch,
+				"1002": resolutionUnresolved, "1004": resolutionMatch, "1005": resolutionMatch,
+				"1006": resolutionMismatch},
+		},
+		{name: "disabled", want: map[string]string{}},
+		{name: "dry run", check: true, dryRun: true, want: map[string]string{}},
+		{name: "failed domain", check: true, failed: true, want: map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Sync: testSyncConfig(), Domains: []config.DomainMapping{domainMapping}}
+			cfg.Sync.ResolveCheck, cfg.Sync.DryRun = tt.check, tt.dryRun
+			cfg.Sync.ResolveConcurrency, cfg.Sync.ResolveTimeout = 2, time.Second
+			store := syncedStore(domainMapping, records)
+			stats := &SyncStats{Domain: domainMapping.Domain}
+			if tt.failed {
+				stats.Error = "failed to get DNS records: request failed"
+			}
+
+			checkResolution(context.Background(), cfg, store, []*SyncStats{stats}, r)
+
+			got := make(map[string]string)
+			for id, status := range store.resolution {
+				got[*store.rows[id].AliyunRecordID] = status
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("resolution status = %v, want %v", got, tt.want)
+			}
+			for recordID, want := range tt.want {
+				if got[recordID] != want {
+					t.Errorf("record %s resolution status = %q, want %q", recordID, got[recordID], want)
+				}
+			}
+		})
+	}
+}
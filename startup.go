@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/database"
+	"dns-sync/internal/provider"
+)
+
+// startupCheck 启动时执行的一项检查，name用于日志和错误信息
+type startupCheck struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// runStartupChecks 并发执行全部检查并等待全部完成，每项失败都输出一条错误日志，返回列出失败项的错误
+// 凭证错误和数据库不可用等问题一次全部报告，而不是修复一个后才看到下一个
+func runStartupChecks(ctx context.Context, checks []startupCheck) error {
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = check.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, checks[i].name)
+			slog.Error("Startup check failed", "check", checks[i].name, "error", err)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d startup checks failed: %s", len(failed), len(checks), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// providerChecks 为每个服务商客户端创建检查：测试连接，并确认配置的域名存在
+// strict_domains关闭时域名检查失败只输出警告
+func providerChecks(cfg *config.Config, providers map[string]provider.DNSProvider) []startupCheck {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checks := make([]startupCheck, 0, len(names))
+	for _, name := range names {
+		dnsClient := providers[name]
+		checks = append(checks, startupCheck{name: name, run: func(ctx context.Context) error {
+			if err := dnsClient.TestConnection(ctx); err != nil {
+				return err
+			}
+			slog.Info("Provider connection test passed", "provider", name)
+
+			// 检查配置的域名是否存在，避免拼写错误的域名长期同步到0条记录
+			var domains []string
+			for _, domainMapping := range cfg.Domains {
+				if domainMapping.ProviderKey() == name && domainMapping.IsEnabled() {
+					domains = append(domains, domainMapping.QueryDomain())
+				}
+			}
+			if err := dnsClient.VerifyDomains(ctx, domains); err != nil {
+				if *cfg.Sync.StrictDomains {
+					return fmt.Errorf("failed to verify domains: %w", err)
+				}
+				slog.Warn("Domain verification failed", "provider", name, "error", err)
+				return nil
+			}
+			slog.Info("Provider domains verified", "provider", name, "count", len(domains))
+			return nil
+		}})
+	}
+	return checks
+}
+
+// databaseCheck 创建数据库客户端并依次测试连接、按需建表、检查表和列，最后预先建立sync.concurrency个连接
+// 客户端创建成功后写入store，检查失败时调用方仍需关闭
+func databaseCheck(cfg *config.Config, runID string, initDB bool, store *database.Store) startupCheck {
+	return startupCheck{name: "database", run: func(ctx context.Context) error {
+		client, err := database.NewStore(cfg)
+		if err != nil {
+			return err
+		}
+		*store = client
+		client.SetSoftDelete(cfg.Sync.DeleteMode == "soft")
+		client.SetAudit(cfg.Sync.Audit, runID)
+		slog.Info("Database client initialized", "driver", cfg.DB.Driver, "dsn", cfg.RedactedDSN(),
+			"delete_mode", cfg.Sync.DeleteMode, "audit", cfg.Sync.Audit)
+
+		if err := client.TestConnection(ctx); err != nil {
+			return fmt.Errorf("failed to test database connection: %w", err)
+		}
+		slog.Info("Database connection test passed")
+
+		// 按需创建表结构，默认只检查表是否存在
+		if initDB {
+			if err := client.InitSchema(ctx); err != nil {
+				return fmt.Errorf("failed to initialize database schema: %w", err)
+			}
+			slog.Info("Database schema initialized")
+		}
+
+		if err := client.CheckTableExists(ctx); err != nil {
+			return err
+		}
+		slog.Info("Database table exists")
+
+		// 检查表结构是否包含需要写入的全部列，旧表缺少列时在同步前失败，而不是在第一次写入时
+		if err := client.CheckColumns(ctx); err != nil {
+			return err
+		}
+
+		// 并发同步的每个域名各需要一个连接，提前建立可以省去第一轮同步的握手和认证时间
+		if err := client.Prewarm(ctx, cfg.Sync.Concurrency); err != nil {
+			return err
+		}
+		slog.Info("Database connections prewarmed", "count", cfg.Sync.Concurrency)
+		return nil
+	}}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/provider"
+)
+
+// checkProvider 连接测试和域名检查返回指定错误的服务商
+type checkProvider struct {
+	fakeProvider
+	connErr   error
+	verifyErr error
+	// verified VerifyDomains收到的域名
+	verified []string
+}
+
+func (p *checkProvider) TestConnection(ctx context.Context) error { return p.connErr }
+
+func (p *checkProvider) VerifyDomains(ctx context.Context, domains []string) error {
+	p.verified = domains
+	return p.verifyErr
+}
+
+// TestRunStartupChecks 全部检查并发执行，一项失败不会跳过其它检查，多项失败时在同一个错误中全部列出
+func TestRunStartupChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		errs    []error
+		wantErr string
+	}{
+		{name: "all passed", errs: []error{nil, nil, nil}},
+		{name: "one failed", errs: []error{nil, errors.New("access denied"), nil},
+			wantErr: "1 of 3 startup checks failed: check-1"},
+		{name: "several failed", errs: []error{errors.New("access denied"), nil, errors.New("connection refused")},
+			wantErr: "2 of 3 startup checks failed: check-0, check-2"},
+		{name: "all failed", errs: []error{errors.New("access denied"), errors.New("domain not found"),
+			errors.New("connection refused")}, wantErr: "3 of 3 startup checks failed: check-0, check-1, check-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 每项检查等待全部检查都已开始后才返回，顺序执行时会超时
+			var started sync.WaitGroup
+			started.Add(len(tt.errs))
+			allStarted := make(chan struct{})
+			go func() {
+				started.Wait()
+				close(allStarted)
+			}()
+
+			var ran sync.Map
+			checks := make([]startupCheck, 0, len(tt.errs))
+			for i, err := range tt.errs {
+				name := fmt.Sprintf("check-%d", i)
+				checks = append(checks, startupCheck{name: name, run: func(ctx context.Context) error {
+					started.Done()
+					select {
+					case <-allStarted:
+					case <-time.After(time.Second):
+						return errors.New("checks did not run concurrently")
+					}
+					ran.Store(name, true)
+					return err
+				}})
+			}
+
+			err := runStartupChecks(context.Background(), checks)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("runStartupChecks() error = %v", err)
+				}
+			} else if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("runStartupChecks() error = %v, want %q", err, tt.wantErr)
+			}
+			for _, check := range checks {
+				if _, ok := ran.Load(check.name); !ok {
+					t.Errorf("%s did not run", check.name)
+				}
+			}
+		})
+	}
+}
+
+// TestProviderChecks 每个服务商一项检查，连接失败和域名检查失败都会报告；
+// strict_domains关闭时域名检查失败不算失败，停用的域名不检查
+func TestProviderChecks(t *testing.T) {
+	strict, lenient := true, false
+	tests := []struct {
+		name      string
+		strict    *bool
+		connErr   error
+		verifyErr error
+		wantErr   string
+	}{
+		{name: "both failed", strict: &strict, connErr: errors.New("InvalidAccessKeyId.NotFound"),
+			verifyErr: errors.New("domain example.org not found"), wantErr: "2 of 2 startup checks failed: aliyun, aliyun/prod"},
+		{name: "lenient domains", strict: &lenient, connErr: errors.New("InvalidAccessKeyId.NotFound"),
+			verifyErr: errors.New("domain example.org not found"), wantErr: "1 of 2 startup checks failed: aliyun"},
+		{name: "passed", strict: &strict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disabled := false
+			primary := testDomain()
+			primary.Account = config.DefaultAccount
+			prod := config.DomainMapping{Domain: "example.org", Provider: "aliyun", Account: "prod"}
+			off := config.DomainMapping{Domain: "example.io", Provider: "aliyun", Account: "prod", Enabled: &disabled}
+			cfg := &config.Config{Domains: []config.DomainMapping{primary, prod, off}}
+			cfg.Sync.StrictDomains = tt.strict
+
+			defaultProvider := &checkProvider{connErr: tt.connErr}
+			prodProvider := &checkProvider{verifyErr: tt.verifyErr}
+			providers := map[string]provider.DNSProvider{
+				primary.ProviderKey(): defaultProvider,
+				prod.ProviderKey():    prodProvider,
+			}
+
+			checks := providerChecks(cfg, providers)
+			err := runStartupChecks(context.Background(), checks)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("runStartupChecks() error = %v", err)
+				}
+			} else if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("runStartupChecks() error = %v, want %q", err, tt.wantErr)
+			}
+			if got := strings.Join(prodProvider.verified, ","); got != "example.org" {
+				t.Errorf("verified domains = %q, want example.org", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"dns-sync/internal/config"
+)
+
+// runValidate 检查配置文件的每个部分并输出OK或错误，不连接服务商和数据库，有任一部分失败时返回exitSetupError
+func runValidate(w io.Writer, configPath string) int {
+	failed := 0
+	for _, result := range config.ValidateFile(configPath) {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(w, "%-10s error: %v\n", result.Section, result.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%-10s OK\n", result.Section)
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(w, "%s: %d sections failed\n", configPath, failed)
+		return exitSetupError
+	}
+	fmt.Fprintf(w, "%s: config is valid\n", configPath)
+	return exitOK
+}
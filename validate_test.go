@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// validConfigYAML 能通过全部检查的配置，密码来自环境变量
+const validConfigYAML = `aliyun:
+  access_key_id: "test-id"
+  access_key_secret: "test-secret"
+mysql:
+  host: "db.internal"
+  username: "root"
+  password: "${DNS_SYNC_TEST_PASSWORD}"
+  database: "assets"
+domains:
+  - domain: "example.com"
+    domain_id: "domain-1"
+    project_id: "project-1"
+`
+
+// TestRunValidate 有效的配置每个部分都输出OK并返回exitOK，无效的配置输出对应部分的错误并返回exitSetupError
+func TestRunValidate(t *testing.T) {
+	t.Setenv("DNS_SYNC_TEST_PASSWORD", "secret")
+	t.Setenv("ALIBABA_CLOUD_ACCESS_KEY_ID", "")
+	t.Setenv("ALIBABA_CLOUD_ACCESS_KEY_SECRET", "")
+
+	tests := []struct {
+		name     string
+		data     string
+		replace  []string
+		want     []string
+		wantCode int
+	}{
+		{
+			name:     "valid",
+			data:     validConfigYAML,
+			want:     []string{"file       OK\n", "providers  OK\n", "database   OK\n", "domains    OK\n", "config is valid\n"},
+			wantCode: exitOK,
+		},
+		{
+			name:     "unknown key",
+			data:     validConfigYAML,
+			replace:  []string{"access_key_id:", "acess_key_id:"},
+			want:     []string{"file       error: ", "line 2: field acess_key_id not found", "1 sections failed\n"},
+			wantCode: exitSetupError,
+		},
+		{
+			name:     "unset environment variable",
+			data:     validConfigYAML,
+			replace:  []string{"DNS_SYNC_TEST_PASSWORD", "DNS_SYNC_TEST_UNSET"},
+			want:     []string{"file       error: ", "DNS_SYNC_TEST_UNSET", "1 sections failed\n"},
+			wantCode: exitSetupError,
+		},
+		{
+			name:    "several sections invalid",
+			data:    validConfigYAML,
+			replace: []string{`host: "db.internal"`, `host: ""`, `project_id: "project-1"`, `project_id: ""`},
+			want: []string{"file       OK\n", "providers  OK\n", "database   error: mysql host is required\n",
+				"domains    error: invalid domain mapping at index 0\n", "2 sections failed\n"},
+			wantCode: exitSetupError,
+		},
+		{
+			name:     "missing file",
+			want:     []string{"file       error: failed to read config file", "1 sections failed\n"},
+			wantCode: exitSetupError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if tt.data != "" {
+				data := strings.NewReplacer(tt.replace...).Replace(tt.data)
+				if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var out bytes.Buffer
+			if code := runValidate(&out, path); code != tt.wantCode {
+				t.Errorf("runValidate() = %d, want %d", code, tt.wantCode)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output %q does not contain %q", out.String(), want)
+				}
+			}
+		})
+	}
+}
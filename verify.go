@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/database"
+	"dns-sync/internal/logger"
+	"dns-sync/internal/provider"
+)
+
+// domainDrift 单个域名的数据库与服务商之间的差异
+type domainDrift struct {
+	Domain string
+	// MissingOnProvider 数据库中存在、服务商上已不存在的记录
+	MissingOnProvider []string
+	// MissingInDB 服务商上存在、数据库中没有的记录
+	MissingInDB []string
+	// Mismatched 两边都存在但字段不一致的记录
+	Mismatched []string
+}
+
+// Total 差异总数
+func (d *domainDrift) Total() int {
+	return len(d.MissingOnProvider) + len(d.MissingInDB) + len(d.Mismatched)
+}
+
+// runVerify 检查每个域名的数据库记录与服务商是否一致并输出分类的差异报告，不写入数据库
+// 差异总数超过maxDrift时返回exitDrift；有域名检查失败时返回exitDomainFailed
+func runVerify(ctx context.Context, cfg *config.Config, providers map[string]provider.DNSProvider,
+	store database.Store, syncTimeout time.Duration, maxDrift, sampleSize int) int {
+
+	if syncTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, syncTimeout)
+		defer cancel()
+	}
+
+	domains := enabledDomains(cfg.Domains)
+
+	// 锁定记录的差异同样属于漂移，不按locked_records策略跳过；差异检查始终对比全部记录，不使用增量水位
+	syncCfg := cfg.Sync
+	syncCfg.LockedRecords = "sync"
+	syncCfg.Since = false
+	// 服务商返回空结果时本地记录同样属于漂移
+	syncCfg.AllowEmpty = true
+
+	totalDrift, failures := 0, 0
+	for _, domainMapping := range domains {
+		stats := &SyncStats{Domain: domainMapping.Domain}
+		changes, _, err := computeSyncChanges(ctx, providers[domainMapping.ProviderKey()], store, domainMapping,
+			syncCfg, stats)
+		if err != nil {
+			failures++
+			slog.Error("Error verifying domain", "domain", domainMapping.Domain, "error", err)
+			fmt.Fprintf(os.Stdout, "%s: error: %s\n\n", domainMapping.Domain, logger.Redact(err.Error()))
+			continue
+		}
+
+		drift := classifyDrift(domainMapping.Domain, changes)
+		fmt.Fprint(os.Stdout, formatDrift(drift, sampleSize))
+		totalDrift += drift.Total()
+	}
+
+	fmt.Fprintf(os.Stdout, "%d domains, %d discrepancies", len(domains), totalDrift)
+	if failures > 0 {
+		fmt.Fprintf(os.Stdout, ", %d failed", failures)
+	}
+	fmt.Fprintln(os.Stdout)
+
+	if failures > 0 {
+		return exitDomainFailed
+	}
+	if totalDrift > maxDrift {
+		slog.Warn("Drift exceeds threshold", "discrepancies", totalDrift, "max_drift", maxDrift)
+		return exitDrift
+	}
+	return exitOK
+}
+
+// classifyDrift 将变更集合转换为差异分类
+// 待删除的记录即服务商上缺失的记录，待新增的记录即数据库中缺失的记录，更新（包括恢复和重新关联）为字段不一致
+func classifyDrift(domain string, changes *database.SyncChanges) *domainDrift {
+	drift := &domainDrift{Domain: domain}
+
+	for _, record := range changes.Deletes {
+		drift.MissingOnProvider = append(drift.MissingOnProvider,
+			fmt.Sprintf("%s %s %s [%s]", record.SubDomain, record.Type, recordValue(record), recordID(record)))
+	}
+	for _, record := range changes.Inserts {
+		drift.MissingInDB = append(drift.MissingInDB,
+			fmt.Sprintf("%s %s %s [%s]", record.SubDomain, record.Type, recordValue(record), recordID(record)))
+	}
+	for _, update := range changes.Updates {
+		drift.Mismatched = append(drift.Mismatched, fmt.Sprintf("%s %s (%s)", update.AliyunRecord.FullDomain(),
+			update.AliyunRecord.Type, describeUpdate(update)))
+	}
+
+	sort.Strings(drift.MissingOnProvider)
+	sort.Strings(drift.MissingInDB)
+	sort.Strings(drift.Mismatched)
+	return drift
+}
+
+// formatDrift 输出单个域名的差异数量，每类差异最多列出sampleSize条样例，末尾为空行
+func formatDrift(drift *domainDrift, sampleSize int) string {
+	var b strings.Builder
+
+	if drift.Total() == 0 {
+		fmt.Fprintf(&b, "%s: in sync\n\n", drift.Domain)
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "%s: %d discrepancies\n", drift.Domain, drift.Total())
+	writeDriftCategory(&b, "missing on provider", drift.MissingOnProvider, sampleSize)
+	writeDriftCategory(&b, "missing in database", drift.MissingInDB, sampleSize)
+	writeDriftCategory(&b, "mismatched", drift.Mismatched, sampleSize)
+
+	b.WriteString("\n")
+	return b.String()
+}
+
+// writeDriftCategory 输出一类差异的数量和样例，数量为0时不输出
+func writeDriftCategory(b *strings.Builder, name string, lines []string, sampleSize int) {
+	if len(lines) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "  %s: %d\n", name, len(lines))
+	for i, line := range lines {
+		if i >= sampleSize {
+			fmt.Fprintf(b, "    ... and %d more\n", len(lines)-sampleSize)
+			break
+		}
+		fmt.Fprintf(b, "    %s\n", line)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"dns-sync/internal/config"
+	"dns-sync/internal/database"
+	"dns-sync/internal/provider"
+)
+
+// driftFixture 本地同步了1000到1004，服务商上1001的值和1003的TTL被修改、1002被删除、新增了2000
+func driftFixture() (*memStore, *fakeProvider) {
+	domainMapping := testDomain()
+	store := syncedStore(domainMapping, testRecords(5))
+
+	remote := testRecords(5)
+	remote[1].Value = "10.0.9.1"
+	remote[3].TTL = 60
+	remote = append(remote[:2], remote[3:]...)
+	remote = append(remote, testRecord("2000", "new", "CNAME", "lb.example.net"))
+	return store, &fakeProvider{records: remote}
+}
+
+// TestClassifyDrift 已知的漂移按服务商缺失、数据库缺失和字段不一致分类，样例数超出时只显示数量
+func TestClassifyDrift(t *testing.T) {
+	domainMapping := testDomain()
+	store, dnsClient := driftFixture()
+	syncCfg := testSyncConfig()
+	syncCfg.AllowEmpty = true
+
+	changes, _, err := computeSyncChanges(context.Background(), dnsClient, store, domainMapping, syncCfg,
+		&SyncStats{Domain: domainMapping.Domain})
+	if err != nil {
+		t.Fatalf("computeSyncChanges() error = %v", err)
+	}
+	drift := classifyDrift(domainMapping.Domain, changes)
+
+	tests := []struct {
+		name       string
+		sampleSize int
+		want       string
+	}{
+		{
+			name:       "all samples",
+			sampleSize: 5,
+			want: "example.com: 4 discrepancies\n" +
+				"  missing on provider: 1\n" +
+				"    host2.example.com A 10.0.0.2 [1002]\n" +
+				"  missing in database: 1\n" +
+				"    new.example.com CNAME lb.example.net [2000]\n" +
+				"  mismatched: 2\n" +
+				"    host1.example.com A (10.0.0.1 → 10.0.9.1)\n" +
+				"    host3.example.com A (ttl 600 → 60)\n" +
+				"\n",
+		},
+		{
+			name:       "limited samples",
+			sampleSize: 1,
+			want: "example.com: 4 discrepancies\n" +
+				"  missing on provider: 1\n" +
+				"    host2.example.com A 10.0.0.2 [1002]\n" +
+				"  missing in database: 1\n" +
+				"    new.example.com CNAME lb.example.net [2000]\n" +
+				"  mismatched: 2\n" +
+				"    host1.example.com A (10.0.0.1 → 10.0.9.1)\n" +
+				"    ... and 1 more\n" +
+				"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatDrift(drift, tt.sampleSize); got != tt.want {
+				t.Errorf("formatDrift() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if got := formatDrift(classifyDrift("example.net", &database.SyncChanges{}), 5); got != "example.net: in sync\n\n" {
+		t.Errorf("formatDrift() without drift = %q", got)
+	}
+}
+
+// TestRunVerify 差异数超过max_drift时以exitDrift退出，不超过时成功；verify不写入数据库
+func TestRunVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxDrift int
+		want     int
+	}{
+		{name: "over threshold", maxDrift: 3, want: exitDrift},
+		{name: "at threshold", maxDrift: 4, want: exitOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domainMapping := testDomain()
+			store, dnsClient := driftFixture()
+			cfg := &config.Config{Sync: testSyncConfig(), Domains: []config.DomainMapping{domainMapping}}
+			providers := map[string]provider.DNSProvider{domainMapping.ProviderKey(): dnsClient}
+
+			if got := runVerify(context.Background(), cfg, providers, store, 0, tt.maxDrift, 5); got != tt.want {
+				t.Errorf("runVerify() = %d, want %d", got, tt.want)
+			}
+			if store.inserts+store.updates+store.deletes != 0 {
+				t.Errorf("verify wrote to the store: %d inserts, %d updates, %d deletes", store.inserts,
+					store.updates, store.deletes)
+			}
+		})
+	}
+}